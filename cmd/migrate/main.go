@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/config"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/database"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/logger"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/migrate"
+	"github.com/rs/zerolog/log"
+)
+
+const usage = `Usage: migrate [flags] <command>
+
+Commands:
+  up             Apply all pending migrations
+  down [steps]   Roll back the last applied migrations (default 1)
+  status         List migrations and whether they are applied
+  create <name>  Create empty up and down files for a new migration
+
+Flags:
+`
+
+func main() {
+	dir := flag.String("dir", "internal/migrate/sql", "migration source directory used by create")
+	configFile := flag.String("config", "", "YAML or TOML config file (default $CONFIG_FILE)")
+	flag.Usage = func() {
+		fmt.Fprint(flag.CommandLine.Output(), usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() < 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	logger.InitDefault()
+
+	command, args := flag.Arg(0), flag.Args()[1:]
+
+	if command == "create" {
+		if len(args) != 1 {
+			flag.Usage()
+			os.Exit(2)
+		}
+		files, err := migrate.Create(*dir, args[0])
+		if err != nil {
+			log.Fatal().Err(err).Msg("Failed to create migration")
+		}
+		for _, file := range files {
+			fmt.Println(file)
+		}
+		return
+	}
+
+	cfg, err := config.LoadFile(*configFile)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to load configuration")
+	}
+	logger.Init(cfg)
+
+	db, err := database.NewDatabase(cfg)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to connect to database")
+	}
+	defer db.Close()
+
+	migrator, err := migrate.New(db.DB)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to load migrations")
+	}
+
+	ctx := context.Background()
+
+	switch command {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		if err != nil {
+			log.Fatal().Err(err).Msg("Failed to apply migrations")
+		}
+		log.Info().Int("applied", len(applied)).Msg("Database schema is up to date")
+
+	case "down":
+		steps := 1
+		if len(args) > 0 {
+			steps, err = strconv.Atoi(args[0])
+			if err != nil || steps < 1 {
+				log.Fatal().Str("steps", args[0]).Msg("Steps must be a positive number")
+			}
+		}
+		rolledBack, err := migrator.Down(ctx, steps)
+		if err != nil {
+			log.Fatal().Err(err).Msg("Failed to roll back migrations")
+		}
+		log.Info().Int("rolled_back", len(rolledBack)).Msg("Rollback completed")
+
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			log.Fatal().Err(err).Msg("Failed to read migration status")
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+		for _, status := range statuses {
+			appliedAt := "pending"
+			if status.AppliedAt != nil {
+				appliedAt = status.AppliedAt.UTC().Format("2006-01-02 15:04:05")
+			}
+			fmt.Fprintf(w, "%04d\t%s\t%s\n", status.Version, status.Name, appliedAt)
+		}
+		w.Flush()
+
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+}
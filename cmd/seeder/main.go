@@ -18,7 +18,10 @@ import (
 )
 
 func main() {
-	cfg := config.Load()
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to load configuration")
+	}
 
 	setupLogging(cfg)
 	log.Info().Msg("Starting Database Seeder")
@@ -29,7 +32,7 @@ func main() {
 	}
 	log.Info().Msg("Connected to database")
 
-	if err := db.AutoMigrate(); err != nil {
+	if err := db.RunMigrations(context.Background()); err != nil {
 		log.Fatal().Err(err).Msg("Failed to migrate database schemas")
 	}
 	log.Info().Msg("Database migrations completed")
@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"flag"
 	"net/http"
 	"os"
 	"os/signal"
@@ -15,19 +16,37 @@ import (
 )
 
 func main() {
+	configFile := flag.String("config", "", "YAML or TOML config file (default $CONFIG_FILE)")
+	flag.Parse()
+
 	logger.InitDefault()
 
-	cfg := config.Load()
+	cfg, err := config.LoadFile(*configFile)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to load configuration")
+	}
 	logger.Init(cfg)
 
-	srv := server.NewServer(cfg)
+	cfgs := config.NewHolder(cfg, *configFile)
+	cfgs.OnReload(func(cfg *config.Config) { logger.SetLevel(cfg) })
+
+	srv, err := server.NewServer(cfgs)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to create server")
+	}
 
 	serverCtx, serverStopCtx := context.WithCancel(context.Background())
 
 	sig := make(chan os.Signal, 1)
 	signal.Notify(sig, syscall.SIGHUP, syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT)
 	go func() {
-		<-sig
+		// SIGHUP reloads the configuration, any other signal shuts down
+		for s := range sig {
+			if s != syscall.SIGHUP {
+				break
+			}
+			reloadConfig(cfgs)
+		}
 
 		shutdownCtx, cancelShutdown := context.WithTimeout(serverCtx, 30*time.Second)
 		defer cancelShutdown()
@@ -46,16 +65,36 @@ func main() {
 		serverStopCtx()
 	}()
 
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8080"
-	}
-	log.Info().Str("port", port).Msg("Starting server")
+	log.Info().Str("port", cfg.Server.Port).Msg("Starting server")
 
-	err := srv.ListenAndServe()
+	err = srv.ListenAndServe()
 	if err != nil && err != http.ErrServerClosed {
 		log.Fatal().Err(err).Msg("Server failed to start")
 	}
 
 	<-serverCtx.Done()
 }
+
+// reloadConfig applies the configuration on disk and in the environment. An
+// invalid configuration, or one that changes settings needing a restart, is
+// rejected and the running configuration stays in place.
+func reloadConfig(cfgs *config.Holder) {
+	changes, err := cfgs.Reload()
+	if err != nil {
+		log.Error().Err(err).Msg("Configuration reload rejected")
+		return
+	}
+
+	if len(changes) == 0 {
+		log.Info().Msg("Configuration reloaded, nothing changed")
+		return
+	}
+	for _, change := range changes {
+		log.Info().
+			Str("setting", change.Setting).
+			Str("old", change.Old).
+			Str("new", change.New).
+			Msg("Configuration setting changed")
+	}
+	log.Info().Int("changes", len(changes)).Msg("Configuration reloaded")
+}
@@ -0,0 +1,102 @@
+// Package accountmail renders the transactional emails about a user's
+// account, such as the email verification, account unlock and password reset
+// links.
+package accountmail
+
+import (
+	"bytes"
+	"embed"
+	htmltemplate "html/template"
+	"net/url"
+	texttemplate "text/template"
+	"time"
+)
+
+//go:embed templates/*
+var templateFS embed.FS
+
+var funcs = map[string]interface{}{
+	"hours": func(d time.Duration) int {
+		return int(d.Hours())
+	},
+	"minutes": func(d time.Duration) int {
+		return int(d.Minutes())
+	},
+}
+
+var (
+	htmlTemplates = htmltemplate.Must(htmltemplate.New("").Funcs(funcs).ParseFS(templateFS, "templates/*.html.tmpl"))
+	textTemplates = texttemplate.Must(texttemplate.New("").Funcs(funcs).ParseFS(templateFS, "templates/*.txt.tmpl"))
+)
+
+// Rendered is an email ready to be handed to a mailer
+type Rendered struct {
+	Subject string
+	HTML    string
+	Text    string
+}
+
+// VerificationData is everything the verification email renders
+type VerificationData struct {
+	Name     string
+	Link     string
+	Lifetime time.Duration
+}
+
+// UnlockData is everything the account locked email renders
+type UnlockData struct {
+	Name     string
+	Link     string
+	Duration time.Duration
+}
+
+// PasswordResetData is everything the password reset email renders
+type PasswordResetData struct {
+	Name     string
+	Link     string
+	Lifetime time.Duration
+}
+
+// Link adds the token to the verification, unlock or password reset page URL
+func Link(base, token string) (string, error) {
+	u, err := url.Parse(base)
+	if err != nil {
+		return "", err
+	}
+	query := u.Query()
+	query.Set("token", token)
+	u.RawQuery = query.Encode()
+	return u.String(), nil
+}
+
+// Verification renders the email that confirms a user's address
+func Verification(data VerificationData) (*Rendered, error) {
+	return render("Confirm your email address", "verification", data)
+}
+
+// Unlock renders the email that tells a user their account was locked after
+// too many failed logins, with a link to unlock it
+func Unlock(data UnlockData) (*Rendered, error) {
+	return render("Your account has been locked", "unlock", data)
+}
+
+// PasswordReset renders the email with the link to choose a new password
+func PasswordReset(data PasswordResetData) (*Rendered, error) {
+	return render("Reset your password", "password_reset", data)
+}
+
+func render(subject, name string, data interface{}) (*Rendered, error) {
+	var html, text bytes.Buffer
+	if err := htmlTemplates.ExecuteTemplate(&html, name+".html.tmpl", data); err != nil {
+		return nil, err
+	}
+	if err := textTemplates.ExecuteTemplate(&text, name+".txt.tmpl", data); err != nil {
+		return nil, err
+	}
+
+	return &Rendered{
+		Subject: subject,
+		HTML:    html.String(),
+		Text:    text.String(),
+	}, nil
+}
@@ -1,8 +1,10 @@
 package config
 
 import (
+	"errors"
+	"fmt"
+	"io/fs"
 	"os"
-	"strconv"
 	"strings"
 	"time"
 
@@ -10,24 +12,66 @@ import (
 	"github.com/rs/zerolog/log"
 )
 
+// Built-in defaults for secrets. They keep development setups working and are
+// rejected by Validate in production, where AUTH_JWT_SECRET has no default.
+const (
+	DefaultJWTSecret        = "habit_tracking_secret_key"
+	DefaultDBPassword       = "postgres"
+	DefaultMFAEncryptionKey = "Y29uc2lzdGVuY3ktZGV2ZWxvcG1lbnQtbWZhLWtleSE="
+)
+
 type Config struct {
 	Server ServerConfig
 	Logger LoggerConfig
 
 	Database DatabaseConfig
 
-	JWT JWTConfig
-	Auth AuthConfig
+	Auth    AuthConfig
+	OIDC    OIDCConfig
+	MFA     MFAConfig
+	Lockout LockoutConfig
+
+	API  APIConfig
+	CORS CORSConfig
 
-	API APIConfig
+	Features     FeatureConfig
+	Verification VerificationConfig
 
 	Monitoring MonitoringConfig
+
+	Push PushConfig
+	Mail MailConfig
+
+	Digest DigestConfig
+
+	Account AccountConfig
 }
 
 type ServerConfig struct {
 	Port string
 	Env  string
+
+	// Limits on a connection, so that a slow client cannot hold one
+	// forever. WriteTimeout must leave room for the API deadlines, see
+	// APIConfig.Timeout. 0 turns a limit off.
+	ReadHeaderTimeout time.Duration
+	ReadTimeout       time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+
+	// TrustedProxies are the IPs or CIDR ranges of the proxies in front of
+	// the server. X-Forwarded-For and X-Real-IP are only believed when the
+	// request comes from one of them; without any, the client IP is always
+	// the address of the connection.
+	TrustedProxies []string
 }
+
+// AllowsTimeTravel reports whether the server clock may be moved for QA,
+// which is only the case in development and test environments
+func (s ServerConfig) AllowsTimeTravel() bool {
+	return s.Env == "development" || s.Env == "test"
+}
+
 type LoggerConfig struct {
 	Level      string
 	Pretty     bool
@@ -35,124 +79,489 @@ type LoggerConfig struct {
 }
 
 type DatabaseConfig struct {
+	// Driver selects the database, "postgres" or "sqlite"
+	Driver string
+
+	// SQLitePath is the database file, or ":memory:" for an in-memory database
+	SQLitePath string
+
 	Host     string
 	Port     string
 	Name     string
 	User     string
 	Password string
 	SSLMode  string
-}
 
-type JWTConfig struct {
-	Secret string
-	Expiry time.Duration
+	// AutoMigrate applies pending migrations at server start instead of refusing to start
+	AutoMigrate bool
+
+	// Connection pool limits for PostgreSQL; SQLite always uses a single connection
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+
+	// ReconnectMin and ReconnectMax bound the backoff between attempts to
+	// reach the database while it is unavailable
+	ReconnectMin time.Duration
+	ReconnectMax time.Duration
+
+	// ReplicaDSNs are PostgreSQL read replicas that serve the analytics and
+	// list reads; everything else uses the primary
+	ReplicaDSNs []string
+
+	// ReadYourWritesWindow is how long a user's reads stay on the primary
+	// after they change data, so that they do not see replica lag
+	ReadYourWritesWindow time.Duration
 }
 
 type AuthConfig struct {
-	JWTSecret            string
+	JWTSecret string
+	// JWTPreviousSecrets still verify tokens after the secret is rotated,
+	// until the tokens they signed have expired
+	JWTPreviousSecrets []string
+	// JWTKeys are RSA or Ed25519 signing keys given as "kid:path" or
+	// "kid:path@activation", see the jwtkeys package
+	JWTKeys             []string
 	JWTExpiryHours      int
 	PasswordResetExpiry time.Duration
-	TokenIssuer         string
+	// PasswordResetURL is the page the password reset link opens, the token
+	// is added as the token query parameter
+	PasswordResetURL string
+	TokenIssuer      string
+}
+
+// TokenLifetime is how long an access token stays valid
+func (a AuthConfig) TokenLifetime() time.Duration {
+	return time.Duration(a.JWTExpiryHours) * time.Hour
 }
+
 type APIConfig struct {
-	Timeout   time.Duration
-	RateLimit int
+	// Timeout is the deadline of a request, after which it is answered
+	// with 504. Export and import get TransferTimeout instead.
+	Timeout         time.Duration
+	TransferTimeout time.Duration
+
+	// Requests per minute in each token bucket: reads and writes per
+	// authenticated user, and auth endpoint calls per client IP. 0 turns a
+	// budget off.
+	RateLimit       int
+	RateLimitWrites int
+	RateLimitAuth   int
+	// RateLimitStore is "memory" for a single instance or "database" to
+	// share the buckets between replicas
+	RateLimitStore string
 }
-type MonitoringConfig struct {
-	MetricsEnabled bool
-	TracingEnabled bool
+
+// OIDCConfig holds the OpenID Connect providers users can sign in with
+type OIDCConfig struct {
+	Google OIDCProviderConfig
+	Apple  OIDCProviderConfig
 }
 
-func Load() *Config {
-	if err := godotenv.Load(); err != nil {
-		log.Warn().Err(err).Msg("Error loading .env file, using environment variables")
-	}
+// OIDCProviderConfig describes how to verify a provider's ID tokens. A
+// provider is only enabled when at least one client ID is set.
+type OIDCProviderConfig struct {
+	// ClientIDs are the audiences our apps are registered under, e.g. the
+	// iOS, Android and web client IDs
+	ClientIDs []string
+	Issuers   []string
+	JWKSURL   string
+}
 
-	return &Config{
-		Server: ServerConfig{
-			Port: getEnv("PORT", "8080"),
-			Env:  getEnv("ENV", "development"),
-		},
-		Logger: LoggerConfig{
-			Level:      getEnv("LOG_LEVEL", "debug"),
-			Pretty:     getBoolEnv("LOG_PRETTY", true),
-			TimeFormat: time.RFC3339,
-		},
-		Database: DatabaseConfig{
-			Host:     getEnv("DB_HOST", "localhost"),
-			Port:     getEnv("DB_PORT", "5432"),
-			Name:     getEnv("DB_NAME", "consistency_service"),
-			User:     getEnv("DB_USER", "postgres"),
-			Password: getEnv("DB_PASSWORD", "postgres"),
-			SSLMode:  getEnv("DB_SSL_MODE", "disable"),
-		},
-		JWT: JWTConfig{
-			Secret: getEnv("JWT_SECRET", "development_secret"),
-			Expiry: getDurationEnv("JWT_EXPIRY", 24*time.Hour),
-		},
-		Auth: AuthConfig{
-			JWTSecret:            getEnv("AUTH_JWT_SECRET", "habit_tracking_secret_key"),
-			JWTExpiryHours:      getIntEnv("AUTH_JWT_EXPIRY_HOURS", 72),
-			PasswordResetExpiry: getDurationEnv("AUTH_PASSWORD_RESET_EXPIRY", 24*time.Hour),
-			TokenIssuer:         getEnv("AUTH_TOKEN_ISSUER", "habit-tracking-app"),
-		},
-		API: APIConfig{
-			Timeout:   getDurationEnv("API_TIMEOUT", 30*time.Second),
-			RateLimit: getIntEnv("API_RATE_LIMIT", 100),
-		},
-		Monitoring: MonitoringConfig{
-			MetricsEnabled: getBoolEnv("METRICS_ENABLED", false),
-			TracingEnabled: getBoolEnv("TRACING_ENABLED", false),
-		},
+// Enabled reports whether sign-in with the provider is configured
+func (p OIDCProviderConfig) Enabled() bool {
+	return len(p.ClientIDs) > 0
+}
+
+// MFAConfig controls two-factor authentication with authenticator apps
+type MFAConfig struct {
+	// Issuer names the service in authenticator apps
+	Issuer string
+	// EncryptionKey is the base64 encoded 32 byte key TOTP secrets are
+	// encrypted with. Users cannot enable two-factor authentication without it.
+	EncryptionKey string
+	// ChallengeLifetime is how long the second login step may take
+	ChallengeLifetime time.Duration
+}
+
+// Enabled reports whether users can enable two-factor authentication
+func (m MFAConfig) Enabled() bool {
+	return m.EncryptionKey != ""
+}
+
+// Where failed logins are counted, see LockoutConfig.Store
+const (
+	LockoutStoreMemory   = "memory"
+	LockoutStoreDatabase = "database"
+)
+
+// Where rate limit buckets are kept, see APIConfig.RateLimitStore
+const (
+	RateLimitStoreMemory   = "memory"
+	RateLimitStoreDatabase = "database"
+)
+
+// LockoutConfig controls how failed logins are throttled. Failures are
+// counted per account and per client IP and forgotten after Window without
+// a failure.
+type LockoutConfig struct {
+	// Store is "memory" for a single instance or "database" to share the
+	// counts between replicas
+	Store  string
+	Window time.Duration
+
+	// After DelayAfter failures every further attempt has to wait, starting
+	// at Delay and doubling with every failure up to MaxDelay
+	DelayAfter int
+	Delay      time.Duration
+	MaxDelay   time.Duration
+
+	// AccountLimit failures lock the account and IPLimit failures lock the
+	// client IP for Duration
+	AccountLimit int
+	IPLimit      int
+	Duration     time.Duration
+
+	// UnlockURL is the page the unlock link opens, the token is added as the
+	// token query parameter
+	UnlockURL string
+}
+
+// CORSConfig lists the browser origins allowed to call the API, "*" allows any
+type CORSConfig struct {
+	AllowedOrigins []string
+}
+
+// AllowsOrigin reports whether a browser request from origin is allowed
+func (c CORSConfig) AllowsOrigin(origin string) bool {
+	for _, allowed := range c.AllowedOrigins {
+		if allowed == "*" || strings.EqualFold(allowed, origin) {
+			return true
+		}
 	}
+	return false
 }
 
-func getEnv(key, defaultValue string) string {
-	value := os.Getenv(key)
-	if value == "" {
-		return defaultValue
+// AllowsCredentials reports whether browsers may send cookies and
+// authorization headers cross-origin. It is refused while any origin is
+// allowed, which would otherwise let every site make credentialed requests.
+func (c CORSConfig) AllowsCredentials() bool {
+	for _, allowed := range c.AllowedOrigins {
+		if allowed == "*" {
+			return false
+		}
 	}
-	return strings.TrimSpace(value)
+	return true
+}
+
+// FeatureConfig switches optional features on and off without a deploy
+type FeatureConfig struct {
+	Registration bool
+	Import       bool
+	Export       bool
 }
 
-func getBoolEnv(key string, defaultValue bool) bool {
-	value := os.Getenv(key)
-	if value == "" {
-		return defaultValue
+// Features an unverified account can be restricted from, see
+// VerificationConfig.RequiredFor
+const (
+	VerifiedExport  = "export"
+	VerifiedImport  = "import"
+	VerifiedDevices = "devices"
+	VerifiedDigest  = "digest"
+)
+
+// VerifiedFeatures lists every feature that can require a verified email
+var VerifiedFeatures = []string{VerifiedExport, VerifiedImport, VerifiedDevices, VerifiedDigest}
+
+// VerificationConfig controls email verification of new accounts
+type VerificationConfig struct {
+	// LinkURL is the page the verification link opens, the token is added
+	// as the token query parameter
+	LinkURL       string
+	TokenLifetime time.Duration
+
+	// Verification emails are throttled per account
+	ResendInterval time.Duration
+	DailyLimit     int
+
+	// RequiredFor lists the features unverified accounts cannot use
+	RequiredFor []string
+}
+
+// Requires reports whether feature is restricted to verified accounts
+func (v VerificationConfig) Requires(feature string) bool {
+	for _, required := range v.RequiredFor {
+		if required == feature {
+			return true
+		}
 	}
+	return false
+}
+
+type MonitoringConfig struct {
+	// MetricsEnabled serves Prometheus metrics at /metrics on AdminPort,
+	// which should not be reachable from the internet
+	MetricsEnabled bool
+	AdminPort      string
+
+	// TracingEnabled exports OpenTelemetry traces with TracingExporter:
+	// "otlp" sends them to the collector at TracingEndpoint over HTTP,
+	// "stdout" writes them as JSON to TracingFile, or to standard output
+	// when no file is set. TracingSampleRatio is the share of new traces
+	// that are kept; incoming traces keep the caller's decision.
+	TracingEnabled     bool
+	TracingExporter    string
+	TracingEndpoint    string
+	TracingFile        string
+	TracingSampleRatio float64
+}
+
+// Where traces are sent, see MonitoringConfig.TracingExporter
+const (
+	TracingExporterOTLP   = "otlp"
+	TracingExporterStdout = "stdout"
+)
+
+// PushConfig holds the credentials for the push notification providers.
+// A provider is only enabled when its credentials are set.
+type PushConfig struct {
+	Timeout time.Duration
+
+	FCMEndpoint  string
+	FCMProjectID string
+	// FCMCredentials is the JSON key of a service account allowed to send
+	// messages; the project defaults to the one of the key
+	FCMCredentials string
+
+	APNsEndpoint   string
+	APNsKeyID      string
+	APNsTeamID     string
+	APNsPrivateKey string
+	APNsTopic      string
+}
+
+// MailConfig selects and configures the outgoing mail transport
+type MailConfig struct {
+	Driver       string // "log" or "smtp"
+	From         string
+	SMTPHost     string
+	SMTPPort     string
+	SMTPUser     string
+	SMTPPassword string
+}
+
+// DigestConfig controls the weekly email digest job
+type DigestConfig struct {
+	Enabled  bool
+	Interval time.Duration
+}
+
+// AccountConfig controls account deletion. Deleted accounts are locked
+// immediately and purged once the grace period has passed.
+type AccountConfig struct {
+	DeletionGracePeriod time.Duration
+	PurgeInterval       time.Duration
+}
+
+// Load builds the configuration from, in increasing order of precedence, the
+// built-in defaults, the config file named by CONFIG_FILE, the .env file and
+// the process environment, and validates it
+func Load() (*Config, error) {
+	return LoadFile("")
+}
 
-	parsedValue, err := strconv.ParseBool(strings.TrimSpace(value))
+// LoadFile is Load with an explicit config file. An empty path falls back to
+// CONFIG_FILE, and no config file is read if that is not set either.
+//
+// The .env file is read on every call rather than copied into the process
+// environment, so that reloading picks up edits to it.
+func LoadFile(path string) (*Config, error) {
+	dotenv, err := godotenv.Read()
 	if err != nil {
-		return defaultValue
+		if !errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("read .env: %w", err)
+		}
+		log.Debug().Msg("No .env file found, using environment variables")
 	}
 
-	return parsedValue
-}
+	if path == "" {
+		path = strings.TrimSpace(os.Getenv("CONFIG_FILE"))
+	}
+	if path == "" {
+		path = strings.TrimSpace(dotenv["CONFIG_FILE"])
+	}
 
-func getIntEnv(key string, defaultValue int) int {
-	value := os.Getenv(key)
-	if value == "" {
-		return defaultValue
+	var file map[string]string
+	if path != "" {
+		if file, err = readFile(path); err != nil {
+			return nil, err
+		}
 	}
 
-	parsedValue, err := strconv.Atoi(strings.TrimSpace(value))
-	if err != nil {
-		return defaultValue
+	src := newSource(dotenv, file)
+	cfg := build(src)
+
+	errs := append(src.errs, src.unknownKeys()...)
+	if err := cfg.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("invalid configuration:\n%w", errors.Join(errs...))
 	}
 
-	return parsedValue
+	return cfg, nil
 }
 
-func getDurationEnv(key string, defaultValue time.Duration) time.Duration {
-	value := os.Getenv(key)
-	if value == "" {
-		return defaultValue
-	}
+// build reads every setting from the source, using the built-in defaults for
+// settings that are not set
+func build(src *source) *Config {
+	env := src.string("ENV", "development")
 
-	parsedValue, err := time.ParseDuration(strings.TrimSpace(value))
-	if err != nil {
-		return defaultValue
+	// Production has no default HMAC secret, so asymmetric keys can be used
+	// alone, and no default MFA key
+	jwtSecret, mfaKey := DefaultJWTSecret, DefaultMFAEncryptionKey
+	if env == "production" {
+		jwtSecret, mfaKey = "", ""
 	}
 
-	return parsedValue
+	return &Config{
+		Server: ServerConfig{
+			Port: src.string("PORT", "8080"),
+			Env:  env,
+
+			ReadHeaderTimeout: src.duration("SERVER_READ_HEADER_TIMEOUT", 5*time.Second),
+			ReadTimeout:       src.duration("SERVER_READ_TIMEOUT", time.Minute),
+			WriteTimeout:      src.duration("SERVER_WRITE_TIMEOUT", 150*time.Second),
+			IdleTimeout:       src.duration("SERVER_IDLE_TIMEOUT", 2*time.Minute),
+
+			TrustedProxies: src.list("SERVER_TRUSTED_PROXIES", nil),
+		},
+		Logger: LoggerConfig{
+			Level:      src.string("LOG_LEVEL", "debug"),
+			Pretty:     src.bool("LOG_PRETTY", true),
+			TimeFormat: time.RFC3339,
+		},
+		Database: DatabaseConfig{
+			Driver:     src.string("DB_DRIVER", "postgres"),
+			SQLitePath: src.string("DB_SQLITE_PATH", "consistency.db"),
+
+			Host:     src.string("DB_HOST", "localhost"),
+			Port:     src.string("DB_PORT", "5432"),
+			Name:     src.string("DB_NAME", "consistency_service"),
+			User:     src.string("DB_USER", "postgres"),
+			Password: src.string("DB_PASSWORD", DefaultDBPassword),
+			SSLMode:  src.string("DB_SSL_MODE", "disable"),
+
+			AutoMigrate: src.bool("DB_AUTO_MIGRATE", false),
+
+			MaxOpenConns:    src.int("DB_MAX_OPEN_CONNS", 100),
+			MaxIdleConns:    src.int("DB_MAX_IDLE_CONNS", 10),
+			ConnMaxLifetime: src.duration("DB_CONN_MAX_LIFETIME", time.Hour),
+			ConnMaxIdleTime: src.duration("DB_CONN_MAX_IDLE_TIME", 30*time.Minute),
+
+			ReconnectMin: src.duration("DB_RECONNECT_MIN", time.Second),
+			ReconnectMax: src.duration("DB_RECONNECT_MAX", 30*time.Second),
+
+			ReplicaDSNs:          src.list("DB_REPLICA_DSNS", nil),
+			ReadYourWritesWindow: src.duration("DB_READ_YOUR_WRITES_WINDOW", 5*time.Second),
+		},
+		Auth: AuthConfig{
+			JWTSecret:           src.string("AUTH_JWT_SECRET", jwtSecret),
+			JWTPreviousSecrets:  src.list("AUTH_JWT_PREVIOUS_SECRETS", nil),
+			JWTKeys:             src.list("AUTH_JWT_KEYS", nil),
+			JWTExpiryHours:      src.int("AUTH_JWT_EXPIRY_HOURS", 72),
+			PasswordResetExpiry: src.duration("AUTH_PASSWORD_RESET_EXPIRY", 24*time.Hour),
+			PasswordResetURL:    src.string("AUTH_PASSWORD_RESET_URL", "http://localhost:8080/reset-password"),
+			TokenIssuer:         src.string("AUTH_TOKEN_ISSUER", "habit-tracking-app"),
+		},
+		OIDC: OIDCConfig{
+			Google: OIDCProviderConfig{
+				ClientIDs: src.list("OIDC_GOOGLE_CLIENT_IDS", nil),
+				Issuers:   src.list("OIDC_GOOGLE_ISSUERS", []string{"https://accounts.google.com", "accounts.google.com"}),
+				JWKSURL:   src.string("OIDC_GOOGLE_JWKS_URL", "https://www.googleapis.com/oauth2/v3/certs"),
+			},
+			Apple: OIDCProviderConfig{
+				ClientIDs: src.list("OIDC_APPLE_CLIENT_IDS", nil),
+				Issuers:   src.list("OIDC_APPLE_ISSUERS", []string{"https://appleid.apple.com"}),
+				JWKSURL:   src.string("OIDC_APPLE_JWKS_URL", "https://appleid.apple.com/auth/keys"),
+			},
+		},
+		MFA: MFAConfig{
+			Issuer:            src.string("MFA_ISSUER", "Consistency"),
+			EncryptionKey:     src.string("MFA_ENCRYPTION_KEY", mfaKey),
+			ChallengeLifetime: src.duration("MFA_CHALLENGE_LIFETIME", 5*time.Minute),
+		},
+		Lockout: LockoutConfig{
+			Store:        src.string("LOGIN_LOCKOUT_STORE", LockoutStoreMemory),
+			Window:       src.duration("LOGIN_FAILURE_WINDOW", 15*time.Minute),
+			DelayAfter:   src.int("LOGIN_DELAY_AFTER", 3),
+			Delay:        src.duration("LOGIN_DELAY", time.Second),
+			MaxDelay:     src.duration("LOGIN_MAX_DELAY", time.Minute),
+			AccountLimit: src.int("LOGIN_ACCOUNT_LIMIT", 10),
+			IPLimit:      src.int("LOGIN_IP_LIMIT", 100),
+			Duration:     src.duration("LOGIN_LOCKOUT_DURATION", 30*time.Minute),
+			UnlockURL:    src.string("LOGIN_UNLOCK_URL", "http://localhost:8080/unlock-account"),
+		},
+		API: APIConfig{
+			Timeout:         src.duration("API_TIMEOUT", 30*time.Second),
+			TransferTimeout: src.duration("API_TRANSFER_TIMEOUT", 2*time.Minute),
+			RateLimit:       src.int("API_RATE_LIMIT", 100),
+			RateLimitWrites: src.int("API_RATE_LIMIT_WRITES", 30),
+			RateLimitAuth:   src.int("API_RATE_LIMIT_AUTH", 20),
+			RateLimitStore:  src.string("API_RATE_LIMIT_STORE", RateLimitStoreMemory),
+		},
+		CORS: CORSConfig{
+			AllowedOrigins: src.list("CORS_ALLOWED_ORIGINS", []string{"*"}),
+		},
+		Features: FeatureConfig{
+			Registration: src.bool("FEATURE_REGISTRATION", true),
+			Import:       src.bool("FEATURE_IMPORT", true),
+			Export:       src.bool("FEATURE_EXPORT", true),
+		},
+		Verification: VerificationConfig{
+			LinkURL:        src.string("EMAIL_VERIFICATION_URL", "http://localhost:8080/verify-email"),
+			TokenLifetime:  src.duration("EMAIL_VERIFICATION_TOKEN_LIFETIME", 48*time.Hour),
+			ResendInterval: src.duration("EMAIL_VERIFICATION_RESEND_INTERVAL", time.Minute),
+			DailyLimit:     src.int("EMAIL_VERIFICATION_DAILY_LIMIT", 5),
+			RequiredFor:    src.list("EMAIL_VERIFICATION_REQUIRED_FOR", []string{VerifiedExport, VerifiedDigest}),
+		},
+		Monitoring: MonitoringConfig{
+			MetricsEnabled: src.bool("METRICS_ENABLED", false),
+			AdminPort:      src.string("ADMIN_PORT", "9090"),
+			TracingEnabled: src.bool("TRACING_ENABLED", false),
+
+			TracingExporter:    src.string("TRACING_EXPORTER", TracingExporterOTLP),
+			TracingEndpoint:    src.string("TRACING_OTLP_ENDPOINT", "http://localhost:4318"),
+			TracingFile:        src.string("TRACING_FILE", ""),
+			TracingSampleRatio: src.float("TRACING_SAMPLE_RATIO", 1),
+		},
+		Push: PushConfig{
+			Timeout:        src.duration("PUSH_TIMEOUT", 10*time.Second),
+			FCMEndpoint:    src.string("PUSH_FCM_ENDPOINT", "https://fcm.googleapis.com"),
+			FCMProjectID:   src.string("PUSH_FCM_PROJECT_ID", ""),
+			FCMCredentials: src.string("PUSH_FCM_CREDENTIALS", ""),
+			APNsEndpoint:   src.string("PUSH_APNS_ENDPOINT", "https://api.push.apple.com"),
+			APNsKeyID:      src.string("PUSH_APNS_KEY_ID", ""),
+			APNsTeamID:     src.string("PUSH_APNS_TEAM_ID", ""),
+			APNsPrivateKey: src.string("PUSH_APNS_PRIVATE_KEY", ""),
+			APNsTopic:      src.string("PUSH_APNS_TOPIC", ""),
+		},
+		Mail: MailConfig{
+			Driver:       src.string("MAIL_DRIVER", "log"),
+			From:         src.string("MAIL_FROM", "Consistency <no-reply@consistency.app>"),
+			SMTPHost:     src.string("MAIL_SMTP_HOST", "localhost"),
+			SMTPPort:     src.string("MAIL_SMTP_PORT", "587"),
+			SMTPUser:     src.string("MAIL_SMTP_USER", ""),
+			SMTPPassword: src.string("MAIL_SMTP_PASSWORD", ""),
+		},
+		Digest: DigestConfig{
+			Enabled:  src.bool("DIGEST_ENABLED", true),
+			Interval: src.duration("DIGEST_INTERVAL", time.Hour),
+		},
+		Account: AccountConfig{
+			DeletionGracePeriod: src.duration("ACCOUNT_DELETION_GRACE_PERIOD", 30*24*time.Hour),
+			PurgeInterval:       src.duration("ACCOUNT_PURGE_INTERVAL", time.Hour),
+		},
+	}
 }
@@ -0,0 +1,163 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeConfigFile(t *testing.T, name, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("write config file: %v", err)
+	}
+	return path
+}
+
+func TestLoadFileLayersEnvironmentOverFile(t *testing.T) {
+	path := writeConfigFile(t, "config.yaml", `
+port: 9090
+log_level: info
+db:
+  driver: sqlite
+  sqlite_path: ":memory:"
+digest:
+  interval: 2h
+`)
+	t.Setenv("LOG_LEVEL", "warn")
+
+	cfg, err := LoadFile(path)
+	if err != nil {
+		t.Fatalf("LoadFile: %v", err)
+	}
+
+	if cfg.Server.Port != "9090" {
+		t.Errorf("port = %q, want the file value 9090", cfg.Server.Port)
+	}
+	if cfg.Logger.Level != "warn" {
+		t.Errorf("log level = %q, want the environment value warn", cfg.Logger.Level)
+	}
+	if cfg.Database.Driver != "sqlite" || cfg.Database.SQLitePath != ":memory:" {
+		t.Errorf("database = %q %q, want the nested file values", cfg.Database.Driver, cfg.Database.SQLitePath)
+	}
+	if cfg.Digest.Interval != 2*time.Hour {
+		t.Errorf("digest interval = %v, want 2h", cfg.Digest.Interval)
+	}
+	if cfg.Auth.JWTExpiryHours != 72 {
+		t.Errorf("jwt expiry = %d, want the default 72", cfg.Auth.JWTExpiryHours)
+	}
+}
+
+func TestLoadFileReadsTOML(t *testing.T) {
+	path := writeConfigFile(t, "config.toml", `
+[api]
+rate_limit = 50
+
+[account]
+deletion_grace_period = "72h"
+`)
+
+	cfg, err := LoadFile(path)
+	if err != nil {
+		t.Fatalf("LoadFile: %v", err)
+	}
+
+	if cfg.API.RateLimit != 50 {
+		t.Errorf("rate limit = %d, want 50", cfg.API.RateLimit)
+	}
+	if cfg.Account.DeletionGracePeriod != 72*time.Hour {
+		t.Errorf("grace period = %v, want 72h", cfg.Account.DeletionGracePeriod)
+	}
+}
+
+func TestLoadFileRejectsInvalidSettings(t *testing.T) {
+	path := writeConfigFile(t, "config.yaml", `
+api_timout: 10s
+digest_interval: weekly
+`)
+
+	_, err := LoadFile(path)
+	if err == nil {
+		t.Fatal("LoadFile succeeded, want an error")
+	}
+	for _, want := range []string{"API_TIMOUT: unknown setting", "DIGEST_INTERVAL: \"weekly\" is not a duration"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not mention %q", err, want)
+		}
+	}
+}
+
+func TestValidateRequiresSecretsInProduction(t *testing.T) {
+	t.Setenv("ENV", "production")
+
+	_, err := LoadFile("")
+	if err == nil {
+		t.Fatal("LoadFile succeeded with development secrets in production")
+	}
+	for _, want := range []string{"AUTH_JWT_SECRET", "DB_PASSWORD"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not mention %q", err, want)
+		}
+	}
+
+	t.Setenv("AUTH_JWT_SECRET", strings.Repeat("s", minProductionSecretLength))
+	t.Setenv("DB_PASSWORD", "a-real-password")
+	if _, err := LoadFile(""); err != nil {
+		t.Fatalf("LoadFile with production secrets: %v", err)
+	}
+}
+
+func TestDiffRedactsSecrets(t *testing.T) {
+	before := &Config{Auth: AuthConfig{JWTSecret: "old-secret"}, Logger: LoggerConfig{Level: "info"}}
+	after := &Config{Auth: AuthConfig{JWTSecret: "new-secret"}, Logger: LoggerConfig{Level: "debug"}}
+
+	changes := Diff(before, after)
+	want := []Change{
+		{Setting: "Logger.Level", Old: "info", New: "debug"},
+		{Setting: "Auth.JWTSecret", Old: "[redacted]", New: "[redacted]"},
+	}
+	if len(changes) != len(want) {
+		t.Fatalf("Diff = %+v, want %+v", changes, want)
+	}
+	for i := range want {
+		if changes[i] != want[i] {
+			t.Errorf("change %d = %+v, want %+v", i, changes[i], want[i])
+		}
+	}
+}
+
+func TestProductionAcceptsAsymmetricKeysWithoutSecret(t *testing.T) {
+	t.Setenv("ENV", "production")
+	t.Setenv("DB_PASSWORD", "a-real-password")
+	t.Setenv("AUTH_JWT_KEYS", "2024-06:keys/2024-06.pem")
+
+	cfg, err := LoadFile("")
+	if err != nil {
+		t.Fatalf("LoadFile: %v", err)
+	}
+	if cfg.Auth.JWTSecret != "" {
+		t.Errorf("JWT secret = %q, want no default in production", cfg.Auth.JWTSecret)
+	}
+}
+
+func TestValidateReportsEachNegativeTimeout(t *testing.T) {
+	t.Setenv("SERVER_READ_TIMEOUT", "-1s")
+	t.Setenv("SERVER_IDLE_TIMEOUT", "-1s")
+
+	_, err := LoadFile("")
+	if err == nil {
+		t.Fatal("LoadFile succeeded with negative timeouts")
+	}
+	for _, want := range []string{"SERVER_READ_TIMEOUT: must not be negative", "SERVER_IDLE_TIMEOUT: must not be negative"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not mention %q", err, want)
+		}
+	}
+	if strings.Contains(err.Error(), "SERVER_WRITE_TIMEOUT") {
+		t.Errorf("error %q mentions a valid timeout", err)
+	}
+}
@@ -0,0 +1,172 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// reloadable lists the settings that may change while the server is running.
+// Everything else is read once at startup, so changing it needs a restart.
+var reloadable = map[string]bool{
+	"Logger.Level":             true,
+	"API.Timeout":              true,
+	"API.TransferTimeout":      true,
+	"API.RateLimit":            true,
+	"API.RateLimitWrites":      true,
+	"API.RateLimitAuth":        true,
+	"CORS.AllowedOrigins":      true,
+	"Features.Registration":    true,
+	"Features.Import":          true,
+	"Features.Export":          true,
+	"Verification.RequiredFor": true,
+	"Lockout.Window":           true,
+	"Lockout.DelayAfter":       true,
+	"Lockout.Delay":            true,
+	"Lockout.MaxDelay":         true,
+	"Lockout.AccountLimit":     true,
+	"Lockout.IPLimit":          true,
+	"Lockout.Duration":         true,
+	"Auth.JWTSecret":           true,
+	"Auth.JWTPreviousSecrets":  true,
+	"Auth.JWTKeys":             true,
+}
+
+// Change describes a setting that differs between two configurations.
+// Secret values are redacted.
+type Change struct {
+	Setting string
+	Old     string
+	New     string
+}
+
+// Holder gives the middleware and services the current configuration and
+// swaps it atomically on reload. Readers call Get for every use instead of
+// keeping the returned config, so they see reloads.
+type Holder struct {
+	path     string
+	current  atomic.Pointer[Config]
+	mu       sync.Mutex
+	checks   []func(*Config) error
+	onReload []func(*Config)
+}
+
+// NewHolder creates a holder for cfg. Reload reads path, or CONFIG_FILE when
+// path is empty, just as cfg was loaded.
+func NewHolder(cfg *Config, path string) *Holder {
+	h := &Holder{path: path}
+	h.current.Store(cfg)
+	return h
+}
+
+// Get returns the current configuration, which must not be modified
+func (h *Holder) Get() *Config {
+	return h.current.Load()
+}
+
+// Check registers fn to vet every configuration before it is applied, for
+// settings that can only be checked by the code using them. An error rejects
+// the reload.
+func (h *Holder) Check(fn func(*Config) error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.checks = append(h.checks, fn)
+}
+
+// OnReload registers fn to be called with the new configuration after every
+// successful reload
+func (h *Holder) OnReload(fn func(*Config)) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.onReload = append(h.onReload, fn)
+}
+
+// Reload loads and validates the configuration again and applies it
+func (h *Holder) Reload() ([]Change, error) {
+	next, err := LoadFile(h.path)
+	if err != nil {
+		return nil, err
+	}
+	return h.Apply(next)
+}
+
+// Apply replaces the current configuration with next and returns what
+// changed. It is rejected, leaving the current configuration in place, if a
+// setting that needs a restart has changed.
+func (h *Holder) Apply(next *Config) ([]Change, error) {
+	if err := next.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid configuration:\n%w", err)
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	changes := Diff(h.Get(), next)
+
+	var errs []error
+	for _, change := range changes {
+		if !reloadable[change.Setting] {
+			errs = append(errs, fmt.Errorf("%s: cannot be changed without a restart", change.Setting))
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	for _, check := range h.checks {
+		if err := check(next); err != nil {
+			return nil, err
+		}
+	}
+
+	h.current.Store(next)
+	for _, fn := range h.onReload {
+		fn(next)
+	}
+
+	return changes, nil
+}
+
+// Diff lists the settings that differ between before and after, in
+// declaration order
+func Diff(before, after *Config) []Change {
+	var changes []Change
+	diffStruct("", reflect.ValueOf(*before), reflect.ValueOf(*after), &changes)
+	return changes
+}
+
+func diffStruct(prefix string, before, after reflect.Value, changes *[]Change) {
+	for i := 0; i < before.NumField(); i++ {
+		field := before.Type().Field(i)
+		name := prefix + field.Name
+
+		if field.Type.Kind() == reflect.Struct {
+			diffStruct(name+".", before.Field(i), after.Field(i), changes)
+			continue
+		}
+
+		oldValue, newValue := before.Field(i).Interface(), after.Field(i).Interface()
+		if reflect.DeepEqual(oldValue, newValue) {
+			continue
+		}
+
+		change := Change{Setting: name, Old: fmt.Sprint(oldValue), New: fmt.Sprint(newValue)}
+		if isSecret(field.Name) {
+			change.Old, change.New = "[redacted]", "[redacted]"
+		}
+		*changes = append(*changes, change)
+	}
+}
+
+// isSecret reports whether a setting holds a credential that must not be logged
+func isSecret(field string) bool {
+	for _, marker := range []string{"Secret", "Password", "AccessToken", "PrivateKey", "EncryptionKey", "Credentials", "DSN"} {
+		if strings.Contains(field, marker) {
+			return true
+		}
+	}
+	return false
+}
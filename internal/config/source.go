@@ -0,0 +1,141 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// source looks settings up by their environment variable name, first in the
+// environment, then in the .env file and then in the config file. Values that
+// cannot be parsed are collected as errors instead of silently falling back
+// to the default.
+type source struct {
+	dotenv map[string]string
+	file   map[string]string
+	used   map[string]bool
+	errs   []error
+}
+
+func newSource(dotenv, file map[string]string) *source {
+	return &source{dotenv: dotenv, file: file, used: make(map[string]bool)}
+}
+
+// lookup returns the trimmed value of a setting and whether it was set
+func (s *source) lookup(key string) (string, bool) {
+	s.used[key] = true
+	if value := strings.TrimSpace(os.Getenv(key)); value != "" {
+		return value, true
+	}
+	if value := strings.TrimSpace(s.dotenv[key]); value != "" {
+		return value, true
+	}
+	if value := s.file[key]; value != "" {
+		return value, true
+	}
+	return "", false
+}
+
+func (s *source) string(key, defaultValue string) string {
+	if value, ok := s.lookup(key); ok {
+		return value
+	}
+	return defaultValue
+}
+
+// list reads a comma-separated setting, dropping empty items
+func (s *source) list(key string, defaultValue []string) []string {
+	value, ok := s.lookup(key)
+	if !ok {
+		return defaultValue
+	}
+
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func (s *source) bool(key string, defaultValue bool) bool {
+	value, ok := s.lookup(key)
+	if !ok {
+		return defaultValue
+	}
+
+	parsedValue, err := strconv.ParseBool(value)
+	if err != nil {
+		s.errs = append(s.errs, fmt.Errorf("%s: %q is not a boolean", key, value))
+		return defaultValue
+	}
+
+	return parsedValue
+}
+
+func (s *source) int(key string, defaultValue int) int {
+	value, ok := s.lookup(key)
+	if !ok {
+		return defaultValue
+	}
+
+	parsedValue, err := strconv.Atoi(value)
+	if err != nil {
+		s.errs = append(s.errs, fmt.Errorf("%s: %q is not an integer", key, value))
+		return defaultValue
+	}
+
+	return parsedValue
+}
+
+func (s *source) float(key string, defaultValue float64) float64 {
+	value, ok := s.lookup(key)
+	if !ok {
+		return defaultValue
+	}
+
+	parsedValue, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		s.errs = append(s.errs, fmt.Errorf("%s: %q is not a number", key, value))
+		return defaultValue
+	}
+
+	return parsedValue
+}
+
+func (s *source) duration(key string, defaultValue time.Duration) time.Duration {
+	value, ok := s.lookup(key)
+	if !ok {
+		return defaultValue
+	}
+
+	parsedValue, err := time.ParseDuration(value)
+	if err != nil {
+		s.errs = append(s.errs, fmt.Errorf("%s: %q is not a duration such as 30s or 24h", key, value))
+		return defaultValue
+	}
+
+	return parsedValue
+}
+
+// unknownKeys reports config file settings that no part of the config reads,
+// which are almost always typos
+func (s *source) unknownKeys() []error {
+	var unknown []string
+	for key := range s.file {
+		if !s.used[key] {
+			unknown = append(unknown, key)
+		}
+	}
+	sort.Strings(unknown)
+
+	errs := make([]error, len(unknown))
+	for i, key := range unknown {
+		errs[i] = fmt.Errorf("%s: unknown setting in config file", key)
+	}
+	return errs
+}
@@ -0,0 +1,273 @@
+package config
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/rs/zerolog"
+)
+
+// minProductionSecretLength is the shortest JWT signing secret accepted in
+// production, 256 bits for HS256
+const minProductionSecretLength = 32
+
+// Validate checks that the configuration is usable and, in production, that
+// no secret is missing or left at its development default. Every problem is
+// reported, not just the first.
+func (c *Config) Validate() error {
+	var errs []error
+	fail := func(format string, args ...interface{}) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	switch c.Server.Env {
+	case "development", "test", "production":
+	default:
+		fail("ENV: %q must be development, test or production", c.Server.Env)
+	}
+	if port, err := strconv.Atoi(c.Server.Port); err != nil || port < 1 || port > 65535 {
+		fail("PORT: %q is not a valid port", c.Server.Port)
+	}
+	if c.Monitoring.MetricsEnabled {
+		if port, err := strconv.Atoi(c.Monitoring.AdminPort); err != nil || port < 1 || port > 65535 {
+			fail("ADMIN_PORT: %q is not a valid port", c.Monitoring.AdminPort)
+		} else if c.Monitoring.AdminPort == c.Server.Port {
+			fail("ADMIN_PORT: must differ from PORT so that metrics are not served publicly")
+		}
+	}
+	if c.Monitoring.TracingEnabled {
+		switch c.Monitoring.TracingExporter {
+		case TracingExporterOTLP:
+			if u, err := url.Parse(c.Monitoring.TracingEndpoint); err != nil || u.Scheme == "" || u.Host == "" {
+				fail("TRACING_OTLP_ENDPOINT: %q is not a URL such as http://localhost:4318", c.Monitoring.TracingEndpoint)
+			}
+		case TracingExporterStdout:
+		default:
+			fail("TRACING_EXPORTER: %q must be %s or %s", c.Monitoring.TracingExporter, TracingExporterOTLP, TracingExporterStdout)
+		}
+		if r := c.Monitoring.TracingSampleRatio; r < 0 || r > 1 {
+			fail("TRACING_SAMPLE_RATIO: %v must be between 0 and 1", r)
+		}
+	}
+	if _, err := zerolog.ParseLevel(c.Logger.Level); err != nil {
+		fail("LOG_LEVEL: %q is not a log level", c.Logger.Level)
+	}
+
+	switch c.Database.Driver {
+	case "postgres", "sqlite":
+	default:
+		fail("DB_DRIVER: %q must be postgres or sqlite", c.Database.Driver)
+	}
+	if c.Database.MaxOpenConns <= 0 {
+		fail("DB_MAX_OPEN_CONNS: must be positive")
+	}
+	if c.Database.MaxIdleConns < 0 || c.Database.MaxIdleConns > c.Database.MaxOpenConns {
+		fail("DB_MAX_IDLE_CONNS: must be between 0 and DB_MAX_OPEN_CONNS")
+	}
+	if c.Database.ConnMaxLifetime < 0 {
+		fail("DB_CONN_MAX_LIFETIME: must not be negative")
+	}
+	if c.Database.ConnMaxIdleTime < 0 {
+		fail("DB_CONN_MAX_IDLE_TIME: must not be negative")
+	}
+	if c.Database.ReconnectMin <= 0 || c.Database.ReconnectMax < c.Database.ReconnectMin {
+		fail("DB_RECONNECT_MIN: must be positive and at most DB_RECONNECT_MAX")
+	}
+	if len(c.Database.ReplicaDSNs) > 0 && c.Database.Driver != "postgres" {
+		fail("DB_REPLICA_DSNS: read replicas are only supported with postgres")
+	}
+	if c.Database.ReadYourWritesWindow < 0 {
+		fail("DB_READ_YOUR_WRITES_WINDOW: must not be negative")
+	}
+
+	if c.Auth.JWTExpiryHours <= 0 {
+		fail("AUTH_JWT_EXPIRY_HOURS: must be positive")
+	}
+	if c.Auth.PasswordResetExpiry <= 0 {
+		fail("AUTH_PASSWORD_RESET_EXPIRY: must be positive")
+	}
+	if !strings.HasPrefix(c.Auth.PasswordResetURL, "https://") && !strings.HasPrefix(c.Auth.PasswordResetURL, "http://") {
+		fail("AUTH_PASSWORD_RESET_URL: %q is not an http(s) URL", c.Auth.PasswordResetURL)
+	}
+	if c.API.Timeout <= 0 {
+		fail("API_TIMEOUT: must be positive")
+	}
+	if c.API.TransferTimeout <= 0 {
+		fail("API_TRANSFER_TIMEOUT: must be positive")
+	}
+	if c.Server.ReadHeaderTimeout < 0 {
+		fail("SERVER_READ_HEADER_TIMEOUT: must not be negative")
+	}
+	if c.Server.ReadTimeout < 0 {
+		fail("SERVER_READ_TIMEOUT: must not be negative")
+	}
+	if c.Server.WriteTimeout < 0 {
+		fail("SERVER_WRITE_TIMEOUT: must not be negative")
+	}
+	if c.Server.IdleTimeout < 0 {
+		fail("SERVER_IDLE_TIMEOUT: must not be negative")
+	}
+	for _, proxy := range c.Server.TrustedProxies {
+		if _, _, err := net.ParseCIDR(proxy); err != nil && net.ParseIP(proxy) == nil {
+			fail("SERVER_TRUSTED_PROXIES: %q is not an IP address or CIDR range", proxy)
+		}
+	}
+	// The connection must outlive the request deadline, or the 504 cannot
+	// be written
+	if w := c.Server.WriteTimeout; w > 0 && (w <= c.API.Timeout || w <= c.API.TransferTimeout) {
+		fail("SERVER_WRITE_TIMEOUT: %s must be longer than API_TIMEOUT and API_TRANSFER_TIMEOUT", w)
+	}
+	if c.API.RateLimit < 0 {
+		fail("API_RATE_LIMIT: must not be negative")
+	}
+	if c.API.RateLimitWrites < 0 {
+		fail("API_RATE_LIMIT_WRITES: must not be negative")
+	}
+	if c.API.RateLimitAuth < 0 {
+		fail("API_RATE_LIMIT_AUTH: must not be negative")
+	}
+	switch c.API.RateLimitStore {
+	case RateLimitStoreMemory, RateLimitStoreDatabase:
+	default:
+		fail("API_RATE_LIMIT_STORE: %q must be %s or %s", c.API.RateLimitStore, RateLimitStoreMemory, RateLimitStoreDatabase)
+	}
+
+	for _, p := range []struct {
+		name     string
+		provider OIDCProviderConfig
+	}{{"GOOGLE", c.OIDC.Google}, {"APPLE", c.OIDC.Apple}} {
+		name, provider := p.name, p.provider
+		if !provider.Enabled() {
+			continue
+		}
+		if len(provider.Issuers) == 0 {
+			fail("OIDC_%s_ISSUERS: must not be empty while OIDC_%s_CLIENT_IDS is set", name, name)
+		}
+		if !strings.HasPrefix(provider.JWKSURL, "https://") && !strings.HasPrefix(provider.JWKSURL, "http://") {
+			fail("OIDC_%s_JWKS_URL: %q is not an http(s) URL", name, provider.JWKSURL)
+		}
+	}
+
+	if !strings.HasPrefix(c.Verification.LinkURL, "https://") && !strings.HasPrefix(c.Verification.LinkURL, "http://") {
+		fail("EMAIL_VERIFICATION_URL: %q is not an http(s) URL", c.Verification.LinkURL)
+	}
+	if c.Verification.TokenLifetime <= 0 {
+		fail("EMAIL_VERIFICATION_TOKEN_LIFETIME: must be positive")
+	}
+	if c.Verification.ResendInterval < 0 {
+		fail("EMAIL_VERIFICATION_RESEND_INTERVAL: must not be negative")
+	}
+	if c.Verification.DailyLimit <= 0 {
+		fail("EMAIL_VERIFICATION_DAILY_LIMIT: must be positive")
+	}
+	for _, feature := range c.Verification.RequiredFor {
+		known := false
+		for _, f := range VerifiedFeatures {
+			known = known || f == feature
+		}
+		if !known {
+			fail("EMAIL_VERIFICATION_REQUIRED_FOR: %q must be one of %s", feature, strings.Join(VerifiedFeatures, ", "))
+		}
+	}
+
+	if c.MFA.Issuer == "" {
+		fail("MFA_ISSUER: must not be empty")
+	}
+	if c.MFA.Enabled() {
+		if key, err := base64.StdEncoding.DecodeString(c.MFA.EncryptionKey); err != nil || len(key) != 32 {
+			fail("MFA_ENCRYPTION_KEY: must be 32 bytes encoded in base64")
+		}
+	}
+	if c.MFA.ChallengeLifetime <= 0 {
+		fail("MFA_CHALLENGE_LIFETIME: must be positive")
+	}
+
+	switch c.Lockout.Store {
+	case LockoutStoreMemory, LockoutStoreDatabase:
+	default:
+		fail("LOGIN_LOCKOUT_STORE: %q must be %s or %s", c.Lockout.Store, LockoutStoreMemory, LockoutStoreDatabase)
+	}
+	if c.Lockout.Window <= 0 {
+		fail("LOGIN_FAILURE_WINDOW: must be positive")
+	}
+	if c.Lockout.DelayAfter < 0 {
+		fail("LOGIN_DELAY_AFTER: must not be negative")
+	}
+	if c.Lockout.Delay < 0 || c.Lockout.MaxDelay < c.Lockout.Delay {
+		fail("LOGIN_MAX_DELAY: must not be less than LOGIN_DELAY, which must not be negative")
+	}
+	if c.Lockout.AccountLimit <= 0 {
+		fail("LOGIN_ACCOUNT_LIMIT: must be positive")
+	}
+	if c.Lockout.IPLimit <= 0 {
+		fail("LOGIN_IP_LIMIT: must be positive")
+	}
+	if c.Lockout.Duration <= 0 {
+		fail("LOGIN_LOCKOUT_DURATION: must be positive")
+	}
+	if !strings.HasPrefix(c.Lockout.UnlockURL, "https://") && !strings.HasPrefix(c.Lockout.UnlockURL, "http://") {
+		fail("LOGIN_UNLOCK_URL: %q is not an http(s) URL", c.Lockout.UnlockURL)
+	}
+
+	for _, origin := range c.CORS.AllowedOrigins {
+		if origin != "*" && !strings.HasPrefix(origin, "http://") && !strings.HasPrefix(origin, "https://") {
+			fail("CORS_ALLOWED_ORIGINS: %q is not * or an http(s) origin", origin)
+		}
+	}
+
+	if c.Push.APNsKeyID != "" && (c.Push.APNsTeamID == "" || c.Push.APNsPrivateKey == "" || c.Push.APNsTopic == "") {
+		fail("PUSH_APNS_KEY_ID: APNs also needs PUSH_APNS_TEAM_ID, PUSH_APNS_PRIVATE_KEY and PUSH_APNS_TOPIC")
+	}
+	if c.Push.FCMProjectID != "" && c.Push.FCMCredentials == "" {
+		fail("PUSH_FCM_PROJECT_ID: FCM also needs PUSH_FCM_CREDENTIALS")
+	}
+
+	switch c.Mail.Driver {
+	case "log", "smtp":
+	default:
+		fail("MAIL_DRIVER: %q must be log or smtp", c.Mail.Driver)
+	}
+
+	if c.Digest.Enabled && c.Digest.Interval <= 0 {
+		fail("DIGEST_INTERVAL: must be positive while the digest is enabled")
+	}
+	if c.Account.DeletionGracePeriod < 0 {
+		fail("ACCOUNT_DELETION_GRACE_PERIOD: must not be negative")
+	}
+	if c.Account.PurgeInterval < 0 {
+		fail("ACCOUNT_PURGE_INTERVAL: must not be negative")
+	}
+
+	if c.Server.Env == "production" {
+		switch {
+		case c.Auth.JWTSecret == DefaultJWTSecret:
+			fail("AUTH_JWT_SECRET: must not be the development default in production")
+		case c.Auth.JWTSecret == "" && len(c.Auth.JWTKeys) == 0:
+			fail("AUTH_JWT_SECRET: must be set in production unless AUTH_JWT_KEYS is")
+		case c.Auth.JWTSecret != "" && len(c.Auth.JWTSecret) < minProductionSecretLength:
+			fail("AUTH_JWT_SECRET: must be at least %d characters in production", minProductionSecretLength)
+		}
+		for _, secret := range c.Auth.JWTPreviousSecrets {
+			if len(secret) < minProductionSecretLength {
+				fail("AUTH_JWT_PREVIOUS_SECRETS: every secret must be at least %d characters in production", minProductionSecretLength)
+				break
+			}
+		}
+		if c.MFA.EncryptionKey == DefaultMFAEncryptionKey {
+			fail("MFA_ENCRYPTION_KEY: must not be the development default in production")
+		}
+		if c.Database.Driver == "postgres" && (c.Database.Password == "" || c.Database.Password == DefaultDBPassword) {
+			fail("DB_PASSWORD: must be set in production")
+		}
+		if c.Mail.Driver == "smtp" && c.Mail.SMTPUser != "" && c.Mail.SMTPPassword == "" {
+			fail("MAIL_SMTP_PASSWORD: must be set in production when MAIL_SMTP_USER is")
+		}
+	}
+
+	return errors.Join(errs...)
+}
@@ -3,31 +3,37 @@ package database
 import (
 	"context"
 	"fmt"
+	"sync/atomic"
 	"time"
 
 	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/config"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/metrics"
 	"github.com/rs/zerolog/log"
-	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
 )
 
 type Database struct {
 	DB *gorm.DB
+
+	// state is the State of the connection, see Supervisor
+	state atomic.Value
 }
 
-// NewDatabase creates a new GORM database connection
-func NewDatabase(cfg *config.Config) (*Database, error) {
-	// Build DSN (Data Source Name)
-	dsn := fmt.Sprintf(
-		"host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
-		cfg.Database.Host,
-		cfg.Database.Port,
-		cfg.Database.User,
-		cfg.Database.Password,
-		cfg.Database.Name,
-		cfg.Database.SSLMode,
-	)
+// Supported database drivers
+const (
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite"
+)
+
+// Open creates the GORM handle without connecting to the database, so that
+// repositories can be built while it is unreachable. The state is
+// StateConnecting until a Supervisor or NewDatabase reaches the database.
+func Open(cfg *config.Config) (*Database, error) {
+	dialector, err := newDialector(cfg.Database)
+	if err != nil {
+		return nil, err
+	}
 
 	// Configure GORM logger
 	gormLogger := logger.New(
@@ -40,35 +46,52 @@ func NewDatabase(cfg *config.Config) (*Database, error) {
 		},
 	)
 
-	// Configure GORM with retry logic
-	var db *gorm.DB
-	var err error
+	db, err := gorm.Open(dialector, &gorm.Config{
+		Logger: gormLogger,
+		NowFunc: func() time.Time {
+			return time.Now().UTC()
+		},
+		// Connections are made on first use; the supervisor pings instead
+		DisableAutomaticPing: true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+	configurePool(sqlDB, cfg.Database)
+
+	if len(cfg.Database.ReplicaDSNs) > 0 {
+		if err := UseReplicas(db, cfg.Database, postgresReplicas(cfg.Database.ReplicaDSNs)); err != nil {
+			return nil, err
+		}
+	}
+
+	database := &Database{DB: db}
+	database.state.Store(StateConnecting)
+	metrics.DatabaseState(string(StateConnecting))
+	return database, nil
+}
+
+// NewDatabase creates a new GORM database connection, retrying a few times
+// before giving up. It is meant for the command line tools; the server
+// uses Open and a Supervisor instead.
+func NewDatabase(cfg *config.Config) (*Database, error) {
+	db, err := Open(cfg)
+	if err != nil {
+		return nil, err
+	}
+
 	var retryCount int
 	maxRetries := 5
 	retryDelay := 2 * time.Second
 
 	for retryCount < maxRetries {
-		// Open GORM connection
-		db, err = gorm.Open(postgres.Open(dsn), &gorm.Config{
-			Logger: gormLogger,
-			NowFunc: func() time.Time {
-				return time.Now().UTC()
-			},
-		})
-
-		if err == nil {
-			// Test connection
-			sqlDB, err := db.DB()
-			if err == nil {
-				err = sqlDB.Ping()
-				if err == nil {
-					// Configure connection pool settings
-					sqlDB.SetMaxIdleConns(10)
-					sqlDB.SetMaxOpenConns(100)
-					sqlDB.SetConnMaxLifetime(time.Hour)
-					break
-				}
-			}
+		if err = db.Ping(context.Background()); err == nil {
+			break
 		}
 
 		retryCount++
@@ -86,17 +109,30 @@ func NewDatabase(cfg *config.Config) (*Database, error) {
 	}
 
 	if err != nil {
+		db.Close()
 		return nil, fmt.Errorf("failed to connect to database after %d attempts: %w", maxRetries, err)
 	}
 
-	log.Info().
-		Str("host", cfg.Database.Host).
-		Str("port", cfg.Database.Port).
-		Str("database", cfg.Database.Name).
-		Str("user", cfg.Database.User).
-		Msg("Successfully connected to database")
+	logConnected(cfg.Database)
+	db.setState(StateReady)
+	return db, nil
+}
 
-	return &Database{DB: db}, nil
+// logConnected logs where the database is, without the password
+func logConnected(cfg config.DatabaseConfig) {
+	if cfg.Driver == DriverSQLite {
+		log.Info().
+			Str("driver", DriverSQLite).
+			Str("path", cfg.SQLitePath).
+			Msg("Successfully connected to database")
+	} else {
+		log.Info().
+			Str("host", cfg.Host).
+			Str("port", cfg.Port).
+			Str("database", cfg.Name).
+			Str("user", cfg.User).
+			Msg("Successfully connected to database")
+	}
 }
 
 // Close closes the database connection
@@ -131,11 +167,6 @@ func (w *GormLogWriter) Printf(format string, args ...interface{}) {
 	log.Debug().Msgf(format, args...)
 }
 
-// AutoMigrate runs migrations for the provided models
-func (db *Database) AutoMigrate(models ...interface{}) error {
-	return db.DB.AutoMigrate(models...)
-}
-
 // CreateRecord creates a new record in the database
 func (db *Database) CreateRecord(value interface{}) error {
 	return db.DB.Create(value).Error
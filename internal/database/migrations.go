@@ -1,30 +1,36 @@
 package database
 
 import (
-	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/models"
+	"context"
+
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/migrate"
 	"github.com/rs/zerolog/log"
 )
 
-// RunMigrations runs all database migrations
-func (db *Database) RunMigrations() error {
+// RunMigrations applies all pending versioned migrations
+func (db *Database) RunMigrations(ctx context.Context) error {
 	log.Info().Msg("Running database migrations...")
 
-	// List of models to migrate
-	models := []interface{}{
-		&models.User{},
-		&models.Habit{},
-		&models.HabitStreak{},
-		&models.HabitCheckIn{},
-		&models.Achievement{},
+	migrator, err := migrate.New(db.DB)
+	if err != nil {
+		return err
 	}
 
-	// Run migrations
-	err := db.DB.AutoMigrate(models...)
+	applied, err := migrator.Up(ctx)
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to run migrations")
 		return err
 	}
 
-	log.Info().Msg("Database migrations completed successfully")
+	log.Info().Int("applied", len(applied)).Msg("Database migrations completed successfully")
 	return nil
 }
+
+// CheckMigrations returns migrate.ErrSchemaBehind when migrations are pending
+func (db *Database) CheckMigrations(ctx context.Context) error {
+	migrator, err := migrate.New(db.DB)
+	if err != nil {
+		return err
+	}
+	return migrator.Check(ctx)
+}
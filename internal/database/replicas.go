@@ -0,0 +1,97 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/config"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/plugin/dbresolver"
+)
+
+// replicaResolver names the resolver of the read replicas. Queries only
+// reach the replicas when the Replica scope selects it, so reads stay on
+// the primary unless a service opts in.
+const replicaResolver = "replicas"
+
+type replicaKey struct{}
+
+type replicaMode int
+
+const (
+	replicaPreferred replicaMode = iota + 1
+	primaryRequired
+)
+
+// PreferReplica marks reads made with ctx as tolerant of replica lag, such
+// as the analytics of the profile or a list of habits. It has no effect
+// when RequirePrimary was called on ctx or a parent of it.
+func PreferReplica(ctx context.Context) context.Context {
+	if mode, _ := ctx.Value(replicaKey{}).(replicaMode); mode == primaryRequired {
+		return ctx
+	}
+	return context.WithValue(ctx, replicaKey{}, replicaPreferred)
+}
+
+// RequirePrimary keeps every read made with ctx on the primary, even where
+// a replica is preferred, so that a user reads their own writes
+func RequirePrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, replicaKey{}, primaryRequired)
+}
+
+// Replica is a GORM scope that sends the query to a read replica when its
+// context prefers one. Without replicas configured it does nothing.
+func Replica(tx *gorm.DB) *gorm.DB {
+	if prefersReplica(tx.Statement.Context) {
+		return tx.Clauses(dbresolver.Use(replicaResolver))
+	}
+	return tx
+}
+
+// FirstOnReplica is First with the Replica scope for lookups of a row the
+// caller expects to exist, such as by ID. When the replica has no such row
+// the primary is asked, since the row may have been written through another
+// instance and not have reached the replica yet.
+func FirstOnReplica(tx *gorm.DB, dest any, conds ...any) *gorm.DB {
+	tx = tx.Session(&gorm.Session{})
+	result := tx.Scopes(Replica).First(dest, conds...)
+	if errors.Is(result.Error, gorm.ErrRecordNotFound) && prefersReplica(tx.Statement.Context) {
+		return tx.First(dest, conds...)
+	}
+	return result
+}
+
+func prefersReplica(ctx context.Context) bool {
+	mode, _ := ctx.Value(replicaKey{}).(replicaMode)
+	return mode == replicaPreferred
+}
+
+// postgresReplicas returns the dialectors of the replica DSNs
+func postgresReplicas(dsns []string) []gorm.Dialector {
+	replicas := make([]gorm.Dialector, len(dsns))
+	for i, dsn := range dsns {
+		replicas[i] = postgres.Open(dsn)
+	}
+	return replicas
+}
+
+// UseReplicas registers the read replicas with the pool limits of the
+// primary. Like the primary, they are connected on first use. Open
+// registers the replicas of the configuration.
+func UseReplicas(db *gorm.DB, cfg config.DatabaseConfig, replicas []gorm.Dialector) error {
+	resolver := dbresolver.Register(dbresolver.Config{
+		Replicas: replicas,
+		Policy:   dbresolver.RandomPolicy{},
+	}, replicaResolver).
+		SetMaxOpenConns(cfg.MaxOpenConns).
+		SetMaxIdleConns(cfg.MaxIdleConns).
+		SetConnMaxLifetime(cfg.ConnMaxLifetime).
+		SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
+
+	if err := db.Use(resolver); err != nil {
+		return fmt.Errorf("failed to set up read replicas: %w", err)
+	}
+	return nil
+}
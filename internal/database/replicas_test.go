@@ -0,0 +1,90 @@
+package database
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/config"
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestReplicaRouting(t *testing.T) {
+	dir := t.TempDir()
+	cfg := config.DatabaseConfig{Driver: DriverSQLite, SQLitePath: filepath.Join(dir, "primary.db")}
+	db, err := Open(&config.Config{Database: cfg})
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	t.Cleanup(db.Close)
+
+	// The replica holds different rows, so each query shows where it ran
+	replicaPath := filepath.Join(dir, "replica.db")
+	replica, err := gorm.Open(sqlite.Open(replicaPath), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("open replica: %v", err)
+	}
+	for name, conn := range map[string]*gorm.DB{"primary": db.DB, "replica": replica} {
+		if err := conn.Exec("CREATE TABLE items (name TEXT)").Error; err != nil {
+			t.Fatalf("create table: %v", err)
+		}
+		if err := conn.Exec("INSERT INTO items (name) VALUES (?)", name).Error; err != nil {
+			t.Fatalf("insert: %v", err)
+		}
+	}
+	if sqlDB, err := replica.DB(); err == nil {
+		sqlDB.Close()
+	}
+
+	if err := UseReplicas(db.DB, cfg, []gorm.Dialector{sqlite.Open(replicaPath)}); err != nil {
+		t.Fatalf("use replicas: %v", err)
+	}
+
+	read := func(tx *gorm.DB) string {
+		t.Helper()
+		var names []string
+		if err := tx.Scopes(Replica).Table("items").Pluck("name", &names).Error; err != nil {
+			t.Fatalf("read: %v", err)
+		}
+		return names[0]
+	}
+
+	ctx := context.Background()
+	tests := []struct {
+		name string
+		ctx  context.Context
+		want string
+	}{
+		{"default", ctx, "primary"},
+		{"preferred", PreferReplica(ctx), "replica"},
+		{"primary required", PreferReplica(RequirePrimary(ctx)), "primary"},
+	}
+	for _, tt := range tests {
+		if got := read(db.DB.WithContext(tt.ctx)); got != tt.want {
+			t.Errorf("%s: read from %s, want %s", tt.name, got, tt.want)
+		}
+	}
+
+	// Lookups that miss on the replica are retried on the primary
+	type item struct{ Name string }
+	var found item
+	if err := FirstOnReplica(db.DB.WithContext(PreferReplica(ctx)).Table("items"), &found, "name = ?", "primary").Error; err != nil || found.Name != "primary" {
+		t.Errorf("first on replica = %+v, %v, want the primary's row", found, err)
+	}
+	found = item{}
+	if err := FirstOnReplica(db.DB.WithContext(PreferReplica(ctx)).Table("items"), &found).Error; err != nil || found.Name != "replica" {
+		t.Errorf("first on replica = %+v, %v, want the replica's row", found, err)
+	}
+
+	// Transactions stay on the primary
+	err = db.DB.WithContext(PreferReplica(ctx)).Transaction(func(tx *gorm.DB) error {
+		if got := read(tx); got != "primary" {
+			t.Errorf("transaction: read from %s, want primary", got)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("transaction: %v", err)
+	}
+}
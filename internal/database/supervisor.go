@@ -0,0 +1,168 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/config"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/metrics"
+	"github.com/rs/zerolog/log"
+)
+
+// State is the state of the database connection
+type State string
+
+const (
+	// StateConnecting is the state until the database is first reached and
+	// prepared
+	StateConnecting State = "connecting"
+	// StateReady means the database answers and the schema is up to date
+	StateReady State = "ready"
+	// StateReconnecting means the database was ready but stopped answering
+	StateReconnecting State = "reconnecting"
+)
+
+// ErrUnavailable is returned while the database cannot be reached
+var ErrUnavailable = errors.New("database is unavailable")
+
+// pingTimeout bounds each attempt to reach the database
+const pingTimeout = 5 * time.Second
+
+// State returns the state of the connection
+func (db *Database) State() State {
+	return db.state.Load().(State)
+}
+
+// Ready returns ErrUnavailable unless the database is ready for queries
+func (db *Database) Ready() error {
+	if state := db.State(); state != StateReady {
+		return fmt.Errorf("%w, the connection is %s", ErrUnavailable, state)
+	}
+	return nil
+}
+
+// setState records a change of state in the logs and metrics
+func (db *Database) setState(state State) {
+	previous := db.state.Swap(state).(State)
+	if previous == state {
+		return
+	}
+
+	metrics.DatabaseState(string(state))
+	event := log.Info()
+	if state == StateReconnecting {
+		event = log.Warn()
+	}
+	event.Str("from", string(previous)).Str("to", string(state)).Msg("Database connection state changed")
+}
+
+// Supervisor keeps reaching for the database in the background. It prepares
+// the database once, the first time it answers, and then pings it so that
+// the state follows outages until it is stopped.
+type Supervisor struct {
+	db      *Database
+	cfg     config.DatabaseConfig
+	prepare func(ctx context.Context) error
+
+	prepared bool
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+}
+
+// NewSupervisor creates a supervisor of the database. prepare runs once the
+// database is first reached, such as to apply or check the migrations; the
+// database is not ready until it succeeds.
+func NewSupervisor(db *Database, cfg config.DatabaseConfig, prepare func(ctx context.Context) error) *Supervisor {
+	if cfg.ReconnectMin <= 0 {
+		cfg.ReconnectMin = time.Second
+	}
+	cfg.ReconnectMax = max(cfg.ReconnectMax, cfg.ReconnectMin)
+	return &Supervisor{db: db, cfg: cfg, prepare: prepare}
+}
+
+// Connect makes a single attempt to reach and prepare the database. It
+// returns an error wrapping ErrUnavailable when the database did not answer,
+// and the error of prepare otherwise. Once Start is called, only the
+// supervisor calls it.
+func (s *Supervisor) Connect(ctx context.Context) error {
+	pingCtx, cancel := context.WithTimeout(ctx, pingTimeout)
+	err := s.db.Ping(pingCtx)
+	cancel()
+	metrics.DatabaseConnectAttempt(err == nil)
+	if err != nil {
+		if s.db.State() == StateReady {
+			s.db.setState(StateReconnecting)
+		}
+		return fmt.Errorf("%w: %w", ErrUnavailable, err)
+	}
+
+	if !s.prepared {
+		if err := s.prepare(ctx); err != nil {
+			return err
+		}
+		s.prepared = true
+		logConnected(s.cfg)
+	}
+	s.db.setState(StateReady)
+	return nil
+}
+
+// Start supervises the connection in a goroutine until Stop is called. While
+// the database is unavailable the attempts back off from ReconnectMin to
+// ReconnectMax; once it is ready it is pinged every ReconnectMax.
+func (s *Supervisor) Start(ctx context.Context) {
+	ctx, s.cancel = context.WithCancel(ctx)
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.loop(ctx)
+	}()
+}
+
+// Stop ends the supervision and waits for the current attempt to finish
+func (s *Supervisor) Stop() {
+	if s.cancel != nil {
+		s.cancel()
+	}
+	s.wg.Wait()
+}
+
+func (s *Supervisor) loop(ctx context.Context) {
+	delay := s.cfg.ReconnectMin
+	for {
+		err := s.Connect(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+
+		next := s.cfg.ReconnectMax
+		if err != nil {
+			log.Warn().Err(err).Dur("retry_in", delay).Msg("Database is not ready, retrying")
+			next = delay
+			delay = min(delay*2, s.cfg.ReconnectMax)
+		} else {
+			delay = s.cfg.ReconnectMin
+		}
+
+		if !s.wait(ctx, next) {
+			return
+		}
+	}
+}
+
+// wait sleeps for d and reports whether the supervisor is still running
+func (s *Supervisor) wait(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
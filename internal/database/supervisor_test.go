@@ -0,0 +1,81 @@
+package database_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/config"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/database"
+)
+
+func TestSupervisorRetriesUntilReady(t *testing.T) {
+	cfg := config.DatabaseConfig{
+		Driver:       database.DriverSQLite,
+		SQLitePath:   ":memory:",
+		ReconnectMin: 5 * time.Millisecond,
+		ReconnectMax: 20 * time.Millisecond,
+	}
+	db, err := database.Open(&config.Config{Database: cfg})
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	t.Cleanup(db.Close)
+
+	if db.State() != database.StateConnecting || !errors.Is(db.Ready(), database.ErrUnavailable) {
+		t.Fatalf("state = %s, want connecting and unavailable", db.State())
+	}
+
+	// The schema is not ready on the first two attempts
+	var attempts atomic.Int32
+	supervisor := database.NewSupervisor(db, cfg, func(context.Context) error {
+		if attempts.Add(1) < 3 {
+			return errors.New("schema is behind")
+		}
+		return nil
+	})
+	supervisor.Start(context.Background())
+	t.Cleanup(supervisor.Stop)
+
+	deadline := time.Now().Add(2 * time.Second)
+	for db.Ready() != nil {
+		if time.Now().After(deadline) {
+			t.Fatalf("state = %s after %d attempts, want ready", db.State(), attempts.Load())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	supervisor.Stop()
+	if got := attempts.Load(); got != 3 {
+		t.Errorf("prepared %d times, want 3", got)
+	}
+}
+
+func TestSupervisorNoticesOutage(t *testing.T) {
+	cfg := config.DatabaseConfig{Driver: database.DriverSQLite, SQLitePath: ":memory:"}
+	db, err := database.Open(&config.Config{Database: cfg})
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+
+	prepared := 0
+	supervisor := database.NewSupervisor(db, cfg, func(context.Context) error {
+		prepared++
+		return nil
+	})
+	if err := supervisor.Connect(context.Background()); err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	if err := supervisor.Connect(context.Background()); err != nil || prepared != 1 {
+		t.Fatalf("second connect: %v, prepared %d times, want once", err, prepared)
+	}
+
+	db.Close()
+	if err := supervisor.Connect(context.Background()); !errors.Is(err, database.ErrUnavailable) {
+		t.Fatalf("connect after close = %v, want ErrUnavailable", err)
+	}
+	if db.State() != database.StateReconnecting {
+		t.Errorf("state = %s, want reconnecting", db.State())
+	}
+}
@@ -0,0 +1,85 @@
+// Package digest renders the weekly habit performance email.
+package digest
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	texttemplate "text/template"
+	"time"
+
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/models"
+)
+
+//go:embed templates/*
+var templateFS embed.FS
+
+var funcs = map[string]interface{}{
+	"date": func(t time.Time) string {
+		return t.Format("Jan 2")
+	},
+	"percent": func(v float64) string {
+		return fmt.Sprintf("%.0f%%", v)
+	},
+	"trend": describeTrend,
+}
+
+var (
+	htmlTemplate = htmltemplate.Must(htmltemplate.New("weekly.html.tmpl").Funcs(funcs).ParseFS(templateFS, "templates/weekly.html.tmpl"))
+	textTemplate = texttemplate.Must(texttemplate.New("weekly.txt.tmpl").Funcs(funcs).ParseFS(templateFS, "templates/weekly.txt.tmpl"))
+)
+
+// Data is everything the weekly digest templates render. The period is
+// [PeriodStart, PeriodEnd).
+type Data struct {
+	Name             string
+	PeriodStart      time.Time
+	PeriodEnd        time.Time
+	Overview         models.OverviewStats
+	TopHabits        []models.HabitPerformance
+	ImprovementTrend string
+	NewAchievements  []models.AchievementResponse
+}
+
+// LastDay returns the last calendar day covered by the digest
+func (d Data) LastDay() time.Time {
+	return d.PeriodEnd.AddDate(0, 0, -1)
+}
+
+// Rendered is a digest ready to be handed to a mailer
+type Rendered struct {
+	Subject string
+	HTML    string
+	Text    string
+}
+
+// Render renders the HTML and plain-text versions of the digest
+func Render(data Data) (*Rendered, error) {
+	var html, text bytes.Buffer
+	if err := htmlTemplate.Execute(&html, data); err != nil {
+		return nil, err
+	}
+	if err := textTemplate.Execute(&text, data); err != nil {
+		return nil, err
+	}
+
+	return &Rendered{
+		Subject: "Your week in habits: " + data.PeriodStart.Format("Jan 2") + " – " + data.LastDay().Format("Jan 2"),
+		HTML:    html.String(),
+		Text:    text.String(),
+	}, nil
+}
+
+func describeTrend(trend string) string {
+	switch trend {
+	case "improving":
+		return "You're on the rise – your consistency improved compared to the week before."
+	case "declining":
+		return "Your consistency dipped a little compared to the week before. A fresh start is one check-in away."
+	case "stable":
+		return "You kept a steady pace compared to the week before."
+	default:
+		return "Keep checking in to unlock your weekly trend."
+	}
+}
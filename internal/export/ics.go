@@ -0,0 +1,89 @@
+package export
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"time"
+)
+
+// ICSEvent is an all-day calendar event
+type ICSEvent struct {
+	UID         string
+	Date        time.Time
+	Summary     string
+	Description string
+	Created     time.Time
+}
+
+// ICSWriter streams an iCalendar (RFC 5545) document
+type ICSWriter struct {
+	w   *bufio.Writer
+	err error
+}
+
+// NewICSWriter creates an iCalendar writer and writes the calendar header
+func NewICSWriter(w io.Writer, name string) *ICSWriter {
+	iw := &ICSWriter{w: bufio.NewWriter(w)}
+	iw.line("BEGIN:VCALENDAR")
+	iw.line("VERSION:2.0")
+	iw.line("PRODID:-//WomenMobileDev//Consistency//EN")
+	iw.line("CALSCALE:GREGORIAN")
+	iw.line("X-WR-CALNAME:" + escapeText(name))
+	return iw
+}
+
+// WriteEvent writes an all-day event
+func (i *ICSWriter) WriteEvent(event ICSEvent) error {
+	day := event.Date.UTC()
+	i.line("BEGIN:VEVENT")
+	i.line("UID:" + event.UID)
+	i.line("DTSTAMP:" + event.Created.UTC().Format("20060102T150405Z"))
+	i.line("DTSTART;VALUE=DATE:" + day.Format("20060102"))
+	i.line("DTEND;VALUE=DATE:" + day.AddDate(0, 0, 1).Format("20060102"))
+	i.line("SUMMARY:" + escapeText(event.Summary))
+	if event.Description != "" {
+		i.line("DESCRIPTION:" + escapeText(event.Description))
+	}
+	i.line("TRANSP:TRANSPARENT")
+	i.line("END:VEVENT")
+	return i.err
+}
+
+// Close writes the calendar footer and flushes the output
+func (i *ICSWriter) Close() error {
+	i.line("END:VCALENDAR")
+	if i.err != nil {
+		return i.err
+	}
+	return i.w.Flush()
+}
+
+// line writes a content line, folding it at 75 octets as required by RFC 5545
+func (i *ICSWriter) line(s string) {
+	if i.err != nil {
+		return
+	}
+
+	limit := 75
+	for len(s) > limit {
+		cut := limit
+		// Never split a multi-byte UTF-8 sequence
+		for cut > 0 && s[cut]&0xC0 == 0x80 {
+			cut--
+		}
+		if _, i.err = i.w.WriteString(s[:cut] + "\r\n "); i.err != nil {
+			return
+		}
+		s = s[cut:]
+		// Continuation lines start with a space, which counts towards the limit
+		limit = 74
+	}
+	_, i.err = i.w.WriteString(s + "\r\n")
+}
+
+var textEscaper = strings.NewReplacer(`\`, `\\`, `;`, `\;`, `,`, `\,`, "\r\n", `\n`, "\n", `\n`)
+
+func escapeText(s string) string {
+	return textEscaper.Replace(s)
+}
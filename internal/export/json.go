@@ -0,0 +1,93 @@
+// Package export contains streaming encoders for user data exports.
+package export
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+)
+
+// JSONWriter streams a JSON object whose members are written one at a time,
+// so that large arrays never have to be held in memory
+type JSONWriter struct {
+	w      *bufio.Writer
+	fields int
+	items  int
+	err    error
+}
+
+// NewJSONWriter creates a JSON writer and opens the top level object
+func NewJSONWriter(w io.Writer) *JSONWriter {
+	bw := bufio.NewWriter(w)
+	jw := &JSONWriter{w: bw}
+	jw.write("{")
+	return jw
+}
+
+// Field writes a complete member of the top level object
+func (j *JSONWriter) Field(name string, value interface{}) error {
+	j.key(name)
+	j.value(value)
+	return j.err
+}
+
+// BeginArray opens an array member of the top level object
+func (j *JSONWriter) BeginArray(name string) error {
+	j.key(name)
+	j.write("[")
+	j.items = 0
+	return j.err
+}
+
+// Item appends a value to the open array
+func (j *JSONWriter) Item(value interface{}) error {
+	if j.items > 0 {
+		j.write(",")
+	}
+	j.value(value)
+	j.items++
+	return j.err
+}
+
+// EndArray closes the open array
+func (j *JSONWriter) EndArray() error {
+	j.write("]")
+	return j.err
+}
+
+// Close closes the top level object and flushes the output
+func (j *JSONWriter) Close() error {
+	j.write("}\n")
+	if j.err != nil {
+		return j.err
+	}
+	return j.w.Flush()
+}
+
+func (j *JSONWriter) key(name string) {
+	if j.fields > 0 {
+		j.write(",")
+	}
+	j.value(name)
+	j.write(":")
+	j.fields++
+}
+
+func (j *JSONWriter) value(v interface{}) {
+	if j.err != nil {
+		return
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		j.err = err
+		return
+	}
+	j.write(string(data))
+}
+
+func (j *JSONWriter) write(s string) {
+	if j.err != nil {
+		return
+	}
+	_, j.err = j.w.WriteString(s)
+}
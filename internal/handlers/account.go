@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/middleware"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/service"
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+)
+
+// AccountHandler handles account deletion requests
+type AccountHandler struct {
+	accountService *service.AccountService
+}
+
+// NewAccountHandler creates a new account handler
+func NewAccountHandler(accountService *service.AccountService) *AccountHandler {
+	return &AccountHandler{
+		accountService: accountService,
+	}
+}
+
+// DeleteAccount handles deleting the current user's account
+func (h *AccountHandler) DeleteAccount() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// Get the user ID from the context
+		userID, err := middleware.GetUserID(c)
+		if err != nil {
+			middleware.RespondWithUnauthorized(c)
+			return
+		}
+
+		// Parse the request body
+		var req service.DeleteAccountRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			middleware.RespondWithBadRequest(c, err.Error())
+			return
+		}
+
+		// Call the service to schedule the deletion
+		deletion, err := h.accountService.DeleteAccount(c.Request.Context(), userID, req)
+		if err != nil {
+			switch err.Error() {
+			case "user not found":
+				middleware.RespondWithNotFound(c, "User")
+			case "invalid password":
+				middleware.RespondWithError(c, http.StatusForbidden, "INVALID_PASSWORD", "Password is incorrect", nil)
+			default:
+				log.Ctx(c.Request.Context()).Error().Err(err).Msg("Failed to delete account")
+				middleware.RespondWithInternalError(c, "Failed to delete account")
+			}
+			return
+		}
+
+		middleware.RespondWithSuccess(c, http.StatusAccepted, "Account scheduled for deletion", deletion)
+	}
+}
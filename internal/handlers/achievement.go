@@ -34,7 +34,7 @@ func (h *AchievementHandler) ListAchievements() gin.HandlerFunc {
 		// Call the service to list achievements
 		achievements, err := h.achievementService.ListAchievements(c.Request.Context(), userID)
 		if err != nil {
-			log.Error().Err(err).Msg("Failed to list achievements")
+			log.Ctx(c.Request.Context()).Error().Err(err).Msg("Failed to list achievements")
 			middleware.RespondWithInternalError(c, "Failed to list achievements")
 			return
 		}
@@ -67,7 +67,7 @@ func (h *AchievementHandler) GetAchievement() gin.HandlerFunc {
 				middleware.RespondWithNotFound(c, "Achievement")
 				return
 			}
-			log.Error().Err(err).Msg("Failed to get achievement")
+			log.Ctx(c.Request.Context()).Error().Err(err).Msg("Failed to get achievement")
 			middleware.RespondWithInternalError(c, "Failed to get achievement")
 			return
 		}
@@ -103,7 +103,7 @@ func (h *AchievementHandler) ListHabitAchievements() gin.HandlerFunc {
 				middleware.RespondWithForbidden(c)
 				return
 			}
-			log.Error().Err(err).Msg("Failed to list habit achievements")
+			log.Ctx(c.Request.Context()).Error().Err(err).Msg("Failed to list habit achievements")
 			middleware.RespondWithInternalError(c, "Failed to list habit achievements")
 			return
 		}
@@ -1,8 +1,10 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/lockout"
 	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/middleware"
 	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/service"
 	"github.com/gin-gonic/gin"
@@ -37,7 +39,7 @@ func (h *AuthHandler) Register() gin.HandlerFunc {
 				middleware.RespondWithConflict(c, err.Error())
 				return
 			}
-			log.Error().Err(err).Msg("Failed to register user")
+			log.Ctx(c.Request.Context()).Error().Err(err).Msg("Failed to register user")
 			middleware.RespondWithInternalError(c, "Failed to register user")
 			return
 		}
@@ -56,13 +58,16 @@ func (h *AuthHandler) Login() gin.HandlerFunc {
 		}
 
 		// Call the service to login the user
-		response, err := h.authService.Login(c.Request.Context(), req)
+		response, err := h.authService.Login(c.Request.Context(), req, c.ClientIP())
 		if err != nil {
 			if err.Error() == "invalid email or password" {
 				middleware.RespondWithError(c, http.StatusUnauthorized, "UNAUTHORIZED", err.Error(), nil)
 				return
 			}
-			log.Error().Err(err).Msg("Failed to login user")
+			if respondWithLockout(c, err) {
+				return
+			}
+			log.Ctx(c.Request.Context()).Error().Err(err).Msg("Failed to login user")
 			middleware.RespondWithInternalError(c, "Failed to login user")
 			return
 		}
@@ -71,6 +76,62 @@ func (h *AuthHandler) Login() gin.HandlerFunc {
 	}
 }
 
+// VerifyMFA handles the second step of a login with two-factor authentication
+func (h *AuthHandler) VerifyMFA() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req service.VerifyMFARequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			middleware.RespondWithBadRequest(c, err.Error())
+			return
+		}
+
+		// Call the service to complete the login
+		response, err := h.authService.VerifyMFA(c.Request.Context(), req, c.ClientIP())
+		if err != nil {
+			switch err.Error() {
+			case "invalid or expired challenge":
+				middleware.RespondWithError(c, http.StatusUnauthorized, "INVALID_CHALLENGE", "The login has expired, please sign in again", nil)
+				return
+			case "invalid code":
+				middleware.RespondWithError(c, http.StatusUnauthorized, "INVALID_CODE", "The code is incorrect", nil)
+				return
+			case "two-factor authentication is not available":
+				middleware.RespondWithError(c, http.StatusServiceUnavailable, "FEATURE_DISABLED", "Two-factor authentication is currently unavailable", nil)
+				return
+			}
+			if respondWithLockout(c, err) {
+				return
+			}
+			log.Ctx(c.Request.Context()).Error().Err(err).Msg("Failed to verify login code")
+			middleware.RespondWithInternalError(c, "Failed to verify login code")
+			return
+		}
+
+		middleware.RespondWithOK(c, response)
+	}
+}
+
+// respondWithLockout answers with 429 and reports true if err is a
+// *lockout.Error
+func respondWithLockout(c *gin.Context, err error) bool {
+	var wait *lockout.Error
+	if !errors.As(err, &wait) {
+		return false
+	}
+
+	seconds := setRetryAfter(c, wait.RetryAfter)
+	if wait.Locked {
+		middleware.RespondWithError(c, http.StatusTooManyRequests, "ACCOUNT_LOCKED", "Too many failed logins, sign-in is locked for now", gin.H{
+			"retry_after": seconds,
+		})
+		return true
+	}
+	middleware.RespondWithError(c, http.StatusTooManyRequests, "TOO_MANY_ATTEMPTS", "Too many failed logins, please wait before trying again", gin.H{
+		"retry_after": seconds,
+	})
+	return true
+}
+
 // ForgotPassword handles forgot password requests
 func (h *AuthHandler) ForgotPassword() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -83,7 +144,7 @@ func (h *AuthHandler) ForgotPassword() gin.HandlerFunc {
 		// Call the service to handle forgot password
 		err := h.authService.ForgotPassword(c.Request.Context(), req)
 		if err != nil {
-			log.Error().Err(err).Msg("Failed to process forgot password request")
+			log.Ctx(c.Request.Context()).Error().Err(err).Msg("Failed to process forgot password request")
 			middleware.RespondWithInternalError(c, "Failed to process forgot password request")
 			return
 		}
@@ -102,9 +163,13 @@ func (h *AuthHandler) ResetPassword() gin.HandlerFunc {
 		}
 
 		// Call the service to handle reset password
-		err := h.authService.ResetPassword(c.Request.Context(), req)
+		err := h.authService.ResetPassword(c.Request.Context(), req, c.ClientIP())
 		if err != nil {
-			log.Error().Err(err).Msg("Failed to reset password")
+			if err.Error() == "invalid or expired token" {
+				middleware.RespondWithError(c, http.StatusBadRequest, "INVALID_TOKEN", "The password reset link is invalid or has expired", nil)
+				return
+			}
+			log.Ctx(c.Request.Context()).Error().Err(err).Msg("Failed to reset password")
 			middleware.RespondWithInternalError(c, "Failed to reset password")
 			return
 		}
@@ -112,3 +177,39 @@ func (h *AuthHandler) ResetPassword() gin.HandlerFunc {
 		middleware.RespondWithSuccess(c, http.StatusOK, "Password has been reset successfully", nil)
 	}
 }
+
+// ChangePassword handles setting or changing the current user's password
+func (h *AuthHandler) ChangePassword() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// Get the user ID from the context
+		userID, err := middleware.GetUserID(c)
+		if err != nil {
+			middleware.RespondWithUnauthorized(c)
+			return
+		}
+
+		// Parse the request body
+		var req service.ChangePasswordRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			middleware.RespondWithBadRequest(c, err.Error())
+			return
+		}
+
+		// Call the service to change the password
+		user, err := h.authService.ChangePassword(c.Request.Context(), userID, req)
+		if err != nil {
+			switch err.Error() {
+			case "user not found":
+				middleware.RespondWithNotFound(c, "User")
+			case "invalid password":
+				middleware.RespondWithError(c, http.StatusForbidden, "INVALID_PASSWORD", "Password is incorrect", nil)
+			default:
+				log.Ctx(c.Request.Context()).Error().Err(err).Msg("Failed to change password")
+				middleware.RespondWithInternalError(c, "Failed to change password")
+			}
+			return
+		}
+
+		middleware.RespondWithSuccess(c, http.StatusOK, "Password updated", user)
+	}
+}
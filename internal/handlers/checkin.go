@@ -53,7 +53,7 @@ func (h *CheckInHandler) CheckIn() gin.HandlerFunc {
 			if appErr, ok := err.(*models.AppError); ok {
 				middleware.RespondWithError(c, http.StatusBadRequest, appErr.Code, appErr.Message, appErr.Details)
 			} else {
-				log.Error().Err(err).Msg("Failed to check in")
+				log.Ctx(c.Request.Context()).Error().Err(err).Msg("Failed to check in")
 				middleware.RespondWithInternalError(c, err.Error())
 			}
 			return
@@ -90,7 +90,7 @@ func (h *CheckInHandler) ListCheckIns() gin.HandlerFunc {
 				middleware.RespondWithForbidden(c)
 				return
 			}
-			log.Error().Err(err).Msg("Failed to list check-ins")
+			log.Ctx(c.Request.Context()).Error().Err(err).Msg("Failed to list check-ins")
 			middleware.RespondWithInternalError(c, "Failed to list check-ins")
 			return
 		}
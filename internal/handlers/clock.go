@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"time"
+
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/clock"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/middleware"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/models"
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+)
+
+// ClockHandler lets QA move the server clock to replay time-dependent
+// behaviour. It is only registered in development and test environments.
+type ClockHandler struct {
+	clock *clock.Offset
+}
+
+// NewClockHandler creates a new clock handler
+func NewClockHandler(clk *clock.Offset) *ClockHandler {
+	return &ClockHandler{
+		clock: clk,
+	}
+}
+
+// SetClockRequest moves the clock to an absolute time or by a relative
+// duration such as "24h" or "-1h30m". Exactly one of the fields is required.
+type SetClockRequest struct {
+	Now     *time.Time `json:"now"`
+	Advance string     `json:"advance"`
+}
+
+// GetClock handles getting the current server time
+func (h *ClockHandler) GetClock() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		middleware.RespondWithOK(c, h.response())
+	}
+}
+
+// SetClock handles moving the server clock
+func (h *ClockHandler) SetClock() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req SetClockRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			middleware.RespondWithBadRequest(c, err.Error())
+			return
+		}
+
+		switch {
+		case req.Now != nil && req.Advance == "":
+			h.clock.Set(*req.Now)
+		case req.Now == nil && req.Advance != "":
+			d, err := time.ParseDuration(req.Advance)
+			if err != nil {
+				middleware.RespondWithValidationError(c, "advance", "must be a duration such as 24h or -90m")
+				return
+			}
+			h.clock.Advance(d)
+		default:
+			middleware.RespondWithBadRequest(c, "Provide either now or advance")
+			return
+		}
+
+		response := h.response()
+		log.Ctx(c.Request.Context()).Warn().Time("now", response.Now).Str("offset", response.Offset).Msg("Server clock moved")
+		middleware.RespondWithOK(c, response)
+	}
+}
+
+// ResetClock handles bringing the server clock back to real time
+func (h *ClockHandler) ResetClock() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		h.clock.Reset()
+		log.Ctx(c.Request.Context()).Warn().Msg("Server clock reset to real time")
+		middleware.RespondWithOK(c, h.response())
+	}
+}
+
+func (h *ClockHandler) response() models.ClockResponse {
+	offset := h.clock.Offset()
+	now := h.clock.Now().UTC()
+	return models.ClockResponse{
+		Now:     now,
+		RealNow: now.Add(-offset),
+		Offset:  offset.String(),
+	}
+}
@@ -0,0 +1,165 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/middleware"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/service"
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+)
+
+// DeviceHandler handles push device registration requests
+type DeviceHandler struct {
+	deviceService *service.DeviceService
+}
+
+// NewDeviceHandler creates a new device handler
+func NewDeviceHandler(deviceService *service.DeviceService) *DeviceHandler {
+	return &DeviceHandler{
+		deviceService: deviceService,
+	}
+}
+
+// RegisterDevice handles registering a push device token
+func (h *DeviceHandler) RegisterDevice() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// Get the user ID from the context
+		userID, err := middleware.GetUserID(c)
+		if err != nil {
+			middleware.RespondWithUnauthorized(c)
+			return
+		}
+
+		// Parse the request body
+		var req service.RegisterDeviceRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			middleware.RespondWithBadRequest(c, err.Error())
+			return
+		}
+
+		// Call the service to register the device
+		device, err := h.deviceService.RegisterDevice(c.Request.Context(), userID, req)
+		if err != nil {
+			log.Ctx(c.Request.Context()).Error().Err(err).Msg("Failed to register device")
+			middleware.RespondWithInternalError(c, "Failed to register device")
+			return
+		}
+
+		middleware.RespondWithCreated(c, device)
+	}
+}
+
+// ListDevices handles listing the current user's devices
+func (h *DeviceHandler) ListDevices() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := middleware.GetUserID(c)
+		if err != nil {
+			middleware.RespondWithUnauthorized(c)
+			return
+		}
+
+		devices, err := h.deviceService.ListDevices(c.Request.Context(), userID)
+		if err != nil {
+			log.Ctx(c.Request.Context()).Error().Err(err).Msg("Failed to list devices")
+			middleware.RespondWithInternalError(c, "Failed to list devices")
+			return
+		}
+
+		middleware.RespondWithOK(c, devices)
+	}
+}
+
+// RefreshDevice handles refreshing a device token or app version
+func (h *DeviceHandler) RefreshDevice() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// Get the user ID from the context
+		userID, err := middleware.GetUserID(c)
+		if err != nil {
+			middleware.RespondWithUnauthorized(c)
+			return
+		}
+
+		// Get the device ID from the URL
+		deviceID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+		if err != nil {
+			middleware.RespondWithBadRequest(c, "Invalid device ID")
+			return
+		}
+
+		// Parse the request body
+		var req service.RefreshDeviceRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			middleware.RespondWithBadRequest(c, err.Error())
+			return
+		}
+
+		// Call the service to refresh the device
+		device, err := h.deviceService.RefreshDevice(c.Request.Context(), userID, uint(deviceID), req)
+		if err != nil {
+			if err.Error() == "device not found" {
+				middleware.RespondWithNotFound(c, "Device")
+				return
+			}
+			log.Ctx(c.Request.Context()).Error().Err(err).Msg("Failed to refresh device")
+			middleware.RespondWithInternalError(c, "Failed to refresh device")
+			return
+		}
+
+		middleware.RespondWithOK(c, device)
+	}
+}
+
+// UnregisterDevice handles removing a device
+func (h *DeviceHandler) UnregisterDevice() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// Get the user ID from the context
+		userID, err := middleware.GetUserID(c)
+		if err != nil {
+			middleware.RespondWithUnauthorized(c)
+			return
+		}
+
+		// Get the device ID from the URL
+		deviceID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+		if err != nil {
+			middleware.RespondWithBadRequest(c, "Invalid device ID")
+			return
+		}
+
+		// Call the service to unregister the device
+		err = h.deviceService.UnregisterDevice(c.Request.Context(), userID, uint(deviceID))
+		if err != nil {
+			if err.Error() == "device not found" {
+				middleware.RespondWithNotFound(c, "Device")
+				return
+			}
+			log.Ctx(c.Request.Context()).Error().Err(err).Msg("Failed to unregister device")
+			middleware.RespondWithInternalError(c, "Failed to unregister device")
+			return
+		}
+
+		middleware.RespondWithSuccess(c, http.StatusOK, "Device unregistered successfully", nil)
+	}
+}
+
+// SendTestNotification handles sending a test push notification to the current user's devices
+func (h *DeviceHandler) SendTestNotification() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := middleware.GetUserID(c)
+		if err != nil {
+			middleware.RespondWithUnauthorized(c)
+			return
+		}
+
+		result, err := h.deviceService.SendTestNotification(c.Request.Context(), userID)
+		if err != nil {
+			log.Ctx(c.Request.Context()).Error().Err(err).Msg("Failed to send test notification")
+			middleware.RespondWithInternalError(c, "Failed to send test notification")
+			return
+		}
+
+		middleware.RespondWithOK(c, result)
+	}
+}
@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/middleware"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/service"
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+)
+
+// DigestHandler handles weekly digest preference requests
+type DigestHandler struct {
+	digestService *service.DigestService
+}
+
+// NewDigestHandler creates a new digest handler
+func NewDigestHandler(digestService *service.DigestService) *DigestHandler {
+	return &DigestHandler{
+		digestService: digestService,
+	}
+}
+
+// GetPreferences handles getting the current user's weekly digest preferences
+func (h *DigestHandler) GetPreferences() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// Get the user ID from the context
+		userID, err := middleware.GetUserID(c)
+		if err != nil {
+			middleware.RespondWithUnauthorized(c)
+			return
+		}
+
+		// Call the service to get the preferences
+		preferences, err := h.digestService.GetPreferences(c.Request.Context(), userID)
+		if err != nil {
+			if err.Error() == "user not found" {
+				middleware.RespondWithNotFound(c, "User")
+				return
+			}
+			log.Ctx(c.Request.Context()).Error().Err(err).Msg("Failed to get digest preferences")
+			middleware.RespondWithInternalError(c, "Failed to get digest preferences")
+			return
+		}
+
+		middleware.RespondWithOK(c, preferences)
+	}
+}
+
+// UpdatePreferences handles opting in or out of the weekly digest
+func (h *DigestHandler) UpdatePreferences() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// Get the user ID from the context
+		userID, err := middleware.GetUserID(c)
+		if err != nil {
+			middleware.RespondWithUnauthorized(c)
+			return
+		}
+
+		// Parse the request body
+		var req service.UpdateDigestPreferencesRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			middleware.RespondWithBadRequest(c, err.Error())
+			return
+		}
+
+		// Call the service to update the preferences
+		preferences, err := h.digestService.UpdatePreferences(c.Request.Context(), userID, req)
+		if err != nil {
+			if err.Error() == "user not found" {
+				middleware.RespondWithNotFound(c, "User")
+				return
+			}
+			log.Ctx(c.Request.Context()).Error().Err(err).Msg("Failed to update digest preferences")
+			middleware.RespondWithInternalError(c, "Failed to update digest preferences")
+			return
+		}
+
+		middleware.RespondWithOK(c, preferences)
+	}
+}
@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/middleware"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/service"
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+)
+
+// ExportHandler handles data export requests
+type ExportHandler struct {
+	exportService *service.ExportService
+}
+
+// NewExportHandler creates a new export handler
+func NewExportHandler(exportService *service.ExportService) *ExportHandler {
+	return &ExportHandler{
+		exportService: exportService,
+	}
+}
+
+// Export handles streaming a download of all of the current user's data
+func (h *ExportHandler) Export() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// Get the user ID from the context
+		userID, err := middleware.GetUserID(c)
+		if err != nil {
+			middleware.RespondWithUnauthorized(c)
+			return
+		}
+
+		// Validate the requested format
+		format := c.DefaultQuery("format", service.ExportFormatJSON)
+		switch format {
+		case service.ExportFormatJSON, service.ExportFormatCSV, service.ExportFormatICS:
+		default:
+			middleware.RespondWithValidationError(c, "format", "must be one of json, csv, ics")
+			return
+		}
+
+		// Prepare the export before anything is written to the response
+		export, err := h.exportService.NewExport(c.Request.Context(), userID)
+		if err != nil {
+			if err.Error() == "user not found" {
+				middleware.RespondWithNotFound(c, "User")
+				return
+			}
+			log.Ctx(c.Request.Context()).Error().Err(err).Msg("Failed to prepare export")
+			middleware.RespondWithInternalError(c, "Failed to prepare export")
+			return
+		}
+
+		c.Header("Content-Type", export.ContentType(format))
+		c.Header("Content-Disposition", `attachment; filename="`+export.Filename(format)+`"`)
+		c.Header("Cache-Control", "no-store")
+		c.Status(http.StatusOK)
+
+		// The status is already committed, so failures can only be logged
+		if err := export.Write(c.Request.Context(), format, c.Writer); err != nil {
+			log.Ctx(c.Request.Context()).Error().Err(err).Uint("userID", userID).Str("format", format).Msg("Failed to stream export")
+		}
+	}
+}
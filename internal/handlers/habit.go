@@ -42,7 +42,7 @@ func (h *HabitHandler) CreateHabit() gin.HandlerFunc {
 		// Call the service to create the habit
 		habit, err := h.habitService.CreateHabit(c.Request.Context(), userID, req)
 		if err != nil {
-			log.Error().Err(err).Msg("Failed to create habit")
+			log.Ctx(c.Request.Context()).Error().Err(err).Msg("Failed to create habit")
 			middleware.RespondWithInternalError(c, "Failed to create habit")
 			return
 		}
@@ -78,7 +78,7 @@ func (h *HabitHandler) GetHabit() gin.HandlerFunc {
 				middleware.RespondWithForbidden(c)
 				return
 			}
-			log.Error().Err(err).Msg("Failed to get habit")
+			log.Ctx(c.Request.Context()).Error().Err(err).Msg("Failed to get habit")
 			middleware.RespondWithInternalError(c, "Failed to get habit")
 			return
 		}
@@ -97,7 +97,7 @@ func (h *HabitHandler) ListHabits() gin.HandlerFunc {
 
 		habits, err := h.habitService.ListHabits(c.Request.Context(), userID)
 		if err != nil {
-			log.Error().Err(err).Msg("Failed to list habits")
+			log.Ctx(c.Request.Context()).Error().Err(err).Msg("Failed to list habits")
 			middleware.RespondWithInternalError(c, "Failed to list habits")
 			return
 		}
@@ -140,7 +140,7 @@ func (h *HabitHandler) UpdateHabit() gin.HandlerFunc {
 				middleware.RespondWithForbidden(c)
 				return
 			}
-			log.Error().Err(err).Msg("Failed to update habit")
+			log.Ctx(c.Request.Context()).Error().Err(err).Msg("Failed to update habit")
 			middleware.RespondWithInternalError(c, "Failed to update habit")
 			return
 		}
@@ -176,7 +176,7 @@ func (h *HabitHandler) DeleteHabit() gin.HandlerFunc {
 				middleware.RespondWithForbidden(c)
 				return
 			}
-			log.Error().Err(err).Msg("Failed to delete habit")
+			log.Ctx(c.Request.Context()).Error().Err(err).Msg("Failed to delete habit")
 			middleware.RespondWithInternalError(c, "Failed to delete habit")
 			return
 		}
@@ -6,11 +6,42 @@ import (
 	"time"
 
 	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/database"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/health"
 	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/middleware"
 	"github.com/gin-gonic/gin"
 	"github.com/rs/zerolog/log"
 )
 
+// Liveness answers as long as the process can serve requests. It checks no
+// dependencies, so that an orchestrator does not restart the service because
+// the database is down.
+func Liveness(c *gin.Context) {
+	middleware.RespondWithOK(c, gin.H{
+		"status": health.StatusUp,
+	})
+}
+
+// Readiness runs the readiness checks and answers 503 with the failing checks
+// while the service cannot handle requests, so that a load balancer stops
+// sending them. Why a check failed is only logged, since the probe is public.
+func Readiness(readiness *health.Readiness) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		report := readiness.Check(c.Request.Context())
+		if !report.Ready() {
+			for _, check := range report.Checks {
+				if check.Status != health.StatusUp {
+					log.Ctx(c.Request.Context()).Warn().Str("check", check.Name).Str("error", check.Error).
+						Float64("latency_ms", check.LatencyMS).Msg("Readiness check failed")
+				}
+			}
+			middleware.RespondWithError(c, http.StatusServiceUnavailable, "NOT_READY", "Service is not ready", report)
+			return
+		}
+		middleware.RespondWithOK(c, report)
+	}
+}
+
+// HealthCheck is the original liveness check, kept for existing monitors
 func HealthCheck(c *gin.Context) {
 	middleware.RespondWithOK(c, gin.H{
 		"status": "up and running",
@@ -32,7 +63,7 @@ func DBHealthCheck(db *database.Database) gin.HandlerFunc {
 		// Ping the database
 		err := db.Ping(ctx)
 		if err != nil {
-			log.Error().Err(err).Msg("Database health check failed")
+			log.Ctx(c.Request.Context()).Error().Err(err).Msg("Database health check failed")
 			middleware.RespondWithError(c, http.StatusServiceUnavailable, "DB_UNAVAILABLE", "Database connection failed", err.Error())
 			return
 		}
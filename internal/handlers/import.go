@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/importer"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/middleware"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/models"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/service"
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+)
+
+// maxImportSize is the largest export file accepted for import
+const maxImportSize = 20 << 20
+
+// ImportHandler handles importing habit histories from other trackers
+type ImportHandler struct {
+	importService *service.ImportService
+}
+
+// NewImportHandler creates a new import handler
+func NewImportHandler(importService *service.ImportService) *ImportHandler {
+	return &ImportHandler{
+		importService: importService,
+	}
+}
+
+// Import handles uploading an export from Loop, Habitica or a generic CSV file
+func (h *ImportHandler) Import() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// Get the user ID from the context
+		userID, err := middleware.GetUserID(c)
+		if err != nil {
+			middleware.RespondWithUnauthorized(c)
+			return
+		}
+
+		// Validate the query parameters
+		format := c.Query("format")
+		switch format {
+		case importer.FormatLoop, importer.FormatHabitica, importer.FormatCSV:
+		default:
+			middleware.RespondWithValidationError(c, "format", "must be one of loop, habitica, csv")
+			return
+		}
+
+		dryRun, err := strconv.ParseBool(c.DefaultQuery("dry_run", "false"))
+		if err != nil {
+			middleware.RespondWithValidationError(c, "dry_run", "must be a boolean")
+			return
+		}
+
+		// Read the uploaded file
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImportSize)
+		fileHeader, err := c.FormFile("file")
+		if err != nil {
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				middleware.RespondWithError(c, http.StatusRequestEntityTooLarge, "FILE_TOO_LARGE", "Import file must be at most 20 MB", nil)
+				return
+			}
+			middleware.RespondWithBadRequest(c, "A file is required")
+			return
+		}
+
+		file, err := fileHeader.Open()
+		if err != nil {
+			log.Ctx(c.Request.Context()).Error().Err(err).Msg("Failed to open import file")
+			middleware.RespondWithInternalError(c, "Failed to read import file")
+			return
+		}
+		defer file.Close()
+
+		data, err := io.ReadAll(file)
+		if err != nil {
+			log.Ctx(c.Request.Context()).Error().Err(err).Msg("Failed to read import file")
+			middleware.RespondWithInternalError(c, "Failed to read import file")
+			return
+		}
+
+		// Call the service to import the data
+		report, err := h.importService.Import(c.Request.Context(), userID, format, data, dryRun)
+		if err != nil {
+			if appErr, ok := err.(*models.AppError); ok {
+				middleware.RespondWithError(c, http.StatusBadRequest, appErr.Code, appErr.Message, appErr.Details)
+				return
+			}
+			log.Ctx(c.Request.Context()).Error().Err(err).Msg("Failed to import habits")
+			middleware.RespondWithInternalError(c, "Failed to import habits")
+			return
+		}
+
+		if dryRun {
+			middleware.RespondWithOK(c, report)
+			return
+		}
+		middleware.RespondWithCreated(c, report)
+	}
+}
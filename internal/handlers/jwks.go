@@ -0,0 +1,28 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/jwtkeys"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/middleware"
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+)
+
+// JWKS serves the public token signing keys as a JSON Web Key Set so that
+// other services can verify Consistency tokens. It is a plain JWKS document
+// rather than the usual response envelope, as JWKS clients expect.
+func JWKS(keys *jwtkeys.Provider) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		set, err := keys.Current()
+		if err != nil {
+			log.Ctx(c.Request.Context()).Error().Err(err).Msg("Failed to load signing keys")
+			middleware.RespondWithInternalError(c, nil)
+			return
+		}
+
+		c.Header("Cache-Control", "public, max-age=300")
+		c.JSON(http.StatusOK, set.JWKS(time.Now()))
+	}
+}
@@ -0,0 +1,181 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/middleware"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/service"
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+)
+
+// MFAHandler handles two-factor authentication settings
+type MFAHandler struct {
+	mfaService *service.MFAService
+}
+
+// NewMFAHandler creates a new MFA handler
+func NewMFAHandler(mfaService *service.MFAService) *MFAHandler {
+	return &MFAHandler{
+		mfaService: mfaService,
+	}
+}
+
+// GetStatus handles getting the current user's two-factor authentication status
+func (h *MFAHandler) GetStatus() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// Get the user ID from the context
+		userID, err := middleware.GetUserID(c)
+		if err != nil {
+			middleware.RespondWithUnauthorized(c)
+			return
+		}
+
+		// Call the service to get the status
+		status, err := h.mfaService.Status(c.Request.Context(), userID)
+		if err != nil {
+			respondWithMFAError(c, err, "Failed to get two-factor authentication status")
+			return
+		}
+
+		middleware.RespondWithOK(c, status)
+	}
+}
+
+// StartEnrolment handles generating an authenticator secret for the current user
+func (h *MFAHandler) StartEnrolment() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// Get the user ID from the context
+		userID, err := middleware.GetUserID(c)
+		if err != nil {
+			middleware.RespondWithUnauthorized(c)
+			return
+		}
+
+		// Parse the request body
+		var req service.MFAPasswordRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			middleware.RespondWithBadRequest(c, err.Error())
+			return
+		}
+
+		// Call the service to start the enrolment
+		enrolment, err := h.mfaService.StartEnrolment(c.Request.Context(), userID, req)
+		if err != nil {
+			respondWithMFAError(c, err, "Failed to start two-factor authentication enrolment")
+			return
+		}
+
+		middleware.RespondWithCreated(c, enrolment)
+	}
+}
+
+// ConfirmEnrolment handles enabling two-factor authentication with a code
+// from the authenticator app
+func (h *MFAHandler) ConfirmEnrolment() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// Get the user ID from the context
+		userID, err := middleware.GetUserID(c)
+		if err != nil {
+			middleware.RespondWithUnauthorized(c)
+			return
+		}
+
+		// Parse the request body
+		var req service.MFACodeRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			middleware.RespondWithBadRequest(c, err.Error())
+			return
+		}
+
+		// Call the service to confirm the enrolment
+		codes, err := h.mfaService.ConfirmEnrolment(c.Request.Context(), userID, req, c.ClientIP())
+		if err != nil {
+			respondWithMFAError(c, err, "Failed to enable two-factor authentication")
+			return
+		}
+
+		middleware.RespondWithSuccess(c, http.StatusOK, "Two-factor authentication enabled", codes)
+	}
+}
+
+// RegenerateRecoveryCodes handles replacing the current user's recovery codes
+func (h *MFAHandler) RegenerateRecoveryCodes() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// Get the user ID from the context
+		userID, err := middleware.GetUserID(c)
+		if err != nil {
+			middleware.RespondWithUnauthorized(c)
+			return
+		}
+
+		// Parse the request body
+		var req service.MFAPasswordRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			middleware.RespondWithBadRequest(c, err.Error())
+			return
+		}
+
+		// Call the service to replace the codes
+		codes, err := h.mfaService.RegenerateRecoveryCodes(c.Request.Context(), userID, req, c.ClientIP())
+		if err != nil {
+			respondWithMFAError(c, err, "Failed to regenerate recovery codes")
+			return
+		}
+
+		middleware.RespondWithCreated(c, codes)
+	}
+}
+
+// Disable handles turning off two-factor authentication for the current user
+func (h *MFAHandler) Disable() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// Get the user ID from the context
+		userID, err := middleware.GetUserID(c)
+		if err != nil {
+			middleware.RespondWithUnauthorized(c)
+			return
+		}
+
+		// Parse the request body
+		var req service.DisableMFARequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			middleware.RespondWithBadRequest(c, err.Error())
+			return
+		}
+
+		// Call the service to disable two-factor authentication
+		if err := h.mfaService.Disable(c.Request.Context(), userID, req, c.ClientIP()); err != nil {
+			respondWithMFAError(c, err, "Failed to disable two-factor authentication")
+			return
+		}
+
+		middleware.RespondWithSuccess(c, http.StatusOK, "Two-factor authentication disabled", nil)
+	}
+}
+
+// respondWithMFAError maps the MFA service errors to responses, logging
+// unexpected ones with the message
+func respondWithMFAError(c *gin.Context, err error, message string) {
+	switch err.Error() {
+	case "user not found":
+		middleware.RespondWithNotFound(c, "User")
+	case "two-factor authentication is not available":
+		middleware.RespondWithError(c, http.StatusForbidden, "FEATURE_DISABLED", "This feature is currently disabled", nil)
+	case "password not set":
+		middleware.RespondWithError(c, http.StatusConflict, "PASSWORD_NOT_SET", "Set a password before enabling two-factor authentication", nil)
+	case "invalid password":
+		middleware.RespondWithError(c, http.StatusForbidden, "INVALID_PASSWORD", "Password is incorrect", nil)
+	case "invalid code":
+		middleware.RespondWithError(c, http.StatusBadRequest, "INVALID_CODE", "The code is incorrect", nil)
+	case "two-factor authentication is already enabled":
+		middleware.RespondWithConflict(c, "Two-factor authentication is already enabled")
+	case "two-factor authentication is not enabled":
+		middleware.RespondWithConflict(c, "Two-factor authentication is not enabled")
+	case "no pending enrolment":
+		middleware.RespondWithConflict(c, "Start the enrolment before confirming it")
+	default:
+		log.Ctx(c.Request.Context()).Error().Err(err).Msg(message)
+		middleware.RespondWithInternalError(c, message)
+	}
+}
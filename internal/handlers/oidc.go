@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/middleware"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/service"
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+)
+
+// OIDCHandler handles sign-in with OpenID Connect providers
+type OIDCHandler struct {
+	oidcService *service.OIDCService
+}
+
+// NewOIDCHandler creates a new OIDC handler
+func NewOIDCHandler(oidcService *service.OIDCService) *OIDCHandler {
+	return &OIDCHandler{
+		oidcService: oidcService,
+	}
+}
+
+// Login handles signing in with a provider's ID token
+func (h *OIDCHandler) Login() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req service.OIDCLoginRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			middleware.RespondWithBadRequest(c, err.Error())
+			return
+		}
+
+		// Call the service to sign the user in
+		response, created, err := h.oidcService.Login(c.Request.Context(), c.Param("provider"), req)
+		if err != nil {
+			switch err.Error() {
+			case "provider not found":
+				middleware.RespondWithNotFound(c, "Sign-in provider")
+			case "invalid ID token":
+				middleware.RespondWithError(c, http.StatusUnauthorized, "INVALID_ID_TOKEN", "The ID token could not be verified", nil)
+			case "account no longer exists":
+				middleware.RespondWithError(c, http.StatusUnauthorized, "UNAUTHORIZED", "Account no longer exists", nil)
+			case "email not verified":
+				middleware.RespondWithError(c, http.StatusForbidden, "EMAIL_NOT_VERIFIED", "The provider has not verified the email address", nil)
+			case "registration disabled":
+				middleware.RespondWithError(c, http.StatusForbidden, "FEATURE_DISABLED", "This feature is currently disabled", nil)
+			case "email already registered":
+				middleware.RespondWithConflict(c, err.Error())
+			case "account not verified":
+				middleware.RespondWithError(c, http.StatusConflict, "ACCOUNT_NOT_VERIFIED", "An account with this email exists but its address is not verified yet; verify it or reset the password first", nil)
+			case "provider unavailable":
+				middleware.RespondWithError(c, http.StatusServiceUnavailable, "PROVIDER_UNAVAILABLE", "The sign-in provider is unavailable, please try again later", nil)
+			default:
+				log.Ctx(c.Request.Context()).Error().Err(err).Msg("Failed to sign in with provider")
+				middleware.RespondWithInternalError(c, "Failed to sign in")
+			}
+			return
+		}
+
+		if created {
+			middleware.RespondWithCreated(c, response)
+			return
+		}
+		middleware.RespondWithOK(c, response)
+	}
+}
+
+// ListIdentities handles listing the sign-in providers linked to the current user
+func (h *OIDCHandler) ListIdentities() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// Get the user ID from the context
+		userID, err := middleware.GetUserID(c)
+		if err != nil {
+			middleware.RespondWithUnauthorized(c)
+			return
+		}
+
+		// Call the service to list the identities
+		identities, err := h.oidcService.ListIdentities(c.Request.Context(), userID)
+		if err != nil {
+			log.Ctx(c.Request.Context()).Error().Err(err).Msg("Failed to list identities")
+			middleware.RespondWithInternalError(c, "Failed to list identities")
+			return
+		}
+
+		middleware.RespondWithOK(c, identities)
+	}
+}
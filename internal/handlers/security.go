@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/middleware"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/service"
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+)
+
+// SecurityHandler handles account lockout and security event requests
+type SecurityHandler struct {
+	securityService *service.SecurityService
+}
+
+// NewSecurityHandler creates a new security handler
+func NewSecurityHandler(securityService *service.SecurityService) *SecurityHandler {
+	return &SecurityHandler{
+		securityService: securityService,
+	}
+}
+
+// UnlockAccount handles unlocking an account with the emailed token
+func (h *SecurityHandler) UnlockAccount() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req service.UnlockAccountRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			middleware.RespondWithBadRequest(c, err.Error())
+			return
+		}
+
+		// Call the service to unlock the account
+		if err := h.securityService.Unlock(c.Request.Context(), req, c.ClientIP()); err != nil {
+			if err.Error() == "invalid or expired token" {
+				middleware.RespondWithError(c, http.StatusBadRequest, "INVALID_TOKEN", "The unlock link is invalid or has expired", nil)
+				return
+			}
+			log.Ctx(c.Request.Context()).Error().Err(err).Msg("Failed to unlock account")
+			middleware.RespondWithInternalError(c, "Failed to unlock account")
+			return
+		}
+
+		middleware.RespondWithSuccess(c, http.StatusOK, "Account unlocked", nil)
+	}
+}
+
+// ListEvents handles listing the current user's security events
+func (h *SecurityHandler) ListEvents() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// Get the user ID from the context
+		userID, err := middleware.GetUserID(c)
+		if err != nil {
+			middleware.RespondWithUnauthorized(c)
+			return
+		}
+
+		// Call the service to list the events
+		events, err := h.securityService.ListEvents(c.Request.Context(), userID)
+		if err != nil {
+			log.Ctx(c.Request.Context()).Error().Err(err).Msg("Failed to list security events")
+			middleware.RespondWithInternalError(c, "Failed to list security events")
+			return
+		}
+
+		middleware.RespondWithOK(c, events)
+	}
+}
+
+// setRetryAfter sets the Retry-After header to the wait in whole seconds,
+// rounded up, and returns them
+func setRetryAfter(c *gin.Context, wait time.Duration) int {
+	seconds := int(math.Ceil(wait.Seconds()))
+	c.Header("Retry-After", strconv.Itoa(seconds))
+	return seconds
+}
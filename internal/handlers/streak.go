@@ -58,7 +58,7 @@ func (h *StreakHandler) CreateStreak() gin.HandlerFunc {
 				middleware.RespondWithForbidden(c)
 				return
 			}
-			log.Error().Err(err).Msg("Failed to create streak")
+			log.Ctx(c.Request.Context()).Error().Err(err).Msg("Failed to create streak")
 			middleware.RespondWithInternalError(c, "Failed to create streak")
 			return
 		}
@@ -97,7 +97,7 @@ func (h *StreakHandler) GetCurrentStreak() gin.HandlerFunc {
 				middleware.RespondWithForbidden(c)
 				return
 			}
-			log.Error().Err(err).Msg("Failed to get current streak")
+			log.Ctx(c.Request.Context()).Error().Err(err).Msg("Failed to get current streak")
 			middleware.RespondWithInternalError(c, "Failed to get current streak")
 			return
 		}
@@ -133,7 +133,7 @@ func (h *StreakHandler) ListStreaks() gin.HandlerFunc {
 				middleware.RespondWithForbidden(c)
 				return
 			}
-			log.Error().Err(err).Msg("Failed to list streaks")
+			log.Ctx(c.Request.Context()).Error().Err(err).Msg("Failed to list streaks")
 			middleware.RespondWithInternalError(c, "Failed to list streaks")
 			return
 		}
@@ -36,7 +36,7 @@ func (h *UserHandler) GetProfile() gin.HandlerFunc {
 				middleware.RespondWithNotFound(c, "User")
 				return
 			}
-			log.Error().Err(err).Msg("Failed to get user profile")
+			log.Ctx(c.Request.Context()).Error().Err(err).Msg("Failed to get user profile")
 			middleware.RespondWithInternalError(c, "Failed to get user profile")
 			return
 		}
@@ -69,7 +69,7 @@ func (h *UserHandler) UpdateProfile() gin.HandlerFunc {
 				middleware.RespondWithNotFound(c, "User")
 				return
 			}
-			log.Error().Err(err).Msg("Failed to update user profile")
+			log.Ctx(c.Request.Context()).Error().Err(err).Msg("Failed to update user profile")
 			middleware.RespondWithInternalError(c, "Failed to update user profile")
 			return
 		}
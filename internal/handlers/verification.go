@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/middleware"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/service"
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+)
+
+// VerificationHandler handles email verification requests
+type VerificationHandler struct {
+	verificationService *service.VerificationService
+}
+
+// NewVerificationHandler creates a new verification handler
+func NewVerificationHandler(verificationService *service.VerificationService) *VerificationHandler {
+	return &VerificationHandler{
+		verificationService: verificationService,
+	}
+}
+
+// VerifyEmail handles confirming an email address with the emailed token
+func (h *VerificationHandler) VerifyEmail() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req service.VerifyEmailRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			middleware.RespondWithBadRequest(c, err.Error())
+			return
+		}
+
+		// Call the service to verify the email
+		user, err := h.verificationService.Verify(c.Request.Context(), req)
+		if err != nil {
+			if err.Error() == "invalid or expired token" {
+				middleware.RespondWithError(c, http.StatusBadRequest, "INVALID_TOKEN", "The verification link is invalid or has expired", nil)
+				return
+			}
+			log.Ctx(c.Request.Context()).Error().Err(err).Msg("Failed to verify email")
+			middleware.RespondWithInternalError(c, "Failed to verify email")
+			return
+		}
+
+		middleware.RespondWithSuccess(c, http.StatusOK, "Email verified", user)
+	}
+}
+
+// ResendVerification handles requesting a new verification email by address.
+// It always succeeds so that it does not reveal which addresses have an account.
+func (h *VerificationHandler) ResendVerification() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req service.ResendVerificationRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			middleware.RespondWithBadRequest(c, err.Error())
+			return
+		}
+
+		// Call the service to send the email
+		if err := h.verificationService.ResendByEmail(c.Request.Context(), req); err != nil {
+			log.Ctx(c.Request.Context()).Error().Err(err).Msg("Failed to resend verification email")
+			middleware.RespondWithInternalError(c, "Failed to resend verification email")
+			return
+		}
+
+		middleware.RespondWithSuccess(c, http.StatusAccepted, "If the address belongs to an unverified account, a verification email has been sent", nil)
+	}
+}
+
+// ResendMyVerification handles requesting a new verification email for the
+// current user
+func (h *VerificationHandler) ResendMyVerification() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// Get the user ID from the context
+		userID, err := middleware.GetUserID(c)
+		if err != nil {
+			middleware.RespondWithUnauthorized(c)
+			return
+		}
+
+		// Call the service to send the email
+		err = h.verificationService.Resend(c.Request.Context(), userID)
+		var throttled *service.ThrottledError
+		switch {
+		case err == nil:
+			middleware.RespondWithSuccess(c, http.StatusAccepted, "Verification email sent", nil)
+		case errors.As(err, &throttled):
+			seconds := setRetryAfter(c, throttled.RetryAfter)
+			middleware.RespondWithError(c, http.StatusTooManyRequests, "TOO_MANY_REQUESTS", "A verification email was sent recently, please try again later", gin.H{
+				"retry_after": seconds,
+			})
+		case err.Error() == "user not found":
+			middleware.RespondWithNotFound(c, "User")
+		case err.Error() == "email already verified":
+			middleware.RespondWithConflict(c, "Email is already verified")
+		default:
+			log.Ctx(c.Request.Context()).Error().Err(err).Msg("Failed to resend verification email")
+			middleware.RespondWithInternalError(c, "Failed to resend verification email")
+		}
+	}
+}
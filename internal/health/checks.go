@@ -0,0 +1,43 @@
+package health
+
+import (
+	"context"
+	"errors"
+
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/database"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/jobs"
+)
+
+var errNotConnected = errors.New("database is not connected")
+
+// Database checks that the connection supervisor considers the database
+// ready and that it answers a ping
+func Database(db *database.Database) Checker {
+	return Checker{Name: "database", Check: func(ctx context.Context) error {
+		if db == nil {
+			return errNotConnected
+		}
+		if err := db.Ready(); err != nil {
+			return err
+		}
+		return db.Ping(ctx)
+	}}
+}
+
+// Migrations checks that every migration embedded in the binary has been
+// applied, so that queries match the schema
+func Migrations(db *database.Database) Checker {
+	return Checker{Name: "migrations", Check: func(ctx context.Context) error {
+		if db == nil {
+			return errNotConnected
+		}
+		return db.CheckMigrations(ctx)
+	}}
+}
+
+// Scheduler checks that the background job scheduler is running
+func Scheduler(scheduler *jobs.Scheduler) Checker {
+	return Checker{Name: "scheduler", Check: func(context.Context) error {
+		return scheduler.Alive()
+	}}
+}
@@ -0,0 +1,156 @@
+// Package health aggregates the checks behind the readiness probe. Each
+// Checker tests one dependency; the service is ready when all of them pass.
+package health
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// DefaultTimeout bounds a single check, so that a hanging dependency makes
+// the probe fail instead of time out
+const DefaultTimeout = 2 * time.Second
+
+// DefaultCacheTTL is how long a readiness report is reused
+const DefaultCacheTTL = time.Second
+
+// Status of a check or the whole report
+const (
+	StatusUp       = "up"
+	StatusDown     = "down"
+	StatusReady    = "ready"
+	StatusNotReady = "not ready"
+)
+
+// Checker checks that a dependency is usable. Check returns nil when it is.
+type Checker struct {
+	Name  string
+	Check func(ctx context.Context) error
+}
+
+// Result is the outcome of one check. Error explains a failing check for the
+// logs; it is left out of the JSON because the probe is public.
+type Result struct {
+	Name      string  `json:"name"`
+	Status    string  `json:"status"`
+	LatencyMS float64 `json:"latency_ms"`
+	Error     string  `json:"-"`
+}
+
+// Report is the outcome of all checks
+type Report struct {
+	Status string   `json:"status"`
+	Checks []Result `json:"checks"`
+}
+
+// Ready reports whether every check passed
+func (r Report) Ready() bool {
+	return r.Status == StatusReady
+}
+
+// Readiness runs the registered checkers
+type Readiness struct {
+	timeout time.Duration
+
+	mu       sync.RWMutex
+	checkers []Checker
+
+	// The last report is reused for ttl, so that frequent probes do not each
+	// query the dependencies
+	checking  sync.Mutex
+	ttl       time.Duration
+	report    Report
+	checkedAt time.Time
+}
+
+// NewReadiness creates a readiness probe that gives each check timeout
+func NewReadiness(timeout time.Duration, checkers ...Checker) *Readiness {
+	return &Readiness{timeout: timeout, checkers: checkers}
+}
+
+// CacheFor makes Check reuse its report for ttl. Probes arriving while the
+// checks run wait for them and share their report.
+func (r *Readiness) CacheFor(ttl time.Duration) {
+	r.checking.Lock()
+	defer r.checking.Unlock()
+	r.ttl, r.checkedAt = ttl, time.Time{}
+}
+
+// Add registers another checker
+func (r *Readiness) Add(checker Checker) {
+	r.mu.Lock()
+	r.checkers = append(r.checkers, checker)
+	r.mu.Unlock()
+
+	// The cached report lacks the new check
+	r.checking.Lock()
+	r.checkedAt = time.Time{}
+	r.checking.Unlock()
+}
+
+// Check runs all checks at once and reports their results in the order the
+// checkers were registered. The checks are only bounded by the timeout, not
+// by ctx, so that a probe that gives up does not leave a failing report for
+// the others.
+func (r *Readiness) Check(ctx context.Context) Report {
+	r.checking.Lock()
+	defer r.checking.Unlock()
+	if r.ttl > 0 && !r.checkedAt.IsZero() && time.Since(r.checkedAt) < r.ttl {
+		return r.report
+	}
+
+	r.mu.RLock()
+	checkers := append([]Checker(nil), r.checkers...)
+	r.mu.RUnlock()
+
+	ctx = context.WithoutCancel(ctx)
+	results := make([]Result, len(checkers))
+	var wg sync.WaitGroup
+	for i, checker := range checkers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = r.check(ctx, checker)
+		}()
+	}
+	wg.Wait()
+
+	report := Report{Status: StatusReady, Checks: results}
+	for _, result := range results {
+		if result.Status != StatusUp {
+			report.Status = StatusNotReady
+		}
+	}
+	r.report, r.checkedAt = report, time.Now()
+	return report
+}
+
+func (r *Readiness) check(ctx context.Context, checker Checker) Result {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := make(chan error, 1)
+	go func() {
+		defer func() {
+			if recover() != nil {
+				err <- errors.New("check panicked")
+			}
+		}()
+		err <- checker.Check(ctx)
+	}()
+
+	result := Result{Name: checker.Name, Status: StatusUp}
+	select {
+	case e := <-err:
+		if e != nil {
+			result.Status, result.Error = StatusDown, e.Error()
+		}
+	case <-ctx.Done():
+		result.Status, result.Error = StatusDown, "timed out"
+	}
+	result.LatencyMS = float64(time.Since(start).Microseconds()) / 1000
+	return result
+}
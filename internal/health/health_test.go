@@ -0,0 +1,70 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestReadinessReportsEveryCheck(t *testing.T) {
+	readiness := NewReadiness(50*time.Millisecond,
+		Checker{Name: "ok", Check: func(context.Context) error { return nil }},
+		Checker{Name: "failing", Check: func(context.Context) error { return errors.New("broken") }},
+		Checker{Name: "hanging", Check: func(ctx context.Context) error { <-ctx.Done(); time.Sleep(time.Second); return nil }},
+		Checker{Name: "panicking", Check: func(context.Context) error { panic("oops") }},
+	)
+
+	start := time.Now()
+	report := readiness.Check(context.Background())
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("checks took %s, want them bounded by the timeout", elapsed)
+	}
+
+	if report.Ready() {
+		t.Error("report is ready, want not ready")
+	}
+	want := []Result{
+		{Name: "ok", Status: StatusUp},
+		{Name: "failing", Status: StatusDown, Error: "broken"},
+		{Name: "hanging", Status: StatusDown, Error: "timed out"},
+		{Name: "panicking", Status: StatusDown, Error: "check panicked"},
+	}
+	for i, result := range report.Checks {
+		result.LatencyMS = 0
+		if result != want[i] {
+			t.Errorf("check %d = %+v, want %+v", i, result, want[i])
+		}
+	}
+}
+
+func TestReadinessCachesReport(t *testing.T) {
+	var calls int
+	readiness := NewReadiness(time.Second, Checker{Name: "counted", Check: func(context.Context) error { calls++; return nil }})
+	readiness.CacheFor(time.Hour)
+
+	readiness.Check(context.Background())
+	readiness.Check(context.Background())
+	if calls != 1 {
+		t.Errorf("check ran %d times, want the report reused", calls)
+	}
+
+	readiness.Add(Checker{Name: "failing", Check: func(context.Context) error { return errors.New("broken") }})
+	if report := readiness.Check(context.Background()); report.Ready() || calls != 2 {
+		t.Errorf("after adding a checker report = %+v after %d runs, want the checks run again", report, calls)
+	}
+}
+
+func TestReadinessIgnoresCancelledProbes(t *testing.T) {
+	readiness := NewReadiness(time.Second, Checker{Name: "ok", Check: func(ctx context.Context) error { return ctx.Err() }})
+	readiness.CacheFor(time.Hour)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if report := readiness.Check(ctx); !report.Ready() {
+		t.Errorf("report for a cancelled probe = %+v, want ready", report)
+	}
+	if report := readiness.Check(context.Background()); !report.Ready() {
+		t.Errorf("cached report = %+v, want ready", report)
+	}
+}
@@ -0,0 +1,53 @@
+package importer
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+)
+
+// parseCSV parses a generic CSV file with a header row and the columns
+// habit, date (YYYY-MM-DD) and an optional notes column. Each row is one
+// completion.
+func parseCSV(data []byte) (*Dataset, error) {
+	rows, err := readCSV(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("invalid csv: %w", err)
+	}
+	if len(rows) == 0 {
+		return nil, errors.New("csv file is empty")
+	}
+
+	header := columnIndex(rows[0])
+	habitCol, dateCol, notesCol := header.col("habit"), header.col("date"), header.col("notes")
+	if habitCol < 0 || dateCol < 0 {
+		return nil, errors.New("csv file must have habit and date columns")
+	}
+
+	habits := make(map[string]*Habit)
+	var order []string
+	for i, row := range rows[1:] {
+		name := cell(row, habitCol)
+		if name == "" {
+			continue
+		}
+		date, err := parseDate(cell(row, dateCol))
+		if err != nil {
+			return nil, fmt.Errorf("csv line %d: %w", i+2, err)
+		}
+
+		habit, ok := habits[name]
+		if !ok {
+			habit = &Habit{Name: name}
+			habits[name] = habit
+			order = append(order, name)
+		}
+		habit.Completions = append(habit.Completions, Completion{Date: date, Notes: cell(row, notesCol)})
+	}
+
+	dataset := &Dataset{}
+	for _, name := range order {
+		dataset.Habits = append(dataset.Habits, *habits[name])
+	}
+	return dataset, nil
+}
@@ -0,0 +1,92 @@
+package importer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// habiticaExport is the subset of Habitica's user data export
+// (Settings > Site Data > Export Data > User Data) that is imported
+type habiticaExport struct {
+	Tasks struct {
+		Habits []habiticaTask `json:"habits"`
+		Dailys []habiticaTask `json:"dailys"`
+	} `json:"tasks"`
+}
+
+type habiticaTask struct {
+	Text      string            `json:"text"`
+	Notes     string            `json:"notes"`
+	CreatedAt time.Time         `json:"createdAt"`
+	History   []habiticaHistory `json:"history"`
+}
+
+type habiticaHistory struct {
+	Date      habiticaTime `json:"date"`
+	Completed *bool        `json:"completed"`
+	ScoredUp  int          `json:"scoredUp"`
+}
+
+// habiticaTime accepts both millisecond timestamps and RFC 3339 strings,
+// which Habitica has used interchangeably for history dates
+type habiticaTime struct {
+	time.Time
+}
+
+func (t *habiticaTime) UnmarshalJSON(data []byte) error {
+	var millis float64
+	if err := json.Unmarshal(data, &millis); err == nil {
+		t.Time = time.UnixMilli(int64(millis)).UTC()
+		return nil
+	}
+
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return fmt.Errorf("invalid habitica date %q", value)
+	}
+	t.Time = parsed
+	return nil
+}
+
+// parseHabitica parses a Habitica user data JSON export. Dailies count a day
+// as done when their history entry is completed, positive habits when they
+// were scored up at least once.
+func parseHabitica(data []byte) (*Dataset, error) {
+	var export habiticaExport
+	if err := json.Unmarshal(data, &export); err != nil {
+		return nil, fmt.Errorf("habitica export must be the user data JSON: %w", err)
+	}
+
+	if len(export.Tasks.Dailys) == 0 && len(export.Tasks.Habits) == 0 {
+		return nil, errors.New("habitica export contains no dailies or habits")
+	}
+
+	dataset := &Dataset{}
+	for _, task := range export.Tasks.Dailys {
+		habit := Habit{Name: task.Text, Description: task.Notes, CreatedAt: task.CreatedAt}
+		for _, entry := range task.History {
+			if entry.Completed != nil && *entry.Completed {
+				habit.Completions = append(habit.Completions, Completion{Date: entry.Date.Time})
+			}
+		}
+		dataset.Habits = append(dataset.Habits, habit)
+	}
+
+	for _, task := range export.Tasks.Habits {
+		habit := Habit{Name: task.Text, Description: task.Notes, CreatedAt: task.CreatedAt}
+		for _, entry := range task.History {
+			if entry.ScoredUp > 0 {
+				habit.Completions = append(habit.Completions, Completion{Date: entry.Date.Time})
+			}
+		}
+		dataset.Habits = append(dataset.Habits, habit)
+	}
+
+	return dataset, nil
+}
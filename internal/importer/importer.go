@@ -0,0 +1,130 @@
+// Package importer parses habit histories exported by other habit trackers
+// into a source independent Dataset.
+package importer
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Supported import formats
+const (
+	FormatLoop     = "loop"
+	FormatHabitica = "habitica"
+	FormatCSV      = "csv"
+)
+
+// ErrUnsupportedFormat is returned for unknown import formats
+var ErrUnsupportedFormat = errors.New("unsupported import format")
+
+// Completion is a single day on which a habit was done
+type Completion struct {
+	Date  time.Time
+	Notes string
+}
+
+// Habit is a habit and its completion history as found in the source
+type Habit struct {
+	Name        string
+	Description string
+	Color       string
+	CreatedAt   time.Time
+	Completions []Completion
+}
+
+// Dataset is the result of parsing an export
+type Dataset struct {
+	Habits []Habit
+}
+
+// Parse parses an export in the given format
+func Parse(format string, data []byte) (*Dataset, error) {
+	var (
+		dataset *Dataset
+		err     error
+	)
+
+	switch format {
+	case FormatLoop:
+		dataset, err = parseLoop(data)
+	case FormatHabitica:
+		dataset, err = parseHabitica(data)
+	case FormatCSV:
+		dataset, err = parseCSV(data)
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedFormat, format)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range dataset.Habits {
+		dataset.Habits[i].normalize()
+	}
+
+	return dataset, nil
+}
+
+// normalize truncates completions to UTC days, merges duplicates and sorts
+// them oldest first
+func (h *Habit) normalize() {
+	byDay := make(map[time.Time]Completion, len(h.Completions))
+	for _, c := range h.Completions {
+		day := truncateDay(c.Date)
+		merged := byDay[day]
+		merged.Date = day
+		if merged.Notes != "" && c.Notes != "" {
+			merged.Notes += "\n" + c.Notes
+		} else if c.Notes != "" {
+			merged.Notes = c.Notes
+		}
+		byDay[day] = merged
+	}
+
+	h.Completions = h.Completions[:0]
+	for _, c := range byDay {
+		h.Completions = append(h.Completions, c)
+	}
+	sort.Slice(h.Completions, func(i, j int) bool {
+		return h.Completions[i].Date.Before(h.Completions[j].Date)
+	})
+
+	h.Name = strings.TrimSpace(h.Name)
+	if len(h.Completions) > 0 && (h.CreatedAt.IsZero() || h.Completions[0].Date.Before(h.CreatedAt)) {
+		h.CreatedAt = h.Completions[0].Date
+	}
+	if !validColor(h.Color) {
+		h.Color = ""
+	}
+}
+
+func truncateDay(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+func validColor(color string) bool {
+	if len(color) != 7 || color[0] != '#' {
+		return false
+	}
+	for _, r := range color[1:] {
+		if !strings.ContainsRune("0123456789abcdefABCDEF", r) {
+			return false
+		}
+	}
+	return true
+}
+
+// parseDate accepts the date layouts used by the supported exports
+func parseDate(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	for _, layout := range []string{"2006-01-02", time.RFC3339, "2006-01-02 15:04:05"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q", value)
+}
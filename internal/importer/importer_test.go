@@ -0,0 +1,285 @@
+package importer
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func day(value string) time.Time {
+	t, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func buildZip(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func completionDays(h Habit) []string {
+	days := make([]string, len(h.Completions))
+	for i, c := range h.Completions {
+		days[i] = c.Date.Format("2006-01-02")
+	}
+	return days
+}
+
+func TestParse(t *testing.T) {
+	loopExport := buildZip(t, map[string]string{
+		"Habits.csv": "Position,Name,Question,Description,Color\n" +
+			"001,Read,Did you read today?,,#FF0000\n" +
+			"002,Run,,Morning run,purple\n",
+		"Checkmarks.csv": "Date,Read,Run,\n" +
+			"2024-01-03,2,0,\n" +
+			"2024-01-02,YES_MANUAL,1,\n" +
+			"2024-01-01,2,2,\n",
+	})
+
+	tests := []struct {
+		name      string
+		format    string
+		data      []byte
+		wantErr   string
+		wantNames []string
+		wantDays  [][]string
+	}{
+		{
+			name:      "loop",
+			format:    FormatLoop,
+			data:      loopExport,
+			wantNames: []string{"Read", "Run"},
+			wantDays:  [][]string{{"2024-01-01", "2024-01-02", "2024-01-03"}, {"2024-01-01"}},
+		},
+		{
+			name:    "loop without Habits.csv",
+			format:  FormatLoop,
+			data:    buildZip(t, map[string]string{"Checkmarks.csv": "Date\n"}),
+			wantErr: "Habits.csv not found",
+		},
+		{
+			name:    "loop not a zip",
+			format:  FormatLoop,
+			data:    []byte("Name\nRead\n"),
+			wantErr: "must be a zip archive",
+		},
+		{
+			name:   "habitica",
+			format: FormatHabitica,
+			data: []byte(`{"tasks":{
+				"dailys":[{"text":"Stretch","createdAt":"2024-01-01T08:00:00Z","history":[
+					{"date":1704103200000,"completed":true},
+					{"date":"2024-01-02T10:00:00Z","completed":false},
+					{"date":"2024-01-03T10:00:00Z","completed":true}]}],
+				"habits":[{"text":"Water","history":[
+					{"date":"2024-01-05T09:00:00Z","scoredUp":0},
+					{"date":"2024-01-06T09:00:00Z","scoredUp":2}]}]}}`),
+			wantNames: []string{"Stretch", "Water"},
+			wantDays:  [][]string{{"2024-01-01", "2024-01-03"}, {"2024-01-06"}},
+		},
+		{
+			name:    "habitica without tasks",
+			format:  FormatHabitica,
+			data:    []byte(`{"tasks":{}}`),
+			wantErr: "no dailies or habits",
+		},
+		{
+			name:   "csv merges duplicate days",
+			format: FormatCSV,
+			data: []byte("Date,Habit,Notes\n" +
+				"2024-01-02,Read,chapter 2\n" +
+				"2024-01-01,Read,chapter 1\n" +
+				"2024-01-02 21:00:00,Read,chapter 3\n" +
+				"2024-01-01,Meditate,\n"),
+			wantNames: []string{"Read", "Meditate"},
+			wantDays:  [][]string{{"2024-01-01", "2024-01-02"}, {"2024-01-01"}},
+		},
+		{
+			name:    "csv without date column",
+			format:  FormatCSV,
+			data:    []byte("habit,notes\nRead,\n"),
+			wantErr: "must have habit and date columns",
+		},
+		{
+			name:    "csv with invalid date",
+			format:  FormatCSV,
+			data:    []byte("habit,date\nRead,yesterday\n"),
+			wantErr: "csv line 2",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			dataset, err := Parse(tc.format, tc.data)
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("err = %v, want it to contain %q", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(dataset.Habits) != len(tc.wantNames) {
+				t.Fatalf("got %d habits, want %d", len(dataset.Habits), len(tc.wantNames))
+			}
+			for i, habit := range dataset.Habits {
+				if habit.Name != tc.wantNames[i] {
+					t.Errorf("habit %d name = %q, want %q", i, habit.Name, tc.wantNames[i])
+				}
+				if got, want := strings.Join(completionDays(habit), ","), strings.Join(tc.wantDays[i], ","); got != want {
+					t.Errorf("%s completions = %s, want %s", habit.Name, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestParseNormalizes(t *testing.T) {
+	data := buildZip(t, map[string]string{
+		"Habits.csv":     "Name,Color\nRead,#abcdef\nRun,purple\n",
+		"Checkmarks.csv": "Date,Read,Run\n2024-01-02,2,2\n",
+	})
+	dataset, err := Parse(FormatLoop, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := dataset.Habits[0].Color; got != "#abcdef" {
+		t.Errorf("valid color = %q, want it kept", got)
+	}
+	if got := dataset.Habits[1].Color; got != "" {
+		t.Errorf("invalid color = %q, want it dropped", got)
+	}
+	if got := dataset.Habits[0].CreatedAt; !got.Equal(day("2024-01-02")) {
+		t.Errorf("created at = %s, want the first completion", got)
+	}
+
+	notes, err := Parse(FormatCSV, []byte("habit,date,notes\nRead,2024-01-01,a\nRead,2024-01-01,b\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := notes.Habits[0].Completions[0].Notes; got != "a\nb" {
+		t.Errorf("merged notes = %q, want %q", got, "a\nb")
+	}
+}
+
+func TestParseUnsupportedFormat(t *testing.T) {
+	if _, err := Parse("streaks", nil); !errors.Is(err, ErrUnsupportedFormat) {
+		t.Fatalf("err = %v, want ErrUnsupportedFormat", err)
+	}
+}
+
+func TestParseLoopRejectsOversizedEntries(t *testing.T) {
+	// Highly compressible content beyond the limit, as in a zip bomb
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	w, err := zw.Create("Habits.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write([]byte("Name\nRead\n"))
+	w, err = zw.Create("Checkmarks.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	row := []byte(strings.Repeat("2024-01-01,2\n", 1<<10))
+	for written := 0; written <= maxZipEntrySize; written += len(row) {
+		w.Write(row)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if buf.Len() >= maxZipEntrySize/100 {
+		t.Fatalf("test archive is %d bytes, expected it to compress well", buf.Len())
+	}
+
+	_, err = Parse(FormatLoop, buf.Bytes())
+	if err == nil || !strings.Contains(err.Error(), "Checkmarks.csv is larger than") {
+		t.Fatalf("err = %v, want the entry to be rejected", err)
+	}
+}
+
+func TestReconstructRuns(t *testing.T) {
+	today := day("2024-03-01")
+
+	var completions []Completion
+	add := func(start string, days int) {
+		for i := 0; i < days; i++ {
+			completions = append(completions, Completion{Date: day(start).AddDate(0, 0, i)})
+		}
+	}
+	add("2024-01-01", 2) // failed, below the first milestone
+	add("2024-01-10", 8) // completed at 7
+	add("2024-02-01", 3) // completed at 3
+	add("2024-02-25", 6) // ends today, still active
+
+	runs := ReconstructRuns(completions, today.Add(15*time.Hour))
+
+	want := []struct {
+		start, end string
+		status     string
+		target     int
+	}{
+		{"2024-01-01", "2024-01-02", StatusFailed, 3},
+		{"2024-01-10", "2024-01-17", StatusCompleted, 7},
+		{"2024-02-01", "2024-02-03", StatusCompleted, 3},
+		{"2024-02-25", "2024-03-01", StatusActive, 7},
+	}
+	if len(runs) != len(want) {
+		t.Fatalf("got %d runs, want %d", len(runs), len(want))
+	}
+	for i, w := range want {
+		run := runs[i]
+		if !run.Start.Equal(day(w.start)) || !run.End.Equal(day(w.end)) {
+			t.Errorf("run %d = %s..%s, want %s..%s", i, run.Start.Format("2006-01-02"), run.End.Format("2006-01-02"), w.start, w.end)
+		}
+		if run.Status != w.status || run.TargetDays != w.target {
+			t.Errorf("run %d = %s/%d, want %s/%d", i, run.Status, run.TargetDays, w.status, w.target)
+		}
+	}
+
+	if got := runs[1].MilestoneReachedAt(); !got.Equal(day("2024-01-16")) {
+		t.Errorf("milestone reached at %s, want 2024-01-16", got.Format("2006-01-02"))
+	}
+}
+
+func TestReconstructRunsEndedBeforeYesterday(t *testing.T) {
+	completions := []Completion{{Date: day("2024-02-27")}, {Date: day("2024-02-28")}}
+
+	runs := ReconstructRuns(completions, day("2024-03-01"))
+	if len(runs) != 1 || runs[0].Status != StatusFailed {
+		t.Fatalf("runs = %+v, want a single failed run", runs)
+	}
+}
+
+func TestReconstructRunsBeyondLastMilestone(t *testing.T) {
+	var completions []Completion
+	for i := 0; i < 400; i++ {
+		completions = append(completions, Completion{Date: day("2023-01-01").AddDate(0, 0, i)})
+	}
+
+	runs := ReconstructRuns(completions, completions[len(completions)-1].Date)
+	if len(runs) != 1 || runs[0].Status != StatusActive || runs[0].TargetDays != 730 {
+		t.Fatalf("runs = %s/%d, want active aiming for 730", runs[0].Status, runs[0].TargetDays)
+	}
+}
@@ -0,0 +1,162 @@
+package importer
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+)
+
+// parseLoop parses the zip archive produced by Loop Habit Tracker's
+// "Export as CSV". The archive contains Habits.csv with one row per habit and
+// Checkmarks.csv with a Date column followed by one column per habit.
+func parseLoop(data []byte) (*Dataset, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("loop export must be a zip archive: %w", err)
+	}
+
+	habitsFile, err := readZipCSV(zr, "Habits.csv")
+	if err != nil {
+		return nil, err
+	}
+	checkmarksFile, err := readZipCSV(zr, "Checkmarks.csv")
+	if err != nil {
+		return nil, err
+	}
+
+	if len(habitsFile) == 0 {
+		return nil, errors.New("loop export: Habits.csv is empty")
+	}
+
+	header := columnIndex(habitsFile[0])
+	nameCol := header.col("name")
+	if nameCol < 0 {
+		return nil, errors.New("loop export: Habits.csv has no Name column")
+	}
+
+	habits := make(map[string]*Habit)
+	var order []string
+	for _, row := range habitsFile[1:] {
+		name := cell(row, nameCol)
+		if name == "" {
+			continue
+		}
+		description := cell(row, header.col("description"))
+		if description == "" {
+			description = cell(row, header.col("question"))
+		}
+		habits[name] = &Habit{
+			Name:        name,
+			Description: description,
+			Color:       cell(row, header.col("color")),
+		}
+		order = append(order, name)
+	}
+
+	if len(checkmarksFile) > 0 {
+		columns := checkmarksFile[0]
+		for _, row := range checkmarksFile[1:] {
+			if len(row) == 0 {
+				continue
+			}
+			date, err := parseDate(row[0])
+			if err != nil {
+				return nil, fmt.Errorf("loop export: %w", err)
+			}
+			for i := 1; i < len(row) && i < len(columns); i++ {
+				habit, ok := habits[strings.TrimSpace(columns[i])]
+				if !ok || !loopChecked(row[i]) {
+					continue
+				}
+				habit.Completions = append(habit.Completions, Completion{Date: date})
+			}
+		}
+	}
+
+	dataset := &Dataset{}
+	for _, name := range order {
+		dataset.Habits = append(dataset.Habits, *habits[name])
+	}
+	return dataset, nil
+}
+
+// loopChecked reports whether a checkmark value is a manual completion.
+// Older versions of Loop write numeric values (2 = checked manually,
+// 1 = implicitly completed by the habit frequency), newer ones write names.
+func loopChecked(value string) bool {
+	switch strings.TrimSpace(strings.ToUpper(value)) {
+	case "2", "YES_MANUAL", "YES":
+		return true
+	default:
+		return false
+	}
+}
+
+// maxZipEntrySize is the largest decompressed CSV file accepted from an
+// archive, so that a small zip bomb cannot exhaust memory
+const maxZipEntrySize = 64 << 20
+
+func readZipCSV(zr *zip.Reader, name string) ([][]string, error) {
+	for _, f := range zr.File {
+		if !strings.EqualFold(path.Base(f.Name), name) || strings.Count(strings.Trim(f.Name, "/"), "/") > 0 {
+			continue
+		}
+		if f.UncompressedSize64 > maxZipEntrySize {
+			return nil, fmt.Errorf("loop export: %s is larger than %d MB", name, maxZipEntrySize>>20)
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		defer rc.Close()
+
+		// The declared size can lie, so the read is limited as well
+		data, err := io.ReadAll(io.LimitReader(rc, maxZipEntrySize+1))
+		if err != nil {
+			return nil, fmt.Errorf("loop export: %s: %w", name, err)
+		}
+		if len(data) > maxZipEntrySize {
+			return nil, fmt.Errorf("loop export: %s is larger than %d MB", name, maxZipEntrySize>>20)
+		}
+		return readCSV(bytes.NewReader(data))
+	}
+	return nil, fmt.Errorf("loop export: %s not found in archive", name)
+}
+
+func readCSV(r io.Reader) ([][]string, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+	return cr.ReadAll()
+}
+
+// columns maps lower-cased CSV header names to their column index
+type columns map[string]int
+
+func columnIndex(header []string) columns {
+	index := make(columns, len(header))
+	for i, name := range header {
+		index[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))] = i
+	}
+	return index
+}
+
+// col returns the index of the named column or -1 if it does not exist
+func (c columns) col(name string) int {
+	if i, ok := c[name]; ok {
+		return i
+	}
+	return -1
+}
+
+func cell(row []string, i int) string {
+	if i < 0 || i >= len(row) {
+		return ""
+	}
+	return strings.TrimSpace(row[i])
+}
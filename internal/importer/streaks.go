@@ -0,0 +1,99 @@
+package importer
+
+import (
+	"time"
+)
+
+// Milestones are the streak targets used when reconstructing historical streaks
+var Milestones = []int{3, 7, 14, 21, 30, 60, 90, 180, 365}
+
+// Streak statuses, matching models.HabitStreak
+const (
+	StatusActive    = "active"
+	StatusCompleted = "completed"
+	StatusFailed    = "failed"
+)
+
+// Run is a streak reconstructed from consecutive days of completions
+type Run struct {
+	Completions []Completion
+	Start       time.Time
+	End         time.Time
+	Status      string
+	TargetDays  int
+}
+
+// Length returns the number of days in the run
+func (r Run) Length() int {
+	return len(r.Completions)
+}
+
+// MilestoneReachedAt returns the day the run reached its target
+func (r Run) MilestoneReachedAt() time.Time {
+	return r.Start.AddDate(0, 0, r.TargetDays-1)
+}
+
+// ReconstructRuns groups sorted, de-duplicated completions into runs of
+// consecutive days. A run that ends today or yesterday can still be continued
+// and becomes the active streak aiming for the next milestone. Earlier runs
+// are completed at the largest milestone they reached, or failed when they
+// never reached the first one.
+func ReconstructRuns(completions []Completion, today time.Time) []Run {
+	today = truncateDay(today)
+
+	var runs []Run
+	for _, c := range completions {
+		if n := len(runs); n > 0 && c.Date.Equal(runs[n-1].End.AddDate(0, 0, 1)) {
+			runs[n-1].Completions = append(runs[n-1].Completions, c)
+			runs[n-1].End = c.Date
+			continue
+		}
+		runs = append(runs, Run{
+			Completions: []Completion{c},
+			Start:       c.Date,
+			End:         c.Date,
+		})
+	}
+
+	for i := range runs {
+		run := &runs[i]
+		length := run.Length()
+
+		ongoing := i == len(runs)-1 && !run.End.Before(today.AddDate(0, 0, -1))
+		switch {
+		case ongoing:
+			run.Status = StatusActive
+			run.TargetDays = nextMilestone(length)
+		case length >= Milestones[0]:
+			run.Status = StatusCompleted
+			run.TargetDays = reachedMilestone(length)
+		default:
+			run.Status = StatusFailed
+			run.TargetDays = Milestones[0]
+		}
+	}
+
+	return runs
+}
+
+// nextMilestone returns the first milestone above length
+func nextMilestone(length int) int {
+	for _, m := range Milestones {
+		if m > length {
+			return m
+		}
+	}
+	last := Milestones[len(Milestones)-1]
+	return (length/last + 1) * last
+}
+
+// reachedMilestone returns the largest milestone not above length
+func reachedMilestone(length int) int {
+	reached := 0
+	for _, m := range Milestones {
+		if m <= length {
+			reached = m
+		}
+	}
+	return reached
+}
@@ -0,0 +1,40 @@
+package jobs
+
+import (
+	"context"
+
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/clock"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/service"
+	"github.com/rs/zerolog/log"
+)
+
+// AccountPurgeJob erases deleted accounts once their grace period has passed
+type AccountPurgeJob struct {
+	accountService *service.AccountService
+	clock          clock.Clock
+}
+
+// NewAccountPurgeJob creates a new account purge job
+func NewAccountPurgeJob(accountService *service.AccountService, clk clock.Clock) *AccountPurgeJob {
+	return &AccountPurgeJob{
+		accountService: accountService,
+		clock:          clk,
+	}
+}
+
+// Name implements Job
+func (j *AccountPurgeJob) Name() string {
+	return "account_purge"
+}
+
+// Run implements Job
+func (j *AccountPurgeJob) Run(ctx context.Context) error {
+	purged, err := j.accountService.PurgeDueAccounts(ctx, j.clock.Now())
+	if err != nil {
+		return err
+	}
+	if purged > 0 {
+		log.Info().Int("purged", purged).Msg("Deleted accounts purged")
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+package jobs
+
+import (
+	"context"
+	"time"
+
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/clock"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/lockout"
+	"github.com/rs/zerolog/log"
+)
+
+// LoginAttemptPruneJob removes failed login counts that no longer delay or
+// lock anything from the database store
+type LoginAttemptPruneJob struct {
+	store  lockout.Store
+	window time.Duration
+	clock  clock.Clock
+}
+
+// NewLoginAttemptPruneJob creates a new login attempt prune job
+func NewLoginAttemptPruneJob(store lockout.Store, window time.Duration, clk clock.Clock) *LoginAttemptPruneJob {
+	return &LoginAttemptPruneJob{
+		store:  store,
+		window: window,
+		clock:  clk,
+	}
+}
+
+// Name implements Job
+func (j *LoginAttemptPruneJob) Name() string {
+	return "login_attempt_prune"
+}
+
+// Run implements Job
+func (j *LoginAttemptPruneJob) Run(ctx context.Context) error {
+	pruned, err := j.store.Prune(ctx, j.clock.Now().UTC(), j.window)
+	if err != nil {
+		return err
+	}
+	if pruned > 0 {
+		log.Info().Int64("pruned", pruned).Msg("Stale login attempts pruned")
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+package jobs
+
+import (
+	"context"
+
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/clock"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/ratelimit"
+	"github.com/rs/zerolog/log"
+)
+
+// RateLimitPruneJob removes rate limit buckets that have refilled completely
+// from the database store
+type RateLimitPruneJob struct {
+	store ratelimit.Store
+	clock clock.Clock
+}
+
+// NewRateLimitPruneJob creates a new rate limit prune job
+func NewRateLimitPruneJob(store ratelimit.Store, clk clock.Clock) *RateLimitPruneJob {
+	return &RateLimitPruneJob{
+		store: store,
+		clock: clk,
+	}
+}
+
+// Name implements Job
+func (j *RateLimitPruneJob) Name() string {
+	return "rate_limit_prune"
+}
+
+// Run implements Job
+func (j *RateLimitPruneJob) Run(ctx context.Context) error {
+	pruned, err := j.store.Prune(ctx, j.clock.Now().UTC(), ratelimit.Period)
+	if err != nil {
+		return err
+	}
+	if pruned > 0 {
+		log.Info().Int64("pruned", pruned).Msg("Full rate limit buckets pruned")
+	}
+	return nil
+}
@@ -0,0 +1,26 @@
+package jobs
+
+import (
+	"context"
+
+	"github.com/rs/zerolog/log"
+)
+
+// RequireReady wraps job so that its runs are skipped while ready returns an
+// error, such as while the database is unavailable
+func RequireReady(job Job, ready func() error) Job {
+	return readyJob{Job: job, ready: ready}
+}
+
+type readyJob struct {
+	Job
+	ready func() error
+}
+
+func (j readyJob) Run(ctx context.Context) error {
+	if err := j.ready(); err != nil {
+		log.Warn().Err(err).Str("job", j.Name()).Msg("Skipping background job")
+		return nil
+	}
+	return j.Job.Run(ctx)
+}
@@ -0,0 +1,138 @@
+// Package jobs runs periodic background work such as the weekly digest.
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Job is a unit of periodic background work
+type Job interface {
+	Name() string
+	Run(ctx context.Context) error
+}
+
+// heartbeatInterval is how often a running scheduler records that it is
+// alive. Alive fails after three missed beats.
+const heartbeatInterval = 10 * time.Second
+
+type scheduledJob struct {
+	job      Job
+	interval time.Duration
+}
+
+// Scheduler runs registered jobs on a fixed interval until it is stopped
+type Scheduler struct {
+	jobs   []scheduledJob
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	// beat is the time of the last heartbeat in Unix nanoseconds, 0 when
+	// the scheduler is not running
+	beat atomic.Int64
+}
+
+// NewScheduler creates a new scheduler
+func NewScheduler() *Scheduler {
+	return &Scheduler{}
+}
+
+// Register adds a job that runs once at start and then every interval.
+// Jobs must be registered before Start is called.
+func (s *Scheduler) Register(job Job, interval time.Duration) {
+	s.jobs = append(s.jobs, scheduledJob{job: job, interval: interval})
+}
+
+// Start runs every registered job in its own goroutine
+func (s *Scheduler) Start(ctx context.Context) {
+	ctx, s.cancel = context.WithCancel(ctx)
+
+	for _, sj := range s.jobs {
+		s.wg.Add(1)
+		go s.loop(ctx, sj)
+	}
+
+	s.beat.Store(time.Now().UnixNano())
+	s.wg.Add(1)
+	go s.heartbeat(ctx)
+
+	log.Info().Int("jobs", len(s.jobs)).Msg("Background job scheduler started")
+}
+
+// Stop cancels all running jobs and waits for them to return
+func (s *Scheduler) Stop() {
+	if s.cancel == nil {
+		return
+	}
+	s.cancel()
+	s.wg.Wait()
+	s.beat.Store(0)
+	log.Info().Msg("Background job scheduler stopped")
+}
+
+// Alive returns an error unless the scheduler is running and its heartbeat
+// is recent
+func (s *Scheduler) Alive() error {
+	beat := s.beat.Load()
+	if beat == 0 {
+		return errors.New("scheduler is not running")
+	}
+	if age := time.Since(time.Unix(0, beat)); age > 3*heartbeatInterval {
+		return fmt.Errorf("no heartbeat for %s", age.Round(time.Second))
+	}
+	return nil
+}
+
+func (s *Scheduler) heartbeat(ctx context.Context) {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(heartbeatInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			s.beat.Store(now.UnixNano())
+		}
+	}
+}
+
+func (s *Scheduler) loop(ctx context.Context, sj scheduledJob) {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(sj.interval)
+	defer ticker.Stop()
+
+	for {
+		s.run(ctx, sj.job)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Scheduler) run(ctx context.Context, job Job) {
+	defer func() {
+		if err := recover(); err != nil {
+			log.Error().Interface("error", err).Str("job", job.Name()).Msg("Background job panicked")
+		}
+	}()
+
+	start := time.Now()
+	if err := job.Run(ctx); err != nil {
+		log.Error().Err(err).Str("job", job.Name()).Msg("Background job failed")
+		return
+	}
+	log.Debug().Str("job", job.Name()).Dur("duration", time.Since(start)).Msg("Background job completed")
+}
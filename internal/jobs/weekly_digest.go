@@ -0,0 +1,40 @@
+package jobs
+
+import (
+	"context"
+
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/clock"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/service"
+	"github.com/rs/zerolog/log"
+)
+
+// WeeklyDigestJob sends the weekly digest to users whose send day is today
+type WeeklyDigestJob struct {
+	digestService *service.DigestService
+	clock         clock.Clock
+}
+
+// NewWeeklyDigestJob creates a new weekly digest job
+func NewWeeklyDigestJob(digestService *service.DigestService, clk clock.Clock) *WeeklyDigestJob {
+	return &WeeklyDigestJob{
+		digestService: digestService,
+		clock:         clk,
+	}
+}
+
+// Name implements Job
+func (j *WeeklyDigestJob) Name() string {
+	return "weekly_digest"
+}
+
+// Run implements Job
+func (j *WeeklyDigestJob) Run(ctx context.Context) error {
+	sent, err := j.digestService.SendDueDigests(ctx, j.clock.Now())
+	if err != nil {
+		return err
+	}
+	if sent > 0 {
+		log.Info().Int("sent", sent).Msg("Weekly digests sent")
+	}
+	return nil
+}
@@ -0,0 +1,218 @@
+// Package jwtkeys manages the keys that sign and verify access tokens.
+//
+// Tokens carry the ID of their signing key in the kid header. A key set holds
+// the HMAC secret from AUTH_JWT_SECRET, former secrets that only verify, and
+// RSA or Ed25519 keys from AUTH_JWT_KEYS. Keys can be scheduled: the newest
+// key whose activation time has passed signs, and the key it replaced keeps
+// verifying until the tokens it signed have expired.
+package jwtkeys
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/config"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Signing algorithms supported for access tokens
+const (
+	AlgorithmHS256 = "HS256"
+	AlgorithmRS256 = "RS256"
+	AlgorithmEdDSA = "EdDSA"
+)
+
+// Algorithms lists every supported signing algorithm
+var Algorithms = []string{AlgorithmHS256, AlgorithmRS256, AlgorithmEdDSA}
+
+// Key is a token signing key
+type Key struct {
+	ID        string
+	Algorithm string
+
+	// ActiveFrom is when the key starts signing. The zero time means it was
+	// not scheduled and signs from the start.
+	ActiveFrom time.Time
+
+	// VerifyOnly keys never sign, they are former secrets kept until the
+	// tokens they signed have expired
+	VerifyOnly bool
+
+	signingKey interface{}
+	verifyKey  interface{}
+}
+
+// Public reports whether the key can be published in the JWKS, which is
+// never the case for shared HMAC secrets
+func (k *Key) Public() bool {
+	return k.Algorithm != AlgorithmHS256
+}
+
+func (k *Key) method() jwt.SigningMethod {
+	return jwt.GetSigningMethod(k.Algorithm)
+}
+
+// NewHMACKey creates an HS256 key for secret. Its ID is derived from the
+// secret so that every replica agrees on it without configuration.
+func NewHMACKey(secret string, verifyOnly bool) *Key {
+	sum := sha256.Sum256([]byte(secret))
+	return &Key{
+		ID:         "hs256-" + hex.EncodeToString(sum[:6]),
+		Algorithm:  AlgorithmHS256,
+		VerifyOnly: verifyOnly,
+		signingKey: []byte(secret),
+		verifyKey:  []byte(secret),
+	}
+}
+
+// Set is an immutable set of keys at a given token lifetime
+type Set struct {
+	keys     []*Key
+	signers  []*Key
+	lifetime time.Duration
+}
+
+// NewSet creates a key set. Tokens live for lifetime, which is how long a
+// replaced key keeps verifying.
+func NewSet(keys []*Key, lifetime time.Duration) (*Set, error) {
+	s := &Set{keys: keys, lifetime: lifetime}
+
+	ids := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		if ids[key.ID] {
+			return nil, fmt.Errorf("duplicate signing key ID %q", key.ID)
+		}
+		ids[key.ID] = true
+
+		if !key.VerifyOnly {
+			s.signers = append(s.signers, key)
+		}
+	}
+	if len(s.signers) == 0 {
+		return nil, errors.New("no signing key is configured, set AUTH_JWT_SECRET or AUTH_JWT_KEYS")
+	}
+
+	// Keys activating at the same time keep their configured order, so the
+	// later one wins
+	sort.SliceStable(s.signers, func(i, j int) bool {
+		return s.signers[i].ActiveFrom.Before(s.signers[j].ActiveFrom)
+	})
+
+	return s, nil
+}
+
+// FromConfig builds the key set from the auth settings
+func FromConfig(cfg *config.Config) (*Set, error) {
+	var keys []*Key
+	for _, secret := range cfg.Auth.JWTPreviousSecrets {
+		keys = append(keys, NewHMACKey(secret, true))
+	}
+	if cfg.Auth.JWTSecret != "" {
+		keys = append(keys, NewHMACKey(cfg.Auth.JWTSecret, false))
+	}
+	for _, spec := range cfg.Auth.JWTKeys {
+		key, err := LoadKey(spec)
+		if err != nil {
+			return nil, fmt.Errorf("AUTH_JWT_KEYS: %w", err)
+		}
+		keys = append(keys, key)
+	}
+
+	return NewSet(keys, cfg.Auth.TokenLifetime())
+}
+
+// Signing returns the key that signs tokens at now
+func (s *Set) Signing(now time.Time) (*Key, error) {
+	var current *Key
+	for _, key := range s.signers {
+		if key.ActiveFrom.After(now) {
+			break
+		}
+		current = key
+	}
+	if current == nil {
+		return nil, fmt.Errorf("no signing key is active yet, the first activates at %s", s.signers[0].ActiveFrom.Format(time.RFC3339))
+	}
+	return current, nil
+}
+
+// Verifies reports whether tokens signed with key are accepted at now
+func (s *Set) Verifies(key *Key, now time.Time) bool {
+	if key.ActiveFrom.After(now) {
+		return false
+	}
+	replacedAt, replaced := s.replacedAt(key, now)
+	return !replaced || now.Before(replacedAt.Add(s.lifetime))
+}
+
+// replacedAt returns when the next scheduled key took over from key. Keys
+// replaced by an unscheduled key keep verifying until they are removed.
+func (s *Set) replacedAt(key *Key, now time.Time) (time.Time, bool) {
+	for i, signer := range s.signers {
+		if signer != key || i == len(s.signers)-1 {
+			continue
+		}
+		next := s.signers[i+1]
+		if next.ActiveFrom.IsZero() || next.ActiveFrom.After(now) {
+			return time.Time{}, false
+		}
+		return next.ActiveFrom, true
+	}
+	return time.Time{}, false
+}
+
+// Sign signs claims with the key that is active at now
+func (s *Set) Sign(claims jwt.Claims, now time.Time) (string, error) {
+	key, err := s.Signing(now)
+	if err != nil {
+		return "", err
+	}
+
+	token := jwt.NewWithClaims(key.method(), claims)
+	token.Header["kid"] = key.ID
+	return token.SignedString(key.signingKey)
+}
+
+// Keyfunc returns the jwt.Keyfunc that verifies tokens at now. Tokens are
+// matched to their key by kid; tokens without one predate key IDs and are
+// checked against the HMAC secrets.
+func (s *Set) Keyfunc(now time.Time) jwt.Keyfunc {
+	return func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		if kid == "" {
+			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+				return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+			}
+
+			var keys jwt.VerificationKeySet
+			for _, key := range s.keys {
+				if key.Algorithm == AlgorithmHS256 && s.Verifies(key, now) {
+					keys.Keys = append(keys.Keys, key.verifyKey)
+				}
+			}
+			if len(keys.Keys) == 0 {
+				return nil, errors.New("no HMAC key verifies tokens without a key ID")
+			}
+			return keys, nil
+		}
+
+		for _, key := range s.keys {
+			if key.ID != kid {
+				continue
+			}
+			if !s.Verifies(key, now) {
+				return nil, fmt.Errorf("signing key %q is no longer accepted", kid)
+			}
+			if token.Method.Alg() != key.Algorithm {
+				return nil, fmt.Errorf("signing key %q does not use %v", kid, token.Header["alg"])
+			}
+			return key.verifyKey, nil
+		}
+
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+}
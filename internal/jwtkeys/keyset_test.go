@@ -0,0 +1,201 @@
+package jwtkeys
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const lifetime = 72 * time.Hour
+
+var (
+	january  = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	february = time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+)
+
+func writeKey(t *testing.T, private interface{}) string {
+	t.Helper()
+
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		t.Fatalf("marshal key: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "key.pem")
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600); err != nil {
+		t.Fatalf("write key: %v", err)
+	}
+	return path
+}
+
+func rsaKeyFile(t *testing.T) string {
+	t.Helper()
+	private, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate RSA key: %v", err)
+	}
+	return writeKey(t, private)
+}
+
+func ed25519KeyFile(t *testing.T) string {
+	t.Helper()
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("generate Ed25519 key: %v", err)
+	}
+	return writeKey(t, private)
+}
+
+func loadKey(t *testing.T, spec string) *Key {
+	t.Helper()
+	key, err := LoadKey(spec)
+	if err != nil {
+		t.Fatalf("LoadKey(%q): %v", spec, err)
+	}
+	return key
+}
+
+func sign(t *testing.T, set *Set, now time.Time) string {
+	t.Helper()
+	token, err := set.Sign(jwt.RegisteredClaims{ExpiresAt: jwt.NewNumericDate(now.Add(lifetime))}, now)
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+	return token
+}
+
+func verify(set *Set, token string, now time.Time) error {
+	_, err := jwt.Parse(token, set.Keyfunc(now), jwt.WithValidMethods(Algorithms), jwt.WithTimeFunc(func() time.Time { return now }))
+	return err
+}
+
+func kidOf(t *testing.T, token string) string {
+	t.Helper()
+	parsed, _, err := jwt.NewParser().ParseUnverified(token, jwt.MapClaims{})
+	if err != nil {
+		t.Fatalf("parse token: %v", err)
+	}
+	kid, _ := parsed.Header["kid"].(string)
+	return kid
+}
+
+// legacyToken signs a token without a kid, as issued before key IDs existed
+func legacyToken(t *testing.T, key *Key) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{}).SignedString(key.signingKey)
+	if err != nil {
+		t.Fatalf("sign HMAC token: %v", err)
+	}
+	return token
+}
+
+func TestScheduledRotation(t *testing.T) {
+	secret := NewHMACKey("a-development-secret", false)
+	rsaKey := loadKey(t, "rsa-2024-01:"+rsaKeyFile(t)+"@"+january.Format(time.RFC3339))
+	edKey := loadKey(t, "ed-2024-02:"+ed25519KeyFile(t)+"@"+february.Format(time.RFC3339))
+
+	set, err := NewSet([]*Key{secret, rsaKey, edKey}, lifetime)
+	if err != nil {
+		t.Fatalf("NewSet: %v", err)
+	}
+
+	before := january.Add(-time.Hour)
+	oldToken := sign(t, set, before)
+	if kid := kidOf(t, oldToken); kid != secret.ID {
+		t.Fatalf("token before any scheduled key has kid %q, want %q", kid, secret.ID)
+	}
+
+	rsaToken := sign(t, set, january.Add(time.Hour))
+	if kid := kidOf(t, rsaToken); kid != "rsa-2024-01" {
+		t.Fatalf("token after the RSA key activated has kid %q", kid)
+	}
+
+	// The replaced secret verifies until the tokens it signed have expired
+	if err := verify(set, oldToken, january.Add(lifetime-2*time.Hour)); err != nil {
+		t.Fatalf("token signed before the rotation rejected within its lifetime: %v", err)
+	}
+	if err := verify(set, legacyToken(t, secret), january.Add(lifetime+time.Minute)); err == nil {
+		t.Fatal("replaced secret still verifies after the token lifetime")
+	}
+
+	// The Ed25519 key is published before it activates and signs after
+	published := set.JWKS(january.Add(time.Hour))
+	if len(published.Keys) != 2 || published.Keys[0].KeyType != "RSA" || published.Keys[1].Curve != "Ed25519" {
+		t.Fatalf("JWKS before the Ed25519 key activates = %+v, want the RSA and Ed25519 keys", published)
+	}
+	edToken := sign(t, set, february.Add(time.Hour))
+	if kid := kidOf(t, edToken); kid != "ed-2024-02" {
+		t.Fatalf("token after the Ed25519 key activated has kid %q", kid)
+	}
+	if err := verify(set, edToken, february.Add(2*time.Hour)); err != nil {
+		t.Fatalf("Ed25519 token rejected: %v", err)
+	}
+	if err := verify(set, sign(t, set, february.Add(-time.Hour)), february.Add(2*time.Hour)); err != nil {
+		t.Fatalf("RSA token rejected right after the rotation: %v", err)
+	}
+
+	if published := set.JWKS(february.Add(lifetime + time.Hour)); len(published.Keys) != 1 || published.Keys[0].ID != "ed-2024-02" {
+		t.Fatalf("JWKS after the RSA key retired = %+v, want only the Ed25519 key", published)
+	}
+}
+
+func TestTokensWithoutKeyIDUseHMACSecrets(t *testing.T) {
+	previous := NewHMACKey("the-previous-secret", true)
+	current := NewHMACKey("the-current-secret", false)
+	set, err := NewSet([]*Key{previous, current}, lifetime)
+	if err != nil {
+		t.Fatalf("NewSet: %v", err)
+	}
+
+	for _, key := range []*Key{previous, current} {
+		if err := verify(set, legacyToken(t, key), january); err != nil {
+			t.Errorf("legacy token for %s rejected: %v", key.ID, err)
+		}
+	}
+	if err := verify(set, legacyToken(t, NewHMACKey("unknown", false)), january); err == nil {
+		t.Error("legacy token signed with an unknown secret accepted")
+	}
+	if len(set.JWKS(january).Keys) != 0 {
+		t.Error("HMAC secrets published in the JWKS")
+	}
+}
+
+func TestKeyIDMustMatchAlgorithm(t *testing.T) {
+	secret := NewHMACKey("a-development-secret", false)
+	rsaKey := loadKey(t, "rsa:"+rsaKeyFile(t))
+	set, err := NewSet([]*Key{secret, rsaKey}, lifetime)
+	if err != nil {
+		t.Fatalf("NewSet: %v", err)
+	}
+
+	// An HS256 token claiming the RSA key's kid must not be accepted
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{})
+	token.Header["kid"] = "rsa"
+	forged, err := token.SignedString(secret.signingKey)
+	if err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+	if err := verify(set, forged, january); err == nil {
+		t.Fatal("token with a mismatched algorithm accepted")
+	}
+}
+
+func TestLoadKeyRejectsBadSpecs(t *testing.T) {
+	for _, spec := range []string{
+		"no-path",
+		"bad kid:" + ed25519KeyFile(t),
+		"kid:/does/not/exist.pem",
+		"kid:" + ed25519KeyFile(t) + "@tomorrow",
+	} {
+		if _, err := LoadKey(spec); err == nil {
+			t.Errorf("LoadKey(%q) succeeded", spec)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package jwtkeys
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// minRSABits is the smallest RSA key accepted for RS256
+const minRSABits = 2048
+
+var keyIDPattern = regexp.MustCompile(`^[A-Za-z0-9._-]+$`)
+
+// LoadKey loads a key from a spec of the form "kid:path" or
+// "kid:path@activation", where path is a PEM encoded RSA or Ed25519 private
+// key and activation is an RFC 3339 time. The algorithm follows from the key
+// type: RS256 for RSA and EdDSA for Ed25519.
+func LoadKey(spec string) (*Key, error) {
+	id, rest, ok := strings.Cut(spec, ":")
+	if !ok || id == "" || rest == "" {
+		return nil, fmt.Errorf("%q is not of the form kid:path or kid:path@activation", spec)
+	}
+	if !keyIDPattern.MatchString(id) {
+		return nil, fmt.Errorf("key ID %q may only contain letters, digits, '.', '_' and '-'", id)
+	}
+
+	path := rest
+	var activeFrom time.Time
+	if at := strings.LastIndex(rest, "@"); at >= 0 {
+		var err error
+		path = rest[:at]
+		if activeFrom, err = time.Parse(time.RFC3339, rest[at+1:]); err != nil {
+			return nil, fmt.Errorf("key %s: activation %q is not an RFC 3339 time", id, rest[at+1:])
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("key %s: %w", id, err)
+	}
+
+	key, err := ParsePrivateKey(data)
+	if err != nil {
+		return nil, fmt.Errorf("key %s: %w", id, err)
+	}
+	key.ID = id
+	key.ActiveFrom = activeFrom
+
+	return key, nil
+}
+
+// ParsePrivateKey parses a PEM encoded PKCS #1 RSA key or PKCS #8 RSA or
+// Ed25519 key. The returned key has no ID yet.
+func ParsePrivateKey(data []byte) (*Key, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM data found")
+	}
+
+	var private interface{}
+	var err error
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		private, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		private, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q, expected a private key", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	switch private := private.(type) {
+	case *rsa.PrivateKey:
+		if private.N.BitLen() < minRSABits {
+			return nil, fmt.Errorf("RSA key has %d bits, at least %d are required", private.N.BitLen(), minRSABits)
+		}
+		return &Key{Algorithm: AlgorithmRS256, signingKey: private, verifyKey: &private.PublicKey}, nil
+	case ed25519.PrivateKey:
+		return &Key{Algorithm: AlgorithmEdDSA, signingKey: private, verifyKey: private.Public()}, nil
+	default:
+		return nil, fmt.Errorf("unsupported private key type %T, expected RSA or Ed25519", private)
+	}
+}
@@ -0,0 +1,55 @@
+package jwtkeys
+
+import (
+	"sync"
+
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/config"
+)
+
+// Provider keeps the key set in step with the configuration holder. Key files
+// are read once per configuration, not per token.
+type Provider struct {
+	cfgs *config.Holder
+
+	mu  sync.Mutex
+	cfg *config.Config
+	set *Set
+}
+
+// NewProvider loads the keys of the current configuration and rejects
+// reloads whose keys cannot be loaded
+func NewProvider(cfgs *config.Holder) (*Provider, error) {
+	p := &Provider{cfgs: cfgs}
+	if _, err := p.Current(); err != nil {
+		return nil, err
+	}
+
+	cfgs.Check(func(cfg *config.Config) error {
+		_, err := p.load(cfg)
+		return err
+	})
+
+	return p, nil
+}
+
+// Current returns the key set of the current configuration
+func (p *Provider) Current() (*Set, error) {
+	return p.load(p.cfgs.Get())
+}
+
+func (p *Provider) load(cfg *config.Config) (*Set, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if cfg == p.cfg {
+		return p.set, nil
+	}
+
+	set, err := FromConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	p.cfg, p.set = cfg, set
+
+	return set, nil
+}
@@ -0,0 +1,119 @@
+package lockout
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/models"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// GormStore keeps the counts in the login_attempts table, so that every
+// replica sees the same counts
+type GormStore struct {
+	db *gorm.DB
+}
+
+// NewGormStore creates a new database store
+func NewGormStore(db *gorm.DB) *GormStore {
+	return &GormStore{db: db}
+}
+
+// Get implements Store
+func (s *GormStore) Get(ctx context.Context, key string) (*models.LoginAttempt, error) {
+	var attempt models.LoginAttempt
+	result := s.db.WithContext(ctx).Where("attempt_key = ?", key).First(&attempt)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		log.Ctx(ctx).Error().Err(result.Error).Msg("Failed to find login attempts")
+		return nil, result.Error
+	}
+	return &attempt, nil
+}
+
+// Fail implements Store. The count is incremented in the database, and the
+// row stays locked until the transaction ends, so concurrent failures on
+// other replicas each see their own count.
+func (s *GormStore) Fail(ctx context.Context, key string, now time.Time, window time.Duration) (*models.LoginAttempt, error) {
+	var attempt models.LoginAttempt
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		upsert := tx.Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "attempt_key"}},
+			DoUpdates: clause.Assignments(map[string]interface{}{
+				"failures":        gorm.Expr("CASE WHEN login_attempts.last_failure_at < ? THEN 1 ELSE login_attempts.failures + 1 END", now.Add(-window)),
+				"last_failure_at": now,
+			}),
+		}).Create(&models.LoginAttempt{Key: key, Failures: 1, LastFailureAt: now})
+		if upsert.Error != nil {
+			return upsert.Error
+		}
+		return tx.Where("attempt_key = ?", key).First(&attempt).Error
+	})
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msg("Failed to record failed login")
+		return nil, err
+	}
+	return &attempt, nil
+}
+
+// Lock implements Store
+func (s *GormStore) Lock(ctx context.Context, key string, now, until time.Time, unlockTokenHash *string) (bool, error) {
+	result := s.db.WithContext(ctx).Model(&models.LoginAttempt{}).
+		Where("attempt_key = ? AND (locked_until IS NULL OR locked_until <= ?)", key, now).
+		Updates(map[string]interface{}{
+			"locked_until":      until,
+			"unlock_token_hash": unlockTokenHash,
+		})
+	if result.Error != nil {
+		log.Ctx(ctx).Error().Err(result.Error).Msg("Failed to lock login")
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// Unlock implements Store
+func (s *GormStore) Unlock(ctx context.Context, unlockTokenHash string, now time.Time) (string, error) {
+	var attempt models.LoginAttempt
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("unlock_token_hash = ? AND locked_until > ?", unlockTokenHash, now).First(&attempt)
+		if result.Error != nil {
+			return result.Error
+		}
+		return tx.Where("attempt_key = ?", attempt.Key).Delete(&models.LoginAttempt{}).Error
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", nil
+	}
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msg("Failed to unlock login")
+		return "", err
+	}
+	return attempt.Key, nil
+}
+
+// Reset implements Store
+func (s *GormStore) Reset(ctx context.Context, key string) error {
+	result := s.db.WithContext(ctx).Where("attempt_key = ?", key).Delete(&models.LoginAttempt{})
+	if result.Error != nil {
+		log.Ctx(ctx).Error().Err(result.Error).Msg("Failed to reset login attempts")
+		return result.Error
+	}
+	return nil
+}
+
+// Prune implements Store
+func (s *GormStore) Prune(ctx context.Context, now time.Time, window time.Duration) (int64, error) {
+	result := s.db.WithContext(ctx).
+		Where("last_failure_at < ? AND (locked_until IS NULL OR locked_until <= ?)", now.Add(-window), now).
+		Delete(&models.LoginAttempt{})
+	if result.Error != nil {
+		log.Ctx(ctx).Error().Err(result.Error).Msg("Failed to prune login attempts")
+		return 0, result.Error
+	}
+	return result.RowsAffected, nil
+}
@@ -0,0 +1,244 @@
+// Package lockout protects password logins against guessing.
+//
+// Failed logins are counted per account and per client IP. After a few
+// failures an account has to wait before the next attempt, with the wait
+// doubling on every failure, and enough failures lock the account or the IP
+// for a while. A locked account can be unlocked early with a token that is
+// emailed to its owner.
+//
+// The counts are kept in a Store: in memory for a single instance, or in the
+// database when several replicas serve logins.
+package lockout
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/clock"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/config"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/models"
+	"gorm.io/gorm"
+)
+
+const (
+	accountPrefix = "account:"
+	ipPrefix      = "ip:"
+)
+
+// Store keeps the failed login counts. Implementations must be safe for
+// concurrent use, and Lock must succeed for only one of several concurrent
+// callers.
+type Store interface {
+	// Get returns the attempts recorded for key, or nil if there are none
+	Get(ctx context.Context, key string) (*models.LoginAttempt, error)
+	// Fail records a failed login at now and returns the updated attempts.
+	// Failures before now-window are forgotten first.
+	Fail(ctx context.Context, key string, now time.Time, window time.Duration) (*models.LoginAttempt, error)
+	// Lock locks key until the given time and reports whether it did, which
+	// it does not if key is already locked at now
+	Lock(ctx context.Context, key string, now, until time.Time, unlockTokenHash *string) (bool, error)
+	// Unlock removes the attempts locked with the token hash until after
+	// now and returns their key, or "" if there are none
+	Unlock(ctx context.Context, unlockTokenHash string, now time.Time) (string, error)
+	// Reset removes the attempts recorded for key
+	Reset(ctx context.Context, key string) error
+	// Prune removes the attempts that are neither locked at now nor had a
+	// failure since now-window, and returns how many were removed
+	Prune(ctx context.Context, now time.Time, window time.Duration) (int64, error)
+}
+
+// NewStoreFromConfig creates the store selected by LOGIN_LOCKOUT_STORE
+func NewStoreFromConfig(cfg *config.Config, db *gorm.DB) Store {
+	if cfg.Lockout.Store == config.LockoutStoreDatabase {
+		return NewGormStore(db)
+	}
+	return NewMemoryStore()
+}
+
+// Error is returned by Check when a login must not be attempted yet
+type Error struct {
+	// Locked is set when the account or client IP is locked, rather than
+	// waiting after a recent failure
+	Locked     bool
+	RetryAfter time.Duration
+}
+
+func (e *Error) Error() string {
+	if e.Locked {
+		return "login locked"
+	}
+	return "login throttled"
+}
+
+// Outcome describes what a failed login led to
+type Outcome struct {
+	// AccountLocked is set when this failure locked the account, in which
+	// case UnlockToken unlocks it early
+	AccountLocked bool
+	UnlockToken   string
+	// IPLocked is set when this failure locked the client IP
+	IPLocked bool
+}
+
+// Guard applies the lockout policy from the configuration
+type Guard struct {
+	store Store
+	cfgs  *config.Holder
+	clock clock.Clock
+}
+
+// NewGuard creates a new guard
+func NewGuard(store Store, cfgs *config.Holder, clk clock.Clock) *Guard {
+	return &Guard{
+		store: store,
+		cfgs:  cfgs,
+		clock: clk,
+	}
+}
+
+// Check returns an *Error if a login to the account with the email from the
+// client IP has to wait. It is called before the password is checked, so
+// that waiting attempts cost nothing and reveal nothing.
+func (g *Guard) Check(ctx context.Context, email, ip string) error {
+	cfg := g.cfgs.Get().Lockout
+	now := g.clock.Now().UTC()
+
+	account, err := g.store.Get(ctx, AccountKey(email))
+	if err != nil {
+		return err
+	}
+	// Only accounts are slowed down, as many users can share an IP
+	wait := waitFor(cfg, account, now, true)
+
+	if ip != "" {
+		client, err := g.store.Get(ctx, IPKey(ip))
+		if err != nil {
+			return err
+		}
+		if ipWait := waitFor(cfg, client, now, false); ipWait != nil && (wait == nil || ipWait.RetryAfter > wait.RetryAfter) {
+			wait = ipWait
+		}
+	}
+
+	if wait == nil {
+		return nil
+	}
+	return wait
+}
+
+// Failure records a failed login and locks the account or client IP when it
+// reaches its limit
+func (g *Guard) Failure(ctx context.Context, email, ip string) (Outcome, error) {
+	cfg := g.cfgs.Get().Lockout
+	now := g.clock.Now().UTC()
+	var outcome Outcome
+
+	account, err := g.store.Fail(ctx, AccountKey(email), now, cfg.Window)
+	if err != nil {
+		return outcome, err
+	}
+	if account.Failures >= cfg.AccountLimit {
+		token, err := newToken()
+		if err != nil {
+			return outcome, err
+		}
+		hash := HashToken(token)
+		locked, err := g.store.Lock(ctx, account.Key, now, now.Add(cfg.Duration), &hash)
+		if err != nil {
+			return outcome, err
+		}
+		if locked {
+			outcome.AccountLocked = true
+			outcome.UnlockToken = token
+		}
+	}
+
+	if ip == "" {
+		return outcome, nil
+	}
+	client, err := g.store.Fail(ctx, IPKey(ip), now, cfg.Window)
+	if err != nil {
+		return outcome, err
+	}
+	if client.Failures >= cfg.IPLimit {
+		locked, err := g.store.Lock(ctx, client.Key, now, now.Add(cfg.Duration), nil)
+		if err != nil {
+			return outcome, err
+		}
+		outcome.IPLocked = locked
+	}
+
+	return outcome, nil
+}
+
+// Success forgets the failed logins of the account. Those of the client IP
+// are kept, so that an attacker cannot reset them with an account of their own.
+func (g *Guard) Success(ctx context.Context, email string) error {
+	return g.store.Reset(ctx, AccountKey(email))
+}
+
+// Unlock unlocks the account the unlock token was sent for and returns its
+// email, or "" if the token is unknown or the lock has already expired
+func (g *Guard) Unlock(ctx context.Context, token string) (string, error) {
+	key, err := g.store.Unlock(ctx, HashToken(token), g.clock.Now().UTC())
+	if err != nil || !strings.HasPrefix(key, accountPrefix) {
+		return "", err
+	}
+	return strings.TrimPrefix(key, accountPrefix), nil
+}
+
+// AccountKey returns the store key for the account with the email
+func AccountKey(email string) string {
+	return accountPrefix + strings.ToLower(strings.TrimSpace(email))
+}
+
+// IPKey returns the store key for a client IP
+func IPKey(ip string) string {
+	return ipPrefix + ip
+}
+
+// HashToken returns the hash stored in place of an unlock token
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// waitFor returns how long the next login has to wait after the attempts,
+// or nil if it does not. Progressive delays only apply when delay is set.
+func waitFor(cfg config.LockoutConfig, attempt *models.LoginAttempt, now time.Time, delay bool) *Error {
+	if attempt == nil {
+		return nil
+	}
+	if attempt.LockedUntil != nil && now.Before(*attempt.LockedUntil) {
+		return &Error{Locked: true, RetryAfter: attempt.LockedUntil.Sub(now)}
+	}
+	if !delay || attempt.Failures < cfg.DelayAfter || now.Sub(attempt.LastFailureAt) >= cfg.Window {
+		return nil
+	}
+
+	wait := cfg.Delay
+	for i := cfg.DelayAfter; i < attempt.Failures && wait > 0 && wait < cfg.MaxDelay; i++ {
+		wait *= 2
+	}
+	if wait > cfg.MaxDelay {
+		wait = cfg.MaxDelay
+	}
+	if retry := attempt.LastFailureAt.Add(wait).Sub(now); retry > 0 {
+		return &Error{RetryAfter: retry}
+	}
+	return nil
+}
+
+// newToken returns a random URL-safe unlock token
+func newToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
@@ -0,0 +1,232 @@
+package lockout_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/clock"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/config"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/database"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/lockout"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/migrate"
+)
+
+var start = time.Date(2024, 3, 10, 9, 0, 0, 0, time.UTC)
+
+// stores returns a fresh store of every kind, the database store on a
+// migrated in-memory SQLite database
+func stores(t *testing.T) map[string]lockout.Store {
+	t.Helper()
+
+	db, err := database.NewDatabase(&config.Config{
+		Database: config.DatabaseConfig{Driver: database.DriverSQLite, SQLitePath: ":memory:"},
+	})
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	t.Cleanup(db.Close)
+	migrator, err := migrate.New(db.DB)
+	if err != nil {
+		t.Fatalf("load migrations: %v", err)
+	}
+	if _, err := migrator.Up(context.Background()); err != nil {
+		t.Fatalf("migrate database: %v", err)
+	}
+
+	return map[string]lockout.Store{
+		"memory":   lockout.NewMemoryStore(),
+		"database": lockout.NewGormStore(db.DB),
+	}
+}
+
+func newGuard(store lockout.Store) (*lockout.Guard, *clock.Fake) {
+	cfg := &config.Config{Lockout: config.LockoutConfig{
+		Window:       15 * time.Minute,
+		DelayAfter:   3,
+		Delay:        time.Second,
+		MaxDelay:     4 * time.Second,
+		AccountLimit: 6,
+		IPLimit:      8,
+		Duration:     30 * time.Minute,
+	}}
+	clk := clock.NewFake(start)
+	return lockout.NewGuard(store, config.NewHolder(cfg, ""), clk), clk
+}
+
+// retryAfter returns the wait Check reports, or 0 if the login may proceed
+func retryAfter(t *testing.T, guard *lockout.Guard, email, ip string) (time.Duration, bool) {
+	t.Helper()
+	err := guard.Check(context.Background(), email, ip)
+	if err == nil {
+		return 0, false
+	}
+	var wait *lockout.Error
+	if !errors.As(err, &wait) {
+		t.Fatalf("Check: %v", err)
+	}
+	return wait.RetryAfter, wait.Locked
+}
+
+func TestStores(t *testing.T) {
+	for name, store := range stores(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+
+			for i := 1; i <= 3; i++ {
+				attempt, err := store.Fail(ctx, "account:ada@example.com", start.Add(time.Duration(i)*time.Minute), 15*time.Minute)
+				if err != nil {
+					t.Fatalf("Fail: %v", err)
+				}
+				if attempt.Failures != i || attempt.Key != "account:ada@example.com" {
+					t.Fatalf("Fail returned %+v, want %d failures", attempt, i)
+				}
+			}
+
+			// Failures older than the window are forgotten
+			attempt, err := store.Fail(ctx, "account:ada@example.com", start.Add(time.Hour), 15*time.Minute)
+			if err != nil || attempt.Failures != 1 {
+				t.Fatalf("Fail after the window returned %+v, %v, want 1 failure", attempt, err)
+			}
+
+			// Only one of two lockers wins
+			hash := "unlock-hash"
+			now := start.Add(time.Hour)
+			if locked, err := store.Lock(ctx, "account:ada@example.com", now, now.Add(30*time.Minute), &hash); err != nil || !locked {
+				t.Fatalf("Lock = %v, %v, want true", locked, err)
+			}
+			if locked, err := store.Lock(ctx, "account:ada@example.com", now, now.Add(time.Hour), nil); err != nil || locked {
+				t.Fatalf("second Lock = %v, %v, want false", locked, err)
+			}
+			if locked, err := store.Lock(ctx, "account:nobody@example.com", now, now.Add(time.Hour), nil); err != nil || locked {
+				t.Fatalf("Lock without attempts = %v, %v, want false", locked, err)
+			}
+
+			// Pruning keeps locked and recent attempts
+			if _, err := store.Fail(ctx, "ip:203.0.113.7", start, 15*time.Minute); err != nil {
+				t.Fatalf("Fail: %v", err)
+			}
+			if pruned, err := store.Prune(ctx, now.Add(20*time.Minute), 15*time.Minute); err != nil || pruned != 1 {
+				t.Fatalf("Prune = %d, %v, want the IP attempts pruned", pruned, err)
+			}
+
+			// The unlock token only works while the lock lasts
+			if key, err := store.Unlock(ctx, hash, now.Add(31*time.Minute)); err != nil || key != "" {
+				t.Fatalf("Unlock after the lock expired = %q, %v, want nothing", key, err)
+			}
+			if key, err := store.Unlock(ctx, hash, now.Add(time.Minute)); err != nil || key != "account:ada@example.com" {
+				t.Fatalf("Unlock = %q, %v, want the account key", key, err)
+			}
+			if attempt, err := store.Get(ctx, "account:ada@example.com"); err != nil || attempt != nil {
+				t.Fatalf("Get after Unlock = %+v, %v, want nothing", attempt, err)
+			}
+
+			if _, err := store.Fail(ctx, "account:grace@example.com", now, 15*time.Minute); err != nil {
+				t.Fatalf("Fail: %v", err)
+			}
+			if err := store.Reset(ctx, "account:grace@example.com"); err != nil {
+				t.Fatalf("Reset: %v", err)
+			}
+			if attempt, err := store.Get(ctx, "account:grace@example.com"); err != nil || attempt != nil {
+				t.Fatalf("Get after Reset = %+v, %v, want nothing", attempt, err)
+			}
+		})
+	}
+}
+
+func TestGuardDelaysAndLocksAccounts(t *testing.T) {
+	for name, store := range stores(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			guard, clk := newGuard(store)
+			fail := func() lockout.Outcome {
+				t.Helper()
+				outcome, err := guard.Failure(ctx, "Ada@Example.com", "203.0.113.7")
+				if err != nil {
+					t.Fatalf("Failure: %v", err)
+				}
+				return outcome
+			}
+
+			// The first failures cost nothing
+			fail()
+			fail()
+			if wait, _ := retryAfter(t, guard, "ada@example.com", "203.0.113.7"); wait != 0 {
+				t.Fatalf("wait after 2 failures = %v, want none", wait)
+			}
+
+			// Then the wait doubles up to the maximum
+			for _, want := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second} {
+				fail()
+				if wait, locked := retryAfter(t, guard, "ada@example.com", "198.51.100.1"); wait != want || locked {
+					t.Fatalf("wait = %v (locked %v), want %v", wait, locked, want)
+				}
+				clk.Advance(want)
+			}
+
+			// The limit locks the account once and hands out an unlock token
+			outcome := fail()
+			if !outcome.AccountLocked || outcome.UnlockToken == "" || outcome.IPLocked {
+				t.Fatalf("outcome at the limit = %+v, want the account locked", outcome)
+			}
+			if wait, locked := retryAfter(t, guard, "ada@example.com", ""); !locked || wait != 30*time.Minute {
+				t.Fatalf("wait = %v (locked %v), want locked for 30m", wait, locked)
+			}
+			if again := fail(); again.AccountLocked {
+				t.Error("a locked account was locked again")
+			}
+
+			// Other accounts are not affected
+			if wait, _ := retryAfter(t, guard, "grace@example.com", ""); wait != 0 {
+				t.Fatalf("another account has to wait %v", wait)
+			}
+
+			email, err := guard.Unlock(ctx, outcome.UnlockToken)
+			if err != nil || email != "ada@example.com" {
+				t.Fatalf("Unlock = %q, %v, want the account", email, err)
+			}
+			if wait, _ := retryAfter(t, guard, "ada@example.com", ""); wait != 0 {
+				t.Fatalf("wait after unlocking = %v, want none", wait)
+			}
+			if email, err := guard.Unlock(ctx, outcome.UnlockToken); err != nil || email != "" {
+				t.Fatalf("second Unlock = %q, %v, want nothing", email, err)
+			}
+		})
+	}
+}
+
+func TestGuardLocksClientIPs(t *testing.T) {
+	for name, store := range stores(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			guard, _ := newGuard(store)
+
+			// One failure each on many accounts never delays an account
+			var outcome lockout.Outcome
+			for _, email := range []string{"a@example.com", "b@example.com", "c@example.com", "d@example.com", "e@example.com", "f@example.com", "g@example.com", "h@example.com"} {
+				var err error
+				if outcome, err = guard.Failure(ctx, email, "203.0.113.7"); err != nil {
+					t.Fatalf("Failure: %v", err)
+				}
+			}
+			if !outcome.IPLocked || outcome.AccountLocked {
+				t.Fatalf("outcome at the IP limit = %+v, want the IP locked", outcome)
+			}
+			if wait, locked := retryAfter(t, guard, "new@example.com", "203.0.113.7"); !locked || wait != 30*time.Minute {
+				t.Fatalf("wait from the IP = %v (locked %v), want locked for 30m", wait, locked)
+			}
+			if wait, _ := retryAfter(t, guard, "new@example.com", "198.51.100.1"); wait != 0 {
+				t.Fatalf("wait from another IP = %v, want none", wait)
+			}
+
+			// Signing in does not clear the IP's failures
+			if err := guard.Success(ctx, "a@example.com"); err != nil {
+				t.Fatalf("Success: %v", err)
+			}
+			if _, locked := retryAfter(t, guard, "a@example.com", "203.0.113.7"); !locked {
+				t.Fatal("a successful login unlocked the IP")
+			}
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package lockout
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/models"
+)
+
+// MemoryStore keeps the counts in process memory. It suits a single
+// instance; the counts are lost on restart and not shared between replicas.
+type MemoryStore struct {
+	mu         sync.Mutex
+	attempts   map[string]models.LoginAttempt
+	lastPruned time.Time
+}
+
+// NewMemoryStore creates an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{attempts: make(map[string]models.LoginAttempt)}
+}
+
+// Get implements Store
+func (s *MemoryStore) Get(_ context.Context, key string) (*models.LoginAttempt, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	attempt, ok := s.attempts[key]
+	if !ok {
+		return nil, nil
+	}
+	return &attempt, nil
+}
+
+// Fail implements Store. Stale attempts are pruned along the way once per
+// window, so the map does not grow with every address ever tried.
+func (s *MemoryStore) Fail(_ context.Context, key string, now time.Time, window time.Duration) (*models.LoginAttempt, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if now.Sub(s.lastPruned) >= window {
+		s.prune(now, window)
+		s.lastPruned = now
+	}
+
+	attempt, ok := s.attempts[key]
+	if !ok {
+		attempt = models.LoginAttempt{Key: key}
+	}
+	if attempt.LastFailureAt.Before(now.Add(-window)) {
+		attempt.Failures = 0
+	}
+	attempt.Failures++
+	attempt.LastFailureAt = now
+	s.attempts[key] = attempt
+
+	return &attempt, nil
+}
+
+// Lock implements Store
+func (s *MemoryStore) Lock(_ context.Context, key string, now, until time.Time, unlockTokenHash *string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	attempt, ok := s.attempts[key]
+	if !ok || (attempt.LockedUntil != nil && attempt.LockedUntil.After(now)) {
+		return false, nil
+	}
+	attempt.LockedUntil = &until
+	attempt.UnlockTokenHash = unlockTokenHash
+	s.attempts[key] = attempt
+	return true, nil
+}
+
+// Unlock implements Store
+func (s *MemoryStore) Unlock(_ context.Context, unlockTokenHash string, now time.Time) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for key, attempt := range s.attempts {
+		if attempt.UnlockTokenHash != nil && *attempt.UnlockTokenHash == unlockTokenHash &&
+			attempt.LockedUntil != nil && attempt.LockedUntil.After(now) {
+			delete(s.attempts, key)
+			return key, nil
+		}
+	}
+	return "", nil
+}
+
+// Reset implements Store
+func (s *MemoryStore) Reset(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.attempts, key)
+	return nil
+}
+
+// Prune implements Store
+func (s *MemoryStore) Prune(_ context.Context, now time.Time, window time.Duration) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.prune(now, window), nil
+}
+
+// prune removes stale attempts. The caller must hold the lock.
+func (s *MemoryStore) prune(now time.Time, window time.Duration) int64 {
+	var pruned int64
+	for key, attempt := range s.attempts {
+		if stale(attempt, now, window) {
+			delete(s.attempts, key)
+			pruned++
+		}
+	}
+	return pruned
+}
+
+// stale reports whether the attempts can be forgotten
+func stale(attempt models.LoginAttempt, now time.Time, window time.Duration) bool {
+	locked := attempt.LockedUntil != nil && attempt.LockedUntil.After(now)
+	return !locked && attempt.LastFailureAt.Before(now.Add(-window))
+}
@@ -2,6 +2,7 @@ package logger
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"time"
 
@@ -10,8 +11,11 @@ import (
 	"github.com/rs/zerolog/log"
 )
 
+// Init sets up the global logger: colored console output when LOG_PRETTY is
+// set, for development, and one JSON object per line otherwise, for log
+// collectors
 func Init(cfg *config.Config) {
-	var output zerolog.ConsoleWriter
+	var output io.Writer = os.Stdout
 	if cfg.Logger.Pretty {
 		output = zerolog.ConsoleWriter{
 			Out:        os.Stdout,
@@ -19,11 +23,7 @@ func Init(cfg *config.Config) {
 			NoColor:    false,
 		}
 	} else {
-		output = zerolog.ConsoleWriter{
-			Out:        os.Stdout,
-			TimeFormat: cfg.Logger.TimeFormat,
-			NoColor:    true,
-		}
+		zerolog.TimeFieldFormat = cfg.Logger.TimeFormat
 	}
 
 	log.Logger = zerolog.New(output).
@@ -31,13 +31,9 @@ func Init(cfg *config.Config) {
 		Timestamp().
 		Caller().
 		Logger()
+	useAsContextDefault()
 
-	level, err := zerolog.ParseLevel(cfg.Logger.Level)
-	if err != nil {
-		fmt.Printf("Invalid log level '%s', defaulting to debug\n", cfg.Logger.Level)
-		level = zerolog.DebugLevel
-	}
-	zerolog.SetGlobalLevel(level)
+	level := SetLevel(cfg)
 
 	log.Info().
 		Str("level", level.String()).
@@ -46,6 +42,18 @@ func Init(cfg *config.Config) {
 		Msg("Logger initialized")
 }
 
+// SetLevel applies the configured log level and returns it. It is called
+// again whenever the configuration is reloaded.
+func SetLevel(cfg *config.Config) zerolog.Level {
+	level, err := zerolog.ParseLevel(cfg.Logger.Level)
+	if err != nil {
+		fmt.Printf("Invalid log level '%s', defaulting to debug\n", cfg.Logger.Level)
+		level = zerolog.DebugLevel
+	}
+	zerolog.SetGlobalLevel(level)
+	return level
+}
+
 func InitDefault() {
 	output := zerolog.ConsoleWriter{
 		Out:        os.Stdout,
@@ -59,11 +67,18 @@ func InitDefault() {
 		Caller().
 		Logger()
 
+	useAsContextDefault()
 	zerolog.SetGlobalLevel(zerolog.DebugLevel)
 
 	log.Info().Msg("Default logger initialized")
 }
 
+// useAsContextDefault makes log.Ctx return the global logger for contexts
+// without a request logger, such as those of background jobs
+func useAsContextDefault() {
+	zerolog.DefaultContextLogger = &log.Logger
+}
+
 func TestLogger() zerolog.Logger {
 	output := zerolog.ConsoleWriter{
 		Out:        os.Stdout,
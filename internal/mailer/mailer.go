@@ -0,0 +1,34 @@
+// Package mailer sends transactional email through a pluggable transport.
+package mailer
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/config"
+)
+
+// Message is an email with both HTML and plain-text bodies
+type Message struct {
+	To      string
+	Subject string
+	HTML    string
+	Text    string
+}
+
+// Mailer delivers email messages
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// NewFromConfig creates the mailer selected by MAIL_DRIVER
+func NewFromConfig(cfg *config.Config) (Mailer, error) {
+	switch cfg.Mail.Driver {
+	case "smtp":
+		return NewSMTPMailer(cfg.Mail.SMTPHost, cfg.Mail.SMTPPort, cfg.Mail.SMTPUser, cfg.Mail.SMTPPassword, cfg.Mail.From), nil
+	case "log", "":
+		return NewLogMailer(), nil
+	default:
+		return nil, fmt.Errorf("unknown mail driver %q", cfg.Mail.Driver)
+	}
+}
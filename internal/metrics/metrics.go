@@ -0,0 +1,150 @@
+// Package metrics defines the Prometheus metrics of the service: request
+// latency, database pool and query latency, and counters of what users do.
+// The metrics are always collected; they are only served when
+// METRICS_ENABLED is set, on the admin port.
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "consistency"
+
+// Registry holds the metrics of the service, without the process and Go
+// runtime metrics of the default registry added by other libraries
+var Registry = prometheus.NewRegistry()
+
+var (
+	requestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "Duration of HTTP requests by method, route template and status.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	queryDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "db",
+		Name:      "query_duration_seconds",
+		Help:      "Duration of database queries by operation.",
+		Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+	}, []string{"operation"})
+
+	dbState = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "db",
+		Name:      "connection_state",
+		Help:      "State of the database connection, 1 for the current state.",
+	}, []string{"state"})
+
+	dbConnectAttempts = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "db",
+		Name:      "connect_attempts_total",
+		Help:      "Attempts to reach the database by result.",
+	}, []string{"result"})
+
+	checkIns = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "check_ins_total",
+		Help:      "Check-ins recorded.",
+	})
+
+	streaks = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "streaks_total",
+		Help:      "Streaks started, completed and failed.",
+	}, []string{"event"})
+
+	achievements = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "achievements_awarded_total",
+		Help:      "Achievements awarded by type.",
+	}, []string{"type"})
+
+	logins = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "logins_total",
+		Help:      "Successful logins by method.",
+	}, []string{"method"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		requestDuration,
+		queryDuration,
+		dbState,
+		dbConnectAttempts,
+		checkIns,
+		streaks,
+		achievements,
+		logins,
+	)
+}
+
+// Handler serves the metrics in the Prometheus text format
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+}
+
+// ObserveRequest records an HTTP request. route is the route template, such
+// as /api/v1/habits/:id, so that IDs do not create a series each.
+func ObserveRequest(method, route string, status int, duration time.Duration) {
+	requestDuration.WithLabelValues(method, route, strconv.Itoa(status)).Observe(duration.Seconds())
+}
+
+// DatabaseState records the state of the database connection, such as
+// connecting or ready
+func DatabaseState(state string) {
+	dbState.Reset()
+	dbState.WithLabelValues(state).Set(1)
+}
+
+// DatabaseConnectAttempt counts an attempt to reach the database
+func DatabaseConnectAttempt(ok bool) {
+	result := "failure"
+	if ok {
+		result = "success"
+	}
+	dbConnectAttempts.WithLabelValues(result).Inc()
+}
+
+// CheckIn counts a check-in
+func CheckIn() {
+	checkIns.Inc()
+}
+
+// StreakStarted counts a new streak
+func StreakStarted() {
+	streaks.WithLabelValues("started").Inc()
+}
+
+// StreakCompleted counts a streak that reached its target
+func StreakCompleted() {
+	streaks.WithLabelValues("completed").Inc()
+}
+
+// StreakFailed counts a streak that was broken by a missed day
+func StreakFailed() {
+	streaks.WithLabelValues("failed").Inc()
+}
+
+// AchievementAwarded counts an achievement of the type
+func AchievementAwarded(achievementType string) {
+	achievements.WithLabelValues(achievementType).Inc()
+}
+
+// Login counts a successful login with the method, password or the sign-in
+// provider
+func Login(method string) {
+	logins.WithLabelValues(method).Inc()
+}
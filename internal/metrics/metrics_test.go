@@ -0,0 +1,56 @@
+package metrics_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/metrics"
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestHandlerExportsMetrics(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	if err := metrics.InstrumentDatabase(db, "test"); err != nil {
+		t.Fatalf("instrument database: %v", err)
+	}
+	if err := db.Exec("CREATE TABLE notes (id integer)").Error; err != nil {
+		t.Fatalf("create table: %v", err)
+	}
+	var ids []int
+	if err := db.Table("notes").Pluck("id", &ids).Error; err != nil {
+		t.Fatalf("query: %v", err)
+	}
+
+	metrics.ObserveRequest(http.MethodGet, "/api/v1/habits/:id", http.StatusOK, 20*time.Millisecond)
+	metrics.CheckIn()
+	metrics.StreakStarted()
+	metrics.AchievementAwarded("streak_completed")
+	metrics.Login("password")
+
+	res := httptest.NewRecorder()
+	metrics.Handler().ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body, _ := io.ReadAll(res.Body)
+
+	for _, want := range []string{
+		`consistency_http_request_duration_seconds_count{method="GET",route="/api/v1/habits/:id",status="200"} 1`,
+		`consistency_db_query_duration_seconds_count{operation="raw"} 1`,
+		`consistency_db_query_duration_seconds_count{operation="query"} 1`,
+		`go_sql_open_connections{db_name="test"}`,
+		`consistency_check_ins_total 1`,
+		`consistency_streaks_total{event="started"} 1`,
+		`consistency_achievements_awarded_total{type="streak_completed"} 1`,
+		`consistency_logins_total{method="password"} 1`,
+	} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("metrics do not contain %s", want)
+		}
+	}
+}
@@ -2,14 +2,17 @@ package middleware
 
 import (
 	"errors"
-	"fmt"
 	"net/http"
 	"strings"
 	"time"
 
 	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/config"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/jwtkeys"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/models"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/repository"
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 )
 
@@ -21,7 +24,8 @@ type JWTClaims struct {
 }
 
 // Auth is a middleware that checks if the request has a valid JWT token
-func Auth() gin.HandlerFunc {
+// signed with one of the accepted keys
+func Auth(keys *jwtkeys.Provider) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Get the JWT token from the Authorization header
 		authHeader := c.GetHeader("Authorization")
@@ -40,20 +44,17 @@ func Auth() gin.HandlerFunc {
 		// Get the JWT token
 		tokenString := parts[1]
 
-		// Parse the JWT token
-		token, err := jwt.ParseWithClaims(tokenString, &JWTClaims{}, func(token *jwt.Token) (interface{}, error) {
-			// Validate the signing method
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-			}
-
-			// Get the JWT secret from the config
-			cfg := config.Load()
-			return []byte(cfg.Auth.JWTSecret), nil
-		})
+		set, err := keys.Current()
+		if err != nil {
+			log.Ctx(c.Request.Context()).Error().Err(err).Msg("Failed to load signing keys")
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify token"})
+			return
+		}
 
+		// Parse the JWT token, matching it to its key by kid
+		token, err := jwt.ParseWithClaims(tokenString, &JWTClaims{}, set.Keyfunc(time.Now()), jwt.WithValidMethods(jwtkeys.Algorithms))
 		if err != nil {
-			log.Error().Err(err).Msg("Failed to parse JWT token")
+			log.Ctx(c.Request.Context()).Error().Err(err).Msg("Failed to parse JWT token")
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
 			return
 		}
@@ -74,38 +75,60 @@ func Auth() gin.HandlerFunc {
 		// Set the user ID and email in the context
 		c.Set("userID", claims.UserID)
 		c.Set("email", claims.Email)
+		addLogField(c, func(l zerolog.Context) zerolog.Context { return l.Uint("user_id", claims.UserID) })
 
 		c.Next()
 	}
 }
 
-// GenerateToken generates a JWT token for the given user ID and email
-func GenerateToken(userID uint, email string) (string, error) {
-	// Get the JWT secret from the config
-	cfg := config.Load()
+// ActiveUser is a middleware that rejects tokens whose account no longer
+// exists, so that deleting an account also invalidates its issued tokens.
+// The account is stored in the context for GetUser. It must run after Auth.
+func ActiveUser(userRepo repository.UserRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := GetUserID(c)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid token claims"})
+			return
+		}
+
+		user, err := userRepo.FindByID(c.Request.Context(), userID)
+		if err != nil {
+			if respondWithContextError(c) {
+				return
+			}
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify account"})
+			return
+		}
+		if user == nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Account no longer exists"})
+			return
+		}
+
+		c.Set("user", user)
+
+		c.Next()
+	}
+}
+
+// GenerateToken generates a JWT token for the given user ID and email, signed
+// with the currently active key
+func GenerateToken(cfg *config.Config, keys *jwtkeys.Set, userID uint, email string) (string, error) {
+	now := time.Now()
 
 	// Create the claims
 	claims := &JWTClaims{
 		UserID: userID,
 		Email:  email,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour * 24 * time.Duration(cfg.Auth.JWTExpiryHours))),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-			NotBefore: jwt.NewNumericDate(time.Now()),
+			ExpiresAt: jwt.NewNumericDate(now.Add(cfg.Auth.TokenLifetime())),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
 			Issuer:    cfg.Auth.TokenIssuer,
 		},
 	}
 
-	// Create the token
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-
-	// Sign the token with the secret
-	tokenString, err := token.SignedString([]byte(cfg.Auth.JWTSecret))
-	if err != nil {
-		return "", err
-	}
-
-	return tokenString, nil
+	return keys.Sign(claims, now)
 }
 
 // GetUserID gets the user ID from the context
@@ -127,3 +150,13 @@ func GetEmail(c *gin.Context) (string, error) {
 
 	return email.(string), nil
 }
+
+// GetUser gets the account loaded by ActiveUser from the context
+func GetUser(c *gin.Context) (*models.User, error) {
+	user, exists := c.Get("user")
+	if !exists {
+		return nil, errors.New("user not found in context")
+	}
+
+	return user.(*models.User), nil
+}
@@ -1,17 +1,33 @@
 package middleware
 
 import (
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/config"
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 )
 
-// CORS is a middleware that handles CORS
-func CORS() gin.HandlerFunc {
-	return cors.New(cors.Config{
-		AllowOrigins:     []string{"*"},
-		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization"},
-		ExposeHeaders:    []string{"Content-Length"},
-		AllowCredentials: true,
-	})
+// CORS is a middleware that handles CORS for the currently configured
+// origins. Credentials are only allowed for an explicit list of origins,
+// never together with "*".
+func CORS(cfgs *config.Holder) gin.HandlerFunc {
+	newCORS := func(credentials bool) gin.HandlerFunc {
+		return cors.New(cors.Config{
+			AllowOriginFunc: func(origin string) bool {
+				return cfgs.Get().CORS.AllowsOrigin(origin)
+			},
+			AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+			AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization"},
+			ExposeHeaders:    []string{"Content-Length", "Retry-After", "X-RateLimit-Limit", "X-RateLimit-Remaining", "X-RateLimit-Reset"},
+			AllowCredentials: credentials,
+		})
+	}
+	withCredentials, withoutCredentials := newCORS(true), newCORS(false)
+
+	return func(c *gin.Context) {
+		if cfgs.Get().CORS.AllowsCredentials() {
+			withCredentials(c)
+		} else {
+			withoutCredentials(c)
+		}
+	}
 }
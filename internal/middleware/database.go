@@ -0,0 +1,29 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// databaseRetryAfter is how long clients are told to wait while the
+// database is unavailable
+const databaseRetryAfter = 5 * time.Second
+
+// DatabaseReady is a middleware that answers 503 while ready returns an
+// error, such as before the database is first reached or during an outage,
+// instead of letting every query fail
+func DatabaseReady(ready func() error) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := ready(); err != nil {
+			c.Header("Retry-After", strconv.Itoa(int(databaseRetryAfter.Seconds())))
+			RespondWithError(c, http.StatusServiceUnavailable, "DB_UNAVAILABLE", "The service is starting or the database is unavailable, please try again shortly", nil)
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
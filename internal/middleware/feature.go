@@ -0,0 +1,22 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/config"
+	"github.com/gin-gonic/gin"
+)
+
+// Feature is a middleware that rejects requests while the feature selected by
+// enabled is switched off in the current configuration
+func Feature(cfgs *config.Holder, enabled func(config.FeatureConfig) bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !enabled(cfgs.Get().Features) {
+			RespondWithError(c, http.StatusForbidden, "FEATURE_DISABLED", "This feature is currently disabled", nil)
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
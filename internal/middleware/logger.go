@@ -1,13 +1,17 @@
 package middleware
 
 import (
+	"net/http"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 )
 
-// Logger is a middleware that logs HTTP requests
+// Logger is a middleware that logs HTTP requests with the logger of the
+// request, so it must run after RequestID. Server errors are logged as
+// errors and client errors as warnings.
 func Logger() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Start timer
@@ -21,12 +25,20 @@ func Logger() gin.HandlerFunc {
 		// Stop timer
 		latency := time.Since(start)
 
+		status := c.Writer.Status()
+		level := zerolog.InfoLevel
+		switch {
+		case status >= http.StatusInternalServerError:
+			level = zerolog.ErrorLevel
+		case status >= http.StatusBadRequest:
+			level = zerolog.WarnLevel
+		}
+
 		// Log request details
-		log.Info().
-			Str("method", c.Request.Method).
+		log.Ctx(c.Request.Context()).WithLevel(level).
 			Str("path", path).
 			Str("query", raw).
-			Int("status", c.Writer.Status()).
+			Int("status", status).
 			Str("ip", c.ClientIP()).
 			Str("user-agent", c.Request.UserAgent()).
 			Dur("latency", latency).
@@ -0,0 +1,25 @@
+package middleware
+
+import (
+	"time"
+
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/metrics"
+	"github.com/gin-gonic/gin"
+)
+
+// Metrics is a middleware that records the duration and status of every
+// request by route template. Requests that match no route are recorded as
+// "unmatched" so that scanners cannot create a series per path.
+func Metrics() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		metrics.ObserveRequest(c.Request.Method, route, c.Writer.Status(), time.Since(start))
+	}
+}
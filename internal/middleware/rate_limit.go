@@ -0,0 +1,68 @@
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/ratelimit"
+	"github.com/gin-gonic/gin"
+)
+
+// RateLimit is a middleware that limits the requests of the authenticated
+// user, with separate budgets for reads and writes. It must run after Auth.
+func RateLimit(limiter *ratelimit.Limiter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := GetUserID(c)
+		if err != nil {
+			RespondWithUnauthorized(c)
+			c.Abort()
+			return
+		}
+
+		budget := ratelimit.BudgetWrite
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			budget = ratelimit.BudgetRead
+		}
+		limit(c, limiter, budget, ratelimit.UserKey(userID))
+	}
+}
+
+// RateLimitByIP is a middleware that limits the requests from the client IP
+// with the budget, for endpoints that are called before signing in. The IP is
+// only taken from forwarded headers sent by the engine's trusted proxies.
+func RateLimitByIP(limiter *ratelimit.Limiter, budget ratelimit.Budget) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		limit(c, limiter, budget, ratelimit.IPKey(c.ClientIP()))
+	}
+}
+
+// limit takes a token for the request and sets the X-RateLimit headers, or
+// rejects the request with 429 when the bucket is empty
+func limit(c *gin.Context, limiter *ratelimit.Limiter, budget ratelimit.Budget, client string) {
+	result := limiter.Take(c.Request.Context(), budget, client)
+	if result.Limit > 0 {
+		c.Header("X-RateLimit-Limit", strconv.Itoa(result.Limit))
+		c.Header("X-RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		c.Header("X-RateLimit-Reset", strconv.Itoa(ceilSeconds(result.Reset)))
+	}
+
+	if !result.Allowed {
+		retryAfter := ceilSeconds(result.RetryAfter)
+		c.Header("Retry-After", strconv.Itoa(retryAfter))
+		RespondWithError(c, http.StatusTooManyRequests, "RATE_LIMITED", "Too many requests, please slow down", gin.H{
+			"retry_after": retryAfter,
+		})
+		c.Abort()
+		return
+	}
+
+	c.Next()
+}
+
+// ceilSeconds returns the duration in whole seconds, rounded up
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
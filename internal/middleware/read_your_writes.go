@@ -0,0 +1,143 @@
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/clock"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/database"
+	"github.com/gin-gonic/gin"
+)
+
+// LastWriteCookie carries the time of the user's last change, so that every
+// instance behind a load balancer knows to keep their reads on the primary
+const LastWriteCookie = "last_write"
+
+// ReadYourWrites is a middleware that keeps the reads of a request on the
+// primary database when the request changes data, or when the user changed
+// data within window, so that users do not read a replica that is behind
+// their own writes. A successful change sets LastWriteCookie, which any
+// instance honours; clients that drop cookies are still remembered by the
+// instance that handled the change. It must run after Auth.
+func ReadYourWrites(window time.Duration, clk clock.Clock) gin.HandlerFunc {
+	writes := &recentWrites{window: window, clock: clk, users: make(map[uint]time.Time)}
+
+	return func(c *gin.Context) {
+		userID, err := GetUserID(c)
+		if err != nil {
+			c.Next()
+			return
+		}
+
+		mutating := true
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			mutating = false
+		}
+
+		if mutating || writes.recent(userID) || writes.recentCookie(c) {
+			c.Request = c.Request.WithContext(database.RequirePrimary(c.Request.Context()))
+		}
+
+		if !mutating || window <= 0 {
+			c.Next()
+			return
+		}
+
+		// The cookie has to be set before the handler writes the response
+		c.Writer = &lastWriteWriter{ResponseWriter: c.Writer, c: c, writes: writes}
+		c.Next()
+
+		if c.Writer.Status() < http.StatusBadRequest {
+			writes.record(userID)
+		}
+	}
+}
+
+// lastWriteWriter sets LastWriteCookie when a change is answered with success
+type lastWriteWriter struct {
+	gin.ResponseWriter
+	c      *gin.Context
+	writes *recentWrites
+	set    bool
+}
+
+func (w *lastWriteWriter) WriteHeader(code int) {
+	if code < http.StatusBadRequest && !w.set && !w.Written() {
+		w.set = true
+		http.SetCookie(w.ResponseWriter, w.writes.cookie(w.c.Request.TLS != nil))
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+// recentWrites remembers when users last changed data, for the window
+type recentWrites struct {
+	window time.Duration
+	clock  clock.Clock
+
+	mu        sync.Mutex
+	users     map[uint]time.Time
+	lastSweep time.Time
+}
+
+func (w *recentWrites) recent(userID uint) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	at, ok := w.users[userID]
+	return ok && w.clock.Now().Sub(at) < w.window
+}
+
+// recentCookie reports whether the request carries a write within the
+// window. Times ahead of this instance's clock by up to the window are
+// accepted, to allow for skew between instances.
+func (w *recentWrites) recentCookie(c *gin.Context) bool {
+	value, err := c.Cookie(LastWriteCookie)
+	if err != nil {
+		return false
+	}
+	millis, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return false
+	}
+	age := w.clock.Now().Sub(time.UnixMilli(millis))
+	return age < w.window && age > -w.window
+}
+
+// cookie records a write made now, for the window
+func (w *recentWrites) cookie(secure bool) *http.Cookie {
+	return &http.Cookie{
+		Name:     LastWriteCookie,
+		Value:    strconv.FormatInt(w.clock.Now().UnixMilli(), 10),
+		Path:     "/",
+		MaxAge:   int(math.Ceil(w.window.Seconds())),
+		Secure:   secure,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	}
+}
+
+func (w *recentWrites) record(userID uint) {
+	if w.window <= 0 {
+		return
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	now := w.clock.Now()
+	w.users[userID] = now
+
+	// Forget the writes that are out of the window, at most once per window
+	if now.Sub(w.lastSweep) >= w.window {
+		for id, at := range w.users {
+			if now.Sub(at) >= w.window {
+				delete(w.users, id)
+			}
+		}
+		w.lastSweep = now
+	}
+}
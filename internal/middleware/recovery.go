@@ -14,7 +14,7 @@ func Recovery() gin.HandlerFunc {
 		defer func() {
 			if err := recover(); err != nil {
 				// Log the error and stack trace
-				log.Error().
+				log.Ctx(c.Request.Context()).Error().
 					Interface("error", err).
 					Str("stack", string(debug.Stack())).
 					Msg("Panic recovered")
@@ -0,0 +1,77 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// RequestIDHeader carries the ID that ties the logs of a request together
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength bounds IDs taken from clients, which end up in every
+// log line of the request
+const maxRequestIDLength = 128
+
+// RequestID is a middleware that gives every request an ID, taken from the
+// X-Request-ID header of a proxy or client when it is sensible or generated
+// otherwise, and returns it in the response. It attaches a logger carrying
+// the ID, route and trace to the request context, which log.Ctx returns;
+// Auth adds the user ID to it.
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(RequestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+		c.Set("request_id", id)
+		c.Header(RequestIDHeader, id)
+
+		fields := log.Logger.With().Str("request_id", id).Str("method", c.Request.Method)
+		if route := c.FullPath(); route != "" {
+			fields = fields.Str("route", route)
+		}
+		if span := trace.SpanContextFromContext(c.Request.Context()); span.IsValid() {
+			fields = fields.Str("trace_id", span.TraceID().String())
+		}
+		logger := fields.Logger()
+		c.Request = c.Request.WithContext(logger.WithContext(c.Request.Context()))
+
+		c.Next()
+	}
+}
+
+// GetRequestID gets the ID of the request
+func GetRequestID(c *gin.Context) string {
+	return c.GetString("request_id")
+}
+
+// addLogField adds fields to the logger of the request
+func addLogField(c *gin.Context, add func(zerolog.Context) zerolog.Context) {
+	logger := add(log.Ctx(c.Request.Context()).With()).Logger()
+	c.Request = c.Request.WithContext(logger.WithContext(c.Request.Context()))
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, r := range id {
+		if r < '!' || r > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(buf)
+}
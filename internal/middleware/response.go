@@ -179,7 +179,12 @@ func RespondWithConflict(c *gin.Context, message string) {
 	RespondWithError(c, http.StatusConflict, "CONFLICT", message, nil)
 }
 
-// RespondWithInternalError sends a standardized internal server error response
+// RespondWithInternalError sends a standardized internal server error
+// response, or the timeout response when the request failed because its
+// context ended
 func RespondWithInternalError(c *gin.Context, err interface{}) {
+	if respondWithContextError(c) {
+		return
+	}
 	RespondWithError(c, http.StatusInternalServerError, "INTERNAL_ERROR", "Internal server error", err)
 }
@@ -0,0 +1,44 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/config"
+	"github.com/gin-gonic/gin"
+)
+
+// Timeout is a middleware that gives the request a deadline, picked for the
+// route from the API configuration. Repository calls abort once it has
+// passed, and the request is answered with 504 if the handler has not
+// responded.
+func Timeout(cfgs *config.Holder, pick func(route string, api config.APIConfig) time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(c.Request.Context(), pick(c.FullPath(), cfgs.Get().API))
+		defer cancel()
+		c.Request = c.Request.WithContext(ctx)
+
+		c.Next()
+
+		if !c.Writer.Written() {
+			respondWithContextError(c)
+		}
+	}
+}
+
+// respondWithContextError answers with 504 when the request ran out of time
+// or 503 when the client went away, and reports whether it did
+func respondWithContextError(c *gin.Context) bool {
+	switch err := c.Request.Context().Err(); {
+	case errors.Is(err, context.DeadlineExceeded):
+		RespondWithError(c, http.StatusGatewayTimeout, "TIMEOUT", "The request took too long, please try again", nil)
+	case errors.Is(err, context.Canceled):
+		RespondWithError(c, http.StatusServiceUnavailable, "REQUEST_CANCELLED", "The request was cancelled", nil)
+	default:
+		return false
+	}
+	c.Abort()
+	return true
+}
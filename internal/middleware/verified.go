@@ -0,0 +1,36 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/config"
+	"github.com/gin-gonic/gin"
+)
+
+// VerifiedEmail is a middleware that rejects accounts whose email address is
+// not verified while the current configuration restricts feature to verified
+// accounts. It must run after ActiveUser.
+func VerifiedEmail(cfgs *config.Holder, feature string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !cfgs.Get().Verification.Requires(feature) {
+			c.Next()
+			return
+		}
+
+		user, err := GetUser(c)
+		if err != nil {
+			RespondWithUnauthorized(c)
+			c.Abort()
+			return
+		}
+		if !user.EmailVerified() {
+			RespondWithError(c, http.StatusForbidden, "EMAIL_NOT_VERIFIED", "Please verify your email address to use this feature", gin.H{
+				"feature": feature,
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,245 @@
+package migrate
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func file(content string) *fstest.MapFile {
+	return &fstest.MapFile{Data: []byte(content)}
+}
+
+func openSQLite(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "migrate.db")), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	return db
+}
+
+func TestLoadOrdersByVersion(t *testing.T) {
+	fsys := fstest.MapFS{
+		"sql/0010_tenth.up.sql":    file("CREATE TABLE tenth (id INTEGER)"),
+		"sql/0010_tenth.down.sql":  file("DROP TABLE tenth"),
+		"sql/0002_second.up.sql":   file("CREATE TABLE second (id INTEGER)"),
+		"sql/0002_second.down.sql": file("DROP TABLE second"),
+		"sql/0001_first.up.sql":    file("CREATE TABLE first (id INTEGER)"),
+		"sql/0001_first.down.sql":  file("DROP TABLE first"),
+	}
+
+	migrations, err := Load(fsys, "sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, m := range migrations {
+		got = append(got, m.Name)
+	}
+	if strings.Join(got, ",") != "first,second,tenth" {
+		t.Fatalf("migrations = %v, want them ordered by version", got)
+	}
+	if migrations[1].Version != 2 || migrations[1].Up != "CREATE TABLE second (id INTEGER)" || migrations[1].Down != "DROP TABLE second" {
+		t.Errorf("second migration = %+v", migrations[1])
+	}
+}
+
+func TestLoadRejectsInvalidFiles(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   fstest.MapFS
+		wantErr string
+	}{
+		{
+			name: "duplicate version",
+			files: fstest.MapFS{
+				"sql/0001_first.up.sql":   file("SELECT 1"),
+				"sql/0001_first.down.sql": file("SELECT 1"),
+				"sql/1_first.up.sql":      file("SELECT 2"),
+			},
+			wantErr: "more than one up file",
+		},
+		{
+			name: "one version with two names",
+			files: fstest.MapFS{
+				"sql/0001_first.up.sql":   file("SELECT 1"),
+				"sql/0001_other.down.sql": file("SELECT 1"),
+			},
+			wantErr: "different names",
+		},
+		{
+			name:    "missing down file",
+			files:   fstest.MapFS{"sql/0001_first.up.sql": file("SELECT 1")},
+			wantErr: "needs both an up and a down file",
+		},
+		{
+			name:    "invalid file name",
+			files:   fstest.MapFS{"sql/first.sql": file("SELECT 1")},
+			wantErr: "invalid migration file name",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := Load(tc.files, "sql"); err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Fatalf("err = %v, want it to contain %q", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestEveryDialectHasTheSameMigrations(t *testing.T) {
+	postgres, err := Load(embedded, "sql/postgres")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlite, err := Load(embedded, "sql/sqlite")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names := func(migrations []Migration) map[int64]string {
+		byVersion := make(map[int64]string, len(migrations))
+		for _, m := range migrations {
+			byVersion[m.Version] = m.Name
+		}
+		return byVersion
+	}
+	want, got := names(postgres), names(sqlite)
+	for version, name := range want {
+		if got[version] != name {
+			t.Errorf("postgres migration %d_%s has no sqlite twin", version, name)
+		}
+	}
+	for version, name := range got {
+		if want[version] != name {
+			t.Errorf("sqlite migration %d_%s has no postgres twin", version, name)
+		}
+	}
+}
+
+func TestUpDownAndStatus(t *testing.T) {
+	ctx := context.Background()
+	db := openSQLite(t)
+	migrations, err := Load(fstest.MapFS{
+		"sql/0001_habits.up.sql":      file("CREATE TABLE habits (id INTEGER)"),
+		"sql/0001_habits.down.sql":    file("DROP TABLE habits"),
+		"sql/0002_streaks.up.sql":     file("CREATE TABLE streaks (id INTEGER)"),
+		"sql/0002_streaks.down.sql":   file("DROP TABLE streaks"),
+		"sql/0003_check_ins.up.sql":   file("CREATE TABLE check_ins (id INTEGER)"),
+		"sql/0003_check_ins.down.sql": file("DROP TABLE check_ins"),
+	}, "sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := &Migrator{db: db, dialect: db.Dialector.Name(), migrations: migrations}
+
+	applied := func() string {
+		t.Helper()
+		statuses, err := m.Status(ctx)
+		if err != nil {
+			t.Fatalf("status: %v", err)
+		}
+		var names []string
+		for _, status := range statuses {
+			if status.AppliedAt != nil {
+				names = append(names, status.Name)
+			}
+		}
+		return strings.Join(names, ",")
+	}
+
+	if err := m.Check(ctx); err == nil {
+		t.Error("Check of a fresh database returned nil, want ErrSchemaBehind")
+	}
+	done, err := m.Up(ctx)
+	if err != nil || len(done) != 3 {
+		t.Fatalf("Up applied %d migrations: %v", len(done), err)
+	}
+	if got := applied(); got != "habits,streaks,check_ins" {
+		t.Errorf("applied after Up = %s", got)
+	}
+	if err := m.Check(ctx); err != nil {
+		t.Errorf("Check after Up: %v", err)
+	}
+	if done, err := m.Up(ctx); err != nil || len(done) != 0 {
+		t.Errorf("second Up applied %d migrations: %v, want none", len(done), err)
+	}
+
+	done, err = m.Down(ctx, 1)
+	if err != nil || len(done) != 1 || done[0].Name != "check_ins" {
+		t.Fatalf("Down(1) rolled back %+v: %v, want the latest", done, err)
+	}
+	if got := applied(); got != "habits,streaks" {
+		t.Errorf("applied after Down(1) = %s", got)
+	}
+	if db.Migrator().HasTable("check_ins") {
+		t.Error("check_ins table still exists after its rollback")
+	}
+	pending, err := m.Pending(ctx)
+	if err != nil || len(pending) != 1 || pending[0].Name != "check_ins" {
+		t.Errorf("pending = %+v: %v, want check_ins", pending, err)
+	}
+
+	done, err = m.Down(ctx, 5)
+	if err != nil || len(done) != 2 || done[0].Name != "streaks" || done[1].Name != "habits" {
+		t.Fatalf("Down(5) rolled back %+v: %v, want the remaining two newest first", done, err)
+	}
+	if got := applied(); got != "" {
+		t.Errorf("applied after rolling back everything = %s", got)
+	}
+}
+
+func TestUpStopsAtFailingMigration(t *testing.T) {
+	ctx := context.Background()
+	db := openSQLite(t)
+	m := &Migrator{db: db, dialect: db.Dialector.Name(), migrations: []Migration{
+		{Version: 1, Name: "habits", Up: "CREATE TABLE habits (id INTEGER)", Down: "DROP TABLE habits"},
+		{Version: 2, Name: "broken", Up: "CREATE TABLE habits (id INTEGER)", Down: "SELECT 1"},
+		{Version: 3, Name: "streaks", Up: "CREATE TABLE streaks (id INTEGER)", Down: "DROP TABLE streaks"},
+	}}
+
+	done, err := m.Up(ctx)
+	if err == nil || !strings.Contains(err.Error(), "2_broken") {
+		t.Fatalf("err = %v, want the failing migration named", err)
+	}
+	if len(done) != 1 || done[0].Name != "habits" {
+		t.Errorf("applied %+v, want only the migration before the failure", done)
+	}
+	pending, err := m.Pending(ctx)
+	if err != nil || len(pending) != 2 {
+		t.Errorf("pending = %+v: %v, want the failed migration and the one after it", pending, err)
+	}
+}
+
+func TestEmbeddedSQLiteMigrationsRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	m, err := New(openSQLite(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	up, err := m.Up(ctx)
+	if err != nil {
+		t.Fatalf("up: %v", err)
+	}
+	if down, err := m.Down(ctx, len(up)); err != nil || len(down) != len(up) {
+		t.Fatalf("down rolled back %d of %d migrations: %v", len(down), len(up), err)
+	}
+	if _, err := m.Up(ctx); err != nil {
+		t.Fatalf("up after a full rollback: %v", err)
+	}
+}
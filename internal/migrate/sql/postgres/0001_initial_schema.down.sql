@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS achievements;
+DROP TABLE IF EXISTS habit_checkins;
+DROP TABLE IF EXISTS habit_streaks;
+DROP TABLE IF EXISTS habits;
+DROP TABLE IF EXISTS users;
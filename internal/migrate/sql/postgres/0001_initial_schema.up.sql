@@ -0,0 +1,77 @@
+-- Tables that existed before versioned migrations. IF NOT EXISTS lets this
+-- migration adopt databases that were created by GORM AutoMigrate.
+
+CREATE TABLE IF NOT EXISTS users (
+    id            BIGSERIAL PRIMARY KEY,
+    email         TEXT NOT NULL,
+    password_hash TEXT NOT NULL,
+    name          TEXT NOT NULL,
+    created_at    TIMESTAMPTZ,
+    updated_at    TIMESTAMPTZ,
+    deleted_at    TIMESTAMPTZ
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email ON users (email);
+CREATE INDEX IF NOT EXISTS idx_users_deleted_at ON users (deleted_at);
+
+CREATE TABLE IF NOT EXISTS habits (
+    id          BIGSERIAL PRIMARY KEY,
+    user_id     BIGINT NOT NULL REFERENCES users (id),
+    name        TEXT NOT NULL,
+    description TEXT,
+    color       VARCHAR(7),
+    icon        VARCHAR(50),
+    is_active   BOOLEAN DEFAULT TRUE,
+    created_at  TIMESTAMPTZ,
+    updated_at  TIMESTAMPTZ,
+    deleted_at  TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS idx_habits_user_id ON habits (user_id);
+CREATE INDEX IF NOT EXISTS idx_habits_deleted_at ON habits (deleted_at);
+
+CREATE TABLE IF NOT EXISTS habit_streaks (
+    id                  BIGSERIAL PRIMARY KEY,
+    habit_id            BIGINT NOT NULL REFERENCES habits (id),
+    target_days         BIGINT NOT NULL CONSTRAINT chk_habit_streaks_target_days CHECK (target_days > 0),
+    current_streak      BIGINT DEFAULT 0,
+    max_streak_achieved BIGINT DEFAULT 0,
+    start_date          TIMESTAMPTZ NOT NULL,
+    last_check_in_date  TIMESTAMPTZ,
+    status              TEXT DEFAULT 'active' CONSTRAINT chk_habit_streaks_status CHECK (status IN ('active', 'completed', 'failed')),
+    completed_at        TIMESTAMPTZ,
+    failed_at           TIMESTAMPTZ,
+    created_at          TIMESTAMPTZ,
+    updated_at          TIMESTAMPTZ,
+    deleted_at          TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS idx_habit_streaks_habit_id ON habit_streaks (habit_id);
+CREATE INDEX IF NOT EXISTS idx_habit_streaks_deleted_at ON habit_streaks (deleted_at);
+
+CREATE TABLE IF NOT EXISTS habit_checkins (
+    id            BIGSERIAL PRIMARY KEY,
+    streak_id     BIGINT NOT NULL REFERENCES habit_streaks (id),
+    check_in_date TIMESTAMPTZ NOT NULL,
+    checked_in_at TIMESTAMPTZ,
+    notes         TEXT,
+    created_at    TIMESTAMPTZ,
+    updated_at    TIMESTAMPTZ,
+    deleted_at    TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS idx_habit_checkins_streak_id ON habit_checkins (streak_id);
+CREATE INDEX IF NOT EXISTS idx_habit_checkins_check_in_date ON habit_checkins (check_in_date);
+CREATE INDEX IF NOT EXISTS idx_habit_checkins_deleted_at ON habit_checkins (deleted_at);
+
+CREATE TABLE IF NOT EXISTS achievements (
+    id               BIGSERIAL PRIMARY KEY,
+    user_id          BIGINT NOT NULL REFERENCES users (id),
+    habit_id         BIGINT NOT NULL REFERENCES habits (id),
+    achievement_type TEXT NOT NULL,
+    target_days      BIGINT NOT NULL,
+    achieved_at      TIMESTAMPTZ,
+    metadata         JSONB,
+    created_at       TIMESTAMPTZ,
+    updated_at       TIMESTAMPTZ,
+    deleted_at       TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS idx_achievements_user_id ON achievements (user_id);
+CREATE INDEX IF NOT EXISTS idx_achievements_habit_id ON achievements (habit_id);
+CREATE INDEX IF NOT EXISTS idx_achievements_deleted_at ON achievements (deleted_at);
@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS devices (
+    id           BIGSERIAL PRIMARY KEY,
+    user_id      BIGINT NOT NULL REFERENCES users (id),
+    token        TEXT NOT NULL,
+    platform     TEXT NOT NULL CONSTRAINT chk_devices_platform CHECK (platform IN ('ios', 'android')),
+    app_version  VARCHAR(32),
+    last_seen_at TIMESTAMPTZ NOT NULL,
+    created_at   TIMESTAMPTZ,
+    updated_at   TIMESTAMPTZ
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_devices_token ON devices (token);
+CREATE INDEX IF NOT EXISTS idx_devices_user_id ON devices (user_id);
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS digest_sends;
+
+ALTER TABLE users
+    DROP COLUMN IF EXISTS weekly_digest_day,
+    DROP COLUMN IF EXISTS weekly_digest_enabled;
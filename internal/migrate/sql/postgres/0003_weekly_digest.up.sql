@@ -0,0 +1,13 @@
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS weekly_digest_enabled BOOLEAN DEFAULT FALSE,
+    ADD COLUMN IF NOT EXISTS weekly_digest_day BIGINT DEFAULT 0 CONSTRAINT chk_users_weekly_digest_day CHECK (weekly_digest_day BETWEEN 0 AND 6);
+
+CREATE TABLE IF NOT EXISTS digest_sends (
+    id           BIGSERIAL PRIMARY KEY,
+    user_id      BIGINT NOT NULL REFERENCES users (id),
+    period_start TIMESTAMPTZ NOT NULL,
+    period_end   TIMESTAMPTZ NOT NULL,
+    sent_at      TIMESTAMPTZ,
+    created_at   TIMESTAMPTZ
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_digest_sends_user_period ON digest_sends (user_id, period_start);
@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS erasure_audits;
+
+DROP INDEX IF EXISTS idx_users_purge_after;
+ALTER TABLE users
+    DROP COLUMN IF EXISTS purge_after,
+    DROP COLUMN IF EXISTS deletion_requested_at;
@@ -0,0 +1,17 @@
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS deletion_requested_at TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS purge_after TIMESTAMPTZ;
+CREATE INDEX IF NOT EXISTS idx_users_purge_after ON users (purge_after);
+
+CREATE TABLE IF NOT EXISTS erasure_audits (
+    id           BIGSERIAL PRIMARY KEY,
+    requested_at TIMESTAMPTZ NOT NULL,
+    purged_at    TIMESTAMPTZ NOT NULL,
+    habits       BIGINT,
+    streaks      BIGINT,
+    check_ins    BIGINT,
+    achievements BIGINT,
+    devices      BIGINT,
+    created_at   TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS idx_erasure_audits_purged_at ON erasure_audits (purged_at);
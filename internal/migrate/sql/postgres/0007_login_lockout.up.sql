@@ -0,0 +1,20 @@
+-- Failed logins per account ("account:<email>") or client IP ("ip:<address>"),
+-- shared by every replica when LOGIN_LOCKOUT_STORE=database
+CREATE TABLE IF NOT EXISTS login_attempts (
+    attempt_key       TEXT PRIMARY KEY,
+    failures          INTEGER NOT NULL DEFAULT 0,
+    last_failure_at   TIMESTAMPTZ NOT NULL,
+    locked_until      TIMESTAMPTZ,
+    unlock_token_hash TEXT
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_login_attempts_unlock_token_hash ON login_attempts (unlock_token_hash);
+CREATE INDEX IF NOT EXISTS idx_login_attempts_last_failure_at ON login_attempts (last_failure_at);
+
+CREATE TABLE IF NOT EXISTS security_events (
+    id         BIGSERIAL PRIMARY KEY,
+    user_id    BIGINT NOT NULL REFERENCES users (id),
+    type       TEXT NOT NULL,
+    ip_address TEXT,
+    created_at TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS idx_security_events_user_id_created_at ON security_events (user_id, created_at);
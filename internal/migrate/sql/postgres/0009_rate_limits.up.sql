@@ -0,0 +1,9 @@
+-- Rate limit token buckets per budget and user or client IP
+-- ("read:user:<id>", "auth:ip:<address>"), shared by every replica when
+-- API_RATE_LIMIT_STORE=database
+CREATE TABLE IF NOT EXISTS rate_limit_buckets (
+    bucket_key  TEXT PRIMARY KEY,
+    tokens      DOUBLE PRECISION NOT NULL,
+    refilled_at TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_rate_limit_buckets_refilled_at ON rate_limit_buckets (refilled_at);
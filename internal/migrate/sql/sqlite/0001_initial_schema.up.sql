@@ -0,0 +1,75 @@
+CREATE TABLE users (
+    id            INTEGER PRIMARY KEY AUTOINCREMENT,
+    email         TEXT NOT NULL,
+    password_hash TEXT NOT NULL,
+    name          TEXT NOT NULL,
+    created_at    DATETIME,
+    updated_at    DATETIME,
+    deleted_at    DATETIME
+);
+CREATE UNIQUE INDEX idx_users_email ON users (email);
+CREATE INDEX idx_users_deleted_at ON users (deleted_at);
+
+CREATE TABLE habits (
+    id          INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id     INTEGER NOT NULL REFERENCES users (id),
+    name        TEXT NOT NULL,
+    description TEXT,
+    color       VARCHAR(7),
+    icon        VARCHAR(50),
+    is_active   BOOLEAN DEFAULT TRUE,
+    created_at  DATETIME,
+    updated_at  DATETIME,
+    deleted_at  DATETIME
+);
+CREATE INDEX idx_habits_user_id ON habits (user_id);
+CREATE INDEX idx_habits_deleted_at ON habits (deleted_at);
+
+CREATE TABLE habit_streaks (
+    id                  INTEGER PRIMARY KEY AUTOINCREMENT,
+    habit_id            INTEGER NOT NULL REFERENCES habits (id),
+    target_days         INTEGER NOT NULL CONSTRAINT chk_habit_streaks_target_days CHECK (target_days > 0),
+    current_streak      INTEGER DEFAULT 0,
+    max_streak_achieved INTEGER DEFAULT 0,
+    start_date          DATETIME NOT NULL,
+    last_check_in_date  DATETIME,
+    status              TEXT DEFAULT 'active' CONSTRAINT chk_habit_streaks_status CHECK (status IN ('active', 'completed', 'failed')),
+    completed_at        DATETIME,
+    failed_at           DATETIME,
+    created_at          DATETIME,
+    updated_at          DATETIME,
+    deleted_at          DATETIME
+);
+CREATE INDEX idx_habit_streaks_habit_id ON habit_streaks (habit_id);
+CREATE INDEX idx_habit_streaks_deleted_at ON habit_streaks (deleted_at);
+
+CREATE TABLE habit_checkins (
+    id            INTEGER PRIMARY KEY AUTOINCREMENT,
+    streak_id     INTEGER NOT NULL REFERENCES habit_streaks (id),
+    check_in_date DATETIME NOT NULL,
+    checked_in_at DATETIME,
+    notes         TEXT,
+    created_at    DATETIME,
+    updated_at    DATETIME,
+    deleted_at    DATETIME
+);
+CREATE INDEX idx_habit_checkins_streak_id ON habit_checkins (streak_id);
+CREATE INDEX idx_habit_checkins_check_in_date ON habit_checkins (check_in_date);
+CREATE INDEX idx_habit_checkins_deleted_at ON habit_checkins (deleted_at);
+
+-- metadata holds datatypes.JSON, stored as TEXT because SQLite has no JSON column type
+CREATE TABLE achievements (
+    id               INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id          INTEGER NOT NULL REFERENCES users (id),
+    habit_id         INTEGER NOT NULL REFERENCES habits (id),
+    achievement_type TEXT NOT NULL,
+    target_days      INTEGER NOT NULL,
+    achieved_at      DATETIME,
+    metadata         TEXT,
+    created_at       DATETIME,
+    updated_at       DATETIME,
+    deleted_at       DATETIME
+);
+CREATE INDEX idx_achievements_user_id ON achievements (user_id);
+CREATE INDEX idx_achievements_habit_id ON achievements (habit_id);
+CREATE INDEX idx_achievements_deleted_at ON achievements (deleted_at);
@@ -0,0 +1,12 @@
+CREATE TABLE devices (
+    id           INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id      INTEGER NOT NULL REFERENCES users (id),
+    token        TEXT NOT NULL,
+    platform     TEXT NOT NULL CONSTRAINT chk_devices_platform CHECK (platform IN ('ios', 'android')),
+    app_version  VARCHAR(32),
+    last_seen_at DATETIME NOT NULL,
+    created_at   DATETIME,
+    updated_at   DATETIME
+);
+CREATE UNIQUE INDEX idx_devices_token ON devices (token);
+CREATE INDEX idx_devices_user_id ON devices (user_id);
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS digest_sends;
+
+ALTER TABLE users DROP COLUMN weekly_digest_day;
+ALTER TABLE users DROP COLUMN weekly_digest_enabled;
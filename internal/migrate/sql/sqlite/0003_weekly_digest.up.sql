@@ -0,0 +1,12 @@
+ALTER TABLE users ADD COLUMN weekly_digest_enabled BOOLEAN DEFAULT FALSE;
+ALTER TABLE users ADD COLUMN weekly_digest_day INTEGER DEFAULT 0 CONSTRAINT chk_users_weekly_digest_day CHECK (weekly_digest_day BETWEEN 0 AND 6);
+
+CREATE TABLE digest_sends (
+    id           INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id      INTEGER NOT NULL REFERENCES users (id),
+    period_start DATETIME NOT NULL,
+    period_end   DATETIME NOT NULL,
+    sent_at      DATETIME,
+    created_at   DATETIME
+);
+CREATE UNIQUE INDEX idx_digest_sends_user_period ON digest_sends (user_id, period_start);
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS erasure_audits;
+
+DROP INDEX IF EXISTS idx_users_purge_after;
+ALTER TABLE users DROP COLUMN purge_after;
+ALTER TABLE users DROP COLUMN deletion_requested_at;
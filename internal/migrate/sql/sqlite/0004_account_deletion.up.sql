@@ -0,0 +1,16 @@
+ALTER TABLE users ADD COLUMN deletion_requested_at DATETIME;
+ALTER TABLE users ADD COLUMN purge_after DATETIME;
+CREATE INDEX idx_users_purge_after ON users (purge_after);
+
+CREATE TABLE erasure_audits (
+    id           INTEGER PRIMARY KEY AUTOINCREMENT,
+    requested_at DATETIME NOT NULL,
+    purged_at    DATETIME NOT NULL,
+    habits       INTEGER,
+    streaks      INTEGER,
+    check_ins    INTEGER,
+    achievements INTEGER,
+    devices      INTEGER,
+    created_at   DATETIME
+);
+CREATE INDEX idx_erasure_audits_purged_at ON erasure_audits (purged_at);
@@ -0,0 +1,12 @@
+CREATE TABLE user_identities (
+    id            INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id       INTEGER NOT NULL REFERENCES users (id),
+    provider      TEXT NOT NULL CONSTRAINT chk_user_identities_provider CHECK (provider IN ('google', 'apple')),
+    subject       TEXT NOT NULL,
+    email         TEXT,
+    last_login_at DATETIME NOT NULL,
+    created_at    DATETIME,
+    updated_at    DATETIME
+);
+CREATE UNIQUE INDEX idx_user_identities_provider_subject ON user_identities (provider, subject);
+CREATE INDEX idx_user_identities_user_id ON user_identities (user_id);
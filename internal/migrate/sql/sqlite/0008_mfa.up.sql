@@ -0,0 +1,30 @@
+ALTER TABLE users ADD COLUMN mfa_enabled_at DATETIME;
+
+-- The secret is encrypted with MFA_ENCRYPTION_KEY
+CREATE TABLE user_totp (
+    user_id        INTEGER PRIMARY KEY REFERENCES users (id),
+    secret         TEXT NOT NULL,
+    confirmed_at   DATETIME,
+    last_used_step INTEGER,
+    created_at     DATETIME,
+    updated_at     DATETIME
+);
+
+CREATE TABLE mfa_recovery_codes (
+    id         INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id    INTEGER NOT NULL REFERENCES users (id),
+    code_hash  TEXT NOT NULL,
+    used_at    DATETIME,
+    created_at DATETIME
+);
+CREATE INDEX idx_mfa_recovery_codes_user_id ON mfa_recovery_codes (user_id);
+
+CREATE TABLE mfa_challenges (
+    id         INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id    INTEGER NOT NULL REFERENCES users (id),
+    token_hash TEXT NOT NULL,
+    expires_at DATETIME NOT NULL,
+    created_at DATETIME
+);
+CREATE UNIQUE INDEX idx_mfa_challenges_token_hash ON mfa_challenges (token_hash);
+CREATE INDEX idx_mfa_challenges_user_id ON mfa_challenges (user_id);
@@ -0,0 +1,9 @@
+-- Rate limit token buckets per budget and user or client IP
+-- ("read:user:<id>", "auth:ip:<address>"), shared by every replica when
+-- API_RATE_LIMIT_STORE=database
+CREATE TABLE rate_limit_buckets (
+    bucket_key  TEXT PRIMARY KEY,
+    tokens      REAL NOT NULL,
+    refilled_at DATETIME NOT NULL
+);
+CREATE INDEX idx_rate_limit_buckets_refilled_at ON rate_limit_buckets (refilled_at);
@@ -0,0 +1,12 @@
+package models
+
+import (
+	"time"
+)
+
+// ClockResponse is the DTO for the server clock used by the time travel endpoint
+type ClockResponse struct {
+	Now     time.Time `json:"now"`
+	RealNow time.Time `json:"real_now"`
+	Offset  string    `json:"offset"`
+}
@@ -0,0 +1,52 @@
+package models
+
+import (
+	"time"
+)
+
+// Supported device platforms
+const (
+	PlatformIOS     = "ios"
+	PlatformAndroid = "android"
+)
+
+// Device represents a mobile device registered to receive push notifications.
+// Devices are hard deleted so that a token can be registered again later.
+type Device struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	UserID     uint      `json:"user_id" gorm:"not null;index"`
+	Token      string    `json:"token" gorm:"uniqueIndex;not null"`
+	Platform   string    `json:"platform" gorm:"not null;check:platform IN ('ios', 'android')"`
+	AppVersion string    `json:"app_version" gorm:"size:32"`
+	LastSeenAt time.Time `json:"last_seen_at" gorm:"not null"`
+	CreatedAt  time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt  time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+
+	// Relationships
+	User User `json:"-" gorm:"foreignKey:UserID"`
+}
+
+// TableName specifies the table name for the Device model
+func (Device) TableName() string {
+	return "devices"
+}
+
+// DeviceResponse is the DTO for device data sent to clients
+type DeviceResponse struct {
+	ID         uint      `json:"id"`
+	Platform   string    `json:"platform"`
+	AppVersion string    `json:"app_version,omitempty"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// ToResponse converts a Device to a DeviceResponse
+func (d *Device) ToResponse() DeviceResponse {
+	return DeviceResponse{
+		ID:         d.ID,
+		Platform:   d.Platform,
+		AppVersion: d.AppVersion,
+		LastSeenAt: d.LastSeenAt,
+		CreatedAt:  d.CreatedAt,
+	}
+}
@@ -0,0 +1,30 @@
+package models
+
+import (
+	"time"
+)
+
+// DigestSend records a weekly digest email so that a period is never sent twice.
+// A row is claimed before sending and SentAt is set once the mailer succeeds.
+type DigestSend struct {
+	ID          uint       `json:"id" gorm:"primaryKey"`
+	UserID      uint       `json:"user_id" gorm:"not null;uniqueIndex:idx_digest_sends_user_period"`
+	PeriodStart time.Time  `json:"period_start" gorm:"not null;uniqueIndex:idx_digest_sends_user_period"`
+	PeriodEnd   time.Time  `json:"period_end" gorm:"not null"`
+	SentAt      *time.Time `json:"sent_at"`
+	CreatedAt   time.Time  `json:"created_at" gorm:"autoCreateTime"`
+
+	// Relationships
+	User User `json:"-" gorm:"foreignKey:UserID"`
+}
+
+// TableName specifies the table name for the DigestSend model
+func (DigestSend) TableName() string {
+	return "digest_sends"
+}
+
+// DigestPreferencesResponse is the DTO for a user's weekly digest preferences
+type DigestPreferencesResponse struct {
+	Enabled bool   `json:"enabled"`
+	SendDay string `json:"send_day"`
+}
@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+)
+
+// EmailVerification is a pending email verification. Only a hash of the
+// token is stored, the token itself is only ever sent to the user.
+type EmailVerification struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	UserID    uint      `json:"user_id" gorm:"not null;index"`
+	TokenHash string    `json:"-" gorm:"not null;uniqueIndex"`
+	ExpiresAt time.Time `json:"expires_at" gorm:"not null"`
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+
+	// Relationships
+	User User `json:"-" gorm:"foreignKey:UserID"`
+}
+
+// TableName specifies the table name for the EmailVerification model
+func (EmailVerification) TableName() string {
+	return "email_verifications"
+}
@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+// ErasureAudit is an anonymised record of a purged account. It holds no
+// personal data, only when the erasure happened and how much was removed.
+type ErasureAudit struct {
+	ID           uint      `json:"id" gorm:"primaryKey"`
+	RequestedAt  time.Time `json:"requested_at" gorm:"not null"`
+	PurgedAt     time.Time `json:"purged_at" gorm:"not null;index"`
+	Habits       int64     `json:"habits"`
+	Streaks      int64     `json:"streaks"`
+	CheckIns     int64     `json:"check_ins"`
+	Achievements int64     `json:"achievements"`
+	Devices      int64     `json:"devices"`
+	CreatedAt    time.Time `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// TableName specifies the table name for the ErasureAudit model
+func (ErasureAudit) TableName() string {
+	return "erasure_audits"
+}
+
+// AccountDeletionResponse is the DTO returned when an account deletion is scheduled
+type AccountDeletionResponse struct {
+	DeletionRequestedAt time.Time `json:"deletion_requested_at"`
+	PurgeAfter          time.Time `json:"purge_after"`
+}
@@ -0,0 +1,51 @@
+package models
+
+import (
+	"time"
+)
+
+// Supported OpenID Connect sign-in providers
+const (
+	ProviderGoogle = "google"
+	ProviderApple  = "apple"
+)
+
+// Identity links a user to their account at an OpenID Connect provider. The
+// subject is the provider's stable user ID; the email is kept for display as
+// it can change at the provider.
+type Identity struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	UserID      uint      `json:"user_id" gorm:"not null;index"`
+	Provider    string    `json:"provider" gorm:"not null;uniqueIndex:idx_user_identities_provider_subject;check:provider IN ('google', 'apple')"`
+	Subject     string    `json:"-" gorm:"not null;uniqueIndex:idx_user_identities_provider_subject"`
+	Email       string    `json:"email"`
+	LastLoginAt time.Time `json:"last_login_at" gorm:"not null"`
+	CreatedAt   time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt   time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+
+	// Relationships
+	User User `json:"-" gorm:"foreignKey:UserID"`
+}
+
+// TableName specifies the table name for the Identity model
+func (Identity) TableName() string {
+	return "user_identities"
+}
+
+// IdentityResponse is the DTO for linked identity data sent to clients
+type IdentityResponse struct {
+	Provider    string    `json:"provider"`
+	Email       string    `json:"email,omitempty"`
+	LastLoginAt time.Time `json:"last_login_at"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// ToResponse converts an Identity to an IdentityResponse
+func (i *Identity) ToResponse() IdentityResponse {
+	return IdentityResponse{
+		Provider:    i.Provider,
+		Email:       i.Email,
+		LastLoginAt: i.LastLoginAt,
+		CreatedAt:   i.CreatedAt,
+	}
+}
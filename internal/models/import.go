@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+// ImportReport is the DTO describing the outcome of a habit history import
+type ImportReport struct {
+	Format       string              `json:"format"`
+	DryRun       bool                `json:"dry_run"`
+	Habits       []ImportHabitReport `json:"habits"`
+	Conflicts    []ImportConflict    `json:"conflicts"`
+	Streaks      int                 `json:"streaks"`
+	CheckIns     int                 `json:"check_ins"`
+	Achievements int                 `json:"achievements"`
+}
+
+// ImportHabitReport describes a habit that was, or in a dry run would be, created
+type ImportHabitReport struct {
+	ID           uint       `json:"id,omitempty"`
+	Name         string     `json:"name"`
+	FirstDay     *time.Time `json:"first_day,omitempty"`
+	LastDay      *time.Time `json:"last_day,omitempty"`
+	Streaks      int        `json:"streaks"`
+	CheckIns     int        `json:"check_ins"`
+	Achievements int        `json:"achievements"`
+	ActiveStreak *int       `json:"active_streak,omitempty"`
+}
+
+// ImportConflict describes a source habit that was skipped
+type ImportConflict struct {
+	Name            string `json:"name"`
+	ExistingHabitID uint   `json:"existing_habit_id,omitempty"`
+	Reason          string `json:"reason"`
+}
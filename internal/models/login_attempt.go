@@ -0,0 +1,21 @@
+package models
+
+import (
+	"time"
+)
+
+// LoginAttempt counts the recent failed logins for an account or client IP,
+// identified by Key. Only a hash of the unlock token is stored, the token
+// itself is only ever sent to the user.
+type LoginAttempt struct {
+	Key             string    `gorm:"column:attempt_key;primaryKey"`
+	Failures        int       `gorm:"not null;default:0"`
+	LastFailureAt   time.Time `gorm:"not null;index"`
+	LockedUntil     *time.Time
+	UnlockTokenHash *string `gorm:"uniqueIndex"`
+}
+
+// TableName specifies the table name for the LoginAttempt model
+func (LoginAttempt) TableName() string {
+	return "login_attempts"
+}
@@ -0,0 +1,18 @@
+package models
+
+import (
+	"time"
+)
+
+// RateLimitBucket is the token bucket for one budget and client, identified
+// by Key. Tokens is the balance as of RefilledAt.
+type RateLimitBucket struct {
+	Key        string    `gorm:"column:bucket_key;primaryKey"`
+	Tokens     float64   `gorm:"not null"`
+	RefilledAt time.Time `gorm:"not null;index"`
+}
+
+// TableName specifies the table name for the RateLimitBucket model
+func (RateLimitBucket) TableName() string {
+	return "rate_limit_buckets"
+}
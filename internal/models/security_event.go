@@ -0,0 +1,52 @@
+package models
+
+import (
+	"time"
+)
+
+// Security event types
+const (
+	SecurityEventLoginSucceeded  = "login_succeeded"
+	SecurityEventLoginFailed     = "login_failed"
+	SecurityEventAccountLocked   = "account_locked"
+	SecurityEventAccountUnlocked = "account_unlocked"
+	SecurityEventMFAEnabled      = "mfa_enabled"
+	SecurityEventMFADisabled     = "mfa_disabled"
+	SecurityEventRecoveryCodes   = "recovery_codes_regenerated"
+	SecurityEventRecoveryUsed    = "recovery_code_used"
+	SecurityEventPasswordReset   = "password_reset"
+)
+
+// SecurityEvent records a sign-in related event on an account, so users can
+// review the activity on it
+type SecurityEvent struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	UserID    uint      `json:"user_id" gorm:"not null;index:idx_security_events_user_id_created_at"`
+	Type      string    `json:"type" gorm:"not null"`
+	IPAddress string    `json:"ip_address"`
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime;index:idx_security_events_user_id_created_at"`
+
+	// Relationships
+	User User `json:"-" gorm:"foreignKey:UserID"`
+}
+
+// TableName specifies the table name for the SecurityEvent model
+func (SecurityEvent) TableName() string {
+	return "security_events"
+}
+
+// SecurityEventResponse is the DTO for security event data sent to clients
+type SecurityEventResponse struct {
+	Type      string    `json:"type"`
+	IPAddress string    `json:"ip_address,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ToResponse converts a SecurityEvent to a SecurityEventResponse
+func (e *SecurityEvent) ToResponse() SecurityEventResponse {
+	return SecurityEventResponse{
+		Type:      e.Type,
+		IPAddress: e.IPAddress,
+		CreatedAt: e.CreatedAt,
+	}
+}
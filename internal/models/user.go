@@ -9,13 +9,28 @@ import (
 )
 
 type User struct {
-	ID           uint           `json:"id" gorm:"primaryKey"`
-	Email        string         `json:"email" gorm:"uniqueIndex;not null"`
-	PasswordHash string         `json:"-" gorm:"not null"`
-	Name         string         `json:"name" gorm:"not null"`
-	CreatedAt    time.Time      `json:"created_at" gorm:"autoCreateTime"`
-	UpdatedAt    time.Time      `json:"updated_at" gorm:"autoUpdateTime"`
-	DeletedAt    gorm.DeletedAt `json:"-" gorm:"index"`
+	ID           uint   `json:"id" gorm:"primaryKey"`
+	Email        string `json:"email" gorm:"uniqueIndex;not null"`
+	PasswordHash string `json:"-" gorm:"not null"`
+	Name         string `json:"name" gorm:"not null"`
+
+	// Weekly digest preferences, WeeklyDigestDay is a time.Weekday (0 = Sunday)
+	WeeklyDigestEnabled bool `json:"weekly_digest_enabled" gorm:"default:false"`
+	WeeklyDigestDay     int  `json:"weekly_digest_day" gorm:"default:0;check:weekly_digest_day BETWEEN 0 AND 6"`
+
+	// Set once the user has confirmed their email address
+	EmailVerifiedAt *time.Time `json:"-"`
+
+	// Set while two-factor authentication is enabled
+	MFAEnabledAt *time.Time `json:"-" gorm:"column:mfa_enabled_at"`
+
+	// Set when the user deletes their account, the account is purged after PurgeAfter
+	DeletionRequestedAt *time.Time `json:"-"`
+	PurgeAfter          *time.Time `json:"-" gorm:"index"`
+
+	CreatedAt time.Time      `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt time.Time      `json:"updated_at" gorm:"autoUpdateTime"`
+	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
 }
 
 // SetPassword hashes and sets the user's password
@@ -28,6 +43,22 @@ func (u *User) SetPassword(password string) error {
 	return nil
 }
 
+// HasPassword reports whether the user can sign in with a password. Users
+// who signed up with an OpenID Connect provider have none until they set one.
+func (u *User) HasPassword() bool {
+	return u.PasswordHash != ""
+}
+
+// EmailVerified reports whether the user has confirmed their email address
+func (u *User) EmailVerified() bool {
+	return u.EmailVerifiedAt != nil
+}
+
+// MFAEnabled reports whether logging in needs a second factor
+func (u *User) MFAEnabled() bool {
+	return u.MFAEnabledAt != nil
+}
+
 // CheckPassword checks if the provided password matches the stored hash
 func (u *User) CheckPassword(password string) bool {
 	err := bcrypt.CompareHashAndPassword([]byte(u.PasswordHash), []byte(password))
@@ -41,10 +72,13 @@ func (User) TableName() string {
 
 // UserResponse is the DTO for user data sent to clients
 type UserResponse struct {
-	ID        uint      `json:"id"`
-	Email     string    `json:"email"`
-	Name      string    `json:"name"`
-	CreatedAt time.Time `json:"created_at"`
+	ID            uint      `json:"id"`
+	Email         string    `json:"email"`
+	Name          string    `json:"name"`
+	HasPassword   bool      `json:"has_password"`
+	EmailVerified bool      `json:"email_verified"`
+	MFAEnabled    bool      `json:"mfa_enabled"`
+	CreatedAt     time.Time `json:"created_at"`
 }
 
 type ConsistencyDataPoint struct {
@@ -100,9 +134,12 @@ type UserProfileResponse struct {
 
 func (u *User) ToResponse() UserResponse {
 	return UserResponse{
-		ID:        u.ID,
-		Email:     u.Email,
-		Name:      u.Name,
-		CreatedAt: u.CreatedAt,
+		ID:            u.ID,
+		Email:         u.Email,
+		Name:          u.Name,
+		HasPassword:   u.HasPassword(),
+		EmailVerified: u.EmailVerified(),
+		MFAEnabled:    u.MFAEnabled(),
+		CreatedAt:     u.CreatedAt,
 	}
 }
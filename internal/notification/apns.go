@@ -0,0 +1,154 @@
+package notification
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// apnsTokenTTL is how long a provider token is reused. Apple rejects tokens
+// older than one hour and throttles tokens refreshed more than every 20 minutes.
+const apnsTokenTTL = 50 * time.Minute
+
+// APNsProvider sends notifications through the Apple Push Notification service
+// using token based authentication
+type APNsProvider struct {
+	client   *http.Client
+	endpoint string
+	topic    string
+	keyID    string
+	teamID   string
+	key      *ecdsa.PrivateKey
+
+	mu          sync.Mutex
+	bearer      string
+	bearerIssue time.Time
+}
+
+// NewAPNsProvider creates a new APNs provider from a PEM encoded .p8 signing key.
+// The endpoint is the API base URL, which allows pointing the provider at a
+// local stub server.
+func NewAPNsProvider(client *http.Client, endpoint, topic, keyID, teamID string, privateKeyPEM []byte) (*APNsProvider, error) {
+	key, err := jwt.ParseECPrivateKeyFromPEM(privateKeyPEM)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse APNs private key: %w", err)
+	}
+
+	return &APNsProvider{
+		client:   client,
+		endpoint: strings.TrimRight(endpoint, "/"),
+		topic:    topic,
+		keyID:    keyID,
+		teamID:   teamID,
+		key:      key,
+	}, nil
+}
+
+type apnsPayload struct {
+	APS  apnsAPS           `json:"aps"`
+	Data map[string]string `json:"data,omitempty"`
+}
+
+type apnsAPS struct {
+	Alert apnsAlert `json:"alert"`
+	Sound string    `json:"sound,omitempty"`
+}
+
+type apnsAlert struct {
+	Title string `json:"title"`
+	Body  string `json:"body"`
+}
+
+type apnsErrorResponse struct {
+	Reason string `json:"reason"`
+}
+
+// Send implements Provider
+func (p *APNsProvider) Send(ctx context.Context, token string, notification Notification) error {
+	body, err := json.Marshal(apnsPayload{
+		APS: apnsAPS{
+			Alert: apnsAlert{
+				Title: notification.Title,
+				Body:  notification.Body,
+			},
+			Sound: "default",
+		},
+		Data: notification.Data,
+	})
+	if err != nil {
+		return err
+	}
+
+	bearer, err := p.providerToken()
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.endpoint+"/3/device/"+token, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "bearer "+bearer)
+	req.Header.Set("apns-topic", p.topic)
+	req.Header.Set("apns-push-type", "alert")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+
+	var errResp apnsErrorResponse
+	_ = json.NewDecoder(io.LimitReader(resp.Body, 64*1024)).Decode(&errResp)
+
+	switch {
+	case resp.StatusCode == http.StatusGone,
+		errResp.Reason == "BadDeviceToken",
+		errResp.Reason == "DeviceTokenNotForTopic",
+		errResp.Reason == "Unregistered":
+		return fmt.Errorf("apns: %s: %w", errResp.Reason, ErrInvalidToken)
+	}
+
+	return fmt.Errorf("apns: unexpected status %d: %s", resp.StatusCode, errResp.Reason)
+}
+
+// providerToken returns a cached ES256 provider token, signing a new one when
+// the cached token is about to expire
+func (p *APNsProvider) providerToken() (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	if p.bearer != "" && now.Sub(p.bearerIssue) < apnsTokenTTL {
+		return p.bearer, nil
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodES256, jwt.RegisteredClaims{
+		Issuer:   p.teamID,
+		IssuedAt: jwt.NewNumericDate(now),
+	})
+	token.Header["kid"] = p.keyID
+
+	signed, err := token.SignedString(p.key)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign APNs provider token: %w", err)
+	}
+
+	p.bearer = signed
+	p.bearerIssue = now
+	return signed, nil
+}
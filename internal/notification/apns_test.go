@@ -0,0 +1,77 @@
+package notification
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestAPNsSend(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pemKey := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+
+	tests := []struct {
+		name        string
+		status      int
+		reason      string
+		wantErr     bool
+		wantInvalid bool
+	}{
+		{name: "sent", status: http.StatusOK},
+		{name: "gone", status: http.StatusGone, reason: "Unregistered", wantErr: true, wantInvalid: true},
+		{name: "bad token", status: http.StatusBadRequest, reason: "BadDeviceToken", wantErr: true, wantInvalid: true},
+		{name: "bad payload", status: http.StatusBadRequest, reason: "PayloadEmpty", wantErr: true},
+		{name: "throttled", status: http.StatusTooManyRequests, reason: "TooManyRequests", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/3/device/device-token" || r.Header.Get("apns-topic") != "com.example.app" {
+					t.Errorf("request %s for topic %q", r.URL.Path, r.Header.Get("apns-topic"))
+				}
+				bearer := strings.TrimPrefix(r.Header.Get("Authorization"), "bearer ")
+				token, err := jwt.Parse(bearer, func(*jwt.Token) (interface{}, error) { return &key.PublicKey, nil })
+				if err != nil || token.Header["kid"] != "KEY123" {
+					t.Errorf("provider token: %v, header %v", err, token.Header)
+				}
+				var payload apnsPayload
+				if err := json.NewDecoder(r.Body).Decode(&payload); err != nil || payload.APS.Alert.Title != "Hi" {
+					t.Errorf("payload = %+v, %v", payload, err)
+				}
+				w.WriteHeader(tt.status)
+				if tt.reason != "" {
+					_ = json.NewEncoder(w).Encode(apnsErrorResponse{Reason: tt.reason})
+				}
+			}))
+			defer server.Close()
+
+			provider, err := NewAPNsProvider(server.Client(), server.URL, "com.example.app", "KEY123", "TEAM", pemKey)
+			if err != nil {
+				t.Fatalf("provider: %v", err)
+			}
+			err = provider.Send(context.Background(), "device-token", Notification{Title: "Hi", Body: "There"})
+			if (err != nil) != tt.wantErr || errors.Is(err, ErrInvalidToken) != tt.wantInvalid {
+				t.Fatalf("Send = %v, want error %v and invalid token %v", err, tt.wantErr, tt.wantInvalid)
+			}
+		})
+	}
+}
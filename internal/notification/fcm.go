@@ -0,0 +1,239 @@
+package notification
+
+import (
+	"bytes"
+	"context"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// fcmScope is the OAuth2 scope of the FCM HTTP v1 API
+const fcmScope = "https://www.googleapis.com/auth/firebase.messaging"
+
+// TokenSource returns an OAuth2 access token for the FCM HTTP v1 API
+type TokenSource func(ctx context.Context) (string, error)
+
+// StaticTokenSource returns a TokenSource that always yields the given token
+func StaticTokenSource(token string) TokenSource {
+	return func(context.Context) (string, error) {
+		return token, nil
+	}
+}
+
+// ServiceAccount is the part of a Google service account key file that is
+// needed to request access tokens
+type ServiceAccount struct {
+	ProjectID    string `json:"project_id"`
+	ClientEmail  string `json:"client_email"`
+	PrivateKey   string `json:"private_key"`
+	PrivateKeyID string `json:"private_key_id"`
+	TokenURI     string `json:"token_uri"`
+}
+
+// ParseServiceAccount parses the JSON key file of a Google service account
+func ParseServiceAccount(data []byte) (*ServiceAccount, error) {
+	var account ServiceAccount
+	if err := json.Unmarshal(data, &account); err != nil {
+		return nil, fmt.Errorf("failed to parse service account: %w", err)
+	}
+	if account.ClientEmail == "" || account.PrivateKey == "" || account.TokenURI == "" {
+		return nil, errors.New("service account needs client_email, private_key and token_uri")
+	}
+	return &account, nil
+}
+
+// serviceAccountToken is a cached access token of a service account
+type serviceAccountToken struct {
+	client  *http.Client
+	account *ServiceAccount
+	key     *rsa.PrivateKey
+
+	mu      sync.Mutex
+	token   string
+	expires time.Time
+}
+
+// ServiceAccountTokenSource returns a TokenSource that exchanges a token
+// signed with the service account's key for an access token, and requests
+// a new one shortly before it expires
+func ServiceAccountTokenSource(client *http.Client, account *ServiceAccount) (TokenSource, error) {
+	key, err := jwt.ParseRSAPrivateKeyFromPEM([]byte(account.PrivateKey))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse service account private key: %w", err)
+	}
+
+	source := &serviceAccountToken{client: client, account: account, key: key}
+	return source.get, nil
+}
+
+type tokenResponse struct {
+	AccessToken string `json:"access_token"`
+	ExpiresIn   int    `json:"expires_in"`
+}
+
+func (s *serviceAccountToken) get(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Refresh a minute early so that a token does not expire in flight
+	now := time.Now()
+	if s.token != "" && now.Before(s.expires.Add(-time.Minute)) {
+		return s.token, nil
+	}
+
+	assertion := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss":   s.account.ClientEmail,
+		"scope": fcmScope,
+		"aud":   s.account.TokenURI,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	})
+	if s.account.PrivateKeyID != "" {
+		assertion.Header["kid"] = s.account.PrivateKeyID
+	}
+	signed, err := assertion.SignedString(s.key)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign token request: %w", err)
+	}
+
+	form := url.Values{
+		"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+		"assertion":  {signed},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.account.TokenURI, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("token endpoint answered %d", resp.StatusCode)
+	}
+	var token tokenResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 64*1024)).Decode(&token); err != nil {
+		return "", fmt.Errorf("failed to decode access token: %w", err)
+	}
+	if token.AccessToken == "" {
+		return "", errors.New("token endpoint returned no access token")
+	}
+
+	s.token = token.AccessToken
+	s.expires = now.Add(time.Duration(token.ExpiresIn) * time.Second)
+	return s.token, nil
+}
+
+// FCMProvider sends notifications through the Firebase Cloud Messaging HTTP v1 API
+type FCMProvider struct {
+	client      *http.Client
+	endpoint    string
+	projectID   string
+	tokenSource TokenSource
+}
+
+// NewFCMProvider creates a new FCM provider. The endpoint is the API base URL,
+// which allows pointing the provider at a local stub server.
+func NewFCMProvider(client *http.Client, endpoint, projectID string, tokenSource TokenSource) *FCMProvider {
+	return &FCMProvider{
+		client:      client,
+		endpoint:    strings.TrimRight(endpoint, "/"),
+		projectID:   projectID,
+		tokenSource: tokenSource,
+	}
+}
+
+type fcmRequest struct {
+	Message fcmMessage `json:"message"`
+}
+
+type fcmMessage struct {
+	Token        string            `json:"token"`
+	Notification fcmNotification   `json:"notification"`
+	Data         map[string]string `json:"data,omitempty"`
+}
+
+type fcmNotification struct {
+	Title string `json:"title"`
+	Body  string `json:"body"`
+}
+
+type fcmErrorResponse struct {
+	Error struct {
+		Status  string `json:"status"`
+		Message string `json:"message"`
+		Details []struct {
+			ErrorCode string `json:"errorCode"`
+		} `json:"details"`
+	} `json:"error"`
+}
+
+// Send implements Provider
+func (p *FCMProvider) Send(ctx context.Context, token string, notification Notification) error {
+	body, err := json.Marshal(fcmRequest{
+		Message: fcmMessage{
+			Token: token,
+			Notification: fcmNotification{
+				Title: notification.Title,
+				Body:  notification.Body,
+			},
+			Data: notification.Data,
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	accessToken, err := p.tokenSource(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get FCM access token: %w", err)
+	}
+
+	url := fmt.Sprintf("%s/v1/projects/%s/messages:send", p.endpoint, p.projectID)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+
+	var errResp fcmErrorResponse
+	_ = json.NewDecoder(io.LimitReader(resp.Body, 64*1024)).Decode(&errResp)
+
+	// INVALID_ARGUMENT is also returned for a malformed message, which must
+	// not cost every device its registration
+	for _, detail := range errResp.Error.Details {
+		if detail.ErrorCode == "UNREGISTERED" {
+			return fmt.Errorf("fcm: %s: %w", detail.ErrorCode, ErrInvalidToken)
+		}
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("fcm: %s: %w", errResp.Error.Status, ErrInvalidToken)
+	}
+
+	return fmt.Errorf("fcm: unexpected status %d: %s", resp.StatusCode, errResp.Error.Message)
+}
@@ -0,0 +1,125 @@
+package notification
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestFCMSend(t *testing.T) {
+	tests := []struct {
+		name        string
+		status      int
+		body        string
+		wantErr     bool
+		wantInvalid bool
+	}{
+		{name: "sent", status: http.StatusOK, body: `{"name":"projects/demo/messages/1"}`},
+		{name: "unregistered", status: http.StatusNotFound, body: `{"error":{"status":"NOT_FOUND","details":[{"errorCode":"UNREGISTERED"}]}}`, wantErr: true, wantInvalid: true},
+		{name: "not found", status: http.StatusNotFound, body: `{"error":{"status":"NOT_FOUND"}}`, wantErr: true, wantInvalid: true},
+		// A malformed message must not prune the device
+		{name: "invalid argument", status: http.StatusBadRequest, body: `{"error":{"status":"INVALID_ARGUMENT","message":"bad data","details":[{"errorCode":"INVALID_ARGUMENT"}]}}`, wantErr: true},
+		{name: "unavailable", status: http.StatusServiceUnavailable, body: `{"error":{"status":"UNAVAILABLE"}}`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got fcmRequest
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/v1/projects/demo/messages:send" || r.Header.Get("Authorization") != "Bearer access" {
+					t.Errorf("request %s with %q, want the send endpoint with the access token", r.URL.Path, r.Header.Get("Authorization"))
+				}
+				_ = json.NewDecoder(r.Body).Decode(&got)
+				w.WriteHeader(tt.status)
+				_, _ = w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			provider := NewFCMProvider(server.Client(), server.URL+"/", "demo", StaticTokenSource("access"))
+			err := provider.Send(context.Background(), "device-token", Notification{Title: "Hi", Body: "There", Data: map[string]string{"k": "v"}})
+
+			if (err != nil) != tt.wantErr || errors.Is(err, ErrInvalidToken) != tt.wantInvalid {
+				t.Fatalf("Send = %v, want error %v and invalid token %v", err, tt.wantErr, tt.wantInvalid)
+			}
+			if got.Message.Token != "device-token" || got.Message.Notification.Title != "Hi" || got.Message.Data["k"] != "v" {
+				t.Errorf("message = %+v", got.Message)
+			}
+		})
+	}
+}
+
+func TestServiceAccountTokenSource(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := requests.Add(1)
+		if err := r.ParseForm(); err != nil || r.Form.Get("grant_type") != "urn:ietf:params:oauth:grant-type:jwt-bearer" {
+			t.Errorf("grant_type = %q", r.Form.Get("grant_type"))
+		}
+		claims := jwt.MapClaims{}
+		if _, err := jwt.ParseWithClaims(r.Form.Get("assertion"), claims, func(*jwt.Token) (interface{}, error) { return &key.PublicKey, nil }); err != nil {
+			t.Errorf("assertion: %v", err)
+		}
+		if claims["iss"] != "push@demo.iam.gserviceaccount.com" || claims["scope"] != fcmScope {
+			t.Errorf("claims = %v", claims)
+		}
+		// The first token is about to expire, so the second call refreshes it
+		expiresIn := 30
+		if n > 1 {
+			expiresIn = 3600
+		}
+		_ = json.NewEncoder(w).Encode(tokenResponse{AccessToken: fmt.Sprintf("token-%d", n), ExpiresIn: expiresIn})
+	}))
+	defer server.Close()
+
+	pemKey := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: mustMarshalPKCS8(t, key)})
+	credentials, _ := json.Marshal(map[string]string{
+		"type":         "service_account",
+		"project_id":   "demo",
+		"client_email": "push@demo.iam.gserviceaccount.com",
+		"private_key":  string(pemKey),
+		"token_uri":    server.URL,
+	})
+	account, err := ParseServiceAccount(credentials)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	source, err := ServiceAccountTokenSource(server.Client(), account)
+	if err != nil {
+		t.Fatalf("token source: %v", err)
+	}
+
+	for i, want := range []string{"token-1", "token-2", "token-2"} {
+		got, err := source(context.Background())
+		if err != nil || got != want {
+			t.Errorf("call %d = %q, %v, want %q", i+1, got, err, want)
+		}
+	}
+	if got := requests.Load(); got != 2 {
+		t.Errorf("token endpoint called %d times, want 2", got)
+	}
+}
+
+func mustMarshalPKCS8(t *testing.T, key *rsa.PrivateKey) []byte {
+	t.Helper()
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return der
+}
@@ -0,0 +1,131 @@
+// Package notification delivers push notifications to users' registered devices.
+package notification
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/config"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/models"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/repository"
+	"github.com/rs/zerolog/log"
+)
+
+// ErrInvalidToken is returned by a Provider when the push service reports that
+// a device token is no longer valid and should not be used again.
+var ErrInvalidToken = errors.New("invalid device token")
+
+// Notification is the platform independent payload of a push notification
+type Notification struct {
+	Title string
+	Body  string
+	Data  map[string]string
+}
+
+// Provider sends a notification to a single device token
+type Provider interface {
+	Send(ctx context.Context, token string, notification Notification) error
+}
+
+// Result summarizes a fan-out to a user's devices
+type Result struct {
+	Sent   int `json:"sent"`
+	Failed int `json:"failed"`
+	Pruned int `json:"pruned"`
+}
+
+// Notifier fans notifications out to every device registered by a user
+type Notifier struct {
+	deviceRepo repository.DeviceRepository
+	providers  map[string]Provider
+}
+
+// NewNotifier creates a new notifier with a provider per device platform
+func NewNotifier(deviceRepo repository.DeviceRepository, providers map[string]Provider) *Notifier {
+	return &Notifier{
+		deviceRepo: deviceRepo,
+		providers:  providers,
+	}
+}
+
+// NewNotifierFromConfig creates a notifier with the providers that are configured
+func NewNotifierFromConfig(deviceRepo repository.DeviceRepository, cfg *config.Config) *Notifier {
+	client := &http.Client{Timeout: cfg.Push.Timeout}
+	providers := make(map[string]Provider)
+
+	if cfg.Push.FCMCredentials != "" {
+		fcm, err := newFCMProviderFromConfig(client, cfg.Push)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to configure FCM provider, Android push notifications are disabled")
+		} else {
+			providers[models.PlatformAndroid] = fcm
+		}
+	}
+
+	if cfg.Push.APNsKeyID != "" && cfg.Push.APNsPrivateKey != "" {
+		apns, err := NewAPNsProvider(client, cfg.Push.APNsEndpoint, cfg.Push.APNsTopic, cfg.Push.APNsKeyID, cfg.Push.APNsTeamID, []byte(cfg.Push.APNsPrivateKey))
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to configure APNs provider, iOS push notifications are disabled")
+		} else {
+			providers[models.PlatformIOS] = apns
+		}
+	}
+
+	return NewNotifier(deviceRepo, providers)
+}
+
+// newFCMProviderFromConfig creates the FCM provider from the service account
+// key, for the project of the key unless another one is configured
+func newFCMProviderFromConfig(client *http.Client, cfg config.PushConfig) (*FCMProvider, error) {
+	account, err := ParseServiceAccount([]byte(cfg.FCMCredentials))
+	if err != nil {
+		return nil, err
+	}
+	tokens, err := ServiceAccountTokenSource(client, account)
+	if err != nil {
+		return nil, err
+	}
+
+	projectID := cfg.FCMProjectID
+	if projectID == "" {
+		projectID = account.ProjectID
+	}
+	return NewFCMProvider(client, cfg.FCMEndpoint, projectID, tokens), nil
+}
+
+// NotifyUser sends the notification to all of the user's devices. Devices whose
+// tokens are reported as invalid by the provider are removed.
+func (n *Notifier) NotifyUser(ctx context.Context, userID uint, notification Notification) (*Result, error) {
+	devices, err := n.deviceRepo.FindByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{}
+	for _, device := range devices {
+		provider, ok := n.providers[device.Platform]
+		if !ok {
+			log.Debug().Str("platform", device.Platform).Uint("deviceID", device.ID).Msg("No push provider configured for platform")
+			result.Failed++
+			continue
+		}
+
+		err := provider.Send(ctx, device.Token, notification)
+		switch {
+		case err == nil:
+			result.Sent++
+		case errors.Is(err, ErrInvalidToken):
+			if err := n.deviceRepo.Delete(ctx, device.ID); err != nil {
+				return nil, err
+			}
+			log.Info().Uint("deviceID", device.ID).Uint("userID", userID).Msg("Pruned invalid device token")
+			result.Pruned++
+		default:
+			log.Error().Err(err).Uint("deviceID", device.ID).Str("platform", device.Platform).Msg("Failed to send push notification")
+			result.Failed++
+		}
+	}
+
+	return result, nil
+}
@@ -0,0 +1,138 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// keysTTL is how long fetched keys are used before they are fetched again
+	keysTTL = time.Hour
+
+	// refreshInterval limits how often a token with an unknown key ID can make
+	// us fetch the keys, so forged tokens cannot flood the provider
+	refreshInterval = time.Minute
+
+	// maxJWKSSize caps the size of a JWKS response
+	maxJWKSSize = 1 << 20
+)
+
+type jwk struct {
+	KeyType string `json:"kty"`
+	ID      string `json:"kid"`
+	Use     string `json:"use"`
+	N       string `json:"n"`
+	E       string `json:"e"`
+}
+
+type jwks struct {
+	Keys []jwk `json:"keys"`
+}
+
+// keyCache holds the RSA keys published at a provider's JWKS URL. Keys are
+// fetched again when they are older than keysTTL or a token names a key ID
+// that is not cached, which is how providers roll over keys.
+type keyCache struct {
+	client *http.Client
+	url    string
+
+	mu        sync.Mutex
+	keys      map[string]*rsa.PublicKey
+	fetchedAt time.Time
+}
+
+func newKeyCache(client *http.Client, url string) *keyCache {
+	return &keyCache{client: client, url: url}
+}
+
+// key returns the public key with the given ID
+func (c *keyCache) key(ctx context.Context, kid string, now time.Time) (*rsa.PublicKey, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stale := c.keys == nil || now.Sub(c.fetchedAt) >= keysTTL
+	if _, ok := c.keys[kid]; (!ok && now.Sub(c.fetchedAt) >= refreshInterval) || stale {
+		if err := c.fetch(ctx, now); err != nil {
+			// Keep verifying with the cached keys while the provider is down
+			if c.keys == nil {
+				return nil, fmt.Errorf("%w: %v", ErrKeysUnavailable, err)
+			}
+			log.Warn().Err(err).Str("url", c.url).Msg("Failed to refresh provider keys")
+		}
+	}
+
+	key, ok := c.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	return key, nil
+}
+
+// fetch replaces the cached keys with the provider's current keys. The caller
+// must hold the lock.
+func (c *keyCache) fetch(ctx context.Context, now time.Time) error {
+	// Failed fetches are rate limited too
+	c.fetchedAt = now
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("fetch provider keys: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("fetch provider keys: %s returned %d", c.url, resp.StatusCode)
+	}
+
+	var set jwks
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxJWKSSize)).Decode(&set); err != nil {
+		return fmt.Errorf("decode provider keys: %w", err)
+	}
+
+	keys := make(map[string]*rsa.PublicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.KeyType != "RSA" || k.ID == "" || (k.Use != "" && k.Use != "sig") {
+			continue
+		}
+		key, err := rsaKey(k)
+		if err != nil {
+			log.Warn().Err(err).Str("kid", k.ID).Str("url", c.url).Msg("Skipping invalid provider key")
+			continue
+		}
+		keys[k.ID] = key
+	}
+	c.keys = keys
+
+	return nil
+}
+
+func rsaKey(k jwk) (*rsa.PublicKey, error) {
+	n, err := base64.RawURLEncoding.DecodeString(k.N)
+	if err != nil {
+		return nil, fmt.Errorf("invalid modulus: %w", err)
+	}
+	e, err := base64.RawURLEncoding.DecodeString(k.E)
+	if err != nil {
+		return nil, fmt.Errorf("invalid exponent: %w", err)
+	}
+	exponent := new(big.Int).SetBytes(e)
+	if !exponent.IsInt64() || exponent.Int64() < 3 || exponent.Int64() > 1<<31-1 {
+		return nil, errors.New("unsupported exponent")
+	}
+	return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exponent.Int64())}, nil
+}
@@ -0,0 +1,98 @@
+// Package oidctest provides a fake OpenID Connect provider for tests. It
+// serves a JWKS over HTTP and signs ID tokens with the matching key.
+package oidctest
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Provider is a fake identity provider
+type Provider struct {
+	Issuer   string
+	ClientID string
+
+	server  *httptest.Server
+	key     *rsa.PrivateKey
+	keyID   string
+	fetches atomic.Int32
+}
+
+// NewProvider starts a provider that issues tokens for clientID. The server
+// is closed when the test ends.
+func NewProvider(t *testing.T, issuer, clientID string) *Provider {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate provider key: %v", err)
+	}
+
+	p := &Provider{Issuer: issuer, ClientID: clientID, key: key, keyID: "test-key"}
+	p.server = httptest.NewServer(http.HandlerFunc(p.serveJWKS))
+	t.Cleanup(p.server.Close)
+
+	return p
+}
+
+// JWKSURL is the URL the provider serves its keys at
+func (p *Provider) JWKSURL() string {
+	return p.server.URL
+}
+
+// Fetches is how often the keys have been fetched
+func (p *Provider) Fetches() int {
+	return int(p.fetches.Load())
+}
+
+func (p *Provider) serveJWKS(w http.ResponseWriter, _ *http.Request) {
+	p.fetches.Add(1)
+
+	encode := base64.RawURLEncoding.EncodeToString
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": p.keyID,
+			"use": "sig",
+			"alg": "RS256",
+			"n":   encode(p.key.N.Bytes()),
+			"e":   encode(big.NewInt(int64(p.key.E)).Bytes()),
+		}},
+	})
+}
+
+// Token signs an ID token for subject issued at now. Extra claims such as
+// email, email_verified and nonce are added to, or override, the defaults.
+func (p *Provider) Token(t *testing.T, subject string, now time.Time, extra map[string]interface{}) string {
+	t.Helper()
+
+	claims := jwt.MapClaims{
+		"iss": p.Issuer,
+		"aud": p.ClientID,
+		"sub": subject,
+		"iat": now.Unix(),
+		"exp": now.Add(time.Hour).Unix(),
+	}
+	for name, value := range extra {
+		claims[name] = value
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = p.keyID
+	signed, err := token.SignedString(p.key)
+	if err != nil {
+		t.Fatalf("sign ID token: %v", err)
+	}
+	return signed
+}
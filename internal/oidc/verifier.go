@@ -0,0 +1,183 @@
+// Package oidc verifies ID tokens issued by the OpenID Connect providers users
+// can sign in with.
+//
+// Mobile apps complete the provider's sign-in flow themselves and send us the
+// ID token they received. A token is accepted when it is signed by a key from
+// the provider's JWKS, was issued by one of the configured issuers for one of
+// our client IDs, and has not expired.
+package oidc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/clock"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/config"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/models"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Errors returned by Verify
+var (
+	ErrUnknownProvider  = errors.New("unknown sign-in provider")
+	ErrProviderDisabled = errors.New("sign-in provider is not configured")
+	ErrInvalidToken     = errors.New("invalid ID token")
+	ErrKeysUnavailable  = errors.New("sign-in provider keys are unavailable")
+)
+
+// leeway tolerates clock skew between us and the provider
+const leeway = time.Minute
+
+// Claims are the verified claims of an ID token that identify the user
+type Claims struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+}
+
+// idTokenClaims are the claims read from an ID token
+type idTokenClaims struct {
+	Email         string   `json:"email"`
+	EmailVerified flexBool `json:"email_verified"`
+	Name          string   `json:"name"`
+	Nonce         string   `json:"nonce"`
+	jwt.RegisteredClaims
+}
+
+// flexBool accepts both JSON booleans and the "true"/"false" strings Apple
+// sends for email_verified
+type flexBool bool
+
+func (b *flexBool) UnmarshalJSON(data []byte) error {
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	switch v := value.(type) {
+	case bool:
+		*b = flexBool(v)
+	case string:
+		*b = flexBool(strings.EqualFold(v, "true"))
+	case nil:
+		*b = false
+	default:
+		return fmt.Errorf("email_verified is not a boolean: %s", data)
+	}
+	return nil
+}
+
+// Verifier verifies ID tokens against the configured providers
+type Verifier struct {
+	cfgs  *config.Holder
+	clock clock.Clock
+
+	keys map[string]*keyCache
+}
+
+// NewVerifier creates a new verifier. Provider keys are fetched with client
+// and cached per JWKS URL.
+func NewVerifier(cfgs *config.Holder, client *http.Client, clk clock.Clock) *Verifier {
+	v := &Verifier{
+		cfgs:  cfgs,
+		clock: clk,
+		keys:  make(map[string]*keyCache),
+	}
+	cfg := cfgs.Get()
+	for _, provider := range []config.OIDCProviderConfig{cfg.OIDC.Google, cfg.OIDC.Apple} {
+		if provider.Enabled() {
+			v.keys[provider.JWKSURL] = newKeyCache(client, provider.JWKSURL)
+		}
+	}
+	return v
+}
+
+// Provider returns the settings of a provider by name
+func Provider(cfg *config.Config, name string) (config.OIDCProviderConfig, error) {
+	switch name {
+	case models.ProviderGoogle:
+		return cfg.OIDC.Google, nil
+	case models.ProviderApple:
+		return cfg.OIDC.Apple, nil
+	default:
+		return config.OIDCProviderConfig{}, ErrUnknownProvider
+	}
+}
+
+// Verify checks an ID token issued by the named provider and returns the
+// user's claims. A non-empty nonce must match the token's nonce claim.
+func (v *Verifier) Verify(ctx context.Context, name, idToken, nonce string) (*Claims, error) {
+	provider, err := Provider(v.cfgs.Get(), name)
+	if err != nil {
+		return nil, err
+	}
+	cache, ok := v.keys[provider.JWKSURL]
+	if !ok || !provider.Enabled() {
+		return nil, ErrProviderDisabled
+	}
+
+	now := v.clock.Now()
+	var claims idTokenClaims
+	_, err = jwt.ParseWithClaims(idToken, &claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		if kid == "" {
+			return nil, errors.New("token has no key ID")
+		}
+		return cache.key(ctx, kid, now)
+	},
+		jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Alg()}),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(leeway),
+		jwt.WithTimeFunc(func() time.Time { return now }),
+	)
+	if errors.Is(err, ErrKeysUnavailable) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	if !contains(provider.Issuers, claims.Issuer) {
+		return nil, fmt.Errorf("%w: unexpected issuer %q", ErrInvalidToken, claims.Issuer)
+	}
+	if !audienceMatches(claims.Audience, provider.ClientIDs) {
+		return nil, fmt.Errorf("%w: token was issued for another client", ErrInvalidToken)
+	}
+	if claims.Subject == "" {
+		return nil, fmt.Errorf("%w: token has no subject", ErrInvalidToken)
+	}
+	if nonce != "" && claims.Nonce != nonce {
+		return nil, fmt.Errorf("%w: nonce does not match", ErrInvalidToken)
+	}
+
+	return &Claims{
+		Subject:       claims.Subject,
+		Email:         strings.TrimSpace(claims.Email),
+		EmailVerified: bool(claims.EmailVerified) && claims.Email != "",
+		Name:          claims.Name,
+	}, nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func audienceMatches(audience jwt.ClaimStrings, clientIDs []string) bool {
+	for _, aud := range audience {
+		if contains(clientIDs, aud) {
+			return true
+		}
+	}
+	return false
+}
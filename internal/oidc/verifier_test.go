@@ -0,0 +1,99 @@
+package oidc_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/clock"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/config"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/models"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/oidc"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/oidc/oidctest"
+)
+
+const (
+	appleIssuer   = "https://appleid.apple.com"
+	appleClientID = "app.consistency.ios"
+)
+
+var issuedAt = time.Date(2024, 3, 10, 9, 0, 0, 0, time.UTC)
+
+func newVerifier(t *testing.T, provider *oidctest.Provider) (*oidc.Verifier, *clock.Fake) {
+	t.Helper()
+	cfg := &config.Config{OIDC: config.OIDCConfig{
+		Apple: config.OIDCProviderConfig{
+			ClientIDs: []string{"app.consistency.web", appleClientID},
+			Issuers:   []string{appleIssuer},
+			JWKSURL:   provider.JWKSURL(),
+		},
+	}}
+	clk := clock.NewFake(issuedAt.Add(time.Minute))
+	return oidc.NewVerifier(config.NewHolder(cfg, ""), http.DefaultClient, clk), clk
+}
+
+func TestVerifyAcceptsProviderTokens(t *testing.T) {
+	provider := oidctest.NewProvider(t, appleIssuer, appleClientID)
+	verifier, _ := newVerifier(t, provider)
+
+	// Apple sends email_verified as a string
+	token := provider.Token(t, "apple-user", issuedAt, map[string]interface{}{
+		"email":          "Ada@Example.com",
+		"email_verified": "true",
+		"nonce":          "n-0S6_WzA2Mj",
+	})
+	claims, err := verifier.Verify(context.Background(), models.ProviderApple, token, "n-0S6_WzA2Mj")
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if claims.Subject != "apple-user" || claims.Email != "Ada@Example.com" || !claims.EmailVerified {
+		t.Fatalf("claims = %+v", claims)
+	}
+
+	// Keys are cached between tokens
+	if _, err := verifier.Verify(context.Background(), models.ProviderApple, token, ""); err != nil {
+		t.Fatalf("Verify without a nonce: %v", err)
+	}
+	if fetches := provider.Fetches(); fetches != 1 {
+		t.Errorf("keys fetched %d times, want 1", fetches)
+	}
+}
+
+func TestVerifyRejectsInvalidTokens(t *testing.T) {
+	provider := oidctest.NewProvider(t, appleIssuer, appleClientID)
+	other := oidctest.NewProvider(t, appleIssuer, appleClientID)
+	verifier, clk := newVerifier(t, provider)
+	ctx := context.Background()
+
+	for name, token := range map[string]string{
+		"another issuer":   provider.Token(t, "apple-user", issuedAt, map[string]interface{}{"iss": "https://accounts.google.com"}),
+		"another audience": provider.Token(t, "apple-user", issuedAt, map[string]interface{}{"aud": "someone.else"}),
+		"another signer":   other.Token(t, "apple-user", issuedAt, nil),
+		"no subject":       provider.Token(t, "", issuedAt, nil),
+		"no expiry":        provider.Token(t, "apple-user", issuedAt, map[string]interface{}{"exp": nil}),
+		"mismatched nonce": provider.Token(t, "apple-user", issuedAt, map[string]interface{}{"nonce": "replayed"}),
+		"not a JWT":        "not-a-token",
+		"expired long ago": provider.Token(t, "apple-user", issuedAt.Add(-3*time.Hour), nil),
+		"issued in future": provider.Token(t, "apple-user", issuedAt.Add(time.Hour), nil),
+	} {
+		if _, err := verifier.Verify(ctx, models.ProviderApple, token, "expected-nonce"); !errors.Is(err, oidc.ErrInvalidToken) {
+			t.Errorf("Verify of a token with %s returned %v, want ErrInvalidToken", name, err)
+		}
+	}
+
+	// Expiry follows the injected clock
+	token := provider.Token(t, "apple-user", issuedAt, nil)
+	clk.Advance(2 * time.Hour)
+	if _, err := verifier.Verify(ctx, models.ProviderApple, token, ""); !errors.Is(err, oidc.ErrInvalidToken) {
+		t.Errorf("Verify of an expired token returned %v, want ErrInvalidToken", err)
+	}
+
+	if _, err := verifier.Verify(ctx, models.ProviderGoogle, token, ""); !errors.Is(err, oidc.ErrProviderDisabled) {
+		t.Errorf("Verify for an unconfigured provider returned %v, want ErrProviderDisabled", err)
+	}
+	if _, err := verifier.Verify(ctx, "github", token, ""); !errors.Is(err, oidc.ErrUnknownProvider) {
+		t.Errorf("Verify for an unknown provider returned %v, want ErrUnknownProvider", err)
+	}
+}
@@ -0,0 +1,64 @@
+package ratelimit
+
+import (
+	"context"
+	"time"
+
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/models"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// GormStore keeps the buckets in the rate_limit_buckets table, so that every
+// replica takes from the same buckets
+type GormStore struct {
+	db *gorm.DB
+}
+
+// NewGormStore creates a new database store
+func NewGormStore(db *gorm.DB) *GormStore {
+	return &GormStore{db: db}
+}
+
+// Take implements Store. A missing bucket is created full, and the row stays
+// locked from reading the balance until the transaction ends, so concurrent
+// requests on other replicas each take their own token.
+func (s *GormStore) Take(ctx context.Context, key string, limit int, period time.Duration, now time.Time) (Result, error) {
+	var result Result
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		create := tx.Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&models.RateLimitBucket{Key: key, Tokens: float64(limit), RefilledAt: now})
+		if create.Error != nil {
+			return create.Error
+		}
+
+		var bucket models.RateLimitBucket
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("bucket_key = ?", key).First(&bucket).Error; err != nil {
+			return err
+		}
+
+		var tokens float64
+		tokens, result = take(bucket.Tokens, bucket.RefilledAt, limit, period, now)
+		if now.After(bucket.RefilledAt) {
+			bucket.RefilledAt = now
+		}
+		return tx.Model(&models.RateLimitBucket{}).Where("bucket_key = ?", key).
+			Updates(map[string]interface{}{"tokens": tokens, "refilled_at": bucket.RefilledAt}).Error
+	})
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msg("Failed to take rate limit token")
+		return Result{}, err
+	}
+	return result, nil
+}
+
+// Prune implements Store
+func (s *GormStore) Prune(ctx context.Context, now time.Time, period time.Duration) (int64, error) {
+	result := s.db.WithContext(ctx).Where("refilled_at < ?", now.Add(-period)).Delete(&models.RateLimitBucket{})
+	if result.Error != nil {
+		log.Ctx(ctx).Error().Err(result.Error).Msg("Failed to prune rate limit buckets")
+		return 0, result.Error
+	}
+	return result.RowsAffected, nil
+}
@@ -0,0 +1,67 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/models"
+)
+
+// MemoryStore keeps the buckets in process memory. It suits a single
+// instance; the buckets are lost on restart and not shared between replicas.
+type MemoryStore struct {
+	mu         sync.Mutex
+	buckets    map[string]models.RateLimitBucket
+	lastPruned time.Time
+}
+
+// NewMemoryStore creates an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{buckets: make(map[string]models.RateLimitBucket)}
+}
+
+// Take implements Store. Full buckets are pruned along the way once per
+// period, so the map does not grow with every client ever seen.
+func (s *MemoryStore) Take(_ context.Context, key string, limit int, period time.Duration, now time.Time) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if now.Sub(s.lastPruned) >= period {
+		s.prune(now, period)
+		s.lastPruned = now
+	}
+
+	bucket, ok := s.buckets[key]
+	if !ok {
+		bucket = models.RateLimitBucket{Key: key, Tokens: float64(limit), RefilledAt: now}
+	}
+	tokens, result := take(bucket.Tokens, bucket.RefilledAt, limit, period, now)
+	bucket.Tokens = tokens
+	if now.After(bucket.RefilledAt) {
+		bucket.RefilledAt = now
+	}
+	s.buckets[key] = bucket
+
+	return result, nil
+}
+
+// Prune implements Store
+func (s *MemoryStore) Prune(_ context.Context, now time.Time, period time.Duration) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.prune(now, period), nil
+}
+
+// prune removes the buckets that are full again. The caller must hold the lock.
+func (s *MemoryStore) prune(now time.Time, period time.Duration) int64 {
+	var pruned int64
+	for key, bucket := range s.buckets {
+		if bucket.RefilledAt.Before(now.Add(-period)) {
+			delete(s.buckets, key)
+			pruned++
+		}
+	}
+	return pruned
+}
@@ -0,0 +1,155 @@
+// Package ratelimit limits how often clients may call the API.
+//
+// Every budget is a token bucket per client: it holds up to the configured
+// number of requests and refills at that many per minute, so short bursts
+// are allowed while the sustained rate is capped. Authenticated requests
+// are counted per user, with separate budgets for reads and writes, and
+// calls to the auth endpoints per client IP.
+//
+// The buckets are kept in a Store: in memory for a single instance, or in
+// the database when several replicas serve requests.
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/clock"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/config"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+// Period is the time in which an empty bucket refills completely
+const Period = time.Minute
+
+// Budget names a separately limited kind of request
+type Budget string
+
+// Budgets, see APIConfig
+const (
+	BudgetRead  Budget = "read"
+	BudgetWrite Budget = "write"
+	BudgetAuth  Budget = "auth"
+)
+
+// Store keeps the buckets. Implementations must be safe for concurrent use,
+// and concurrent calls to Take for the same key must each take their own
+// token.
+type Store interface {
+	// Take takes a token from the bucket for key, which holds up to limit
+	// tokens and refills limit tokens per period, as of now
+	Take(ctx context.Context, key string, limit int, period time.Duration, now time.Time) (Result, error)
+	// Prune removes the buckets not used since now-period, which are full
+	// again, and returns how many were removed
+	Prune(ctx context.Context, now time.Time, period time.Duration) (int64, error)
+}
+
+// NewStoreFromConfig creates the store selected by API_RATE_LIMIT_STORE
+func NewStoreFromConfig(cfg *config.Config, db *gorm.DB) Store {
+	if cfg.API.RateLimitStore == config.RateLimitStoreDatabase {
+		return NewGormStore(db)
+	}
+	return NewMemoryStore()
+}
+
+// Result describes a bucket after a request took, or failed to take, a token
+type Result struct {
+	// Allowed is set when the request may proceed
+	Allowed bool
+	// Limit is the size of the bucket, 0 when the budget is turned off
+	Limit int
+	// Remaining is the number of whole tokens left
+	Remaining int
+	// RetryAfter is how long a refused request has to wait for a token
+	RetryAfter time.Duration
+	// Reset is how long until the bucket is full again
+	Reset time.Duration
+}
+
+// take takes a token from a bucket holding tokens as of refilledAt and
+// returns the new balance as of now
+func take(tokens float64, refilledAt time.Time, limit int, period time.Duration, now time.Time) (float64, Result) {
+	rate := float64(limit) / period.Seconds()
+	if elapsed := now.Sub(refilledAt).Seconds(); elapsed > 0 {
+		tokens += elapsed * rate
+	}
+	// The limit may have been lowered by a reload since the last request
+	tokens = math.Min(float64(limit), tokens)
+
+	result := Result{Limit: limit}
+	if tokens >= 1 {
+		tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = seconds((1 - tokens) / rate)
+	}
+	result.Remaining = int(math.Floor(tokens))
+	result.Reset = seconds((float64(limit) - tokens) / rate)
+	return tokens, result
+}
+
+// seconds converts a number of seconds to a duration
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
+
+// Limiter applies the budgets from the configuration
+type Limiter struct {
+	store Store
+	cfgs  *config.Holder
+	clock clock.Clock
+}
+
+// NewLimiter creates a new limiter
+func NewLimiter(store Store, cfgs *config.Holder, clk clock.Clock) *Limiter {
+	return &Limiter{
+		store: store,
+		cfgs:  cfgs,
+		clock: clk,
+	}
+}
+
+// Take takes a token from the client's bucket for the budget. The client is
+// a user or client IP key, see UserKey and IPKey. Budgets that are turned
+// off always allow the request, and so does a failing store, as it is
+// better to serve too much than nothing.
+func (l *Limiter) Take(ctx context.Context, budget Budget, client string) Result {
+	limit := l.limit(budget)
+	if limit <= 0 {
+		return Result{Allowed: true}
+	}
+
+	result, err := l.store.Take(ctx, string(budget)+":"+client, limit, Period, l.clock.Now().UTC())
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("budget", string(budget)).Msg("Failed to take rate limit token, allowing the request")
+		return Result{Allowed: true}
+	}
+	return result
+}
+
+// limit returns the configured size of the budget
+func (l *Limiter) limit(budget Budget) int {
+	cfg := l.cfgs.Get().API
+	switch budget {
+	case BudgetRead:
+		return cfg.RateLimit
+	case BudgetWrite:
+		return cfg.RateLimitWrites
+	case BudgetAuth:
+		return cfg.RateLimitAuth
+	}
+	return 0
+}
+
+// UserKey returns the client key for an authenticated user
+func UserKey(userID uint) string {
+	return "user:" + strconv.FormatUint(uint64(userID), 10)
+}
+
+// IPKey returns the client key for a client IP
+func IPKey(ip string) string {
+	return "ip:" + ip
+}
@@ -0,0 +1,118 @@
+package ratelimit_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/clock"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/config"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/database"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/migrate"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/ratelimit"
+)
+
+var start = time.Date(2024, 3, 10, 9, 0, 0, 0, time.UTC)
+
+// stores returns a fresh store of every kind, the database store on a
+// migrated in-memory SQLite database
+func stores(t *testing.T) map[string]ratelimit.Store {
+	t.Helper()
+
+	db, err := database.NewDatabase(&config.Config{
+		Database: config.DatabaseConfig{Driver: database.DriverSQLite, SQLitePath: ":memory:"},
+	})
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	t.Cleanup(db.Close)
+	migrator, err := migrate.New(db.DB)
+	if err != nil {
+		t.Fatalf("load migrations: %v", err)
+	}
+	if _, err := migrator.Up(context.Background()); err != nil {
+		t.Fatalf("migrate database: %v", err)
+	}
+
+	return map[string]ratelimit.Store{
+		"memory":   ratelimit.NewMemoryStore(),
+		"database": ratelimit.NewGormStore(db.DB),
+	}
+}
+
+func TestStores(t *testing.T) {
+	for name, store := range stores(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			take := func(key string, now time.Time) ratelimit.Result {
+				t.Helper()
+				result, err := store.Take(ctx, key, 3, time.Minute, now)
+				if err != nil {
+					t.Fatalf("Take: %v", err)
+				}
+				return result
+			}
+
+			// A new bucket is full, and a burst can empty it
+			for remaining := 2; remaining >= 0; remaining-- {
+				result := take("read:user:1", start)
+				if !result.Allowed || result.Remaining != remaining || result.Limit != 3 {
+					t.Fatalf("Take = %+v, want allowed with %d remaining", result, remaining)
+				}
+			}
+			result := take("read:user:1", start)
+			if result.Allowed || result.RetryAfter != 20*time.Second || result.Reset != time.Minute {
+				t.Fatalf("Take from an empty bucket = %+v, want refused for 20s", result)
+			}
+
+			// Other keys have their own buckets
+			if result := take("read:user:2", start); !result.Allowed || result.Remaining != 2 {
+				t.Fatalf("Take for another key = %+v, want a full bucket", result)
+			}
+
+			// Tokens come back at the limit per period
+			result = take("read:user:1", start.Add(30*time.Second))
+			if !result.Allowed || result.Remaining != 0 || result.Reset != 50*time.Second {
+				t.Fatalf("Take after 30s = %+v, want allowed with half a token left", result)
+			}
+
+			// Pruning removes the buckets that have refilled
+			if pruned, err := store.Prune(ctx, start.Add(90*time.Second), time.Minute); err != nil || pruned != 1 {
+				t.Fatalf("Prune = %d, %v, want the idle bucket pruned", pruned, err)
+			}
+
+			// Idle buckets fill up to the limit and no further
+			result = take("read:user:1", start.Add(time.Hour))
+			if !result.Allowed || result.Remaining != 2 {
+				t.Fatalf("Take after an hour = %+v, want a full bucket", result)
+			}
+		})
+	}
+}
+
+func TestLimiterBudgets(t *testing.T) {
+	cfg := &config.Config{API: config.APIConfig{RateLimit: 2, RateLimitWrites: 1}}
+	cfgs := config.NewHolder(cfg, "")
+	limiter := ratelimit.NewLimiter(ratelimit.NewMemoryStore(), cfgs, clock.NewFake(start))
+	ctx := context.Background()
+	user := ratelimit.UserKey(7)
+
+	if result := limiter.Take(ctx, ratelimit.BudgetWrite, user); !result.Allowed {
+		t.Fatalf("first write = %+v, want allowed", result)
+	}
+	if result := limiter.Take(ctx, ratelimit.BudgetWrite, user); result.Allowed {
+		t.Fatalf("second write = %+v, want refused", result)
+	}
+
+	// Reads are counted separately
+	if result := limiter.Take(ctx, ratelimit.BudgetRead, user); !result.Allowed || result.Remaining != 1 {
+		t.Fatalf("read = %+v, want allowed from a full bucket", result)
+	}
+
+	// A budget of 0 is not limited
+	for i := 0; i < 10; i++ {
+		if result := limiter.Take(ctx, ratelimit.BudgetAuth, ratelimit.IPKey("203.0.113.7")); !result.Allowed || result.Limit != 0 {
+			t.Fatalf("auth request = %+v, want allowed without a limit", result)
+		}
+	}
+}
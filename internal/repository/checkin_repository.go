@@ -3,7 +3,9 @@ package repository
 import (
 	"context"
 	"errors"
+	"time"
 
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/database"
 	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/models"
 	"github.com/rs/zerolog/log"
 	"gorm.io/gorm"
@@ -23,7 +25,7 @@ func NewCheckInRepository(db *gorm.DB) CheckInRepository {
 func (r *GormCheckInRepository) Create(ctx context.Context, checkIn *models.HabitCheckIn) error {
 	result := r.db.WithContext(ctx).Create(checkIn)
 	if result.Error != nil {
-		log.Error().Err(result.Error).Msg("Failed to create check-in")
+		log.Ctx(ctx).Error().Err(result.Error).Msg("Failed to create check-in")
 		return result.Error
 	}
 	return nil
@@ -32,12 +34,12 @@ func (r *GormCheckInRepository) Create(ctx context.Context, checkIn *models.Habi
 // FindByID finds a check-in by ID
 func (r *GormCheckInRepository) FindByID(ctx context.Context, id uint) (*models.HabitCheckIn, error) {
 	var checkIn models.HabitCheckIn
-	result := r.db.WithContext(ctx).First(&checkIn, id)
+	result := database.FirstOnReplica(r.db.WithContext(ctx), &checkIn, id)
 	if result.Error != nil {
 		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
 			return nil, nil
 		}
-		log.Error().Err(result.Error).Uint("id", id).Msg("Failed to find check-in by ID")
+		log.Ctx(ctx).Error().Err(result.Error).Uint("id", id).Msg("Failed to find check-in by ID")
 		return nil, result.Error
 	}
 	return &checkIn, nil
@@ -46,23 +48,45 @@ func (r *GormCheckInRepository) FindByID(ctx context.Context, id uint) (*models.
 // FindByStreakID finds all check-ins for a streak
 func (r *GormCheckInRepository) FindByStreakID(ctx context.Context, streakID uint) ([]models.HabitCheckIn, error) {
 	var checkIns []models.HabitCheckIn
-	result := r.db.WithContext(ctx).Where("streak_id = ?", streakID).Order("check_in_date DESC").Find(&checkIns)
+	result := r.db.WithContext(ctx).Scopes(database.Replica).Where("streak_id = ?", streakID).Order("check_in_date DESC").Find(&checkIns)
 	if result.Error != nil {
-		log.Error().Err(result.Error).Uint("streakID", streakID).Msg("Failed to find check-ins by streak ID")
+		log.Ctx(ctx).Error().Err(result.Error).Uint("streakID", streakID).Msg("Failed to find check-ins by streak ID")
 		return nil, result.Error
 	}
 	return checkIns, nil
 }
 
-// FindByDate finds a check-in by date
+// FindByStreakIDInBatches calls fn with the check-ins of a streak in batches
+// ordered by ID, so that long histories are never loaded at once
+func (r *GormCheckInRepository) FindByStreakIDInBatches(ctx context.Context, streakID uint, batchSize int, fn func([]models.HabitCheckIn) error) error {
+	var batch []models.HabitCheckIn
+	result := r.db.WithContext(ctx).Scopes(database.Replica).Where("streak_id = ?", streakID).FindInBatches(&batch, batchSize, func(_ *gorm.DB, _ int) error {
+		return fn(batch)
+	})
+	if result.Error != nil {
+		log.Ctx(ctx).Error().Err(result.Error).Uint("streakID", streakID).Msg("Failed to find check-ins in batches")
+		return result.Error
+	}
+	return nil
+}
+
+// FindByDate finds a check-in by its UTC date in the form 2006-01-02. The
+// date is matched as a range so the query works on every supported database.
 func (r *GormCheckInRepository) FindByDate(ctx context.Context, streakID uint, date string) (*models.HabitCheckIn, error) {
+	day, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		return nil, err
+	}
+
 	var checkIn models.HabitCheckIn
-	result := r.db.WithContext(ctx).Where("streak_id = ? AND check_in_date = ?", streakID, date).First(&checkIn)
+	result := r.db.WithContext(ctx).Scopes(database.Replica).
+		Where("streak_id = ? AND check_in_date >= ? AND check_in_date < ?", streakID, day, day.AddDate(0, 0, 1)).
+		First(&checkIn)
 	if result.Error != nil {
 		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
 			return nil, nil
 		}
-		log.Error().Err(result.Error).Uint("streakID", streakID).Str("date", date).Msg("Failed to find check-in by date")
+		log.Ctx(ctx).Error().Err(result.Error).Uint("streakID", streakID).Str("date", date).Msg("Failed to find check-in by date")
 		return nil, result.Error
 	}
 	return &checkIn, nil
@@ -71,12 +95,12 @@ func (r *GormCheckInRepository) FindByDate(ctx context.Context, streakID uint, d
 // FindLatestByStreakID finds the latest check-in for a streak
 func (r *GormCheckInRepository) FindLatestByStreakID(ctx context.Context, streakID uint) (*models.HabitCheckIn, error) {
 	var checkIn models.HabitCheckIn
-	result := r.db.WithContext(ctx).Where("streak_id = ?", streakID).Order("check_in_date DESC").First(&checkIn)
+	result := r.db.WithContext(ctx).Scopes(database.Replica).Where("streak_id = ?", streakID).Order("check_in_date DESC").First(&checkIn)
 	if result.Error != nil {
 		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
 			return nil, nil
 		}
-		log.Error().Err(result.Error).Uint("streakID", streakID).Msg("Failed to find latest check-in")
+		log.Ctx(ctx).Error().Err(result.Error).Uint("streakID", streakID).Msg("Failed to find latest check-in")
 		return nil, result.Error
 	}
 	return &checkIn, nil
@@ -86,7 +110,7 @@ func (r *GormCheckInRepository) FindLatestByStreakID(ctx context.Context, streak
 func (r *GormCheckInRepository) Delete(ctx context.Context, id uint) error {
 	result := r.db.WithContext(ctx).Delete(&models.HabitCheckIn{}, id)
 	if result.Error != nil {
-		log.Error().Err(result.Error).Uint("id", id).Msg("Failed to delete check-in")
+		log.Ctx(ctx).Error().Err(result.Error).Uint("id", id).Msg("Failed to delete check-in")
 		return result.Error
 	}
 	return nil
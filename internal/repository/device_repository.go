@@ -0,0 +1,89 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/models"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+// GormDeviceRepository implements DeviceRepository using GORM
+type GormDeviceRepository struct {
+	db *gorm.DB
+}
+
+// NewDeviceRepository creates a new device repository
+func NewDeviceRepository(db *gorm.DB) DeviceRepository {
+	return &GormDeviceRepository{db: db}
+}
+
+// Create creates a new device
+func (r *GormDeviceRepository) Create(ctx context.Context, device *models.Device) error {
+	result := r.db.WithContext(ctx).Create(device)
+	if result.Error != nil {
+		log.Ctx(ctx).Error().Err(result.Error).Msg("Failed to create device")
+		return result.Error
+	}
+	return nil
+}
+
+// FindByID finds a device by ID
+func (r *GormDeviceRepository) FindByID(ctx context.Context, id uint) (*models.Device, error) {
+	var device models.Device
+	result := r.db.WithContext(ctx).First(&device, id)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		log.Ctx(ctx).Error().Err(result.Error).Uint("id", id).Msg("Failed to find device by ID")
+		return nil, result.Error
+	}
+	return &device, nil
+}
+
+// FindByToken finds a device by its push token
+func (r *GormDeviceRepository) FindByToken(ctx context.Context, token string) (*models.Device, error) {
+	var device models.Device
+	result := r.db.WithContext(ctx).Where("token = ?", token).First(&device)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		log.Ctx(ctx).Error().Err(result.Error).Msg("Failed to find device by token")
+		return nil, result.Error
+	}
+	return &device, nil
+}
+
+// FindByUserID finds all devices for a user
+func (r *GormDeviceRepository) FindByUserID(ctx context.Context, userID uint) ([]models.Device, error) {
+	var devices []models.Device
+	result := r.db.WithContext(ctx).Where("user_id = ?", userID).Order("last_seen_at DESC").Find(&devices)
+	if result.Error != nil {
+		log.Ctx(ctx).Error().Err(result.Error).Uint("userID", userID).Msg("Failed to find devices by user ID")
+		return nil, result.Error
+	}
+	return devices, nil
+}
+
+// Update updates a device
+func (r *GormDeviceRepository) Update(ctx context.Context, device *models.Device) error {
+	result := r.db.WithContext(ctx).Save(device)
+	if result.Error != nil {
+		log.Ctx(ctx).Error().Err(result.Error).Uint("id", device.ID).Msg("Failed to update device")
+		return result.Error
+	}
+	return nil
+}
+
+// Delete deletes a device
+func (r *GormDeviceRepository) Delete(ctx context.Context, id uint) error {
+	result := r.db.WithContext(ctx).Delete(&models.Device{}, id)
+	if result.Error != nil {
+		log.Ctx(ctx).Error().Err(result.Error).Uint("id", id).Msg("Failed to delete device")
+		return result.Error
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/models"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// GormDigestRepository implements DigestRepository using GORM
+type GormDigestRepository struct {
+	db *gorm.DB
+}
+
+// NewDigestRepository creates a new digest repository
+func NewDigestRepository(db *gorm.DB) DigestRepository {
+	return &GormDigestRepository{db: db}
+}
+
+// Claim inserts a send record, relying on the unique (user_id, period_start)
+// index so that concurrent jobs cannot claim the same period twice. A stale
+// unsent claim is updated to the new one instead, keeping its ID.
+func (r *GormDigestRepository) Claim(ctx context.Context, send *models.DigestSend, staleBefore time.Time) (bool, error) {
+	result := r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "period_start"}},
+		DoUpdates: clause.AssignmentColumns([]string{"period_end", "created_at"}),
+		Where: clause.Where{Exprs: []clause.Expression{
+			clause.Expr{SQL: "digest_sends.sent_at IS NULL AND digest_sends.created_at < ?", Vars: []interface{}{staleBefore}},
+		}},
+	}).Create(send)
+	if result.Error != nil {
+		log.Ctx(ctx).Error().Err(result.Error).Uint("userID", send.UserID).Msg("Failed to claim digest send")
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// MarkSent records when a claimed digest was delivered
+func (r *GormDigestRepository) MarkSent(ctx context.Context, id uint, sentAt time.Time) error {
+	result := r.db.WithContext(ctx).Model(&models.DigestSend{}).Where("id = ?", id).Update("sent_at", sentAt)
+	if result.Error != nil {
+		log.Ctx(ctx).Error().Err(result.Error).Uint("id", id).Msg("Failed to mark digest as sent")
+		return result.Error
+	}
+	return nil
+}
+
+// Delete deletes a send record, releasing the period so it can be retried
+func (r *GormDigestRepository) Delete(ctx context.Context, id uint) error {
+	result := r.db.WithContext(ctx).Delete(&models.DigestSend{}, id)
+	if result.Error != nil {
+		log.Ctx(ctx).Error().Err(result.Error).Uint("id", id).Msg("Failed to delete digest send")
+		return result.Error
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/models"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+// GormEmailVerificationRepository implements EmailVerificationRepository using GORM
+type GormEmailVerificationRepository struct {
+	db *gorm.DB
+}
+
+// NewEmailVerificationRepository creates a new email verification repository
+func NewEmailVerificationRepository(db *gorm.DB) EmailVerificationRepository {
+	return &GormEmailVerificationRepository{db: db}
+}
+
+// Create creates a new pending verification
+func (r *GormEmailVerificationRepository) Create(ctx context.Context, verification *models.EmailVerification) error {
+	result := r.db.WithContext(ctx).Omit("User").Create(verification)
+	if result.Error != nil {
+		log.Ctx(ctx).Error().Err(result.Error).Msg("Failed to create email verification")
+		return result.Error
+	}
+	return nil
+}
+
+// FindByTokenHash finds a pending verification by the hash of its token
+func (r *GormEmailVerificationRepository) FindByTokenHash(ctx context.Context, tokenHash string) (*models.EmailVerification, error) {
+	var verification models.EmailVerification
+	result := r.db.WithContext(ctx).Where("token_hash = ?", tokenHash).First(&verification)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		log.Ctx(ctx).Error().Err(result.Error).Msg("Failed to find email verification")
+		return nil, result.Error
+	}
+	return &verification, nil
+}
+
+// FindByUserIDSince finds the verifications created for a user at or after since, newest first
+func (r *GormEmailVerificationRepository) FindByUserIDSince(ctx context.Context, userID uint, since time.Time) ([]models.EmailVerification, error) {
+	var verifications []models.EmailVerification
+	result := r.db.WithContext(ctx).
+		Where("user_id = ? AND created_at >= ?", userID, since).
+		Order("created_at DESC, id DESC").
+		Find(&verifications)
+	if result.Error != nil {
+		log.Ctx(ctx).Error().Err(result.Error).Uint("userID", userID).Msg("Failed to find email verifications")
+		return nil, result.Error
+	}
+	return verifications, nil
+}
+
+// DeleteByUserID deletes every pending verification of a user
+func (r *GormEmailVerificationRepository) DeleteByUserID(ctx context.Context, userID uint) error {
+	result := r.db.WithContext(ctx).Where("user_id = ?", userID).Delete(&models.EmailVerification{})
+	if result.Error != nil {
+		log.Ctx(ctx).Error().Err(result.Error).Uint("userID", userID).Msg("Failed to delete email verifications")
+		return result.Error
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+package repository_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/config"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/database"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/migrate"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/repository"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/repository/repositorytest"
+)
+
+// TestGormRepositories runs the contract suite against the GORM repositories
+// on a migrated in-memory SQLite database
+func TestGormRepositories(t *testing.T) {
+	repositorytest.RunContractTests(t, func(t *testing.T) repositorytest.Repositories {
+		db, err := database.NewDatabase(&config.Config{
+			Database: config.DatabaseConfig{Driver: database.DriverSQLite, SQLitePath: ":memory:"},
+		})
+		if err != nil {
+			t.Fatalf("open database: %v", err)
+		}
+		sqlDB, err := db.DB.DB()
+		if err != nil {
+			t.Fatalf("open database: %v", err)
+		}
+		t.Cleanup(func() { sqlDB.Close() })
+
+		migrator, err := migrate.New(db.DB)
+		if err != nil {
+			t.Fatalf("load migrations: %v", err)
+		}
+		if _, err := migrator.Up(context.Background()); err != nil {
+			t.Fatalf("migrate database: %v", err)
+		}
+
+		return repositorytest.Repositories{
+			Users:         repository.NewUserRepository(db.DB),
+			Habits:        repository.NewHabitRepository(db.DB),
+			Streaks:       repository.NewStreakRepository(db.DB),
+			CheckIns:      repository.NewCheckInRepository(db.DB),
+			Achievements:  repository.NewAchievementRepository(db.DB),
+			Devices:       repository.NewDeviceRepository(db.DB),
+			Digests:       repository.NewDigestRepository(db.DB),
+			Identities:    repository.NewIdentityRepository(db.DB),
+			Verifications: repository.NewEmailVerificationRepository(db.DB),
+			Resets:        repository.NewPasswordResetRepository(db.DB),
+			Events:        repository.NewSecurityEventRepository(db.DB),
+			MFA:           repository.NewMFARepository(db.DB),
+		}
+	})
+}
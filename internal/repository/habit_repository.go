@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/database"
 	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/models"
 	"github.com/rs/zerolog/log"
 	"gorm.io/gorm"
@@ -23,21 +24,67 @@ func NewHabitRepository(db *gorm.DB) HabitRepository {
 func (r *GormHabitRepository) Create(ctx context.Context, habit *models.Habit) error {
 	result := r.db.WithContext(ctx).Create(habit)
 	if result.Error != nil {
-		log.Error().Err(result.Error).Msg("Failed to create habit")
+		log.Ctx(ctx).Error().Err(result.Error).Msg("Failed to create habit")
 		return result.Error
 	}
 	return nil
 }
 
+// CreateWithHistory creates the habit with its streaks, check-ins and
+// achievements in one transaction, so that a failure leaves nothing behind
+func (r *GormHabitRepository) CreateWithHistory(ctx context.Context, habit *models.Habit, history []StreakHistory) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(habit).Error; err != nil {
+			return err
+		}
+
+		for i := range history {
+			streak := &history[i].Streak
+			streak.HabitID = habit.ID
+			if err := tx.Create(streak).Error; err != nil {
+				return err
+			}
+
+			checkIns := history[i].CheckIns
+			for j := range checkIns {
+				checkIns[j].StreakID = streak.ID
+			}
+			if len(checkIns) > 0 {
+				if err := tx.CreateInBatches(checkIns, 500).Error; err != nil {
+					return err
+				}
+			}
+
+			if history[i].Achievement == nil {
+				continue
+			}
+			achievement, err := history[i].Achievement(streak)
+			if err != nil {
+				return err
+			}
+			achievement.HabitID = habit.ID
+			if err := tx.Create(achievement).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Uint("userID", habit.UserID).Msg("Failed to create habit with history")
+		return err
+	}
+	return nil
+}
+
 // FindByID finds a habit by ID
 func (r *GormHabitRepository) FindByID(ctx context.Context, id uint) (*models.Habit, error) {
 	var habit models.Habit
-	result := r.db.WithContext(ctx).First(&habit, id)
+	result := database.FirstOnReplica(r.db.WithContext(ctx), &habit, id)
 	if result.Error != nil {
 		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
 			return nil, nil
 		}
-		log.Error().Err(result.Error).Uint("id", id).Msg("Failed to find habit by ID")
+		log.Ctx(ctx).Error().Err(result.Error).Uint("id", id).Msg("Failed to find habit by ID")
 		return nil, result.Error
 	}
 	return &habit, nil
@@ -46,9 +93,9 @@ func (r *GormHabitRepository) FindByID(ctx context.Context, id uint) (*models.Ha
 // FindByUserID finds all habits for a user
 func (r *GormHabitRepository) FindByUserID(ctx context.Context, userID uint) ([]models.Habit, error) {
 	var habits []models.Habit
-	result := r.db.WithContext(ctx).Where("user_id = ?", userID).Find(&habits)
+	result := r.db.WithContext(ctx).Scopes(database.Replica).Where("user_id = ?", userID).Find(&habits)
 	if result.Error != nil {
-		log.Error().Err(result.Error).Uint("userID", userID).Msg("Failed to find habits by user ID")
+		log.Ctx(ctx).Error().Err(result.Error).Uint("userID", userID).Msg("Failed to find habits by user ID")
 		return nil, result.Error
 	}
 	return habits, nil
@@ -58,7 +105,7 @@ func (r *GormHabitRepository) FindByUserID(ctx context.Context, userID uint) ([]
 func (r *GormHabitRepository) Update(ctx context.Context, habit *models.Habit) error {
 	result := r.db.WithContext(ctx).Save(habit)
 	if result.Error != nil {
-		log.Error().Err(result.Error).Uint("id", habit.ID).Msg("Failed to update habit")
+		log.Ctx(ctx).Error().Err(result.Error).Uint("id", habit.ID).Msg("Failed to update habit")
 		return result.Error
 	}
 	return nil
@@ -68,7 +115,7 @@ func (r *GormHabitRepository) Update(ctx context.Context, habit *models.Habit) e
 func (r *GormHabitRepository) Delete(ctx context.Context, id uint) error {
 	result := r.db.WithContext(ctx).Delete(&models.Habit{}, id)
 	if result.Error != nil {
-		log.Error().Err(result.Error).Uint("id", id).Msg("Failed to delete habit")
+		log.Ctx(ctx).Error().Err(result.Error).Uint("id", id).Msg("Failed to delete habit")
 		return result.Error
 	}
 	return nil
@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/models"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+// GormIdentityRepository implements IdentityRepository using GORM
+type GormIdentityRepository struct {
+	db *gorm.DB
+}
+
+// NewIdentityRepository creates a new identity repository
+func NewIdentityRepository(db *gorm.DB) IdentityRepository {
+	return &GormIdentityRepository{db: db}
+}
+
+// Create links a new identity to an existing user
+func (r *GormIdentityRepository) Create(ctx context.Context, identity *models.Identity) error {
+	result := r.db.WithContext(ctx).Omit("User").Create(identity)
+	if result.Error != nil {
+		log.Ctx(ctx).Error().Err(result.Error).Msg("Failed to create identity")
+		return result.Error
+	}
+	return nil
+}
+
+// CreateWithUser creates a new user and their first identity in one
+// transaction, so that a failed link does not leave a user behind
+func (r *GormIdentityRepository) CreateWithUser(ctx context.Context, user *models.User, identity *models.Identity) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(user).Error; err != nil {
+			return err
+		}
+		identity.UserID = user.ID
+		return tx.Omit("User").Create(identity).Error
+	})
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msg("Failed to create user with identity")
+		return err
+	}
+	return nil
+}
+
+// FindByProviderSubject finds the identity for a provider's user ID
+func (r *GormIdentityRepository) FindByProviderSubject(ctx context.Context, provider, subject string) (*models.Identity, error) {
+	var identity models.Identity
+	result := r.db.WithContext(ctx).Where("provider = ? AND subject = ?", provider, subject).First(&identity)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		log.Ctx(ctx).Error().Err(result.Error).Str("provider", provider).Msg("Failed to find identity")
+		return nil, result.Error
+	}
+	return &identity, nil
+}
+
+// FindByUserID finds all identities linked to a user, oldest first
+func (r *GormIdentityRepository) FindByUserID(ctx context.Context, userID uint) ([]models.Identity, error) {
+	var identities []models.Identity
+	result := r.db.WithContext(ctx).Where("user_id = ?", userID).Order("created_at, id").Find(&identities)
+	if result.Error != nil {
+		log.Ctx(ctx).Error().Err(result.Error).Uint("userID", userID).Msg("Failed to find identities by user ID")
+		return nil, result.Error
+	}
+	return identities, nil
+}
+
+// Update updates an identity
+func (r *GormIdentityRepository) Update(ctx context.Context, identity *models.Identity) error {
+	result := r.db.WithContext(ctx).Omit("User").Save(identity)
+	if result.Error != nil {
+		log.Ctx(ctx).Error().Err(result.Error).Uint("id", identity.ID).Msg("Failed to update identity")
+		return result.Error
+	}
+	return nil
+}
@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/models"
 )
@@ -11,19 +12,39 @@ type UserRepository interface {
 	Create(ctx context.Context, user *models.User) error
 	FindByID(ctx context.Context, id uint) (*models.User, error)
 	FindByEmail(ctx context.Context, email string) (*models.User, error)
+	FindDigestRecipients(ctx context.Context, day time.Weekday) ([]models.User, error)
+	// ExistsByEmail also reports accounts that are deleted but not purged yet
+	ExistsByEmail(ctx context.Context, email string) (bool, error)
+	FindDueForPurge(ctx context.Context, now time.Time) ([]models.User, error)
 	Update(ctx context.Context, user *models.User) error
 	Delete(ctx context.Context, id uint) error
+	// Purge permanently removes the user and all of their data and records the audit entry
+	Purge(ctx context.Context, id uint, audit *models.ErasureAudit) error
 }
 
 // HabitRepository defines the interface for habit data access
 type HabitRepository interface {
 	Create(ctx context.Context, habit *models.Habit) error
+	// CreateWithHistory creates the habit with its streaks, their check-ins
+	// and achievements, all or nothing
+	CreateWithHistory(ctx context.Context, habit *models.Habit, history []StreakHistory) error
 	FindByID(ctx context.Context, id uint) (*models.Habit, error)
 	FindByUserID(ctx context.Context, userID uint) ([]models.Habit, error)
 	Update(ctx context.Context, habit *models.Habit) error
 	Delete(ctx context.Context, id uint) error
 }
 
+// StreakHistory is a past streak that HabitRepository.CreateWithHistory
+// creates along with its habit
+type StreakHistory struct {
+	Streak   models.HabitStreak
+	CheckIns []models.HabitCheckIn
+
+	// Achievement, when set, returns the achievement earned by the streak
+	// once the streak has been created
+	Achievement func(streak *models.HabitStreak) (*models.Achievement, error)
+}
+
 // StreakRepository defines the interface for streak data access
 type StreakRepository interface {
 	Create(ctx context.Context, streak *models.HabitStreak) error
@@ -39,6 +60,7 @@ type CheckInRepository interface {
 	Create(ctx context.Context, checkIn *models.HabitCheckIn) error
 	FindByID(ctx context.Context, id uint) (*models.HabitCheckIn, error)
 	FindByStreakID(ctx context.Context, streakID uint) ([]models.HabitCheckIn, error)
+	FindByStreakIDInBatches(ctx context.Context, streakID uint, batchSize int, fn func([]models.HabitCheckIn) error) error
 	FindByDate(ctx context.Context, streakID uint, date string) (*models.HabitCheckIn, error)
 	FindLatestByStreakID(ctx context.Context, streakID uint) (*models.HabitCheckIn, error)
 	Delete(ctx context.Context, id uint) error
@@ -52,3 +74,85 @@ type AchievementRepository interface {
 	FindByHabitID(ctx context.Context, habitID uint) ([]models.Achievement, error)
 	Delete(ctx context.Context, id uint) error
 }
+
+// DeviceRepository defines the interface for push device data access
+type DeviceRepository interface {
+	Create(ctx context.Context, device *models.Device) error
+	FindByID(ctx context.Context, id uint) (*models.Device, error)
+	FindByToken(ctx context.Context, token string) (*models.Device, error)
+	FindByUserID(ctx context.Context, userID uint) ([]models.Device, error)
+	Update(ctx context.Context, device *models.Device) error
+	Delete(ctx context.Context, id uint) error
+}
+
+// IdentityRepository defines the interface for linked sign-in identities
+type IdentityRepository interface {
+	Create(ctx context.Context, identity *models.Identity) error
+	// CreateWithUser creates a new user and their first identity in one transaction
+	CreateWithUser(ctx context.Context, user *models.User, identity *models.Identity) error
+	FindByProviderSubject(ctx context.Context, provider, subject string) (*models.Identity, error)
+	FindByUserID(ctx context.Context, userID uint) ([]models.Identity, error)
+	Update(ctx context.Context, identity *models.Identity) error
+}
+
+// EmailVerificationRepository defines the interface for pending email verifications
+type EmailVerificationRepository interface {
+	Create(ctx context.Context, verification *models.EmailVerification) error
+	FindByTokenHash(ctx context.Context, tokenHash string) (*models.EmailVerification, error)
+	// FindByUserIDSince returns the verifications created for the user at or
+	// after since, newest first
+	FindByUserIDSince(ctx context.Context, userID uint, since time.Time) ([]models.EmailVerification, error)
+	DeleteByUserID(ctx context.Context, userID uint) error
+}
+
+// PasswordResetRepository defines the interface for pending password resets
+type PasswordResetRepository interface {
+	Create(ctx context.Context, reset *models.PasswordReset) error
+	FindByTokenHash(ctx context.Context, tokenHash string) (*models.PasswordReset, error)
+	DeleteByUserID(ctx context.Context, userID uint) error
+}
+
+// SecurityEventRepository defines the interface for security events
+type SecurityEventRepository interface {
+	Create(ctx context.Context, event *models.SecurityEvent) error
+	// FindByUserID returns the user's latest events, newest first
+	FindByUserID(ctx context.Context, userID uint, limit int) ([]models.SecurityEvent, error)
+}
+
+// MFARepository defines the interface for authenticator credentials,
+// recovery codes and pending login challenges
+type MFARepository interface {
+	FindTOTP(ctx context.Context, userID uint) (*models.TOTPCredential, error)
+	// SaveTOTP creates the user's credential or replaces the existing one
+	SaveTOTP(ctx context.Context, credential *models.TOTPCredential) error
+	// UseTOTPStep records the time step of an accepted code and reports false
+	// if that step or a later one was already used
+	UseTOTPStep(ctx context.Context, userID uint, step int64) (bool, error)
+	// Enable confirms the credential, replaces the recovery codes and marks
+	// MFA enabled on the user in one transaction
+	Enable(ctx context.Context, userID uint, at time.Time, codes []models.RecoveryCode) error
+	// Disable removes the credential, recovery codes and challenges and marks
+	// MFA disabled on the user in one transaction
+	Disable(ctx context.Context, userID uint) error
+	ReplaceRecoveryCodes(ctx context.Context, userID uint, codes []models.RecoveryCode) error
+	// UseRecoveryCode marks the user's unused code with the hash as used and
+	// reports false if there is none
+	UseRecoveryCode(ctx context.Context, userID uint, codeHash string, at time.Time) (bool, error)
+	CountUnusedRecoveryCodes(ctx context.Context, userID uint) (int64, error)
+	CreateChallenge(ctx context.Context, challenge *models.MFAChallenge) error
+	FindChallengeByTokenHash(ctx context.Context, tokenHash string) (*models.MFAChallenge, error)
+	// DeleteChallenge deletes a challenge and reports false if it was already
+	// deleted, so that each challenge is completed once
+	DeleteChallenge(ctx context.Context, id uint) (bool, error)
+	DeleteExpiredChallenges(ctx context.Context, userID uint, now time.Time) error
+}
+
+// DigestRepository defines the interface for weekly digest send records
+type DigestRepository interface {
+	// Claim inserts the send record and reports false if the period was
+	// already claimed. A claim that was never marked sent and was made before
+	// staleBefore is taken over, as its sender is presumed dead.
+	Claim(ctx context.Context, send *models.DigestSend, staleBefore time.Time) (bool, error)
+	MarkSent(ctx context.Context, id uint, sentAt time.Time) error
+	Delete(ctx context.Context, id uint) error
+}
@@ -0,0 +1,121 @@
+package memory
+
+import (
+	"context"
+	"sort"
+
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/models"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/repository"
+)
+
+// AchievementRepository implements repository.AchievementRepository in memory
+type AchievementRepository struct {
+	store *Store
+}
+
+// NewAchievementRepository creates a new in-memory achievement repository
+func NewAchievementRepository(store *Store) repository.AchievementRepository {
+	return &AchievementRepository{store: store}
+}
+
+// Create creates a new achievement
+func (r *AchievementRepository) Create(ctx context.Context, achievement *models.Achievement) error {
+	if err := checkContext(ctx); err != nil {
+		return err
+	}
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.users[achievement.UserID]; !ok {
+		return constraint("achievement references missing user %d", achievement.UserID)
+	}
+	if _, ok := r.store.habits[achievement.HabitID]; !ok {
+		return constraint("achievement references missing habit %d", achievement.HabitID)
+	}
+
+	created := now()
+	achievement.ID = r.store.id("achievements")
+	if achievement.AchievedAt.IsZero() {
+		achievement.AchievedAt = created
+	}
+	if achievement.CreatedAt.IsZero() {
+		achievement.CreatedAt = created
+	}
+	if achievement.UpdatedAt.IsZero() {
+		achievement.UpdatedAt = created
+	}
+
+	stored := *achievement
+	stored.User = models.User{}
+	stored.Habit = models.Habit{}
+	stored.Metadata = append(stored.Metadata[:0:0], achievement.Metadata...)
+	r.store.achievements[achievement.ID] = stored
+	return nil
+}
+
+// FindByID finds an achievement by ID
+func (r *AchievementRepository) FindByID(ctx context.Context, id uint) (*models.Achievement, error) {
+	if err := checkContext(ctx); err != nil {
+		return nil, err
+	}
+
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	achievement, ok := r.store.achievements[id]
+	if !ok || deleted(achievement.DeletedAt) {
+		return nil, nil
+	}
+	return &achievement, nil
+}
+
+// FindByUserID finds all achievements for a user, most recent first
+func (r *AchievementRepository) FindByUserID(ctx context.Context, userID uint) ([]models.Achievement, error) {
+	return r.find(ctx, func(a models.Achievement) bool { return a.UserID == userID })
+}
+
+// FindByHabitID finds all achievements for a habit, most recent first
+func (r *AchievementRepository) FindByHabitID(ctx context.Context, habitID uint) ([]models.Achievement, error) {
+	return r.find(ctx, func(a models.Achievement) bool { return a.HabitID == habitID })
+}
+
+// Delete soft deletes an achievement
+func (r *AchievementRepository) Delete(ctx context.Context, id uint) error {
+	if err := checkContext(ctx); err != nil {
+		return err
+	}
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if achievement, ok := r.store.achievements[id]; ok && !deleted(achievement.DeletedAt) {
+		achievement.DeletedAt = softDelete()
+		r.store.achievements[id] = achievement
+	}
+	return nil
+}
+
+// find returns the visible achievements matching the filter ordered by achieved_at descending
+func (r *AchievementRepository) find(ctx context.Context, match func(models.Achievement) bool) ([]models.Achievement, error) {
+	if err := checkContext(ctx); err != nil {
+		return nil, err
+	}
+
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	achievements := []models.Achievement{}
+	for _, achievement := range r.store.achievements {
+		if match(achievement) && !deleted(achievement.DeletedAt) {
+			achievements = append(achievements, achievement)
+		}
+	}
+	sort.Slice(achievements, func(i, j int) bool {
+		if !achievements[i].AchievedAt.Equal(achievements[j].AchievedAt) {
+			return achievements[i].AchievedAt.After(achievements[j].AchievedAt)
+		}
+		return achievements[i].ID < achievements[j].ID
+	})
+	return achievements, nil
+}
@@ -0,0 +1,180 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/models"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/repository"
+)
+
+// CheckInRepository implements repository.CheckInRepository in memory
+type CheckInRepository struct {
+	store *Store
+}
+
+// NewCheckInRepository creates a new in-memory check-in repository
+func NewCheckInRepository(store *Store) repository.CheckInRepository {
+	return &CheckInRepository{store: store}
+}
+
+// Create creates a new check-in
+func (r *CheckInRepository) Create(ctx context.Context, checkIn *models.HabitCheckIn) error {
+	if err := checkContext(ctx); err != nil {
+		return err
+	}
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.streaks[checkIn.StreakID]; !ok {
+		return constraint("check-in references missing streak %d", checkIn.StreakID)
+	}
+
+	created := now()
+	checkIn.ID = r.store.id("habit_checkins")
+	if checkIn.CheckedInAt.IsZero() {
+		checkIn.CheckedInAt = created
+	}
+	if checkIn.CreatedAt.IsZero() {
+		checkIn.CreatedAt = created
+	}
+	if checkIn.UpdatedAt.IsZero() {
+		checkIn.UpdatedAt = created
+	}
+
+	stored := *checkIn
+	stored.Streak = models.HabitStreak{}
+	r.store.checkIns[checkIn.ID] = stored
+	return nil
+}
+
+// FindByID finds a check-in by ID
+func (r *CheckInRepository) FindByID(ctx context.Context, id uint) (*models.HabitCheckIn, error) {
+	if err := checkContext(ctx); err != nil {
+		return nil, err
+	}
+
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	checkIn, ok := r.store.checkIns[id]
+	if !ok || deleted(checkIn.DeletedAt) {
+		return nil, nil
+	}
+	return &checkIn, nil
+}
+
+// FindByStreakID finds all check-ins for a streak, latest date first
+func (r *CheckInRepository) FindByStreakID(ctx context.Context, streakID uint) ([]models.HabitCheckIn, error) {
+	if err := checkContext(ctx); err != nil {
+		return nil, err
+	}
+
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	checkIns := r.byStreak(streakID)
+	sort.SliceStable(checkIns, func(i, j int) bool {
+		return checkIns[i].CheckInDate.After(checkIns[j].CheckInDate)
+	})
+	return checkIns, nil
+}
+
+// FindByStreakIDInBatches calls fn with the check-ins of a streak in batches
+// ordered by ID. The batches are copied before fn is called, so fn may use
+// the other repositories.
+func (r *CheckInRepository) FindByStreakIDInBatches(ctx context.Context, streakID uint, batchSize int, fn func([]models.HabitCheckIn) error) error {
+	if err := checkContext(ctx); err != nil {
+		return err
+	}
+
+	r.store.mu.RLock()
+	checkIns := r.byStreak(streakID)
+	r.store.mu.RUnlock()
+
+	if batchSize <= 0 {
+		batchSize = len(checkIns)
+	}
+	for start := 0; start < len(checkIns); start += batchSize {
+		if err := checkContext(ctx); err != nil {
+			return err
+		}
+		end := min(start+batchSize, len(checkIns))
+		if err := fn(checkIns[start:end:end]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// FindByDate finds a check-in by its UTC date in the form 2006-01-02
+func (r *CheckInRepository) FindByDate(ctx context.Context, streakID uint, date string) (*models.HabitCheckIn, error) {
+	day, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkContext(ctx); err != nil {
+		return nil, err
+	}
+
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	next := day.AddDate(0, 0, 1)
+	for _, checkIn := range r.byStreak(streakID) {
+		if !checkIn.CheckInDate.Before(day) && checkIn.CheckInDate.Before(next) {
+			return &checkIn, nil
+		}
+	}
+	return nil, nil
+}
+
+// FindLatestByStreakID finds the latest check-in for a streak
+func (r *CheckInRepository) FindLatestByStreakID(ctx context.Context, streakID uint) (*models.HabitCheckIn, error) {
+	if err := checkContext(ctx); err != nil {
+		return nil, err
+	}
+
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var latest *models.HabitCheckIn
+	for _, checkIn := range r.byStreak(streakID) {
+		if latest == nil || checkIn.CheckInDate.After(latest.CheckInDate) {
+			found := checkIn
+			latest = &found
+		}
+	}
+	return latest, nil
+}
+
+// Delete soft deletes a check-in
+func (r *CheckInRepository) Delete(ctx context.Context, id uint) error {
+	if err := checkContext(ctx); err != nil {
+		return err
+	}
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if checkIn, ok := r.store.checkIns[id]; ok && !deleted(checkIn.DeletedAt) {
+		checkIn.DeletedAt = softDelete()
+		r.store.checkIns[id] = checkIn
+	}
+	return nil
+}
+
+// byStreak returns the visible check-ins of a streak ordered by ID. The
+// caller must hold the lock.
+func (r *CheckInRepository) byStreak(streakID uint) []models.HabitCheckIn {
+	checkIns := []models.HabitCheckIn{}
+	for _, checkIn := range r.store.checkIns {
+		if checkIn.StreakID == streakID && !deleted(checkIn.DeletedAt) {
+			checkIns = append(checkIns, checkIn)
+		}
+	}
+	sort.Slice(checkIns, func(i, j int) bool { return checkIns[i].ID < checkIns[j].ID })
+	return checkIns
+}
@@ -0,0 +1,163 @@
+package memory
+
+import (
+	"context"
+	"sort"
+
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/models"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/repository"
+)
+
+// DeviceRepository implements repository.DeviceRepository in memory
+type DeviceRepository struct {
+	store *Store
+}
+
+// NewDeviceRepository creates a new in-memory device repository
+func NewDeviceRepository(store *Store) repository.DeviceRepository {
+	return &DeviceRepository{store: store}
+}
+
+// Create creates a new device
+func (r *DeviceRepository) Create(ctx context.Context, device *models.Device) error {
+	if err := checkContext(ctx); err != nil {
+		return err
+	}
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if err := r.validate(device); err != nil {
+		return err
+	}
+
+	created := now()
+	device.ID = r.store.id("devices")
+	if device.CreatedAt.IsZero() {
+		device.CreatedAt = created
+	}
+	if device.UpdatedAt.IsZero() {
+		device.UpdatedAt = created
+	}
+
+	r.store.devices[device.ID] = storedDevice(device)
+	return nil
+}
+
+// FindByID finds a device by ID
+func (r *DeviceRepository) FindByID(ctx context.Context, id uint) (*models.Device, error) {
+	if err := checkContext(ctx); err != nil {
+		return nil, err
+	}
+
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	device, ok := r.store.devices[id]
+	if !ok {
+		return nil, nil
+	}
+	return &device, nil
+}
+
+// FindByToken finds a device by its push token
+func (r *DeviceRepository) FindByToken(ctx context.Context, token string) (*models.Device, error) {
+	if err := checkContext(ctx); err != nil {
+		return nil, err
+	}
+
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	for _, device := range r.store.devices {
+		if device.Token == token {
+			return &device, nil
+		}
+	}
+	return nil, nil
+}
+
+// FindByUserID finds all devices for a user, most recently seen first
+func (r *DeviceRepository) FindByUserID(ctx context.Context, userID uint) ([]models.Device, error) {
+	if err := checkContext(ctx); err != nil {
+		return nil, err
+	}
+
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	devices := []models.Device{}
+	for _, device := range r.store.devices {
+		if device.UserID == userID {
+			devices = append(devices, device)
+		}
+	}
+	sort.Slice(devices, func(i, j int) bool {
+		if !devices[i].LastSeenAt.Equal(devices[j].LastSeenAt) {
+			return devices[i].LastSeenAt.After(devices[j].LastSeenAt)
+		}
+		return devices[i].ID < devices[j].ID
+	})
+	return devices, nil
+}
+
+// Update updates a device, creating it if it does not exist
+func (r *DeviceRepository) Update(ctx context.Context, device *models.Device) error {
+	if device.ID == 0 {
+		return r.Create(ctx, device)
+	}
+	if err := checkContext(ctx); err != nil {
+		return err
+	}
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if err := r.validate(device); err != nil {
+		return err
+	}
+
+	device.UpdatedAt = now()
+	r.store.devices[device.ID] = storedDevice(device)
+	return nil
+}
+
+// Delete permanently deletes a device so its token can be registered again
+func (r *DeviceRepository) Delete(ctx context.Context, id uint) error {
+	if err := checkContext(ctx); err != nil {
+		return err
+	}
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	delete(r.store.devices, id)
+	return nil
+}
+
+// validate enforces the user foreign key, the platform check and the unique
+// token index. The caller must hold the lock.
+func (r *DeviceRepository) validate(device *models.Device) error {
+	if _, ok := r.store.users[device.UserID]; !ok {
+		return constraint("device references missing user %d", device.UserID)
+	}
+	if device.Platform != models.PlatformIOS && device.Platform != models.PlatformAndroid {
+		return constraint("invalid platform %q", device.Platform)
+	}
+	if len(device.AppVersion) > 32 {
+		return constraint("app_version is longer than 32 characters")
+	}
+	for id, existing := range r.store.devices {
+		if id != device.ID && existing.Token == device.Token {
+			return constraint("device token already exists")
+		}
+	}
+	return nil
+}
+
+// storedDevice drops the user association, which is never loaded from the table
+func storedDevice(device *models.Device) models.Device {
+	stored := *device
+	stored.User = models.User{}
+	return stored
+}
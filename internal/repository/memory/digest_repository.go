@@ -0,0 +1,86 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/models"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/repository"
+)
+
+// DigestRepository implements repository.DigestRepository in memory
+type DigestRepository struct {
+	store *Store
+}
+
+// NewDigestRepository creates a new in-memory digest repository
+func NewDigestRepository(store *Store) repository.DigestRepository {
+	return &DigestRepository{store: store}
+}
+
+// Claim inserts a send record and reports false if the user's period was
+// already claimed, like the unique (user_id, period_start) index. A stale
+// unsent claim is updated to the new one instead, keeping its ID.
+func (r *DigestRepository) Claim(ctx context.Context, send *models.DigestSend, staleBefore time.Time) (bool, error) {
+	if err := checkContext(ctx); err != nil {
+		return false, err
+	}
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.users[send.UserID]; !ok {
+		return false, constraint("digest send references missing user %d", send.UserID)
+	}
+	if send.CreatedAt.IsZero() {
+		send.CreatedAt = now()
+	}
+	for id, existing := range r.store.digestSends {
+		if existing.UserID != send.UserID || !existing.PeriodStart.Equal(send.PeriodStart) {
+			continue
+		}
+		if existing.SentAt != nil || !existing.CreatedAt.Before(staleBefore) {
+			return false, nil
+		}
+		existing.PeriodEnd, existing.CreatedAt = send.PeriodEnd, send.CreatedAt
+		r.store.digestSends[id] = existing
+		send.ID = id
+		return true, nil
+	}
+
+	send.ID = r.store.id("digest_sends")
+
+	stored := *send
+	stored.User = models.User{}
+	r.store.digestSends[send.ID] = stored
+	return true, nil
+}
+
+// MarkSent records when a claimed digest was delivered
+func (r *DigestRepository) MarkSent(ctx context.Context, id uint, sentAt time.Time) error {
+	if err := checkContext(ctx); err != nil {
+		return err
+	}
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if send, ok := r.store.digestSends[id]; ok {
+		send.SentAt = &sentAt
+		r.store.digestSends[id] = send
+	}
+	return nil
+}
+
+// Delete deletes a send record, releasing the period so it can be retried
+func (r *DigestRepository) Delete(ctx context.Context, id uint) error {
+	if err := checkContext(ctx); err != nil {
+		return err
+	}
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	delete(r.store.digestSends, id)
+	return nil
+}
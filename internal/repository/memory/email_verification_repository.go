@@ -0,0 +1,112 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/models"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/repository"
+)
+
+// EmailVerificationRepository implements repository.EmailVerificationRepository in memory
+type EmailVerificationRepository struct {
+	store *Store
+}
+
+// NewEmailVerificationRepository creates a new in-memory email verification repository
+func NewEmailVerificationRepository(store *Store) repository.EmailVerificationRepository {
+	return &EmailVerificationRepository{store: store}
+}
+
+// Create creates a new pending verification
+func (r *EmailVerificationRepository) Create(ctx context.Context, verification *models.EmailVerification) error {
+	if err := checkContext(ctx); err != nil {
+		return err
+	}
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if err := r.validate(verification); err != nil {
+		return err
+	}
+
+	verification.ID = r.store.id("email_verifications")
+	if verification.CreatedAt.IsZero() {
+		verification.CreatedAt = now()
+	}
+
+	stored := *verification
+	stored.User = models.User{}
+	r.store.verifications[verification.ID] = stored
+	return nil
+}
+
+// FindByTokenHash finds a pending verification by the hash of its token
+func (r *EmailVerificationRepository) FindByTokenHash(ctx context.Context, tokenHash string) (*models.EmailVerification, error) {
+	if err := checkContext(ctx); err != nil {
+		return nil, err
+	}
+
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	for _, verification := range r.store.verifications {
+		if verification.TokenHash == tokenHash {
+			return &verification, nil
+		}
+	}
+	return nil, nil
+}
+
+// FindByUserIDSince finds the verifications created for a user at or after since, newest first
+func (r *EmailVerificationRepository) FindByUserIDSince(ctx context.Context, userID uint, since time.Time) ([]models.EmailVerification, error) {
+	if err := checkContext(ctx); err != nil {
+		return nil, err
+	}
+
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	verifications := []models.EmailVerification{}
+	for _, verification := range r.store.verifications {
+		if verification.UserID == userID && !verification.CreatedAt.Before(since) {
+			verifications = append(verifications, verification)
+		}
+	}
+	sort.Slice(verifications, func(i, j int) bool {
+		if !verifications[i].CreatedAt.Equal(verifications[j].CreatedAt) {
+			return verifications[i].CreatedAt.After(verifications[j].CreatedAt)
+		}
+		return verifications[i].ID > verifications[j].ID
+	})
+	return verifications, nil
+}
+
+// DeleteByUserID deletes every pending verification of a user
+func (r *EmailVerificationRepository) DeleteByUserID(ctx context.Context, userID uint) error {
+	if err := checkContext(ctx); err != nil {
+		return err
+	}
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	deleteWhere(r.store.verifications, func(v models.EmailVerification) bool { return v.UserID == userID })
+	return nil
+}
+
+// validate enforces the user foreign key and the unique token hash index.
+// The caller must hold the lock.
+func (r *EmailVerificationRepository) validate(verification *models.EmailVerification) error {
+	if _, ok := r.store.users[verification.UserID]; !ok {
+		return constraint("email verification references missing user %d", verification.UserID)
+	}
+	for _, existing := range r.store.verifications {
+		if existing.TokenHash == verification.TokenHash {
+			return constraint("email verification token already exists")
+		}
+	}
+	return nil
+}
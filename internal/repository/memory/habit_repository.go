@@ -0,0 +1,208 @@
+package memory
+
+import (
+	"context"
+	"sort"
+
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/models"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/repository"
+)
+
+// HabitRepository implements repository.HabitRepository in memory
+type HabitRepository struct {
+	store *Store
+}
+
+// NewHabitRepository creates a new in-memory habit repository
+func NewHabitRepository(store *Store) repository.HabitRepository {
+	return &HabitRepository{store: store}
+}
+
+// Create creates a new habit. Like the is_active column default, a habit
+// created with IsActive false is stored as active.
+func (r *HabitRepository) Create(ctx context.Context, habit *models.Habit) error {
+	if err := checkContext(ctx); err != nil {
+		return err
+	}
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if err := r.validate(habit); err != nil {
+		return err
+	}
+
+	created := now()
+	habit.ID = r.store.id("habits")
+	habit.IsActive = true
+	if habit.CreatedAt.IsZero() {
+		habit.CreatedAt = created
+	}
+	if habit.UpdatedAt.IsZero() {
+		habit.UpdatedAt = created
+	}
+
+	r.store.habits[habit.ID] = storedHabit(habit)
+	return nil
+}
+
+// CreateWithHistory creates the habit with its streaks, check-ins and
+// achievements, and removes whatever it created when a write fails
+func (r *HabitRepository) CreateWithHistory(ctx context.Context, habit *models.Habit, history []repository.StreakHistory) error {
+	streaks := &StreakRepository{store: r.store}
+	checkIns := &CheckInRepository{store: r.store}
+	achievements := &AchievementRepository{store: r.store}
+
+	var created struct{ streaks, checkIns, achievements []uint }
+	err := func() error {
+		if err := r.Create(ctx, habit); err != nil {
+			return err
+		}
+
+		for i := range history {
+			streak := &history[i].Streak
+			streak.HabitID = habit.ID
+			if err := streaks.Create(ctx, streak); err != nil {
+				return err
+			}
+			created.streaks = append(created.streaks, streak.ID)
+
+			for j := range history[i].CheckIns {
+				checkIn := &history[i].CheckIns[j]
+				checkIn.StreakID = streak.ID
+				if err := checkIns.Create(ctx, checkIn); err != nil {
+					return err
+				}
+				created.checkIns = append(created.checkIns, checkIn.ID)
+			}
+
+			if history[i].Achievement == nil {
+				continue
+			}
+			achievement, err := history[i].Achievement(streak)
+			if err != nil {
+				return err
+			}
+			achievement.HabitID = habit.ID
+			if err := achievements.Create(ctx, achievement); err != nil {
+				return err
+			}
+			created.achievements = append(created.achievements, achievement.ID)
+		}
+		return nil
+	}()
+	if err == nil {
+		return nil
+	}
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if habit.ID != 0 {
+		delete(r.store.habits, habit.ID)
+	}
+	for _, id := range created.streaks {
+		delete(r.store.streaks, id)
+	}
+	for _, id := range created.checkIns {
+		delete(r.store.checkIns, id)
+	}
+	for _, id := range created.achievements {
+		delete(r.store.achievements, id)
+	}
+	return err
+}
+
+// FindByID finds a habit by ID
+func (r *HabitRepository) FindByID(ctx context.Context, id uint) (*models.Habit, error) {
+	if err := checkContext(ctx); err != nil {
+		return nil, err
+	}
+
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	habit, ok := r.store.habits[id]
+	if !ok || deleted(habit.DeletedAt) {
+		return nil, nil
+	}
+	return &habit, nil
+}
+
+// FindByUserID finds all habits for a user ordered by ID
+func (r *HabitRepository) FindByUserID(ctx context.Context, userID uint) ([]models.Habit, error) {
+	if err := checkContext(ctx); err != nil {
+		return nil, err
+	}
+
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	habits := []models.Habit{}
+	for _, habit := range r.store.habits {
+		if habit.UserID == userID && !deleted(habit.DeletedAt) {
+			habits = append(habits, habit)
+		}
+	}
+	sort.Slice(habits, func(i, j int) bool { return habits[i].ID < habits[j].ID })
+	return habits, nil
+}
+
+// Update updates a habit, creating it if it does not exist
+func (r *HabitRepository) Update(ctx context.Context, habit *models.Habit) error {
+	if habit.ID == 0 {
+		return r.Create(ctx, habit)
+	}
+	if err := checkContext(ctx); err != nil {
+		return err
+	}
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if err := r.validate(habit); err != nil {
+		return err
+	}
+
+	habit.UpdatedAt = now()
+	r.store.habits[habit.ID] = storedHabit(habit)
+	return nil
+}
+
+// Delete soft deletes a habit
+func (r *HabitRepository) Delete(ctx context.Context, id uint) error {
+	if err := checkContext(ctx); err != nil {
+		return err
+	}
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if habit, ok := r.store.habits[id]; ok && !deleted(habit.DeletedAt) {
+		habit.DeletedAt = softDelete()
+		r.store.habits[id] = habit
+	}
+	return nil
+}
+
+// validate enforces the user foreign key and column sizes. The caller must hold the lock.
+func (r *HabitRepository) validate(habit *models.Habit) error {
+	if _, ok := r.store.users[habit.UserID]; !ok {
+		return constraint("habit references missing user %d", habit.UserID)
+	}
+	if len(habit.Color) > 7 {
+		return constraint("color %q is longer than 7 characters", habit.Color)
+	}
+	if len(habit.Icon) > 50 {
+		return constraint("icon is longer than 50 characters")
+	}
+	return nil
+}
+
+// storedHabit drops the associations, which are never loaded from the table
+func storedHabit(habit *models.Habit) models.Habit {
+	stored := *habit
+	stored.User = models.User{}
+	stored.Streaks = nil
+	return stored
+}
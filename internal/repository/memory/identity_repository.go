@@ -0,0 +1,171 @@
+package memory
+
+import (
+	"context"
+	"sort"
+
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/models"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/repository"
+)
+
+// IdentityRepository implements repository.IdentityRepository in memory
+type IdentityRepository struct {
+	store *Store
+}
+
+// NewIdentityRepository creates a new in-memory identity repository
+func NewIdentityRepository(store *Store) repository.IdentityRepository {
+	return &IdentityRepository{store: store}
+}
+
+// Create links a new identity to an existing user
+func (r *IdentityRepository) Create(ctx context.Context, identity *models.Identity) error {
+	if err := checkContext(ctx); err != nil {
+		return err
+	}
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	return r.create(identity)
+}
+
+// CreateWithUser creates a new user and their first identity. Neither is
+// stored when either violates a constraint.
+func (r *IdentityRepository) CreateWithUser(ctx context.Context, user *models.User, identity *models.Identity) error {
+	if err := checkContext(ctx); err != nil {
+		return err
+	}
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	users := &UserRepository{store: r.store}
+	if err := users.validate(user); err != nil {
+		return err
+	}
+	// The identity is checked before the user is stored so that a violation
+	// leaves the user table untouched
+	if err := r.validate(identity, false); err != nil {
+		return err
+	}
+
+	created := now()
+	user.ID = r.store.id("users")
+	if user.CreatedAt.IsZero() {
+		user.CreatedAt = created
+	}
+	if user.UpdatedAt.IsZero() {
+		user.UpdatedAt = created
+	}
+	r.store.users[user.ID] = *user
+
+	identity.UserID = user.ID
+	return r.create(identity)
+}
+
+// create stores a new identity. The caller must hold the lock.
+func (r *IdentityRepository) create(identity *models.Identity) error {
+	if err := r.validate(identity, true); err != nil {
+		return err
+	}
+
+	created := now()
+	identity.ID = r.store.id("user_identities")
+	if identity.CreatedAt.IsZero() {
+		identity.CreatedAt = created
+	}
+	if identity.UpdatedAt.IsZero() {
+		identity.UpdatedAt = created
+	}
+
+	r.store.identities[identity.ID] = storedIdentity(identity)
+	return nil
+}
+
+// FindByProviderSubject finds the identity for a provider's user ID
+func (r *IdentityRepository) FindByProviderSubject(ctx context.Context, provider, subject string) (*models.Identity, error) {
+	if err := checkContext(ctx); err != nil {
+		return nil, err
+	}
+
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	for _, identity := range r.store.identities {
+		if identity.Provider == provider && identity.Subject == subject {
+			return &identity, nil
+		}
+	}
+	return nil, nil
+}
+
+// FindByUserID finds all identities linked to a user, oldest first
+func (r *IdentityRepository) FindByUserID(ctx context.Context, userID uint) ([]models.Identity, error) {
+	if err := checkContext(ctx); err != nil {
+		return nil, err
+	}
+
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	identities := []models.Identity{}
+	for _, identity := range r.store.identities {
+		if identity.UserID == userID {
+			identities = append(identities, identity)
+		}
+	}
+	sort.Slice(identities, func(i, j int) bool {
+		if !identities[i].CreatedAt.Equal(identities[j].CreatedAt) {
+			return identities[i].CreatedAt.Before(identities[j].CreatedAt)
+		}
+		return identities[i].ID < identities[j].ID
+	})
+	return identities, nil
+}
+
+// Update updates an identity, creating it if it does not exist
+func (r *IdentityRepository) Update(ctx context.Context, identity *models.Identity) error {
+	if identity.ID == 0 {
+		return r.Create(ctx, identity)
+	}
+	if err := checkContext(ctx); err != nil {
+		return err
+	}
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if err := r.validate(identity, true); err != nil {
+		return err
+	}
+
+	identity.UpdatedAt = now()
+	r.store.identities[identity.ID] = storedIdentity(identity)
+	return nil
+}
+
+// validate enforces the user foreign key, the provider check and the unique
+// provider and subject index. The foreign key is skipped for identities whose
+// user is created in the same call. The caller must hold the lock.
+func (r *IdentityRepository) validate(identity *models.Identity, checkUser bool) error {
+	if _, ok := r.store.users[identity.UserID]; checkUser && !ok {
+		return constraint("identity references missing user %d", identity.UserID)
+	}
+	if identity.Provider != models.ProviderGoogle && identity.Provider != models.ProviderApple {
+		return constraint("invalid provider %q", identity.Provider)
+	}
+	for id, existing := range r.store.identities {
+		if id != identity.ID && existing.Provider == identity.Provider && existing.Subject == identity.Subject {
+			return constraint("identity %s/%s already exists", identity.Provider, identity.Subject)
+		}
+	}
+	return nil
+}
+
+// storedIdentity drops the user association, which is never loaded from the table
+func storedIdentity(identity *models.Identity) models.Identity {
+	stored := *identity
+	stored.User = models.User{}
+	return stored
+}
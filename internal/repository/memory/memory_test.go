@@ -0,0 +1,28 @@
+package memory_test
+
+import (
+	"testing"
+
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/repository/memory"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/repository/repositorytest"
+)
+
+func TestMemoryRepositories(t *testing.T) {
+	repositorytest.RunContractTests(t, func(t *testing.T) repositorytest.Repositories {
+		store := memory.NewStore()
+		return repositorytest.Repositories{
+			Users:         memory.NewUserRepository(store),
+			Habits:        memory.NewHabitRepository(store),
+			Streaks:       memory.NewStreakRepository(store),
+			CheckIns:      memory.NewCheckInRepository(store),
+			Achievements:  memory.NewAchievementRepository(store),
+			Devices:       memory.NewDeviceRepository(store),
+			Digests:       memory.NewDigestRepository(store),
+			Identities:    memory.NewIdentityRepository(store),
+			Verifications: memory.NewEmailVerificationRepository(store),
+			Resets:        memory.NewPasswordResetRepository(store),
+			Events:        memory.NewSecurityEventRepository(store),
+			MFA:           memory.NewMFARepository(store),
+		}
+	})
+}
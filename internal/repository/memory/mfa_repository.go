@@ -0,0 +1,273 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/models"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/repository"
+)
+
+// MFARepository implements repository.MFARepository in memory
+type MFARepository struct {
+	store *Store
+}
+
+// NewMFARepository creates a new in-memory MFA repository
+func NewMFARepository(store *Store) repository.MFARepository {
+	return &MFARepository{store: store}
+}
+
+// FindTOTP finds the user's authenticator credential
+func (r *MFARepository) FindTOTP(ctx context.Context, userID uint) (*models.TOTPCredential, error) {
+	if err := checkContext(ctx); err != nil {
+		return nil, err
+	}
+
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	credential, ok := r.store.totp[userID]
+	if !ok {
+		return nil, nil
+	}
+	return &credential, nil
+}
+
+// SaveTOTP creates the user's credential or replaces the existing one
+func (r *MFARepository) SaveTOTP(ctx context.Context, credential *models.TOTPCredential) error {
+	if err := checkContext(ctx); err != nil {
+		return err
+	}
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.users[credential.UserID]; !ok {
+		return constraint("TOTP credential references missing user %d", credential.UserID)
+	}
+
+	credential.UpdatedAt = now()
+	if existing, ok := r.store.totp[credential.UserID]; ok {
+		credential.CreatedAt = existing.CreatedAt
+	} else if credential.CreatedAt.IsZero() {
+		credential.CreatedAt = credential.UpdatedAt
+	}
+
+	stored := *credential
+	stored.User = models.User{}
+	r.store.totp[credential.UserID] = stored
+	return nil
+}
+
+// UseTOTPStep records the time step of an accepted code unless that step or a
+// later one was already used
+func (r *MFARepository) UseTOTPStep(ctx context.Context, userID uint, step int64) (bool, error) {
+	if err := checkContext(ctx); err != nil {
+		return false, err
+	}
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	credential, ok := r.store.totp[userID]
+	if !ok || (credential.LastUsedStep != nil && *credential.LastUsedStep >= step) {
+		return false, nil
+	}
+	credential.LastUsedStep = &step
+	credential.UpdatedAt = now()
+	r.store.totp[userID] = credential
+	return true, nil
+}
+
+// Enable confirms the credential, replaces the recovery codes and marks MFA
+// enabled on the user
+func (r *MFARepository) Enable(ctx context.Context, userID uint, at time.Time, codes []models.RecoveryCode) error {
+	if err := checkContext(ctx); err != nil {
+		return err
+	}
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if err := r.replaceRecoveryCodes(userID, codes); err != nil {
+		return err
+	}
+	if credential, ok := r.store.totp[userID]; ok {
+		credential.ConfirmedAt = &at
+		credential.UpdatedAt = now()
+		r.store.totp[userID] = credential
+	}
+	if user, ok := r.store.users[userID]; ok {
+		user.MFAEnabledAt = &at
+		r.store.users[userID] = user
+	}
+	return nil
+}
+
+// Disable removes the credential, recovery codes and challenges and marks MFA
+// disabled on the user
+func (r *MFARepository) Disable(ctx context.Context, userID uint) error {
+	if err := checkContext(ctx); err != nil {
+		return err
+	}
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	delete(r.store.totp, userID)
+	deleteWhere(r.store.recoveryCodes, func(c models.RecoveryCode) bool { return c.UserID == userID })
+	deleteWhere(r.store.challenges, func(c models.MFAChallenge) bool { return c.UserID == userID })
+	if user, ok := r.store.users[userID]; ok {
+		user.MFAEnabledAt = nil
+		r.store.users[userID] = user
+	}
+	return nil
+}
+
+// ReplaceRecoveryCodes replaces all of the user's recovery codes
+func (r *MFARepository) ReplaceRecoveryCodes(ctx context.Context, userID uint, codes []models.RecoveryCode) error {
+	if err := checkContext(ctx); err != nil {
+		return err
+	}
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	return r.replaceRecoveryCodes(userID, codes)
+}
+
+// replaceRecoveryCodes deletes the user's recovery codes and stores the new
+// ones. The caller must hold the lock.
+func (r *MFARepository) replaceRecoveryCodes(userID uint, codes []models.RecoveryCode) error {
+	if _, ok := r.store.users[userID]; !ok && len(codes) > 0 {
+		return constraint("recovery code references missing user %d", userID)
+	}
+
+	deleteWhere(r.store.recoveryCodes, func(c models.RecoveryCode) bool { return c.UserID == userID })
+	for i := range codes {
+		codes[i].ID = r.store.id("mfa_recovery_codes")
+		codes[i].UserID = userID
+		if codes[i].CreatedAt.IsZero() {
+			codes[i].CreatedAt = now()
+		}
+		stored := codes[i]
+		stored.User = models.User{}
+		r.store.recoveryCodes[stored.ID] = stored
+	}
+	return nil
+}
+
+// UseRecoveryCode marks the user's unused code with the hash as used
+func (r *MFARepository) UseRecoveryCode(ctx context.Context, userID uint, codeHash string, at time.Time) (bool, error) {
+	if err := checkContext(ctx); err != nil {
+		return false, err
+	}
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for id, code := range r.store.recoveryCodes {
+		if code.UserID == userID && code.CodeHash == codeHash && code.UsedAt == nil {
+			code.UsedAt = &at
+			r.store.recoveryCodes[id] = code
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// CountUnusedRecoveryCodes counts the user's recovery codes that are left
+func (r *MFARepository) CountUnusedRecoveryCodes(ctx context.Context, userID uint) (int64, error) {
+	if err := checkContext(ctx); err != nil {
+		return 0, err
+	}
+
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var count int64
+	for _, code := range r.store.recoveryCodes {
+		if code.UserID == userID && code.UsedAt == nil {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// CreateChallenge creates a pending login challenge
+func (r *MFARepository) CreateChallenge(ctx context.Context, challenge *models.MFAChallenge) error {
+	if err := checkContext(ctx); err != nil {
+		return err
+	}
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.users[challenge.UserID]; !ok {
+		return constraint("MFA challenge references missing user %d", challenge.UserID)
+	}
+	for _, existing := range r.store.challenges {
+		if existing.TokenHash == challenge.TokenHash {
+			return constraint("MFA challenge token already exists")
+		}
+	}
+
+	challenge.ID = r.store.id("mfa_challenges")
+	if challenge.CreatedAt.IsZero() {
+		challenge.CreatedAt = now()
+	}
+
+	stored := *challenge
+	stored.User = models.User{}
+	r.store.challenges[challenge.ID] = stored
+	return nil
+}
+
+// FindChallengeByTokenHash finds a pending login challenge by the hash of its token
+func (r *MFARepository) FindChallengeByTokenHash(ctx context.Context, tokenHash string) (*models.MFAChallenge, error) {
+	if err := checkContext(ctx); err != nil {
+		return nil, err
+	}
+
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	for _, challenge := range r.store.challenges {
+		if challenge.TokenHash == tokenHash {
+			return &challenge, nil
+		}
+	}
+	return nil, nil
+}
+
+// DeleteChallenge deletes a challenge and reports whether it still existed
+func (r *MFARepository) DeleteChallenge(ctx context.Context, id uint) (bool, error) {
+	if err := checkContext(ctx); err != nil {
+		return false, err
+	}
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.challenges[id]; !ok {
+		return false, nil
+	}
+	delete(r.store.challenges, id)
+	return true, nil
+}
+
+// DeleteExpiredChallenges deletes the user's challenges that expired by now
+func (r *MFARepository) DeleteExpiredChallenges(ctx context.Context, userID uint, now time.Time) error {
+	if err := checkContext(ctx); err != nil {
+		return err
+	}
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	deleteWhere(r.store.challenges, func(c models.MFAChallenge) bool {
+		return c.UserID == userID && !c.ExpiresAt.After(now)
+	})
+	return nil
+}
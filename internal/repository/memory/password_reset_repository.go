@@ -0,0 +1,86 @@
+package memory
+
+import (
+	"context"
+
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/models"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/repository"
+)
+
+// PasswordResetRepository implements repository.PasswordResetRepository in memory
+type PasswordResetRepository struct {
+	store *Store
+}
+
+// NewPasswordResetRepository creates a new in-memory password reset repository
+func NewPasswordResetRepository(store *Store) repository.PasswordResetRepository {
+	return &PasswordResetRepository{store: store}
+}
+
+// Create creates a new pending password reset
+func (r *PasswordResetRepository) Create(ctx context.Context, reset *models.PasswordReset) error {
+	if err := checkContext(ctx); err != nil {
+		return err
+	}
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if err := r.validate(reset); err != nil {
+		return err
+	}
+
+	reset.ID = r.store.id("password_resets")
+	if reset.CreatedAt.IsZero() {
+		reset.CreatedAt = now()
+	}
+
+	stored := *reset
+	stored.User = models.User{}
+	r.store.passwordResets[reset.ID] = stored
+	return nil
+}
+
+// FindByTokenHash finds a pending password reset by the hash of its token
+func (r *PasswordResetRepository) FindByTokenHash(ctx context.Context, tokenHash string) (*models.PasswordReset, error) {
+	if err := checkContext(ctx); err != nil {
+		return nil, err
+	}
+
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	for _, reset := range r.store.passwordResets {
+		if reset.TokenHash == tokenHash {
+			return &reset, nil
+		}
+	}
+	return nil, nil
+}
+
+// DeleteByUserID deletes every pending password reset of a user
+func (r *PasswordResetRepository) DeleteByUserID(ctx context.Context, userID uint) error {
+	if err := checkContext(ctx); err != nil {
+		return err
+	}
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	deleteWhere(r.store.passwordResets, func(p models.PasswordReset) bool { return p.UserID == userID })
+	return nil
+}
+
+// validate enforces the user foreign key and the unique token hash index.
+// The caller must hold the lock.
+func (r *PasswordResetRepository) validate(reset *models.PasswordReset) error {
+	if _, ok := r.store.users[reset.UserID]; !ok {
+		return constraint("password reset references missing user %d", reset.UserID)
+	}
+	for _, existing := range r.store.passwordResets {
+		if existing.TokenHash == reset.TokenHash {
+			return constraint("password reset token already exists")
+		}
+	}
+	return nil
+}
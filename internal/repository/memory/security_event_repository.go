@@ -0,0 +1,70 @@
+package memory
+
+import (
+	"context"
+	"sort"
+
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/models"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/repository"
+)
+
+// SecurityEventRepository implements repository.SecurityEventRepository in memory
+type SecurityEventRepository struct {
+	store *Store
+}
+
+// NewSecurityEventRepository creates a new in-memory security event repository
+func NewSecurityEventRepository(store *Store) repository.SecurityEventRepository {
+	return &SecurityEventRepository{store: store}
+}
+
+// Create records a new security event
+func (r *SecurityEventRepository) Create(ctx context.Context, event *models.SecurityEvent) error {
+	if err := checkContext(ctx); err != nil {
+		return err
+	}
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.users[event.UserID]; !ok {
+		return constraint("security event references missing user %d", event.UserID)
+	}
+
+	event.ID = r.store.id("security_events")
+	if event.CreatedAt.IsZero() {
+		event.CreatedAt = now()
+	}
+
+	stored := *event
+	stored.User = models.User{}
+	r.store.events[event.ID] = stored
+	return nil
+}
+
+// FindByUserID finds a user's latest security events, newest first
+func (r *SecurityEventRepository) FindByUserID(ctx context.Context, userID uint, limit int) ([]models.SecurityEvent, error) {
+	if err := checkContext(ctx); err != nil {
+		return nil, err
+	}
+
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	events := []models.SecurityEvent{}
+	for _, event := range r.store.events {
+		if event.UserID == userID {
+			events = append(events, event)
+		}
+	}
+	sort.Slice(events, func(i, j int) bool {
+		if !events[i].CreatedAt.Equal(events[j].CreatedAt) {
+			return events[i].CreatedAt.After(events[j].CreatedAt)
+		}
+		return events[i].ID > events[j].ID
+	})
+	if limit > 0 && len(events) > limit {
+		events = events[:limit]
+	}
+	return events, nil
+}
@@ -0,0 +1,99 @@
+// Package memory provides thread-safe in-memory implementations of the
+// repository interfaces. They follow the semantics of the GORM repositories,
+// including soft deletes, nil results for missing records, result ordering and
+// the unique, check and foreign key constraints of the schema, so services
+// can be tested without a database.
+package memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/models"
+	"gorm.io/gorm"
+)
+
+// ErrConstraint is returned when a write violates a schema constraint
+var ErrConstraint = errors.New("constraint violation")
+
+// Store holds the tables shared by the in-memory repositories. Repositories
+// created from the same store see each other's data, as they would in a
+// database.
+type Store struct {
+	mu sync.RWMutex
+
+	users          map[uint]models.User
+	habits         map[uint]models.Habit
+	streaks        map[uint]models.HabitStreak
+	checkIns       map[uint]models.HabitCheckIn
+	achievements   map[uint]models.Achievement
+	devices        map[uint]models.Device
+	digestSends    map[uint]models.DigestSend
+	erasures       map[uint]models.ErasureAudit
+	identities     map[uint]models.Identity
+	verifications  map[uint]models.EmailVerification
+	passwordResets map[uint]models.PasswordReset
+	events         map[uint]models.SecurityEvent
+	totp           map[uint]models.TOTPCredential // by user ID
+	recoveryCodes  map[uint]models.RecoveryCode
+	challenges     map[uint]models.MFAChallenge
+
+	// nextID holds the last issued primary key per table
+	nextID map[string]uint
+}
+
+// NewStore creates an empty store
+func NewStore() *Store {
+	return &Store{
+		users:          make(map[uint]models.User),
+		habits:         make(map[uint]models.Habit),
+		streaks:        make(map[uint]models.HabitStreak),
+		checkIns:       make(map[uint]models.HabitCheckIn),
+		achievements:   make(map[uint]models.Achievement),
+		devices:        make(map[uint]models.Device),
+		digestSends:    make(map[uint]models.DigestSend),
+		erasures:       make(map[uint]models.ErasureAudit),
+		identities:     make(map[uint]models.Identity),
+		verifications:  make(map[uint]models.EmailVerification),
+		passwordResets: make(map[uint]models.PasswordReset),
+		events:         make(map[uint]models.SecurityEvent),
+		totp:           make(map[uint]models.TOTPCredential),
+		recoveryCodes:  make(map[uint]models.RecoveryCode),
+		challenges:     make(map[uint]models.MFAChallenge),
+		nextID:         make(map[string]uint),
+	}
+}
+
+// id issues the next primary key for the table. The caller must hold the lock.
+func (s *Store) id(table string) uint {
+	s.nextID[table]++
+	return s.nextID[table]
+}
+
+// now matches the UTC NowFunc the GORM connection is configured with
+func now() time.Time {
+	return time.Now().UTC()
+}
+
+// deleted reports whether a soft deleted record should be hidden
+func deleted(deletedAt gorm.DeletedAt) bool {
+	return deletedAt.Valid
+}
+
+// softDelete returns the value GORM writes to deleted_at
+func softDelete() gorm.DeletedAt {
+	return gorm.DeletedAt{Time: now(), Valid: true}
+}
+
+// constraint wraps ErrConstraint with a description of the violation
+func constraint(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: %s", ErrConstraint, fmt.Sprintf(format, args...))
+}
+
+// checkContext fails like a database call made with a cancelled context
+func checkContext(ctx context.Context) error {
+	return ctx.Err()
+}
@@ -0,0 +1,172 @@
+package memory
+
+import (
+	"context"
+	"sort"
+
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/models"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/repository"
+)
+
+// StreakRepository implements repository.StreakRepository in memory
+type StreakRepository struct {
+	store *Store
+}
+
+// NewStreakRepository creates a new in-memory streak repository
+func NewStreakRepository(store *Store) repository.StreakRepository {
+	return &StreakRepository{store: store}
+}
+
+// Create creates a new streak. An empty status defaults to active.
+func (r *StreakRepository) Create(ctx context.Context, streak *models.HabitStreak) error {
+	if err := checkContext(ctx); err != nil {
+		return err
+	}
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if streak.Status == "" {
+		streak.Status = "active"
+	}
+	if err := r.validate(streak); err != nil {
+		return err
+	}
+
+	created := now()
+	streak.ID = r.store.id("habit_streaks")
+	if streak.CreatedAt.IsZero() {
+		streak.CreatedAt = created
+	}
+	if streak.UpdatedAt.IsZero() {
+		streak.UpdatedAt = created
+	}
+
+	r.store.streaks[streak.ID] = storedStreak(streak)
+	return nil
+}
+
+// FindByID finds a streak by ID
+func (r *StreakRepository) FindByID(ctx context.Context, id uint) (*models.HabitStreak, error) {
+	if err := checkContext(ctx); err != nil {
+		return nil, err
+	}
+
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	streak, ok := r.store.streaks[id]
+	if !ok || deleted(streak.DeletedAt) {
+		return nil, nil
+	}
+	return &streak, nil
+}
+
+// FindByHabitID finds all streaks for a habit, newest first
+func (r *StreakRepository) FindByHabitID(ctx context.Context, habitID uint) ([]models.HabitStreak, error) {
+	if err := checkContext(ctx); err != nil {
+		return nil, err
+	}
+
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	streaks := []models.HabitStreak{}
+	for _, streak := range r.store.streaks {
+		if streak.HabitID == habitID && !deleted(streak.DeletedAt) {
+			streaks = append(streaks, streak)
+		}
+	}
+	sort.Slice(streaks, func(i, j int) bool {
+		if !streaks[i].CreatedAt.Equal(streaks[j].CreatedAt) {
+			return streaks[i].CreatedAt.After(streaks[j].CreatedAt)
+		}
+		return streaks[i].ID > streaks[j].ID
+	})
+	return streaks, nil
+}
+
+// FindActiveByHabitID finds the active streak with the lowest ID for a habit
+func (r *StreakRepository) FindActiveByHabitID(ctx context.Context, habitID uint) (*models.HabitStreak, error) {
+	if err := checkContext(ctx); err != nil {
+		return nil, err
+	}
+
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var active *models.HabitStreak
+	for _, streak := range r.store.streaks {
+		if streak.HabitID != habitID || streak.Status != "active" || deleted(streak.DeletedAt) {
+			continue
+		}
+		if active == nil || streak.ID < active.ID {
+			found := streak
+			active = &found
+		}
+	}
+	return active, nil
+}
+
+// Update updates a streak, creating it if it does not exist
+func (r *StreakRepository) Update(ctx context.Context, streak *models.HabitStreak) error {
+	if streak.ID == 0 {
+		return r.Create(ctx, streak)
+	}
+	if err := checkContext(ctx); err != nil {
+		return err
+	}
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if err := r.validate(streak); err != nil {
+		return err
+	}
+
+	streak.UpdatedAt = now()
+	r.store.streaks[streak.ID] = storedStreak(streak)
+	return nil
+}
+
+// Delete soft deletes a streak
+func (r *StreakRepository) Delete(ctx context.Context, id uint) error {
+	if err := checkContext(ctx); err != nil {
+		return err
+	}
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if streak, ok := r.store.streaks[id]; ok && !deleted(streak.DeletedAt) {
+		streak.DeletedAt = softDelete()
+		r.store.streaks[id] = streak
+	}
+	return nil
+}
+
+// validate enforces the habit foreign key and the target and status checks.
+// The caller must hold the lock.
+func (r *StreakRepository) validate(streak *models.HabitStreak) error {
+	if _, ok := r.store.habits[streak.HabitID]; !ok {
+		return constraint("streak references missing habit %d", streak.HabitID)
+	}
+	if streak.TargetDays <= 0 {
+		return constraint("target_days must be greater than 0")
+	}
+	switch streak.Status {
+	case "active", "completed", "failed":
+	default:
+		return constraint("invalid streak status %q", streak.Status)
+	}
+	return nil
+}
+
+// storedStreak drops the associations, which are never loaded from the table
+func storedStreak(streak *models.HabitStreak) models.HabitStreak {
+	stored := *streak
+	stored.Habit = models.Habit{}
+	stored.CheckIns = nil
+	return stored
+}
@@ -0,0 +1,239 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/models"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/repository"
+)
+
+// UserRepository implements repository.UserRepository in memory
+type UserRepository struct {
+	store *Store
+}
+
+// NewUserRepository creates a new in-memory user repository
+func NewUserRepository(store *Store) repository.UserRepository {
+	return &UserRepository{store: store}
+}
+
+// Create creates a new user
+func (r *UserRepository) Create(ctx context.Context, user *models.User) error {
+	if err := checkContext(ctx); err != nil {
+		return err
+	}
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if err := r.validate(user); err != nil {
+		return err
+	}
+
+	created := now()
+	user.ID = r.store.id("users")
+	if user.CreatedAt.IsZero() {
+		user.CreatedAt = created
+	}
+	if user.UpdatedAt.IsZero() {
+		user.UpdatedAt = created
+	}
+
+	r.store.users[user.ID] = *user
+	return nil
+}
+
+// FindByID finds a user by ID
+func (r *UserRepository) FindByID(ctx context.Context, id uint) (*models.User, error) {
+	if err := checkContext(ctx); err != nil {
+		return nil, err
+	}
+
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	user, ok := r.store.users[id]
+	if !ok || deleted(user.DeletedAt) {
+		return nil, nil
+	}
+	return &user, nil
+}
+
+// FindByEmail finds a user by email
+func (r *UserRepository) FindByEmail(ctx context.Context, email string) (*models.User, error) {
+	if err := checkContext(ctx); err != nil {
+		return nil, err
+	}
+
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	for _, user := range r.store.users {
+		if user.Email == email && !deleted(user.DeletedAt) {
+			return &user, nil
+		}
+	}
+	return nil, nil
+}
+
+// FindDigestRecipients finds all users who opted in to the weekly digest on the given day
+func (r *UserRepository) FindDigestRecipients(ctx context.Context, day time.Weekday) ([]models.User, error) {
+	return r.find(ctx, func(user models.User) bool {
+		return !deleted(user.DeletedAt) && user.WeeklyDigestEnabled && user.WeeklyDigestDay == int(day)
+	})
+}
+
+// ExistsByEmail checks whether an account uses the email, including deleted accounts awaiting purge
+func (r *UserRepository) ExistsByEmail(ctx context.Context, email string) (bool, error) {
+	if err := checkContext(ctx); err != nil {
+		return false, err
+	}
+
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	for _, user := range r.store.users {
+		if user.Email == email {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// FindDueForPurge finds deleted users whose grace period has passed
+func (r *UserRepository) FindDueForPurge(ctx context.Context, now time.Time) ([]models.User, error) {
+	return r.find(ctx, func(user models.User) bool {
+		return deleted(user.DeletedAt) && user.PurgeAfter != nil && !user.PurgeAfter.After(now)
+	})
+}
+
+// Update updates a user, creating it if it does not exist
+func (r *UserRepository) Update(ctx context.Context, user *models.User) error {
+	if user.ID == 0 {
+		return r.Create(ctx, user)
+	}
+	if err := checkContext(ctx); err != nil {
+		return err
+	}
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if err := r.validate(user); err != nil {
+		return err
+	}
+
+	user.UpdatedAt = now()
+	r.store.users[user.ID] = *user
+	return nil
+}
+
+// Delete soft deletes a user
+func (r *UserRepository) Delete(ctx context.Context, id uint) error {
+	if err := checkContext(ctx); err != nil {
+		return err
+	}
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if user, ok := r.store.users[id]; ok && !deleted(user.DeletedAt) {
+		user.DeletedAt = softDelete()
+		r.store.users[id] = user
+	}
+	return nil
+}
+
+// Purge permanently removes the user together with all of their data and
+// records the audit entry
+func (r *UserRepository) Purge(ctx context.Context, id uint, audit *models.ErasureAudit) error {
+	if err := checkContext(ctx); err != nil {
+		return err
+	}
+
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	habitIDs := make(map[uint]bool)
+	for habitID, habit := range s.habits {
+		if habit.UserID == id {
+			habitIDs[habitID] = true
+		}
+	}
+	streakIDs := make(map[uint]bool)
+	for streakID, streak := range s.streaks {
+		if habitIDs[streak.HabitID] {
+			streakIDs[streakID] = true
+		}
+	}
+
+	audit.CheckIns = deleteWhere(s.checkIns, func(c models.HabitCheckIn) bool { return streakIDs[c.StreakID] })
+	audit.Streaks = deleteWhere(s.streaks, func(st models.HabitStreak) bool { return habitIDs[st.HabitID] })
+	audit.Achievements = deleteWhere(s.achievements, func(a models.Achievement) bool { return a.UserID == id })
+	audit.Habits = deleteWhere(s.habits, func(h models.Habit) bool { return h.UserID == id })
+	audit.Devices = deleteWhere(s.devices, func(d models.Device) bool { return d.UserID == id })
+	deleteWhere(s.digestSends, func(d models.DigestSend) bool { return d.UserID == id })
+	deleteWhere(s.identities, func(i models.Identity) bool { return i.UserID == id })
+	deleteWhere(s.verifications, func(v models.EmailVerification) bool { return v.UserID == id })
+	deleteWhere(s.passwordResets, func(p models.PasswordReset) bool { return p.UserID == id })
+	deleteWhere(s.events, func(e models.SecurityEvent) bool { return e.UserID == id })
+	delete(s.totp, id)
+	deleteWhere(s.recoveryCodes, func(c models.RecoveryCode) bool { return c.UserID == id })
+	deleteWhere(s.challenges, func(c models.MFAChallenge) bool { return c.UserID == id })
+	delete(s.users, id)
+
+	audit.ID = s.id("erasure_audits")
+	if audit.CreatedAt.IsZero() {
+		audit.CreatedAt = now()
+	}
+	s.erasures[audit.ID] = *audit
+	return nil
+}
+
+// find returns the users matching the filter ordered by ID
+func (r *UserRepository) find(ctx context.Context, match func(models.User) bool) ([]models.User, error) {
+	if err := checkContext(ctx); err != nil {
+		return nil, err
+	}
+
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	users := []models.User{}
+	for _, user := range r.store.users {
+		if match(user) {
+			users = append(users, user)
+		}
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].ID < users[j].ID })
+	return users, nil
+}
+
+// validate enforces the unique email index and the digest day check. The
+// caller must hold the lock.
+func (r *UserRepository) validate(user *models.User) error {
+	if user.WeeklyDigestDay < 0 || user.WeeklyDigestDay > 6 {
+		return constraint("weekly_digest_day %d out of range", user.WeeklyDigestDay)
+	}
+	for id, existing := range r.store.users {
+		if id != user.ID && existing.Email == user.Email {
+			return constraint("email %q already exists", user.Email)
+		}
+	}
+	return nil
+}
+
+// deleteWhere permanently removes the matching rows and returns how many were removed
+func deleteWhere[T any](table map[uint]T, match func(T) bool) int64 {
+	var n int64
+	for id, row := range table {
+		if match(row) {
+			delete(table, id)
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,201 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/models"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// GormMFARepository implements MFARepository using GORM
+type GormMFARepository struct {
+	db *gorm.DB
+}
+
+// NewMFARepository creates a new MFA repository
+func NewMFARepository(db *gorm.DB) MFARepository {
+	return &GormMFARepository{db: db}
+}
+
+// FindTOTP finds the user's authenticator credential
+func (r *GormMFARepository) FindTOTP(ctx context.Context, userID uint) (*models.TOTPCredential, error) {
+	var credential models.TOTPCredential
+	result := r.db.WithContext(ctx).Where("user_id = ?", userID).First(&credential)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		log.Ctx(ctx).Error().Err(result.Error).Uint("userID", userID).Msg("Failed to find TOTP credential")
+		return nil, result.Error
+	}
+	return &credential, nil
+}
+
+// SaveTOTP creates the user's credential or replaces the existing one
+func (r *GormMFARepository) SaveTOTP(ctx context.Context, credential *models.TOTPCredential) error {
+	result := r.db.WithContext(ctx).Omit("User").
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "user_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"secret", "confirmed_at", "last_used_step", "updated_at"}),
+		}).
+		Create(credential)
+	if result.Error != nil {
+		log.Ctx(ctx).Error().Err(result.Error).Uint("userID", credential.UserID).Msg("Failed to save TOTP credential")
+		return result.Error
+	}
+	return nil
+}
+
+// UseTOTPStep records the time step of an accepted code unless that step or a
+// later one was already used
+func (r *GormMFARepository) UseTOTPStep(ctx context.Context, userID uint, step int64) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&models.TOTPCredential{}).
+		Where("user_id = ? AND (last_used_step IS NULL OR last_used_step < ?)", userID, step).
+		Update("last_used_step", step)
+	if result.Error != nil {
+		log.Ctx(ctx).Error().Err(result.Error).Uint("userID", userID).Msg("Failed to record TOTP step")
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// Enable confirms the credential, replaces the recovery codes and marks MFA
+// enabled on the user in one transaction
+func (r *GormMFARepository) Enable(ctx context.Context, userID uint, at time.Time, codes []models.RecoveryCode) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.TOTPCredential{}).Where("user_id = ?", userID).Update("confirmed_at", at).Error; err != nil {
+			return err
+		}
+		if err := replaceRecoveryCodes(tx, userID, codes); err != nil {
+			return err
+		}
+		return tx.Model(&models.User{}).Where("id = ?", userID).Update("mfa_enabled_at", at).Error
+	})
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Uint("userID", userID).Msg("Failed to enable MFA")
+		return err
+	}
+	return nil
+}
+
+// Disable removes the credential, recovery codes and challenges and marks MFA
+// disabled on the user in one transaction
+func (r *GormMFARepository) Disable(ctx context.Context, userID uint) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&models.TOTPCredential{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", userID).Delete(&models.MFAChallenge{}).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.User{}).Where("id = ?", userID).Update("mfa_enabled_at", nil).Error
+	})
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Uint("userID", userID).Msg("Failed to disable MFA")
+		return err
+	}
+	return nil
+}
+
+// ReplaceRecoveryCodes replaces all of the user's recovery codes
+func (r *GormMFARepository) ReplaceRecoveryCodes(ctx context.Context, userID uint, codes []models.RecoveryCode) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return replaceRecoveryCodes(tx, userID, codes)
+	})
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Uint("userID", userID).Msg("Failed to replace recovery codes")
+		return err
+	}
+	return nil
+}
+
+// replaceRecoveryCodes deletes the user's recovery codes and creates the new
+// ones within the transaction
+func replaceRecoveryCodes(tx *gorm.DB, userID uint, codes []models.RecoveryCode) error {
+	if err := tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+		return err
+	}
+	if len(codes) == 0 {
+		return nil
+	}
+	for i := range codes {
+		codes[i].UserID = userID
+	}
+	return tx.Omit("User").Create(&codes).Error
+}
+
+// UseRecoveryCode marks the user's unused code with the hash as used
+func (r *GormMFARepository) UseRecoveryCode(ctx context.Context, userID uint, codeHash string, at time.Time) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&models.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, codeHash).
+		Update("used_at", at)
+	if result.Error != nil {
+		log.Ctx(ctx).Error().Err(result.Error).Uint("userID", userID).Msg("Failed to use recovery code")
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// CountUnusedRecoveryCodes counts the user's recovery codes that are left
+func (r *GormMFARepository) CountUnusedRecoveryCodes(ctx context.Context, userID uint) (int64, error) {
+	var count int64
+	result := r.db.WithContext(ctx).Model(&models.RecoveryCode{}).
+		Where("user_id = ? AND used_at IS NULL", userID).
+		Count(&count)
+	if result.Error != nil {
+		log.Ctx(ctx).Error().Err(result.Error).Uint("userID", userID).Msg("Failed to count recovery codes")
+		return 0, result.Error
+	}
+	return count, nil
+}
+
+// CreateChallenge creates a pending login challenge
+func (r *GormMFARepository) CreateChallenge(ctx context.Context, challenge *models.MFAChallenge) error {
+	result := r.db.WithContext(ctx).Omit("User").Create(challenge)
+	if result.Error != nil {
+		log.Ctx(ctx).Error().Err(result.Error).Uint("userID", challenge.UserID).Msg("Failed to create MFA challenge")
+		return result.Error
+	}
+	return nil
+}
+
+// FindChallengeByTokenHash finds a pending login challenge by the hash of its token
+func (r *GormMFARepository) FindChallengeByTokenHash(ctx context.Context, tokenHash string) (*models.MFAChallenge, error) {
+	var challenge models.MFAChallenge
+	result := r.db.WithContext(ctx).Where("token_hash = ?", tokenHash).First(&challenge)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		log.Ctx(ctx).Error().Err(result.Error).Msg("Failed to find MFA challenge")
+		return nil, result.Error
+	}
+	return &challenge, nil
+}
+
+// DeleteChallenge deletes a challenge and reports whether it still existed
+func (r *GormMFARepository) DeleteChallenge(ctx context.Context, id uint) (bool, error) {
+	result := r.db.WithContext(ctx).Delete(&models.MFAChallenge{}, id)
+	if result.Error != nil {
+		log.Ctx(ctx).Error().Err(result.Error).Uint("id", id).Msg("Failed to delete MFA challenge")
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// DeleteExpiredChallenges deletes the user's challenges that expired by now
+func (r *GormMFARepository) DeleteExpiredChallenges(ctx context.Context, userID uint, now time.Time) error {
+	result := r.db.WithContext(ctx).Where("user_id = ? AND expires_at <= ?", userID, now).Delete(&models.MFAChallenge{})
+	if result.Error != nil {
+		log.Ctx(ctx).Error().Err(result.Error).Uint("userID", userID).Msg("Failed to delete expired MFA challenges")
+		return result.Error
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/models"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+// GormPasswordResetRepository implements PasswordResetRepository using GORM
+type GormPasswordResetRepository struct {
+	db *gorm.DB
+}
+
+// NewPasswordResetRepository creates a new password reset repository
+func NewPasswordResetRepository(db *gorm.DB) PasswordResetRepository {
+	return &GormPasswordResetRepository{db: db}
+}
+
+// Create creates a new pending password reset
+func (r *GormPasswordResetRepository) Create(ctx context.Context, reset *models.PasswordReset) error {
+	result := r.db.WithContext(ctx).Omit("User").Create(reset)
+	if result.Error != nil {
+		log.Ctx(ctx).Error().Err(result.Error).Msg("Failed to create password reset")
+		return result.Error
+	}
+	return nil
+}
+
+// FindByTokenHash finds a pending password reset by the hash of its token
+func (r *GormPasswordResetRepository) FindByTokenHash(ctx context.Context, tokenHash string) (*models.PasswordReset, error) {
+	var reset models.PasswordReset
+	result := r.db.WithContext(ctx).Where("token_hash = ?", tokenHash).First(&reset)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		log.Ctx(ctx).Error().Err(result.Error).Msg("Failed to find password reset")
+		return nil, result.Error
+	}
+	return &reset, nil
+}
+
+// DeleteByUserID deletes every pending password reset of a user
+func (r *GormPasswordResetRepository) DeleteByUserID(ctx context.Context, userID uint) error {
+	result := r.db.WithContext(ctx).Where("user_id = ?", userID).Delete(&models.PasswordReset{})
+	if result.Error != nil {
+		log.Ctx(ctx).Error().Err(result.Error).Uint("userID", userID).Msg("Failed to delete password resets")
+		return result.Error
+	}
+	return nil
+}
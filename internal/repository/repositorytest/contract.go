@@ -0,0 +1,1156 @@
+// Package repositorytest holds the contract every repository implementation
+// must satisfy. Implementations run RunContractTests from their own tests so
+// that the GORM and in-memory repositories cannot drift apart.
+package repositorytest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/models"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/repository"
+	"gorm.io/datatypes"
+)
+
+// Repositories is one set of repositories sharing the same empty storage
+type Repositories struct {
+	Users         repository.UserRepository
+	Habits        repository.HabitRepository
+	Streaks       repository.StreakRepository
+	CheckIns      repository.CheckInRepository
+	Achievements  repository.AchievementRepository
+	Devices       repository.DeviceRepository
+	Digests       repository.DigestRepository
+	Identities    repository.IdentityRepository
+	Verifications repository.EmailVerificationRepository
+	Resets        repository.PasswordResetRepository
+	Events        repository.SecurityEventRepository
+	MFA           repository.MFARepository
+}
+
+// Factory returns repositories backed by fresh, empty storage
+type Factory func(t *testing.T) Repositories
+
+// RunContractTests runs the contract suite against the repositories returned
+// by newRepos. Every subtest gets its own storage.
+func RunContractTests(t *testing.T, newRepos Factory) {
+	t.Run("Users", func(t *testing.T) { testUsers(t, newRepos) })
+	t.Run("Habits", func(t *testing.T) { testHabits(t, newRepos) })
+	t.Run("HabitHistory", func(t *testing.T) { testHabitHistory(t, newRepos) })
+	t.Run("Streaks", func(t *testing.T) { testStreaks(t, newRepos) })
+	t.Run("CheckIns", func(t *testing.T) { testCheckIns(t, newRepos) })
+	t.Run("Achievements", func(t *testing.T) { testAchievements(t, newRepos) })
+	t.Run("Devices", func(t *testing.T) { testDevices(t, newRepos) })
+	t.Run("Digests", func(t *testing.T) { testDigests(t, newRepos) })
+	t.Run("Identities", func(t *testing.T) { testIdentities(t, newRepos) })
+	t.Run("EmailVerifications", func(t *testing.T) { testEmailVerifications(t, newRepos) })
+	t.Run("PasswordResets", func(t *testing.T) { testPasswordResets(t, newRepos) })
+	t.Run("SecurityEvents", func(t *testing.T) { testSecurityEvents(t, newRepos) })
+	t.Run("MFA", func(t *testing.T) { testMFA(t, newRepos) })
+	t.Run("Purge", func(t *testing.T) { testPurge(t, newRepos) })
+	t.Run("CancelledContext", func(t *testing.T) { testCancelledContext(t, newRepos) })
+}
+
+// day returns midnight UTC of the given date
+func day(year int, month time.Month, d int) time.Time {
+	return time.Date(year, month, d, 0, 0, 0, 0, time.UTC)
+}
+
+func mustNoError(t *testing.T, err error, action string) {
+	t.Helper()
+	if err != nil {
+		t.Fatalf("%s: unexpected error: %v", action, err)
+	}
+}
+
+func createUser(t *testing.T, repos Repositories, email string) *models.User {
+	t.Helper()
+	user := &models.User{Email: email, PasswordHash: "hash", Name: "Test User"}
+	mustNoError(t, repos.Users.Create(context.Background(), user), "create user")
+	return user
+}
+
+func createHabit(t *testing.T, repos Repositories, userID uint, name string) *models.Habit {
+	t.Helper()
+	habit := &models.Habit{UserID: userID, Name: name, Color: "#FF0000", IsActive: true}
+	mustNoError(t, repos.Habits.Create(context.Background(), habit), "create habit")
+	return habit
+}
+
+func createStreak(t *testing.T, repos Repositories, habitID uint, status string) *models.HabitStreak {
+	t.Helper()
+	streak := &models.HabitStreak{HabitID: habitID, TargetDays: 7, StartDate: day(2024, 1, 1), Status: status}
+	mustNoError(t, repos.Streaks.Create(context.Background(), streak), "create streak")
+	return streak
+}
+
+func createCheckIn(t *testing.T, repos Repositories, streakID uint, date time.Time) *models.HabitCheckIn {
+	t.Helper()
+	checkIn := &models.HabitCheckIn{StreakID: streakID, CheckInDate: date}
+	mustNoError(t, repos.CheckIns.Create(context.Background(), checkIn), "create check-in")
+	return checkIn
+}
+
+func ids[T any](rows []T, id func(T) uint) []uint {
+	out := make([]uint, len(rows))
+	for i, row := range rows {
+		out[i] = id(row)
+	}
+	return out
+}
+
+func assertIDs(t *testing.T, what string, got, want []uint) {
+	t.Helper()
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("%s: got IDs %v, want %v", what, got, want)
+	}
+}
+
+func testUsers(t *testing.T, newRepos Factory) {
+	ctx := context.Background()
+	repos := newRepos(t)
+
+	user := createUser(t, repos, "ada@example.com")
+	if user.ID == 0 {
+		t.Fatal("Create did not assign an ID")
+	}
+	if user.CreatedAt.IsZero() || user.UpdatedAt.IsZero() {
+		t.Error("Create did not set the timestamps")
+	}
+
+	found, err := repos.Users.FindByID(ctx, user.ID)
+	mustNoError(t, err, "find user by ID")
+	if found == nil || found.Email != user.Email || found.Name != user.Name {
+		t.Fatalf("FindByID returned %+v, want the created user", found)
+	}
+
+	missing, err := repos.Users.FindByID(ctx, user.ID+100)
+	mustNoError(t, err, "find missing user")
+	if missing != nil {
+		t.Errorf("FindByID of a missing user returned %+v, want nil", missing)
+	}
+
+	found, err = repos.Users.FindByEmail(ctx, "ada@example.com")
+	mustNoError(t, err, "find user by email")
+	if found == nil || found.ID != user.ID {
+		t.Errorf("FindByEmail returned %+v, want user %d", found, user.ID)
+	}
+	found, err = repos.Users.FindByEmail(ctx, "nobody@example.com")
+	mustNoError(t, err, "find missing email")
+	if found != nil {
+		t.Errorf("FindByEmail of a missing email returned %+v, want nil", found)
+	}
+
+	if err := repos.Users.Create(ctx, &models.User{Email: "ada@example.com", PasswordHash: "hash", Name: "Dup"}); err == nil {
+		t.Error("Create with a duplicate email succeeded, want a unique constraint error")
+	}
+	if err := repos.Users.Create(ctx, &models.User{Email: "bad-day@example.com", PasswordHash: "hash", Name: "Bad", WeeklyDigestDay: 7}); err == nil {
+		t.Error("Create with weekly_digest_day 7 succeeded, want a check constraint error")
+	}
+
+	user.Name = "Ada Lovelace"
+	user.WeeklyDigestEnabled = true
+	user.WeeklyDigestDay = int(time.Monday)
+	mustNoError(t, repos.Users.Update(ctx, user), "update user")
+	found, err = repos.Users.FindByID(ctx, user.ID)
+	mustNoError(t, err, "find updated user")
+	if found.Name != "Ada Lovelace" || !found.WeeklyDigestEnabled || found.WeeklyDigestDay != int(time.Monday) {
+		t.Errorf("Update was not persisted, got %+v", found)
+	}
+
+	other := createUser(t, repos, "grace@example.com")
+	other.WeeklyDigestEnabled = true
+	other.WeeklyDigestDay = int(time.Monday)
+	mustNoError(t, repos.Users.Update(ctx, other), "update second user")
+	createUser(t, repos, "optout@example.com")
+
+	recipients, err := repos.Users.FindDigestRecipients(ctx, time.Monday)
+	mustNoError(t, err, "find digest recipients")
+	assertIDs(t, "FindDigestRecipients(Monday)", ids(recipients, func(u models.User) uint { return u.ID }), []uint{user.ID, other.ID})
+	recipients, err = repos.Users.FindDigestRecipients(ctx, time.Tuesday)
+	mustNoError(t, err, "find digest recipients on another day")
+	if len(recipients) != 0 {
+		t.Errorf("FindDigestRecipients(Tuesday) returned %d users, want 0", len(recipients))
+	}
+
+	// Deleted accounts disappear from lookups but keep their email reserved
+	purgeAfter := time.Now().UTC().Add(time.Hour).Truncate(time.Second)
+	other.PurgeAfter = &purgeAfter
+	mustNoError(t, repos.Users.Update(ctx, other), "schedule purge")
+	mustNoError(t, repos.Users.Delete(ctx, other.ID), "delete user")
+
+	found, err = repos.Users.FindByID(ctx, other.ID)
+	mustNoError(t, err, "find deleted user")
+	if found != nil {
+		t.Error("FindByID returned a deleted user")
+	}
+	found, err = repos.Users.FindByEmail(ctx, "grace@example.com")
+	mustNoError(t, err, "find deleted user by email")
+	if found != nil {
+		t.Error("FindByEmail returned a deleted user")
+	}
+	recipients, err = repos.Users.FindDigestRecipients(ctx, time.Monday)
+	mustNoError(t, err, "find digest recipients after delete")
+	assertIDs(t, "FindDigestRecipients after delete", ids(recipients, func(u models.User) uint { return u.ID }), []uint{user.ID})
+
+	exists, err := repos.Users.ExistsByEmail(ctx, "grace@example.com")
+	mustNoError(t, err, "check deleted email")
+	if !exists {
+		t.Error("ExistsByEmail did not report a deleted account awaiting purge")
+	}
+	exists, err = repos.Users.ExistsByEmail(ctx, "nobody@example.com")
+	mustNoError(t, err, "check unknown email")
+	if exists {
+		t.Error("ExistsByEmail reported an unknown email")
+	}
+
+	due, err := repos.Users.FindDueForPurge(ctx, purgeAfter.Add(-time.Minute))
+	mustNoError(t, err, "find due before grace period")
+	if len(due) != 0 {
+		t.Errorf("FindDueForPurge before the grace period returned %d users, want 0", len(due))
+	}
+	due, err = repos.Users.FindDueForPurge(ctx, purgeAfter.Add(time.Minute))
+	mustNoError(t, err, "find due after grace period")
+	assertIDs(t, "FindDueForPurge", ids(due, func(u models.User) uint { return u.ID }), []uint{other.ID})
+	if due[0].PurgeAfter == nil || !due[0].PurgeAfter.Equal(purgeAfter) {
+		t.Errorf("FindDueForPurge returned purge_after %v, want %v", due[0].PurgeAfter, purgeAfter)
+	}
+}
+
+func testHabits(t *testing.T, newRepos Factory) {
+	ctx := context.Background()
+	repos := newRepos(t)
+	user := createUser(t, repos, "habits@example.com")
+	other := createUser(t, repos, "other@example.com")
+
+	first := createHabit(t, repos, user.ID, "Read")
+	second := createHabit(t, repos, user.ID, "Run")
+	createHabit(t, repos, other.ID, "Swim")
+
+	if first.ID == 0 || second.ID <= first.ID {
+		t.Fatalf("Create assigned IDs %d and %d, want increasing IDs", first.ID, second.ID)
+	}
+
+	// A zero IsActive is replaced by the column default
+	inactive := &models.Habit{UserID: user.ID, Name: "Write"}
+	mustNoError(t, repos.Habits.Create(ctx, inactive), "create habit without is_active")
+	found, err := repos.Habits.FindByID(ctx, inactive.ID)
+	mustNoError(t, err, "find habit")
+	if found == nil || !found.IsActive {
+		t.Errorf("habit created with IsActive false was stored as %+v, want active", found)
+	}
+
+	habits, err := repos.Habits.FindByUserID(ctx, user.ID)
+	mustNoError(t, err, "find habits by user")
+	assertIDs(t, "FindByUserID", ids(habits, func(h models.Habit) uint { return h.ID }), []uint{first.ID, second.ID, inactive.ID})
+
+	habits, err = repos.Habits.FindByUserID(ctx, user.ID+100)
+	mustNoError(t, err, "find habits of unknown user")
+	if habits == nil || len(habits) != 0 {
+		t.Errorf("FindByUserID of an unknown user returned %#v, want an empty slice", habits)
+	}
+
+	second.Name = "Run 5k"
+	second.IsActive = false
+	mustNoError(t, repos.Habits.Update(ctx, second), "update habit")
+	found, err = repos.Habits.FindByID(ctx, second.ID)
+	mustNoError(t, err, "find updated habit")
+	if found.Name != "Run 5k" || found.IsActive {
+		t.Errorf("Update was not persisted, got %+v", found)
+	}
+	if found.UpdatedAt.Before(found.CreatedAt) {
+		t.Errorf("UpdatedAt %v is before CreatedAt %v", found.UpdatedAt, found.CreatedAt)
+	}
+
+	if err := repos.Habits.Create(ctx, &models.Habit{UserID: user.ID + 100, Name: "Orphan"}); err == nil {
+		t.Error("Create for a missing user succeeded, want a foreign key error")
+	}
+
+	mustNoError(t, repos.Habits.Delete(ctx, first.ID), "delete habit")
+	found, err = repos.Habits.FindByID(ctx, first.ID)
+	mustNoError(t, err, "find deleted habit")
+	if found != nil {
+		t.Error("FindByID returned a deleted habit")
+	}
+	habits, err = repos.Habits.FindByUserID(ctx, user.ID)
+	mustNoError(t, err, "find habits after delete")
+	assertIDs(t, "FindByUserID after delete", ids(habits, func(h models.Habit) uint { return h.ID }), []uint{second.ID, inactive.ID})
+
+	mustNoError(t, repos.Habits.Delete(ctx, first.ID+100), "delete missing habit")
+}
+
+func testHabitHistory(t *testing.T, newRepos Factory) {
+	ctx := context.Background()
+	repos := newRepos(t)
+	user := createUser(t, repos, "history@example.com")
+
+	history := func(fail bool) []repository.StreakHistory {
+		return []repository.StreakHistory{
+			{
+				Streak: models.HabitStreak{TargetDays: 3, StartDate: day(2024, 1, 1), Status: "completed"},
+				CheckIns: []models.HabitCheckIn{
+					{CheckInDate: day(2024, 1, 1)},
+					{CheckInDate: day(2024, 1, 2)},
+					{CheckInDate: day(2024, 1, 3)},
+				},
+				Achievement: func(streak *models.HabitStreak) (*models.Achievement, error) {
+					if fail {
+						return nil, errors.New("achievement failed")
+					}
+					return &models.Achievement{UserID: user.ID, AchievementType: "streak_completed", TargetDays: streak.TargetDays}, nil
+				},
+			},
+			{
+				Streak:   models.HabitStreak{TargetDays: 3, StartDate: day(2024, 2, 1), Status: "active"},
+				CheckIns: []models.HabitCheckIn{{CheckInDate: day(2024, 2, 1)}},
+			},
+		}
+	}
+
+	habit := &models.Habit{UserID: user.ID, Name: "Imported", Color: "#00FF00", IsActive: true}
+	mustNoError(t, repos.Habits.CreateWithHistory(ctx, habit, history(false)), "create habit with history")
+
+	streaks, err := repos.Streaks.FindByHabitID(ctx, habit.ID)
+	mustNoError(t, err, "find imported streaks")
+	if len(streaks) != 2 {
+		t.Fatalf("CreateWithHistory stored %d streaks, want 2", len(streaks))
+	}
+	for _, streak := range streaks {
+		checkIns, err := repos.CheckIns.FindByStreakID(ctx, streak.ID)
+		mustNoError(t, err, "find imported check-ins")
+		if want := map[string]int{"completed": 3, "active": 1}[streak.Status]; len(checkIns) != want {
+			t.Errorf("%s streak has %d check-ins, want %d", streak.Status, len(checkIns), want)
+		}
+	}
+	achievements, err := repos.Achievements.FindByHabitID(ctx, habit.ID)
+	mustNoError(t, err, "find imported achievements")
+	if len(achievements) != 1 || achievements[0].TargetDays != 3 {
+		t.Errorf("CreateWithHistory stored achievements %+v, want one for the completed streak", achievements)
+	}
+
+	// A failure part way leaves none of the habit behind
+	failed := &models.Habit{UserID: user.ID, Name: "Broken", Color: "#00FF00", IsActive: true}
+	if err := repos.Habits.CreateWithHistory(ctx, failed, history(true)); err == nil {
+		t.Fatal("CreateWithHistory succeeded although the achievement failed")
+	}
+	habits, err := repos.Habits.FindByUserID(ctx, user.ID)
+	mustNoError(t, err, "find habits after failed import")
+	assertIDs(t, "FindByUserID after failed import", ids(habits, func(h models.Habit) uint { return h.ID }), []uint{habit.ID})
+	if failed.ID != 0 {
+		streaks, err = repos.Streaks.FindByHabitID(ctx, failed.ID)
+		mustNoError(t, err, "find streaks of failed import")
+		if len(streaks) != 0 {
+			t.Errorf("failed import left %d streaks behind", len(streaks))
+		}
+	}
+}
+
+func testStreaks(t *testing.T, newRepos Factory) {
+	ctx := context.Background()
+	repos := newRepos(t)
+	user := createUser(t, repos, "streaks@example.com")
+	habit := createHabit(t, repos, user.ID, "Meditate")
+
+	defaulted := &models.HabitStreak{HabitID: habit.ID, TargetDays: 3, StartDate: day(2024, 1, 1)}
+	mustNoError(t, repos.Streaks.Create(ctx, defaulted), "create streak without status")
+	if defaulted.Status != "active" {
+		t.Errorf("streak created without status has status %q, want active", defaulted.Status)
+	}
+
+	active, err := repos.Streaks.FindActiveByHabitID(ctx, habit.ID)
+	mustNoError(t, err, "find active streak")
+	if active == nil || active.ID != defaulted.ID {
+		t.Fatalf("FindActiveByHabitID returned %+v, want streak %d", active, defaulted.ID)
+	}
+
+	defaulted.Status = "failed"
+	failedAt := day(2024, 1, 5)
+	defaulted.FailedAt = &failedAt
+	mustNoError(t, repos.Streaks.Update(ctx, defaulted), "fail streak")
+
+	found, err := repos.Streaks.FindByID(ctx, defaulted.ID)
+	mustNoError(t, err, "find failed streak")
+	if found.Status != "failed" || found.FailedAt == nil || !found.FailedAt.Equal(failedAt) {
+		t.Errorf("Update was not persisted, got %+v", found)
+	}
+
+	active, err = repos.Streaks.FindActiveByHabitID(ctx, habit.ID)
+	mustNoError(t, err, "find active streak after failing")
+	if active != nil {
+		t.Errorf("FindActiveByHabitID returned %+v, want nil", active)
+	}
+
+	completed := createStreak(t, repos, habit.ID, "completed")
+	current := createStreak(t, repos, habit.ID, "active")
+
+	// Newest first, so give the streaks distinct creation times
+	for i, streak := range []*models.HabitStreak{defaulted, completed, current} {
+		streak.CreatedAt = day(2024, 2, 1+i)
+		mustNoError(t, repos.Streaks.Update(ctx, streak), "set created_at")
+	}
+
+	streaks, err := repos.Streaks.FindByHabitID(ctx, habit.ID)
+	mustNoError(t, err, "find streaks by habit")
+	assertIDs(t, "FindByHabitID", ids(streaks, func(s models.HabitStreak) uint { return s.ID }), []uint{current.ID, completed.ID, defaulted.ID})
+
+	active, err = repos.Streaks.FindActiveByHabitID(ctx, habit.ID)
+	mustNoError(t, err, "find new active streak")
+	if active == nil || active.ID != current.ID {
+		t.Errorf("FindActiveByHabitID returned %+v, want streak %d", active, current.ID)
+	}
+
+	for name, streak := range map[string]*models.HabitStreak{
+		"zero target":    {HabitID: habit.ID, TargetDays: 0, StartDate: day(2024, 1, 1)},
+		"unknown status": {HabitID: habit.ID, TargetDays: 7, StartDate: day(2024, 1, 1), Status: "paused"},
+		"missing habit":  {HabitID: habit.ID + 100, TargetDays: 7, StartDate: day(2024, 1, 1)},
+	} {
+		if err := repos.Streaks.Create(ctx, streak); err == nil {
+			t.Errorf("Create with %s succeeded, want a constraint error", name)
+		}
+	}
+
+	mustNoError(t, repos.Streaks.Delete(ctx, current.ID), "delete streak")
+	found, err = repos.Streaks.FindByID(ctx, current.ID)
+	mustNoError(t, err, "find deleted streak")
+	if found != nil {
+		t.Error("FindByID returned a deleted streak")
+	}
+	active, err = repos.Streaks.FindActiveByHabitID(ctx, habit.ID)
+	mustNoError(t, err, "find active streak after delete")
+	if active != nil {
+		t.Errorf("FindActiveByHabitID returned deleted streak %d", active.ID)
+	}
+}
+
+func testCheckIns(t *testing.T, newRepos Factory) {
+	ctx := context.Background()
+	repos := newRepos(t)
+	user := createUser(t, repos, "checkins@example.com")
+	habit := createHabit(t, repos, user.ID, "Stretch")
+	streak := createStreak(t, repos, habit.ID, "active")
+	otherStreak := createStreak(t, repos, habit.ID, "completed")
+
+	// Created out of date order so that ID order and date order differ
+	second := createCheckIn(t, repos, streak.ID, day(2024, 3, 2))
+	first := createCheckIn(t, repos, streak.ID, day(2024, 3, 1))
+	third := createCheckIn(t, repos, streak.ID, day(2024, 3, 3).Add(15*time.Hour))
+	createCheckIn(t, repos, otherStreak.ID, day(2024, 3, 10))
+
+	if first.CheckedInAt.IsZero() || first.CreatedAt.IsZero() {
+		t.Error("Create did not set checked_in_at and created_at")
+	}
+
+	found, err := repos.CheckIns.FindByID(ctx, third.ID)
+	mustNoError(t, err, "find check-in")
+	if found == nil || !found.CheckInDate.Equal(third.CheckInDate) {
+		t.Fatalf("FindByID returned %+v, want check-in %d", found, third.ID)
+	}
+
+	checkIns, err := repos.CheckIns.FindByStreakID(ctx, streak.ID)
+	mustNoError(t, err, "find check-ins by streak")
+	assertIDs(t, "FindByStreakID", ids(checkIns, func(c models.HabitCheckIn) uint { return c.ID }), []uint{third.ID, second.ID, first.ID})
+
+	latest, err := repos.CheckIns.FindLatestByStreakID(ctx, streak.ID)
+	mustNoError(t, err, "find latest check-in")
+	if latest == nil || latest.ID != third.ID {
+		t.Errorf("FindLatestByStreakID returned %+v, want check-in %d", latest, third.ID)
+	}
+
+	byDate, err := repos.CheckIns.FindByDate(ctx, streak.ID, "2024-03-03")
+	mustNoError(t, err, "find check-in by date")
+	if byDate == nil || byDate.ID != third.ID {
+		t.Errorf("FindByDate matched %+v, want check-in %d", byDate, third.ID)
+	}
+	byDate, err = repos.CheckIns.FindByDate(ctx, streak.ID, "2024-03-04")
+	mustNoError(t, err, "find check-in on a day without one")
+	if byDate != nil {
+		t.Errorf("FindByDate on a day without a check-in returned %+v, want nil", byDate)
+	}
+	if _, err := repos.CheckIns.FindByDate(ctx, streak.ID, "03/03/2024"); err == nil {
+		t.Error("FindByDate with a malformed date succeeded, want an error")
+	}
+
+	var batches [][]uint
+	err = repos.CheckIns.FindByStreakIDInBatches(ctx, streak.ID, 2, func(batch []models.HabitCheckIn) error {
+		batches = append(batches, ids(batch, func(c models.HabitCheckIn) uint { return c.ID }))
+		return nil
+	})
+	mustNoError(t, err, "find check-ins in batches")
+	if want := fmt.Sprint([][]uint{{second.ID, first.ID}, {third.ID}}); fmt.Sprint(batches) != want {
+		t.Errorf("FindByStreakIDInBatches produced batches %v, want %v", batches, want)
+	}
+
+	stop := errors.New("stop")
+	calls := 0
+	err = repos.CheckIns.FindByStreakIDInBatches(ctx, streak.ID, 1, func([]models.HabitCheckIn) error {
+		calls++
+		return stop
+	})
+	if !errors.Is(err, stop) || calls != 1 {
+		t.Errorf("FindByStreakIDInBatches returned %v after %d calls, want the callback error after 1 call", err, calls)
+	}
+
+	calls = 0
+	err = repos.CheckIns.FindByStreakIDInBatches(ctx, streak.ID+100, 2, func([]models.HabitCheckIn) error {
+		calls++
+		return nil
+	})
+	mustNoError(t, err, "find batches of an unknown streak")
+	if calls != 0 {
+		t.Errorf("FindByStreakIDInBatches called fn %d times for a streak without check-ins", calls)
+	}
+
+	if err := repos.CheckIns.Create(ctx, &models.HabitCheckIn{StreakID: streak.ID + 100, CheckInDate: day(2024, 3, 1)}); err == nil {
+		t.Error("Create for a missing streak succeeded, want a foreign key error")
+	}
+
+	mustNoError(t, repos.CheckIns.Delete(ctx, third.ID), "delete check-in")
+	found, err = repos.CheckIns.FindByID(ctx, third.ID)
+	mustNoError(t, err, "find deleted check-in")
+	if found != nil {
+		t.Error("FindByID returned a deleted check-in")
+	}
+	latest, err = repos.CheckIns.FindLatestByStreakID(ctx, streak.ID)
+	mustNoError(t, err, "find latest after delete")
+	if latest == nil || latest.ID != second.ID {
+		t.Errorf("FindLatestByStreakID after delete returned %+v, want check-in %d", latest, second.ID)
+	}
+
+	latest, err = repos.CheckIns.FindLatestByStreakID(ctx, streak.ID+100)
+	mustNoError(t, err, "find latest of an unknown streak")
+	if latest != nil {
+		t.Errorf("FindLatestByStreakID of an unknown streak returned %+v, want nil", latest)
+	}
+}
+
+func testAchievements(t *testing.T, newRepos Factory) {
+	ctx := context.Background()
+	repos := newRepos(t)
+	user := createUser(t, repos, "achievements@example.com")
+	habit := createHabit(t, repos, user.ID, "Journal")
+	otherHabit := createHabit(t, repos, user.ID, "Walk")
+
+	create := func(habitID uint, achievedAt time.Time) *models.Achievement {
+		t.Helper()
+		achievement := &models.Achievement{
+			UserID:          user.ID,
+			HabitID:         habitID,
+			AchievementType: "streak_completed",
+			TargetDays:      7,
+			AchievedAt:      achievedAt,
+			Metadata:        datatypes.JSON(`{"streak_id":1}`),
+		}
+		mustNoError(t, repos.Achievements.Create(ctx, achievement), "create achievement")
+		return achievement
+	}
+
+	older := create(habit.ID, day(2024, 4, 1))
+	newest := create(otherHabit.ID, day(2024, 4, 20))
+	middle := create(habit.ID, day(2024, 4, 10))
+
+	defaulted := &models.Achievement{UserID: user.ID, HabitID: habit.ID, AchievementType: "max_streak", TargetDays: 3}
+	mustNoError(t, repos.Achievements.Create(ctx, defaulted), "create achievement without achieved_at")
+	if defaulted.AchievedAt.IsZero() {
+		t.Error("Create did not set achieved_at")
+	}
+
+	found, err := repos.Achievements.FindByID(ctx, older.ID)
+	mustNoError(t, err, "find achievement")
+	if found == nil || !found.AchievedAt.Equal(older.AchievedAt) || string(found.Metadata) != `{"streak_id":1}` {
+		t.Fatalf("FindByID returned %+v, want achievement %d", found, older.ID)
+	}
+
+	achievements, err := repos.Achievements.FindByUserID(ctx, user.ID)
+	mustNoError(t, err, "find achievements by user")
+	assertIDs(t, "FindByUserID", ids(achievements, func(a models.Achievement) uint { return a.ID }), []uint{defaulted.ID, newest.ID, middle.ID, older.ID})
+
+	achievements, err = repos.Achievements.FindByHabitID(ctx, habit.ID)
+	mustNoError(t, err, "find achievements by habit")
+	assertIDs(t, "FindByHabitID", ids(achievements, func(a models.Achievement) uint { return a.ID }), []uint{defaulted.ID, middle.ID, older.ID})
+
+	if err := repos.Achievements.Create(ctx, &models.Achievement{UserID: user.ID, HabitID: habit.ID + 100, AchievementType: "x", TargetDays: 1}); err == nil {
+		t.Error("Create for a missing habit succeeded, want a foreign key error")
+	}
+
+	mustNoError(t, repos.Achievements.Delete(ctx, middle.ID), "delete achievement")
+	found, err = repos.Achievements.FindByID(ctx, middle.ID)
+	mustNoError(t, err, "find deleted achievement")
+	if found != nil {
+		t.Error("FindByID returned a deleted achievement")
+	}
+	achievements, err = repos.Achievements.FindByHabitID(ctx, habit.ID)
+	mustNoError(t, err, "find achievements after delete")
+	assertIDs(t, "FindByHabitID after delete", ids(achievements, func(a models.Achievement) uint { return a.ID }), []uint{defaulted.ID, older.ID})
+}
+
+func testDevices(t *testing.T, newRepos Factory) {
+	ctx := context.Background()
+	repos := newRepos(t)
+	user := createUser(t, repos, "devices@example.com")
+
+	create := func(token, platform string, lastSeen time.Time) *models.Device {
+		t.Helper()
+		device := &models.Device{UserID: user.ID, Token: token, Platform: platform, LastSeenAt: lastSeen}
+		mustNoError(t, repos.Devices.Create(ctx, device), "create device")
+		return device
+	}
+
+	phone := create("token-phone", models.PlatformIOS, day(2024, 5, 1))
+	tablet := create("token-tablet", models.PlatformAndroid, day(2024, 5, 3))
+
+	found, err := repos.Devices.FindByToken(ctx, "token-phone")
+	mustNoError(t, err, "find device by token")
+	if found == nil || found.ID != phone.ID {
+		t.Fatalf("FindByToken returned %+v, want device %d", found, phone.ID)
+	}
+	found, err = repos.Devices.FindByToken(ctx, "unknown")
+	mustNoError(t, err, "find unknown token")
+	if found != nil {
+		t.Errorf("FindByToken of an unknown token returned %+v, want nil", found)
+	}
+
+	devices, err := repos.Devices.FindByUserID(ctx, user.ID)
+	mustNoError(t, err, "find devices by user")
+	assertIDs(t, "FindByUserID", ids(devices, func(d models.Device) uint { return d.ID }), []uint{tablet.ID, phone.ID})
+
+	phone.LastSeenAt = day(2024, 5, 5)
+	phone.AppVersion = "2.0.0"
+	mustNoError(t, repos.Devices.Update(ctx, phone), "update device")
+	devices, err = repos.Devices.FindByUserID(ctx, user.ID)
+	mustNoError(t, err, "find devices after update")
+	assertIDs(t, "FindByUserID after update", ids(devices, func(d models.Device) uint { return d.ID }), []uint{phone.ID, tablet.ID})
+	if devices[0].AppVersion != "2.0.0" {
+		t.Errorf("Update was not persisted, got app version %q", devices[0].AppVersion)
+	}
+
+	for name, device := range map[string]*models.Device{
+		"duplicate token":  {UserID: user.ID, Token: "token-phone", Platform: models.PlatformIOS, LastSeenAt: day(2024, 5, 1)},
+		"unknown platform": {UserID: user.ID, Token: "token-web", Platform: "web", LastSeenAt: day(2024, 5, 1)},
+		"missing user":     {UserID: user.ID + 100, Token: "token-orphan", Platform: models.PlatformIOS, LastSeenAt: day(2024, 5, 1)},
+	} {
+		if err := repos.Devices.Create(ctx, device); err == nil {
+			t.Errorf("Create with %s succeeded, want a constraint error", name)
+		}
+	}
+
+	// Devices are hard deleted, so the token can be registered again
+	mustNoError(t, repos.Devices.Delete(ctx, phone.ID), "delete device")
+	found, err = repos.Devices.FindByID(ctx, phone.ID)
+	mustNoError(t, err, "find deleted device")
+	if found != nil {
+		t.Error("FindByID returned a deleted device")
+	}
+	create("token-phone", models.PlatformIOS, day(2024, 5, 6))
+}
+
+func testDigests(t *testing.T, newRepos Factory) {
+	ctx := context.Background()
+	repos := newRepos(t)
+	user := createUser(t, repos, "digests@example.com")
+	other := createUser(t, repos, "digests2@example.com")
+
+	period := day(2024, 6, 3)
+	send := &models.DigestSend{UserID: user.ID, PeriodStart: period, PeriodEnd: period.AddDate(0, 0, 7)}
+	claimed, err := repos.Digests.Claim(ctx, send, period)
+	mustNoError(t, err, "claim digest")
+	if !claimed || send.ID == 0 {
+		t.Fatalf("Claim returned %v with ID %d, want a new claim", claimed, send.ID)
+	}
+
+	claimed, err = repos.Digests.Claim(ctx, &models.DigestSend{UserID: user.ID, PeriodStart: period, PeriodEnd: period.AddDate(0, 0, 7)}, period)
+	mustNoError(t, err, "claim the same period")
+	if claimed {
+		t.Error("Claim of an already claimed period returned true")
+	}
+
+	claimed, err = repos.Digests.Claim(ctx, &models.DigestSend{UserID: other.ID, PeriodStart: period, PeriodEnd: period.AddDate(0, 0, 7)}, period)
+	mustNoError(t, err, "claim the period for another user")
+	if !claimed {
+		t.Error("Claim of the same period for another user returned false")
+	}
+
+	mustNoError(t, repos.Digests.MarkSent(ctx, send.ID, time.Now().UTC()), "mark digest sent")
+
+	// Deleting the record releases the period for a retry
+	mustNoError(t, repos.Digests.Delete(ctx, send.ID), "delete digest send")
+	claimed, err = repos.Digests.Claim(ctx, &models.DigestSend{UserID: user.ID, PeriodStart: period, PeriodEnd: period.AddDate(0, 0, 7)}, period)
+	mustNoError(t, err, "claim a released period")
+	if !claimed {
+		t.Error("Claim of a released period returned false")
+	}
+
+	// An unsent claim is only taken over once it is older than staleBefore
+	next := period.AddDate(0, 0, 7)
+	stuck := &models.DigestSend{UserID: other.ID, PeriodStart: next, PeriodEnd: next.AddDate(0, 0, 7), CreatedAt: next}
+	claimed, err = repos.Digests.Claim(ctx, stuck, next)
+	mustNoError(t, err, "claim the next period")
+	if !claimed {
+		t.Fatal("Claim of the next period returned false")
+	}
+	claimed, err = repos.Digests.Claim(ctx, &models.DigestSend{UserID: other.ID, PeriodStart: next, PeriodEnd: next.AddDate(0, 0, 7), CreatedAt: next.Add(30 * time.Minute)}, next)
+	mustNoError(t, err, "claim a fresh unsent period")
+	if claimed {
+		t.Error("Claim of a fresh unsent period returned true")
+	}
+	takeover := &models.DigestSend{UserID: other.ID, PeriodStart: next, PeriodEnd: next.AddDate(0, 0, 8), CreatedAt: next.Add(2 * time.Hour)}
+	claimed, err = repos.Digests.Claim(ctx, takeover, next.Add(time.Hour))
+	mustNoError(t, err, "claim a stale unsent period")
+	if !claimed || takeover.ID != stuck.ID {
+		t.Errorf("Claim of a stale period returned %v with ID %d, want the claim %d taken over", claimed, takeover.ID, stuck.ID)
+	}
+	claimed, err = repos.Digests.Claim(ctx, &models.DigestSend{UserID: other.ID, PeriodStart: next, PeriodEnd: next.AddDate(0, 0, 7), CreatedAt: next.Add(3 * time.Hour)}, next.Add(2*time.Hour))
+	mustNoError(t, err, "claim a period just taken over")
+	if claimed {
+		t.Error("Claim right after a takeover returned true, want the new claim to hold the lease")
+	}
+
+	// Sent digests are never taken over
+	mustNoError(t, repos.Digests.MarkSent(ctx, takeover.ID, next.Add(2*time.Hour)), "mark taken over digest sent")
+	claimed, err = repos.Digests.Claim(ctx, &models.DigestSend{UserID: other.ID, PeriodStart: next, PeriodEnd: next.AddDate(0, 0, 7), CreatedAt: next.AddDate(0, 0, 1)}, next.AddDate(0, 0, 1))
+	mustNoError(t, err, "claim a sent period")
+	if claimed {
+		t.Error("Claim of a sent period returned true")
+	}
+}
+
+func testIdentities(t *testing.T, newRepos Factory) {
+	ctx := context.Background()
+	repos := newRepos(t)
+	user := createUser(t, repos, "identities@example.com")
+
+	google := &models.Identity{UserID: user.ID, Provider: models.ProviderGoogle, Subject: "google-1", Email: user.Email, LastLoginAt: day(2024, 8, 1)}
+	mustNoError(t, repos.Identities.Create(ctx, google), "create identity")
+	apple := &models.Identity{UserID: user.ID, Provider: models.ProviderApple, Subject: "apple-1", LastLoginAt: day(2024, 8, 2)}
+	mustNoError(t, repos.Identities.Create(ctx, apple), "create second identity")
+
+	found, err := repos.Identities.FindByProviderSubject(ctx, models.ProviderGoogle, "google-1")
+	mustNoError(t, err, "find identity")
+	if found == nil || found.ID != google.ID || found.UserID != user.ID {
+		t.Fatalf("FindByProviderSubject returned %+v, want identity %d", found, google.ID)
+	}
+	// Subjects are only unique per provider
+	found, err = repos.Identities.FindByProviderSubject(ctx, models.ProviderApple, "google-1")
+	mustNoError(t, err, "find identity of another provider")
+	if found != nil {
+		t.Errorf("FindByProviderSubject matched another provider's subject: %+v", found)
+	}
+
+	identities, err := repos.Identities.FindByUserID(ctx, user.ID)
+	mustNoError(t, err, "find identities by user")
+	assertIDs(t, "FindByUserID", ids(identities, func(i models.Identity) uint { return i.ID }), []uint{google.ID, apple.ID})
+
+	google.LastLoginAt = day(2024, 8, 5)
+	google.Email = "renamed@example.com"
+	mustNoError(t, repos.Identities.Update(ctx, google), "update identity")
+	found, err = repos.Identities.FindByProviderSubject(ctx, models.ProviderGoogle, "google-1")
+	mustNoError(t, err, "find updated identity")
+	if found.Email != "renamed@example.com" || !found.LastLoginAt.Equal(day(2024, 8, 5)) {
+		t.Errorf("Update was not persisted, got %+v", found)
+	}
+
+	for name, identity := range map[string]*models.Identity{
+		"duplicate subject": {UserID: user.ID, Provider: models.ProviderGoogle, Subject: "google-1", LastLoginAt: day(2024, 8, 1)},
+		"unknown provider":  {UserID: user.ID, Provider: "github", Subject: "github-1", LastLoginAt: day(2024, 8, 1)},
+		"missing user":      {UserID: user.ID + 100, Provider: models.ProviderGoogle, Subject: "google-2", LastLoginAt: day(2024, 8, 1)},
+	} {
+		if err := repos.Identities.Create(ctx, identity); err == nil {
+			t.Errorf("Create with %s succeeded, want a constraint error", name)
+		}
+	}
+
+	created := &models.User{Email: "social@example.com", Name: "Social"}
+	identity := &models.Identity{Provider: models.ProviderApple, Subject: "apple-2", LastLoginAt: day(2024, 8, 3)}
+	mustNoError(t, repos.Identities.CreateWithUser(ctx, created, identity), "create user with identity")
+	if created.ID == 0 || identity.UserID != created.ID {
+		t.Fatalf("CreateWithUser linked the identity to user %d, want %d", identity.UserID, created.ID)
+	}
+
+	// A failed identity leaves no user behind
+	orphan := &models.User{Email: "orphan@example.com", Name: "Orphan"}
+	if err := repos.Identities.CreateWithUser(ctx, orphan, &models.Identity{Provider: models.ProviderApple, Subject: "apple-2", LastLoginAt: day(2024, 8, 3)}); err == nil {
+		t.Fatal("CreateWithUser with a duplicate subject succeeded")
+	}
+	exists, err := repos.Users.ExistsByEmail(ctx, "orphan@example.com")
+	mustNoError(t, err, "check rolled back user")
+	if exists {
+		t.Error("CreateWithUser kept the user after the identity failed")
+	}
+}
+
+func testEmailVerifications(t *testing.T, newRepos Factory) {
+	ctx := context.Background()
+	repos := newRepos(t)
+	user := createUser(t, repos, "verify@example.com")
+	other := createUser(t, repos, "verify2@example.com")
+
+	create := func(userID uint, hash string, created time.Time) *models.EmailVerification {
+		t.Helper()
+		verification := &models.EmailVerification{UserID: userID, TokenHash: hash, ExpiresAt: created.Add(48 * time.Hour), CreatedAt: created}
+		mustNoError(t, repos.Verifications.Create(ctx, verification), "create email verification")
+		return verification
+	}
+
+	first := create(user.ID, "hash-1", day(2024, 9, 1))
+	second := create(user.ID, "hash-2", day(2024, 9, 2))
+	third := create(user.ID, "hash-3", day(2024, 9, 3))
+	kept := create(other.ID, "hash-4", day(2024, 9, 2))
+
+	found, err := repos.Verifications.FindByTokenHash(ctx, "hash-2")
+	mustNoError(t, err, "find verification by token hash")
+	if found == nil || found.ID != second.ID || found.UserID != user.ID {
+		t.Fatalf("FindByTokenHash returned %+v, want verification %d", found, second.ID)
+	}
+
+	recent, err := repos.Verifications.FindByUserIDSince(ctx, user.ID, day(2024, 9, 2))
+	mustNoError(t, err, "find verifications since")
+	assertIDs(t, "FindByUserIDSince", ids(recent, func(v models.EmailVerification) uint { return v.ID }), []uint{third.ID, second.ID})
+	all, err := repos.Verifications.FindByUserIDSince(ctx, user.ID, time.Time{})
+	mustNoError(t, err, "find all verifications")
+	assertIDs(t, "FindByUserIDSince(zero)", ids(all, func(v models.EmailVerification) uint { return v.ID }), []uint{third.ID, second.ID, first.ID})
+
+	for name, verification := range map[string]*models.EmailVerification{
+		"duplicate token hash": {UserID: user.ID, TokenHash: "hash-1", ExpiresAt: day(2024, 9, 5)},
+		"missing user":         {UserID: user.ID + 100, TokenHash: "hash-5", ExpiresAt: day(2024, 9, 5)},
+	} {
+		if err := repos.Verifications.Create(ctx, verification); err == nil {
+			t.Errorf("Create with %s succeeded, want a constraint error", name)
+		}
+	}
+
+	mustNoError(t, repos.Verifications.DeleteByUserID(ctx, user.ID), "delete verifications")
+	found, err = repos.Verifications.FindByTokenHash(ctx, "hash-3")
+	mustNoError(t, err, "find deleted verification")
+	if found != nil {
+		t.Error("FindByTokenHash returned a deleted verification")
+	}
+	found, err = repos.Verifications.FindByTokenHash(ctx, "hash-4")
+	mustNoError(t, err, "find other user's verification")
+	if found == nil || found.ID != kept.ID {
+		t.Error("DeleteByUserID removed another user's verification")
+	}
+}
+
+func testPasswordResets(t *testing.T, newRepos Factory) {
+	ctx := context.Background()
+	repos := newRepos(t)
+	user := createUser(t, repos, "reset@example.com")
+	other := createUser(t, repos, "reset2@example.com")
+
+	create := func(userID uint, hash string) *models.PasswordReset {
+		t.Helper()
+		reset := &models.PasswordReset{UserID: userID, TokenHash: hash, ExpiresAt: day(2024, 9, 2)}
+		mustNoError(t, repos.Resets.Create(ctx, reset), "create password reset")
+		return reset
+	}
+
+	create(user.ID, "reset-1")
+	second := create(user.ID, "reset-2")
+	kept := create(other.ID, "reset-3")
+
+	found, err := repos.Resets.FindByTokenHash(ctx, "reset-2")
+	mustNoError(t, err, "find password reset by token hash")
+	if found == nil || found.ID != second.ID || found.UserID != user.ID || !found.ExpiresAt.Equal(day(2024, 9, 2)) {
+		t.Fatalf("FindByTokenHash returned %+v, want password reset %d", found, second.ID)
+	}
+	found, err = repos.Resets.FindByTokenHash(ctx, "unknown")
+	mustNoError(t, err, "find unknown password reset")
+	if found != nil {
+		t.Errorf("FindByTokenHash of an unknown hash returned %+v, want nil", found)
+	}
+
+	for name, reset := range map[string]*models.PasswordReset{
+		"duplicate token hash": {UserID: user.ID, TokenHash: "reset-1", ExpiresAt: day(2024, 9, 5)},
+		"missing user":         {UserID: user.ID + 100, TokenHash: "reset-5", ExpiresAt: day(2024, 9, 5)},
+	} {
+		if err := repos.Resets.Create(ctx, reset); err == nil {
+			t.Errorf("Create with %s succeeded, want a constraint error", name)
+		}
+	}
+
+	mustNoError(t, repos.Resets.DeleteByUserID(ctx, user.ID), "delete password resets")
+	found, err = repos.Resets.FindByTokenHash(ctx, "reset-2")
+	mustNoError(t, err, "find deleted password reset")
+	if found != nil {
+		t.Error("FindByTokenHash returned a deleted password reset")
+	}
+	found, err = repos.Resets.FindByTokenHash(ctx, "reset-3")
+	mustNoError(t, err, "find other user's password reset")
+	if found == nil || found.ID != kept.ID {
+		t.Error("DeleteByUserID removed another user's password reset")
+	}
+}
+
+func testSecurityEvents(t *testing.T, newRepos Factory) {
+	ctx := context.Background()
+	repos := newRepos(t)
+	user := createUser(t, repos, "events@example.com")
+	other := createUser(t, repos, "events2@example.com")
+
+	create := func(userID uint, eventType string, created time.Time) *models.SecurityEvent {
+		t.Helper()
+		event := &models.SecurityEvent{UserID: userID, Type: eventType, IPAddress: "203.0.113.7", CreatedAt: created}
+		mustNoError(t, repos.Events.Create(ctx, event), "create security event")
+		return event
+	}
+
+	failed := create(user.ID, models.SecurityEventLoginFailed, day(2024, 9, 1))
+	locked := create(user.ID, models.SecurityEventAccountLocked, day(2024, 9, 1))
+	unlocked := create(user.ID, models.SecurityEventAccountUnlocked, day(2024, 9, 2))
+	create(other.ID, models.SecurityEventLoginSucceeded, day(2024, 9, 3))
+
+	events, err := repos.Events.FindByUserID(ctx, user.ID, 10)
+	mustNoError(t, err, "find security events")
+	assertIDs(t, "FindByUserID", ids(events, func(e models.SecurityEvent) uint { return e.ID }), []uint{unlocked.ID, locked.ID, failed.ID})
+	if events[0].Type != models.SecurityEventAccountUnlocked || events[0].IPAddress != "203.0.113.7" {
+		t.Errorf("FindByUserID returned %+v, want the stored event", events[0])
+	}
+
+	latest, err := repos.Events.FindByUserID(ctx, user.ID, 2)
+	mustNoError(t, err, "find latest security events")
+	assertIDs(t, "FindByUserID(limit 2)", ids(latest, func(e models.SecurityEvent) uint { return e.ID }), []uint{unlocked.ID, locked.ID})
+
+	if err := repos.Events.Create(ctx, &models.SecurityEvent{UserID: other.ID + 100, Type: models.SecurityEventLoginFailed}); err == nil {
+		t.Error("Create with a missing user succeeded, want a constraint error")
+	}
+}
+
+func testMFA(t *testing.T, newRepos Factory) {
+	ctx := context.Background()
+	repos := newRepos(t)
+	user := createUser(t, repos, "mfa@example.com")
+	other := createUser(t, repos, "mfa2@example.com")
+
+	credential, err := repos.MFA.FindTOTP(ctx, user.ID)
+	mustNoError(t, err, "find missing TOTP credential")
+	if credential != nil {
+		t.Error("FindTOTP returned a credential before one was saved")
+	}
+
+	mustNoError(t, repos.MFA.SaveTOTP(ctx, &models.TOTPCredential{UserID: user.ID, Secret: "first"}), "save TOTP credential")
+	mustNoError(t, repos.MFA.SaveTOTP(ctx, &models.TOTPCredential{UserID: user.ID, Secret: "second"}), "replace TOTP credential")
+	credential, err = repos.MFA.FindTOTP(ctx, user.ID)
+	mustNoError(t, err, "find TOTP credential")
+	if credential == nil || credential.Secret != "second" || credential.ConfirmedAt != nil {
+		t.Fatalf("FindTOTP = %+v, want the replacing, unconfirmed credential", credential)
+	}
+
+	for _, step := range []struct {
+		step int64
+		want bool
+	}{{100, true}, {100, false}, {99, false}, {101, true}} {
+		used, err := repos.MFA.UseTOTPStep(ctx, user.ID, step.step)
+		mustNoError(t, err, "use TOTP step")
+		if used != step.want {
+			t.Errorf("UseTOTPStep(%d) = %v, want %v", step.step, used, step.want)
+		}
+	}
+
+	enabledAt := day(2024, 10, 1)
+	codes := []models.RecoveryCode{{CodeHash: "code-a"}, {CodeHash: "code-b"}}
+	mustNoError(t, repos.MFA.Enable(ctx, user.ID, enabledAt, codes), "enable MFA")
+	credential, err = repos.MFA.FindTOTP(ctx, user.ID)
+	mustNoError(t, err, "find confirmed TOTP credential")
+	if credential.ConfirmedAt == nil || !credential.ConfirmedAt.Equal(enabledAt) {
+		t.Errorf("ConfirmedAt = %v, want %v", credential.ConfirmedAt, enabledAt)
+	}
+	found, err := repos.Users.FindByID(ctx, user.ID)
+	mustNoError(t, err, "find user")
+	if !found.MFAEnabled() {
+		t.Error("Enable did not mark MFA enabled on the user")
+	}
+
+	used, err := repos.MFA.UseRecoveryCode(ctx, user.ID, "code-a", enabledAt)
+	mustNoError(t, err, "use recovery code")
+	if !used {
+		t.Error("UseRecoveryCode rejected an unused code")
+	}
+	for _, attempt := range []struct {
+		userID uint
+		hash   string
+	}{{user.ID, "code-a"}, {other.ID, "code-b"}, {user.ID, "unknown"}} {
+		used, err := repos.MFA.UseRecoveryCode(ctx, attempt.userID, attempt.hash, enabledAt)
+		mustNoError(t, err, "use recovery code")
+		if used {
+			t.Errorf("UseRecoveryCode(%d, %q) succeeded, want it refused", attempt.userID, attempt.hash)
+		}
+	}
+	left, err := repos.MFA.CountUnusedRecoveryCodes(ctx, user.ID)
+	mustNoError(t, err, "count recovery codes")
+	if left != 1 {
+		t.Errorf("CountUnusedRecoveryCodes = %d, want 1", left)
+	}
+
+	mustNoError(t, repos.MFA.ReplaceRecoveryCodes(ctx, user.ID, []models.RecoveryCode{{CodeHash: "code-c"}, {CodeHash: "code-d"}, {CodeHash: "code-e"}}), "replace recovery codes")
+	left, err = repos.MFA.CountUnusedRecoveryCodes(ctx, user.ID)
+	mustNoError(t, err, "count replaced recovery codes")
+	if left != 3 {
+		t.Errorf("CountUnusedRecoveryCodes after replacing = %d, want 3", left)
+	}
+	if used, _ := repos.MFA.UseRecoveryCode(ctx, user.ID, "code-b", enabledAt); used {
+		t.Error("UseRecoveryCode accepted a replaced code")
+	}
+
+	expired := &models.MFAChallenge{UserID: user.ID, TokenHash: "expired", ExpiresAt: day(2024, 10, 1)}
+	pending := &models.MFAChallenge{UserID: user.ID, TokenHash: "pending", ExpiresAt: day(2024, 10, 3)}
+	mustNoError(t, repos.MFA.CreateChallenge(ctx, expired), "create expired challenge")
+	mustNoError(t, repos.MFA.CreateChallenge(ctx, pending), "create pending challenge")
+	if err := repos.MFA.CreateChallenge(ctx, &models.MFAChallenge{UserID: other.ID, TokenHash: "pending", ExpiresAt: day(2024, 10, 3)}); err == nil {
+		t.Error("CreateChallenge with a duplicate token succeeded, want a constraint error")
+	}
+	mustNoError(t, repos.MFA.DeleteExpiredChallenges(ctx, user.ID, day(2024, 10, 2)), "delete expired challenges")
+	challenge, err := repos.MFA.FindChallengeByTokenHash(ctx, "expired")
+	mustNoError(t, err, "find expired challenge")
+	if challenge != nil {
+		t.Error("DeleteExpiredChallenges kept an expired challenge")
+	}
+	challenge, err = repos.MFA.FindChallengeByTokenHash(ctx, "pending")
+	mustNoError(t, err, "find pending challenge")
+	if challenge == nil || challenge.ID != pending.ID || challenge.UserID != user.ID {
+		t.Fatalf("FindChallengeByTokenHash = %+v, want the pending challenge", challenge)
+	}
+	deleted, err := repos.MFA.DeleteChallenge(ctx, pending.ID)
+	mustNoError(t, err, "delete challenge")
+	if !deleted {
+		t.Error("DeleteChallenge reported an existing challenge as gone")
+	}
+	deleted, err = repos.MFA.DeleteChallenge(ctx, pending.ID)
+	mustNoError(t, err, "delete challenge again")
+	if deleted {
+		t.Error("DeleteChallenge deleted a challenge twice")
+	}
+
+	mustNoError(t, repos.MFA.CreateChallenge(ctx, &models.MFAChallenge{UserID: user.ID, TokenHash: "disabled", ExpiresAt: day(2024, 10, 3)}), "create challenge")
+	mustNoError(t, repos.MFA.Disable(ctx, user.ID), "disable MFA")
+	credential, err = repos.MFA.FindTOTP(ctx, user.ID)
+	mustNoError(t, err, "find removed TOTP credential")
+	challenge, err = repos.MFA.FindChallengeByTokenHash(ctx, "disabled")
+	mustNoError(t, err, "find removed challenge")
+	left, err = repos.MFA.CountUnusedRecoveryCodes(ctx, user.ID)
+	mustNoError(t, err, "count removed recovery codes")
+	if credential != nil || challenge != nil || left != 0 {
+		t.Error("Disable kept the credential, a challenge or recovery codes")
+	}
+	found, err = repos.Users.FindByID(ctx, user.ID)
+	mustNoError(t, err, "find user")
+	if found.MFAEnabled() {
+		t.Error("Disable did not mark MFA disabled on the user")
+	}
+
+	if err := repos.MFA.SaveTOTP(ctx, &models.TOTPCredential{UserID: other.ID + 100, Secret: "missing"}); err == nil {
+		t.Error("SaveTOTP with a missing user succeeded, want a constraint error")
+	}
+}
+
+func testPurge(t *testing.T, newRepos Factory) {
+	ctx := context.Background()
+	repos := newRepos(t)
+	user := createUser(t, repos, "purge@example.com")
+	keep := createUser(t, repos, "keep@example.com")
+
+	habit := createHabit(t, repos, user.ID, "Floss")
+	deletedHabit := createHabit(t, repos, user.ID, "Old")
+	streak := createStreak(t, repos, habit.ID, "active")
+	createCheckIn(t, repos, streak.ID, day(2024, 7, 1))
+	createCheckIn(t, repos, streak.ID, day(2024, 7, 2))
+	mustNoError(t, repos.Achievements.Create(ctx, &models.Achievement{UserID: user.ID, HabitID: habit.ID, AchievementType: "streak_completed", TargetDays: 3}), "create achievement")
+	mustNoError(t, repos.Devices.Create(ctx, &models.Device{UserID: user.ID, Token: "purge-token", Platform: models.PlatformAndroid, LastSeenAt: day(2024, 7, 1)}), "create device")
+	_, err := repos.Digests.Claim(ctx, &models.DigestSend{UserID: user.ID, PeriodStart: day(2024, 7, 1), PeriodEnd: day(2024, 7, 8)}, day(2024, 7, 1))
+	mustNoError(t, err, "claim digest")
+	mustNoError(t, repos.Identities.Create(ctx, &models.Identity{UserID: user.ID, Provider: models.ProviderGoogle, Subject: "purge-subject", LastLoginAt: day(2024, 7, 1)}), "create identity")
+	mustNoError(t, repos.Verifications.Create(ctx, &models.EmailVerification{UserID: user.ID, TokenHash: "purge-hash", ExpiresAt: day(2024, 7, 3)}), "create email verification")
+	mustNoError(t, repos.Resets.Create(ctx, &models.PasswordReset{UserID: user.ID, TokenHash: "purge-reset", ExpiresAt: day(2024, 7, 3)}), "create password reset")
+	mustNoError(t, repos.Events.Create(ctx, &models.SecurityEvent{UserID: user.ID, Type: models.SecurityEventLoginFailed}), "create security event")
+	mustNoError(t, repos.MFA.SaveTOTP(ctx, &models.TOTPCredential{UserID: user.ID, Secret: "purge-secret"}), "save TOTP credential")
+	mustNoError(t, repos.MFA.Enable(ctx, user.ID, day(2024, 7, 1), []models.RecoveryCode{{CodeHash: "purge-code"}}), "enable MFA")
+	mustNoError(t, repos.MFA.CreateChallenge(ctx, &models.MFAChallenge{UserID: user.ID, TokenHash: "purge-challenge", ExpiresAt: day(2024, 7, 3)}), "create MFA challenge")
+	mustNoError(t, repos.Habits.Delete(ctx, deletedHabit.ID), "soft delete habit")
+
+	keptHabit := createHabit(t, repos, keep.ID, "Keep")
+
+	mustNoError(t, repos.Users.Delete(ctx, user.ID), "delete user")
+
+	audit := &models.ErasureAudit{RequestedAt: day(2024, 7, 3), PurgedAt: day(2024, 8, 3)}
+	mustNoError(t, repos.Users.Purge(ctx, user.ID, audit), "purge user")
+
+	got := [5]int64{audit.Habits, audit.Streaks, audit.CheckIns, audit.Achievements, audit.Devices}
+	if want := [5]int64{2, 1, 2, 1, 1}; got != want {
+		t.Errorf("audit counts (habits, streaks, check-ins, achievements, devices) = %v, want %v", got, want)
+	}
+	if audit.ID == 0 {
+		t.Error("Purge did not record the audit entry")
+	}
+
+	exists, err := repos.Users.ExistsByEmail(ctx, "purge@example.com")
+	mustNoError(t, err, "check purged email")
+	if exists {
+		t.Error("ExistsByEmail still reports a purged account")
+	}
+	found, err := repos.Habits.FindByID(ctx, habit.ID)
+	mustNoError(t, err, "find purged habit")
+	if found != nil {
+		t.Error("habit of a purged user still exists")
+	}
+	device, err := repos.Devices.FindByToken(ctx, "purge-token")
+	mustNoError(t, err, "find purged device")
+	if device != nil {
+		t.Error("device of a purged user still exists")
+	}
+	identity, err := repos.Identities.FindByProviderSubject(ctx, models.ProviderGoogle, "purge-subject")
+	mustNoError(t, err, "find purged identity")
+	if identity != nil {
+		t.Error("identity of a purged user still exists")
+	}
+	verification, err := repos.Verifications.FindByTokenHash(ctx, "purge-hash")
+	mustNoError(t, err, "find purged email verification")
+	if verification != nil {
+		t.Error("email verification of a purged user still exists")
+	}
+	reset, err := repos.Resets.FindByTokenHash(ctx, "purge-reset")
+	mustNoError(t, err, "find purged password reset")
+	if reset != nil {
+		t.Error("password reset of a purged user still exists")
+	}
+	events, err := repos.Events.FindByUserID(ctx, user.ID, 10)
+	mustNoError(t, err, "find purged security events")
+	if len(events) != 0 {
+		t.Error("security events of a purged user still exist")
+	}
+	credential, err := repos.MFA.FindTOTP(ctx, user.ID)
+	mustNoError(t, err, "find purged TOTP credential")
+	challenge, err := repos.MFA.FindChallengeByTokenHash(ctx, "purge-challenge")
+	mustNoError(t, err, "find purged MFA challenge")
+	codes, err := repos.MFA.CountUnusedRecoveryCodes(ctx, user.ID)
+	mustNoError(t, err, "count purged recovery codes")
+	if credential != nil || challenge != nil || codes != 0 {
+		t.Error("MFA credentials of a purged user still exist")
+	}
+	kept, err := repos.Habits.FindByID(ctx, keptHabit.ID)
+	mustNoError(t, err, "find other user's habit")
+	if kept == nil {
+		t.Error("Purge removed another user's habit")
+	}
+
+	// The email can be used again once the account is purged
+	createUser(t, repos, "purge@example.com")
+}
+
+func testCancelledContext(t *testing.T, newRepos Factory) {
+	repos := newRepos(t)
+	user := createUser(t, repos, "cancel@example.com")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := repos.Users.FindByID(ctx, user.ID); err == nil {
+		t.Error("Users.FindByID with a cancelled context succeeded")
+	}
+	if err := repos.Habits.Create(ctx, &models.Habit{UserID: user.ID, Name: "Cancelled"}); err == nil {
+		t.Error("Habits.Create with a cancelled context succeeded")
+	}
+	if _, err := repos.Achievements.FindByUserID(ctx, user.ID); err == nil {
+		t.Error("Achievements.FindByUserID with a cancelled context succeeded")
+	}
+}
@@ -0,0 +1,44 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/models"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+// GormSecurityEventRepository implements SecurityEventRepository using GORM
+type GormSecurityEventRepository struct {
+	db *gorm.DB
+}
+
+// NewSecurityEventRepository creates a new security event repository
+func NewSecurityEventRepository(db *gorm.DB) SecurityEventRepository {
+	return &GormSecurityEventRepository{db: db}
+}
+
+// Create records a new security event
+func (r *GormSecurityEventRepository) Create(ctx context.Context, event *models.SecurityEvent) error {
+	result := r.db.WithContext(ctx).Omit("User").Create(event)
+	if result.Error != nil {
+		log.Ctx(ctx).Error().Err(result.Error).Uint("userID", event.UserID).Msg("Failed to create security event")
+		return result.Error
+	}
+	return nil
+}
+
+// FindByUserID finds a user's latest security events, newest first
+func (r *GormSecurityEventRepository) FindByUserID(ctx context.Context, userID uint, limit int) ([]models.SecurityEvent, error) {
+	var events []models.SecurityEvent
+	result := r.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Order("created_at DESC, id DESC").
+		Limit(limit).
+		Find(&events)
+	if result.Error != nil {
+		log.Ctx(ctx).Error().Err(result.Error).Uint("userID", userID).Msg("Failed to find security events")
+		return nil, result.Error
+	}
+	return events, nil
+}
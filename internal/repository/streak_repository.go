@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/database"
 	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/models"
 	"github.com/rs/zerolog/log"
 	"gorm.io/gorm"
@@ -23,7 +24,7 @@ func NewStreakRepository(db *gorm.DB) StreakRepository {
 func (r *GormStreakRepository) Create(ctx context.Context, streak *models.HabitStreak) error {
 	result := r.db.WithContext(ctx).Create(streak)
 	if result.Error != nil {
-		log.Error().Err(result.Error).Msg("Failed to create streak")
+		log.Ctx(ctx).Error().Err(result.Error).Msg("Failed to create streak")
 		return result.Error
 	}
 	return nil
@@ -32,12 +33,12 @@ func (r *GormStreakRepository) Create(ctx context.Context, streak *models.HabitS
 // FindByID finds a streak by ID
 func (r *GormStreakRepository) FindByID(ctx context.Context, id uint) (*models.HabitStreak, error) {
 	var streak models.HabitStreak
-	result := r.db.WithContext(ctx).First(&streak, id)
+	result := database.FirstOnReplica(r.db.WithContext(ctx), &streak, id)
 	if result.Error != nil {
 		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
 			return nil, nil
 		}
-		log.Error().Err(result.Error).Uint("id", id).Msg("Failed to find streak by ID")
+		log.Ctx(ctx).Error().Err(result.Error).Uint("id", id).Msg("Failed to find streak by ID")
 		return nil, result.Error
 	}
 	return &streak, nil
@@ -46,9 +47,9 @@ func (r *GormStreakRepository) FindByID(ctx context.Context, id uint) (*models.H
 // FindByHabitID finds all streaks for a habit
 func (r *GormStreakRepository) FindByHabitID(ctx context.Context, habitID uint) ([]models.HabitStreak, error) {
 	var streaks []models.HabitStreak
-	result := r.db.WithContext(ctx).Where("habit_id = ?", habitID).Order("created_at DESC").Find(&streaks)
+	result := r.db.WithContext(ctx).Scopes(database.Replica).Where("habit_id = ?", habitID).Order("created_at DESC").Find(&streaks)
 	if result.Error != nil {
-		log.Error().Err(result.Error).Uint("habitID", habitID).Msg("Failed to find streaks by habit ID")
+		log.Ctx(ctx).Error().Err(result.Error).Uint("habitID", habitID).Msg("Failed to find streaks by habit ID")
 		return nil, result.Error
 	}
 	return streaks, nil
@@ -57,12 +58,12 @@ func (r *GormStreakRepository) FindByHabitID(ctx context.Context, habitID uint)
 // FindActiveByHabitID finds the active streak for a habit
 func (r *GormStreakRepository) FindActiveByHabitID(ctx context.Context, habitID uint) (*models.HabitStreak, error) {
 	var streak models.HabitStreak
-	result := r.db.WithContext(ctx).Where("habit_id = ? AND status = 'active'", habitID).First(&streak)
+	result := r.db.WithContext(ctx).Scopes(database.Replica).Where("habit_id = ? AND status = 'active'", habitID).First(&streak)
 	if result.Error != nil {
 		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
 			return nil, nil
 		}
-		log.Error().Err(result.Error).Uint("habitID", habitID).Msg("Failed to find active streak by habit ID")
+		log.Ctx(ctx).Error().Err(result.Error).Uint("habitID", habitID).Msg("Failed to find active streak by habit ID")
 		return nil, result.Error
 	}
 	return &streak, nil
@@ -72,7 +73,7 @@ func (r *GormStreakRepository) FindActiveByHabitID(ctx context.Context, habitID
 func (r *GormStreakRepository) Update(ctx context.Context, streak *models.HabitStreak) error {
 	result := r.db.WithContext(ctx).Save(streak)
 	if result.Error != nil {
-		log.Error().Err(result.Error).Uint("id", streak.ID).Msg("Failed to update streak")
+		log.Ctx(ctx).Error().Err(result.Error).Uint("id", streak.ID).Msg("Failed to update streak")
 		return result.Error
 	}
 	return nil
@@ -82,7 +83,7 @@ func (r *GormStreakRepository) Update(ctx context.Context, streak *models.HabitS
 func (r *GormStreakRepository) Delete(ctx context.Context, id uint) error {
 	result := r.db.WithContext(ctx).Delete(&models.HabitStreak{}, id)
 	if result.Error != nil {
-		log.Error().Err(result.Error).Uint("id", id).Msg("Failed to delete streak")
+		log.Ctx(ctx).Error().Err(result.Error).Uint("id", id).Msg("Failed to delete streak")
 		return result.Error
 	}
 	return nil
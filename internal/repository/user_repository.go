@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 	"errors"
+	"time"
 
 	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/models"
 	"github.com/rs/zerolog/log"
@@ -23,7 +24,7 @@ func NewUserRepository(db *gorm.DB) UserRepository {
 func (r *GormUserRepository) Create(ctx context.Context, user *models.User) error {
 	result := r.db.WithContext(ctx).Create(user)
 	if result.Error != nil {
-		log.Error().Err(result.Error).Msg("Failed to create user")
+		log.Ctx(ctx).Error().Err(result.Error).Msg("Failed to create user")
 		return result.Error
 	}
 	return nil
@@ -37,7 +38,7 @@ func (r *GormUserRepository) FindByID(ctx context.Context, id uint) (*models.Use
 		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
 			return nil, nil
 		}
-		log.Error().Err(result.Error).Uint("id", id).Msg("Failed to find user by ID")
+		log.Ctx(ctx).Error().Err(result.Error).Uint("id", id).Msg("Failed to find user by ID")
 		return nil, result.Error
 	}
 	return &user, nil
@@ -51,17 +52,52 @@ func (r *GormUserRepository) FindByEmail(ctx context.Context, email string) (*mo
 		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
 			return nil, nil
 		}
-		log.Error().Err(result.Error).Str("email", email).Msg("Failed to find user by email")
+		log.Ctx(ctx).Error().Err(result.Error).Str("email", email).Msg("Failed to find user by email")
 		return nil, result.Error
 	}
 	return &user, nil
 }
 
+// FindDigestRecipients finds all users who opted in to the weekly digest on the given day
+func (r *GormUserRepository) FindDigestRecipients(ctx context.Context, day time.Weekday) ([]models.User, error) {
+	var users []models.User
+	result := r.db.WithContext(ctx).Where("weekly_digest_enabled = ? AND weekly_digest_day = ?", true, int(day)).Order("id").Find(&users)
+	if result.Error != nil {
+		log.Ctx(ctx).Error().Err(result.Error).Int("day", int(day)).Msg("Failed to find digest recipients")
+		return nil, result.Error
+	}
+	return users, nil
+}
+
+// ExistsByEmail checks whether an account uses the email, including deleted accounts awaiting purge
+func (r *GormUserRepository) ExistsByEmail(ctx context.Context, email string) (bool, error) {
+	var count int64
+	result := r.db.WithContext(ctx).Unscoped().Model(&models.User{}).Where("email = ?", email).Count(&count)
+	if result.Error != nil {
+		log.Ctx(ctx).Error().Err(result.Error).Str("email", email).Msg("Failed to check email")
+		return false, result.Error
+	}
+	return count > 0, nil
+}
+
+// FindDueForPurge finds deleted users whose grace period has passed
+func (r *GormUserRepository) FindDueForPurge(ctx context.Context, now time.Time) ([]models.User, error) {
+	var users []models.User
+	result := r.db.WithContext(ctx).Unscoped().
+		Where("deleted_at IS NOT NULL AND purge_after IS NOT NULL AND purge_after <= ?", now).
+		Order("id").Find(&users)
+	if result.Error != nil {
+		log.Ctx(ctx).Error().Err(result.Error).Msg("Failed to find users due for purge")
+		return nil, result.Error
+	}
+	return users, nil
+}
+
 // Update updates a user
 func (r *GormUserRepository) Update(ctx context.Context, user *models.User) error {
 	result := r.db.WithContext(ctx).Save(user)
 	if result.Error != nil {
-		log.Error().Err(result.Error).Uint("id", user.ID).Msg("Failed to update user")
+		log.Ctx(ctx).Error().Err(result.Error).Uint("id", user.ID).Msg("Failed to update user")
 		return result.Error
 	}
 	return nil
@@ -71,8 +107,90 @@ func (r *GormUserRepository) Update(ctx context.Context, user *models.User) erro
 func (r *GormUserRepository) Delete(ctx context.Context, id uint) error {
 	result := r.db.WithContext(ctx).Delete(&models.User{}, id)
 	if result.Error != nil {
-		log.Error().Err(result.Error).Uint("id", id).Msg("Failed to delete user")
+		log.Ctx(ctx).Error().Err(result.Error).Uint("id", id).Msg("Failed to delete user")
 		return result.Error
 	}
 	return nil
 }
+
+// Purge permanently deletes the user together with their habits, streaks,
+// check-ins, achievements, devices, digest records, linked identities,
+// pending email verifications and password resets, security events and MFA
+// credentials in one transaction.
+// The number of removed rows is recorded on the audit entry, which is saved
+// in the same transaction.
+func (r *GormUserRepository) Purge(ctx context.Context, id uint, audit *models.ErasureAudit) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		tx = tx.Unscoped().Session(&gorm.Session{})
+
+		habitIDs := tx.Model(&models.Habit{}).Select("id").Where("user_id = ?", id)
+		streakIDs := tx.Model(&models.HabitStreak{}).Select("id").Where("habit_id IN (?)", habitIDs)
+
+		result := tx.Where("streak_id IN (?)", streakIDs).Delete(&models.HabitCheckIn{})
+		if result.Error != nil {
+			return result.Error
+		}
+		audit.CheckIns = result.RowsAffected
+
+		result = tx.Where("habit_id IN (?)", habitIDs).Delete(&models.HabitStreak{})
+		if result.Error != nil {
+			return result.Error
+		}
+		audit.Streaks = result.RowsAffected
+
+		result = tx.Where("user_id = ?", id).Delete(&models.Achievement{})
+		if result.Error != nil {
+			return result.Error
+		}
+		audit.Achievements = result.RowsAffected
+
+		result = tx.Where("user_id = ?", id).Delete(&models.Habit{})
+		if result.Error != nil {
+			return result.Error
+		}
+		audit.Habits = result.RowsAffected
+
+		result = tx.Where("user_id = ?", id).Delete(&models.Device{})
+		if result.Error != nil {
+			return result.Error
+		}
+		audit.Devices = result.RowsAffected
+
+		if err := tx.Where("user_id = ?", id).Delete(&models.DigestSend{}).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Where("user_id = ?", id).Delete(&models.Identity{}).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Where("user_id = ?", id).Delete(&models.EmailVerification{}).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Where("user_id = ?", id).Delete(&models.PasswordReset{}).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Where("user_id = ?", id).Delete(&models.SecurityEvent{}).Error; err != nil {
+			return err
+		}
+
+		for _, model := range []interface{}{&models.TOTPCredential{}, &models.RecoveryCode{}, &models.MFAChallenge{}} {
+			if err := tx.Where("user_id = ?", id).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Delete(&models.User{}, id).Error; err != nil {
+			return err
+		}
+
+		return tx.Create(audit).Error
+	})
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Uint("id", id).Msg("Failed to purge user")
+		return err
+	}
+	return nil
+}
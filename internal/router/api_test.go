@@ -0,0 +1,288 @@
+package router_test
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestAuthentication(t *testing.T) {
+	h := newHarness(t)
+
+	token := h.register("ada@example.com")
+
+	h.expectError(h.do(http.MethodPost, "/api/v1/auth/register", "", gin.H{
+		"name": "Ada", "email": "ada@example.com", "password": "password123",
+	}), http.StatusConflict, "CONFLICT")
+
+	h.expectError(h.do(http.MethodPost, "/api/v1/auth/register", "", gin.H{
+		"name": "Short", "email": "short@example.com", "password": "short",
+	}), http.StatusBadRequest, "INVALID_REQUEST")
+
+	h.expectError(h.do(http.MethodPost, "/api/v1/auth/login", "", gin.H{
+		"email": "ada@example.com", "password": "wrong-password",
+	}), http.StatusUnauthorized, "UNAUTHORIZED")
+
+	var auth struct {
+		Token string `json:"token"`
+		User  struct {
+			Email string `json:"email"`
+		} `json:"user"`
+	}
+	h.expectSuccess(h.do(http.MethodPost, "/api/v1/auth/login", "", gin.H{
+		"email": "ada@example.com", "password": "password123",
+	}), http.StatusOK, &auth)
+	if auth.Token == "" || auth.User.Email != "ada@example.com" {
+		t.Errorf("login returned %+v, want a token for ada@example.com", auth)
+	}
+
+	h.expectUnauthorized(h.do(http.MethodGet, "/api/v1/profile", "", nil), "Authorization header is required")
+	h.expectUnauthorized(h.do(http.MethodGet, "/api/v1/profile", "not-a-jwt", nil), "Invalid or expired token")
+
+	var profile struct {
+		Name  string `json:"name"`
+		Email string `json:"email"`
+	}
+	h.expectSuccess(h.do(http.MethodGet, "/api/v1/profile", token, nil), http.StatusOK, &profile)
+	if profile.Email != "ada@example.com" {
+		t.Errorf("profile returned email %q, want ada@example.com", profile.Email)
+	}
+
+	h.expectSuccess(h.do(http.MethodPut, "/api/v1/profile", token, gin.H{"name": "Ada Lovelace"}), http.StatusOK, &profile)
+	if profile.Name != "Ada Lovelace" {
+		t.Errorf("updated profile has name %q, want Ada Lovelace", profile.Name)
+	}
+}
+
+func TestHabitLifecycle(t *testing.T) {
+	h := newHarness(t)
+	token := h.register("habits@example.com")
+	other := h.register("other@example.com")
+
+	h.expectError(h.do(http.MethodPost, "/api/v1/habits", token, gin.H{"description": "no name"}), http.StatusBadRequest, "INVALID_REQUEST")
+	h.expectError(h.do(http.MethodPost, "/api/v1/habits", token, gin.H{"name": "Bad colour", "color": "red"}), http.StatusBadRequest, "INVALID_REQUEST")
+
+	read := h.createHabit(token, "Read")
+	h.createHabit(token, "Run")
+	if !read.IsActive || read.Color != "#00AA00" {
+		t.Errorf("created habit is %+v, want an active habit with colour #00AA00", read)
+	}
+
+	var habits []habitData
+	h.expectSuccess(h.do(http.MethodGet, "/api/v1/habits", token, nil), http.StatusOK, &habits)
+	if len(habits) != 2 {
+		t.Fatalf("listed %d habits, want 2", len(habits))
+	}
+
+	var habit habitData
+	h.expectSuccess(h.do(http.MethodGet, habitPath(read.ID, ""), token, nil), http.StatusOK, &habit)
+	if habit.Name != "Read" {
+		t.Errorf("got habit %+v, want Read", habit)
+	}
+
+	inactive := false
+	h.expectSuccess(h.do(http.MethodPut, habitPath(read.ID, ""), token, gin.H{"name": "Read a chapter", "is_active": &inactive}), http.StatusOK, &habit)
+	if habit.Name != "Read a chapter" || habit.IsActive {
+		t.Errorf("updated habit is %+v, want an inactive habit named Read a chapter", habit)
+	}
+
+	// Other users can neither see nor change the habit
+	if res := h.do(http.MethodGet, habitPath(read.ID, ""), other, nil); res.Status < 400 || res.Body.Success {
+		t.Errorf("another user read the habit: %d\n%s", res.Status, res.Raw)
+	}
+	if res := h.do(http.MethodDelete, habitPath(read.ID, ""), other, nil); res.Status < 400 || res.Body.Success {
+		t.Errorf("another user deleted the habit: %d\n%s", res.Status, res.Raw)
+	}
+	var otherHabits []habitData
+	h.expectSuccess(h.do(http.MethodGet, "/api/v1/habits", other, nil), http.StatusOK, &otherHabits)
+	if len(otherHabits) != 0 {
+		t.Errorf("another user listed %d habits, want 0", len(otherHabits))
+	}
+
+	h.expectError(h.do(http.MethodGet, "/api/v1/habits/abc", token, nil), http.StatusBadRequest, "INVALID_REQUEST")
+
+	h.expectSuccess(h.do(http.MethodDelete, habitPath(read.ID, ""), token, nil), http.StatusOK, nil)
+	h.expectError(h.do(http.MethodGet, habitPath(read.ID, ""), token, nil), http.StatusNotFound, "NOT_FOUND")
+
+	h.expectSuccess(h.do(http.MethodGet, "/api/v1/habits", token, nil), http.StatusOK, &habits)
+	if len(habits) != 1 {
+		t.Errorf("listed %d habits after delete, want 1", len(habits))
+	}
+}
+
+func TestStreakCompletionEarnsAchievement(t *testing.T) {
+	h := newHarness(t)
+	token := h.register("streaks@example.com")
+	habit := h.createHabit(token, "Meditate")
+
+	h.expectError(h.checkIn(token, habit.ID), http.StatusBadRequest, "STREAK_NOT_FOUND")
+	h.expectError(h.do(http.MethodPost, habitPath(habit.ID, "/streaks"), token, gin.H{"target_days": 0}), http.StatusBadRequest, "INVALID_REQUEST")
+
+	streak := h.startStreak(token, habit.ID, 3)
+	if streak.Status != "active" || streak.TargetDays != 3 || streak.CurrentStreak != 0 {
+		t.Fatalf("started streak is %+v, want an active 3 day streak", streak)
+	}
+	h.expectError(h.do(http.MethodPost, habitPath(habit.ID, "/streaks"), token, gin.H{"target_days": 7}), http.StatusConflict, "CONFLICT")
+
+	for day := 1; day <= 3; day++ {
+		var checkIn checkInData
+		h.expectSuccess(h.checkIn(token, habit.ID), http.StatusCreated, &checkIn)
+		if want := h.clock.Now().UTC().Truncate(24 * time.Hour); !checkIn.CheckInDate.Equal(want) {
+			t.Errorf("day %d: check-in date is %v, want %v", day, checkIn.CheckInDate, want)
+		}
+
+		if day == 3 {
+			// The last check-in completes the streak, so there is nothing left to check in to
+			h.expectError(h.checkIn(token, habit.ID), http.StatusBadRequest, "STREAK_NOT_FOUND")
+		} else {
+			// A second check-in on the same day is rejected
+			h.expectError(h.checkIn(token, habit.ID), http.StatusBadRequest, "ALREADY_CHECKED_IN")
+
+			var current streakData
+			h.expectSuccess(h.do(http.MethodGet, habitPath(habit.ID, "/streaks/current"), token, nil), http.StatusOK, &current)
+			if current.CurrentStreak != day || current.MaxStreakAchieved != day {
+				t.Errorf("day %d: current streak is %+v, want %d days", day, current, day)
+			}
+			h.nextDay(1)
+		}
+	}
+
+	// The completed streak is no longer current
+	h.expectError(h.do(http.MethodGet, habitPath(habit.ID, "/streaks/current"), token, nil), http.StatusNotFound, "NOT_FOUND")
+
+	var streaks []streakData
+	h.expectSuccess(h.do(http.MethodGet, habitPath(habit.ID, "/streaks"), token, nil), http.StatusOK, &streaks)
+	if len(streaks) != 1 || streaks[0].Status != "completed" || streaks[0].CurrentStreak != 3 {
+		t.Fatalf("streaks are %+v, want one completed 3 day streak", streaks)
+	}
+
+	var checkIns []checkInData
+	h.expectSuccess(h.do(http.MethodGet, habitPath(habit.ID, "/check-ins"), token, nil), http.StatusOK, &checkIns)
+	if len(checkIns) != 3 || !checkIns[0].CheckInDate.After(checkIns[2].CheckInDate) {
+		t.Errorf("check-ins are %+v, want 3 check-ins newest first", checkIns)
+	}
+
+	var achievements []achievementData
+	h.expectSuccess(h.do(http.MethodGet, "/api/v1/achievements", token, nil), http.StatusOK, &achievements)
+	if len(achievements) != 1 {
+		t.Fatalf("got %d achievements, want 1", len(achievements))
+	}
+	if a := achievements[0]; a.AchievementType != "streak_completed" || a.TargetDays != 3 || a.HabitID != habit.ID {
+		t.Errorf("achievement is %+v, want streak_completed for 3 days on habit %d", a, habit.ID)
+	}
+
+	var habitAchievements []achievementData
+	h.expectSuccess(h.do(http.MethodGet, habitPath(habit.ID, "/achievements"), token, nil), http.StatusOK, &habitAchievements)
+	if len(habitAchievements) != 1 || habitAchievements[0].ID != achievements[0].ID {
+		t.Errorf("habit achievements are %+v, want the completed streak", habitAchievements)
+	}
+
+	var achievement achievementData
+	h.expectSuccess(h.do(http.MethodGet, "/api/v1/achievements/"+itoa(achievements[0].ID), token, nil), http.StatusOK, &achievement)
+	if achievement.ID != achievements[0].ID {
+		t.Errorf("got achievement %+v, want %d", achievement, achievements[0].ID)
+	}
+
+	// A new streak can be started once the previous one is completed
+	h.nextDay(1)
+	next := h.startStreak(token, habit.ID, 7)
+	h.expectSuccess(h.checkIn(token, habit.ID), http.StatusCreated, nil)
+	var current streakData
+	h.expectSuccess(h.do(http.MethodGet, habitPath(habit.ID, "/streaks/current"), token, nil), http.StatusOK, &current)
+	if current.ID != next.ID || current.CurrentStreak != 1 {
+		t.Errorf("current streak is %+v, want streak %d at 1 day", current, next.ID)
+	}
+}
+
+func TestMissedDayBreaksStreak(t *testing.T) {
+	h := newHarness(t)
+	token := h.register("broken@example.com")
+	habit := h.createHabit(token, "Stretch")
+	streak := h.startStreak(token, habit.ID, 5)
+
+	h.expectSuccess(h.checkIn(token, habit.ID), http.StatusCreated, nil)
+	h.nextDay(1)
+	h.expectSuccess(h.checkIn(token, habit.ID), http.StatusCreated, nil)
+
+	// Skipping a day breaks the streak and deletes it with its check-ins
+	h.nextDay(2)
+	h.expectError(h.checkIn(token, habit.ID), http.StatusBadRequest, "STREAK_BROKEN")
+
+	h.expectError(h.do(http.MethodGet, habitPath(habit.ID, "/streaks/current"), token, nil), http.StatusNotFound, "NOT_FOUND")
+	h.expectError(h.checkIn(token, habit.ID), http.StatusBadRequest, "STREAK_NOT_FOUND")
+
+	var streaks []streakData
+	h.expectSuccess(h.do(http.MethodGet, habitPath(habit.ID, "/streaks"), token, nil), http.StatusOK, &streaks)
+	for _, s := range streaks {
+		if s.ID == streak.ID {
+			t.Errorf("broken streak %d is still listed", streak.ID)
+		}
+	}
+
+	var achievements []achievementData
+	h.expectSuccess(h.do(http.MethodGet, "/api/v1/achievements", token, nil), http.StatusOK, &achievements)
+	if len(achievements) != 0 {
+		t.Errorf("got %d achievements for a broken streak, want 0", len(achievements))
+	}
+
+	// Starting over is allowed on the same day
+	restarted := h.startStreak(token, habit.ID, 5)
+	if restarted.ID == streak.ID {
+		t.Errorf("restarted streak reused ID %d", streak.ID)
+	}
+	h.expectSuccess(h.checkIn(token, habit.ID), http.StatusCreated, nil)
+}
+
+func TestCheckInsFollowUTCDayBoundaries(t *testing.T) {
+	h := newHarness(t)
+	token := h.register("midnight@example.com")
+	habit := h.createHabit(token, "Journal")
+	h.startStreak(token, habit.ID, 10)
+
+	h.clock.Set(time.Date(2024, 3, 10, 23, 59, 0, 0, time.UTC))
+	h.expectSuccess(h.checkIn(token, habit.ID), http.StatusCreated, nil)
+
+	// Two minutes later it is the next UTC day, which continues the streak
+	h.clock.Advance(2 * time.Minute)
+	h.expectSuccess(h.checkIn(token, habit.ID), http.StatusCreated, nil)
+
+	// The end of that day is still the same day
+	h.clock.Set(time.Date(2024, 3, 11, 23, 59, 59, 0, time.UTC))
+	h.expectError(h.checkIn(token, habit.ID), http.StatusBadRequest, "ALREADY_CHECKED_IN")
+
+	var current streakData
+	h.expectSuccess(h.do(http.MethodGet, habitPath(habit.ID, "/streaks/current"), token, nil), http.StatusOK, &current)
+	if current.CurrentStreak != 2 {
+		t.Errorf("current streak is %d days, want 2", current.CurrentStreak)
+	}
+}
+
+func TestAccountDeletionRevokesAccess(t *testing.T) {
+	h := newHarness(t)
+	token := h.register("leaving@example.com")
+	h.createHabit(token, "Walk")
+
+	h.expectError(h.do(http.MethodDelete, "/api/v1/profile", token, gin.H{"password": "wrong-password"}), http.StatusForbidden, "INVALID_PASSWORD")
+	h.expectSuccess(h.do(http.MethodDelete, "/api/v1/profile", token, gin.H{"password": "password123"}), http.StatusAccepted, nil)
+
+	// Issued tokens stop working and the email stays reserved until the purge
+	h.expectUnauthorized(h.do(http.MethodGet, "/api/v1/habits", token, nil), "Account no longer exists")
+	h.expectError(h.do(http.MethodPost, "/api/v1/auth/login", "", gin.H{
+		"email": "leaving@example.com", "password": "password123",
+	}), http.StatusUnauthorized, "UNAUTHORIZED")
+	h.expectError(h.do(http.MethodPost, "/api/v1/auth/register", "", gin.H{
+		"name": "Again", "email": "leaving@example.com", "password": "password123",
+	}), http.StatusConflict, "CONFLICT")
+}
+
+func TestUnknownRouteUsesErrorEnvelope(t *testing.T) {
+	h := newHarness(t)
+	h.expectError(h.do(http.MethodGet, "/api/v1/does-not-exist", "", nil), http.StatusNotFound, "NOT_FOUND")
+}
+
+func itoa(id uint) string {
+	return strconv.FormatUint(uint64(id), 10)
+}
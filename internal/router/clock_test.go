@@ -0,0 +1,60 @@
+package router_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/clock"
+	"github.com/gin-gonic/gin"
+)
+
+type clockData struct {
+	Now     time.Time `json:"now"`
+	RealNow time.Time `json:"real_now"`
+	Offset  string    `json:"offset"`
+}
+
+func TestTimeTravelReplaysStreakRules(t *testing.T) {
+	h := newHarnessWithClock(t, func(fake *clock.Fake) clock.Clock { return clock.NewOffset(fake) })
+	token := h.register("qa@example.com")
+	habit := h.createHabit(token, "Practice")
+	h.startStreak(token, habit.ID, 2)
+
+	h.expectSuccess(h.checkIn(token, habit.ID), http.StatusCreated, nil)
+
+	var moved clockData
+	h.expectSuccess(h.do(http.MethodPut, "/debug/clock", "", gin.H{"advance": "24h"}), http.StatusOK, &moved)
+	if moved.Offset != "24h0m0s" || !moved.Now.Equal(moved.RealNow.Add(24*time.Hour)) {
+		t.Fatalf("clock after advancing is %+v, want 24h ahead", moved)
+	}
+
+	// Tomorrow's check-in completes the streak
+	h.expectSuccess(h.checkIn(token, habit.ID), http.StatusCreated, nil)
+	var achievements []achievementData
+	h.expectSuccess(h.do(http.MethodGet, "/api/v1/achievements", token, nil), http.StatusOK, &achievements)
+	if len(achievements) != 1 {
+		t.Fatalf("got %d achievements, want 1", len(achievements))
+	}
+
+	target := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	h.expectSuccess(h.do(http.MethodPut, "/debug/clock", "", gin.H{"now": target}), http.StatusOK, &moved)
+	if !moved.Now.Equal(target) {
+		t.Errorf("clock after setting is at %v, want %v", moved.Now, target)
+	}
+
+	h.expectError(h.do(http.MethodPut, "/debug/clock", "", gin.H{"advance": "tomorrow"}), http.StatusBadRequest, "VALIDATION_ERROR")
+	h.expectError(h.do(http.MethodPut, "/debug/clock", "", gin.H{}), http.StatusBadRequest, "INVALID_REQUEST")
+
+	h.expectSuccess(h.do(http.MethodDelete, "/debug/clock", "", nil), http.StatusOK, &moved)
+	if moved.Offset != "0s" || !moved.Now.Equal(h.clock.Now()) {
+		t.Errorf("clock after reset is %+v, want real time", moved)
+	}
+}
+
+func TestTimeTravelIsDisabledInProduction(t *testing.T) {
+	t.Setenv("ENV", "production")
+	t.Setenv("AUTH_JWT_SECRET", "a-production-secret-of-at-least-32-chars")
+	h := newHarnessWithClock(t, func(fake *clock.Fake) clock.Clock { return clock.NewOffset(fake) })
+	h.expectError(h.do(http.MethodGet, "/debug/clock", "", nil), http.StatusNotFound, "NOT_FOUND")
+}
@@ -0,0 +1,64 @@
+package router_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/config"
+)
+
+func TestCORSCredentialsNeedExplicitOrigins(t *testing.T) {
+	h := newHarness(t)
+
+	preflight := func(origin string) http.Header {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodOptions, "/api/v1/habits", nil)
+		req.Header.Set("Origin", origin)
+		req.Header.Set("Access-Control-Request-Method", http.MethodGet)
+		rec := httptest.NewRecorder()
+		h.router.ServeHTTP(rec, req)
+		return rec.Header()
+	}
+
+	// Any origin is allowed by default, but without credentials
+	header := preflight("https://evil.example")
+	if got := header.Get("Access-Control-Allow-Origin"); got != "https://evil.example" {
+		t.Errorf("wildcard: Access-Control-Allow-Origin = %q, want the origin", got)
+	}
+	if got := header.Get("Access-Control-Allow-Credentials"); got != "" {
+		t.Errorf("wildcard: Access-Control-Allow-Credentials = %q, want none", got)
+	}
+
+	h.reconfigure(func(cfg *config.Config) {
+		cfg.CORS.AllowedOrigins = []string{"https://app.example"}
+	})
+
+	header = preflight("https://app.example")
+	if got := header.Get("Access-Control-Allow-Credentials"); got != "true" {
+		t.Errorf("listed origin: Access-Control-Allow-Credentials = %q, want true", got)
+	}
+	header = preflight("https://evil.example")
+	if got := header.Get("Access-Control-Allow-Origin"); got != "" {
+		t.Errorf("unlisted origin: Access-Control-Allow-Origin = %q, want none", got)
+	}
+}
+
+func TestCORSExposesRateLimitHeaders(t *testing.T) {
+	h := newHarness(t)
+	token := h.register("ada@example.com")
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/habits", nil)
+	req.Header.Set("Origin", "https://app.example")
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec := httptest.NewRecorder()
+	h.router.ServeHTTP(rec, req)
+
+	exposed := rec.Header().Get("Access-Control-Expose-Headers")
+	for _, name := range []string{"Retry-After", "X-Ratelimit-Limit", "X-Ratelimit-Remaining", "X-Ratelimit-Reset"} {
+		if !strings.Contains(strings.ToLower(exposed), strings.ToLower(name)) {
+			t.Errorf("Access-Control-Expose-Headers = %q, want it to include %s", exposed, name)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package router_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/clock"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/config"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/database"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/migrate"
+)
+
+func TestAPIWaitsForDatabase(t *testing.T) {
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatalf("load config: %v", err)
+	}
+
+	// The handle is open, but nothing has reached the database yet
+	db, err := database.Open(cfg)
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	t.Cleanup(db.Close)
+	h := newHarnessOn(t, cfg, db, func(fake *clock.Fake) clock.Clock { return fake })
+
+	login := map[string]string{"email": "ada@example.com", "password": "password123"}
+	res := h.do(http.MethodPost, "/api/v1/auth/login", "", login)
+	h.expectError(res, http.StatusServiceUnavailable, "DB_UNAVAILABLE")
+	if res.Header.Get("Retry-After") == "" {
+		t.Error("Retry-After is missing")
+	}
+	h.expectError(h.do(http.MethodGet, "/api/v1/habits", "", nil), http.StatusServiceUnavailable, "DB_UNAVAILABLE")
+	h.expectError(h.do(http.MethodGet, "/readyz", "", nil), http.StatusServiceUnavailable, "NOT_READY")
+	h.expectSuccess(h.do(http.MethodGet, "/livez", "", nil), http.StatusOK, nil)
+
+	supervisor := database.NewSupervisor(db, cfg.Database, func(ctx context.Context) error {
+		migrator, err := migrate.New(db.DB)
+		if err != nil {
+			return err
+		}
+		_, err = migrator.Up(ctx)
+		return err
+	})
+	if err := supervisor.Connect(context.Background()); err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+
+	h.register("ada@example.com")
+	h.expectSuccess(h.do(http.MethodPost, "/api/v1/auth/login", "", login), http.StatusOK, nil)
+	h.expectSuccess(h.do(http.MethodGet, "/readyz", "", nil), http.StatusOK, nil)
+}
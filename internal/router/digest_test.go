@@ -0,0 +1,110 @@
+package router_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/jobs"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/mailer"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/repository"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// flakyMailer delivers to the harness outbox unless it is failing
+type flakyMailer struct {
+	outbox  *mailer.Outbox
+	failing bool
+}
+
+func (m *flakyMailer) Send(ctx context.Context, msg mailer.Message) error {
+	if m.failing {
+		return errors.New("mail server unavailable")
+	}
+	return m.outbox.Send(ctx, msg)
+}
+
+// digestJob builds the weekly digest job the way the server does, on the
+// harness's database and clock
+func (h *harness) digestJob(mail mailer.Mailer) *jobs.WeeklyDigestJob {
+	db := h.db.DB
+	userRepo := repository.NewUserRepository(db)
+	habitRepo := repository.NewHabitRepository(db)
+	achievementRepo := repository.NewAchievementRepository(db)
+	userService := service.NewUserService(userRepo, habitRepo, repository.NewStreakRepository(db), repository.NewCheckInRepository(db), achievementRepo, h.clock)
+	digestService := service.NewDigestService(userRepo, achievementRepo, habitRepo, repository.NewDigestRepository(db), userService, mail, h.clock)
+	return jobs.NewWeeklyDigestJob(digestService, h.clock)
+}
+
+// digests returns the weekly digests sent to the address so far
+func (h *harness) digests(email string) []mailer.Message {
+	var digests []mailer.Message
+	for _, msg := range h.mail.Messages() {
+		if msg.To == email && strings.HasPrefix(msg.Subject, "Your week in habits") {
+			digests = append(digests, msg)
+		}
+	}
+	return digests
+}
+
+func TestWeeklyDigest(t *testing.T) {
+	h := newHarness(t)
+	mail := &flakyMailer{outbox: h.mail}
+	job := h.digestJob(mail)
+	run := func() {
+		t.Helper()
+		if err := job.Run(context.Background()); err != nil {
+			t.Fatalf("run digest job: %v", err)
+		}
+	}
+
+	// The clock starts on Sunday 10 March; Ada wants her digest on Tuesdays
+	ada := h.register("ada@example.com")
+	h.expectSuccess(h.do(http.MethodPut, "/api/v1/profile/digest", ada, gin.H{"enabled": true, "send_day": "tuesday"}), http.StatusOK, nil)
+	h.register("grace@example.com")
+
+	habit := h.createHabit(ada, "Read")
+	h.startStreak(ada, habit.ID, 7)
+	h.expectSuccess(h.checkIn(ada, habit.ID), http.StatusCreated, nil)
+
+	run()
+	if sent := h.digests("ada@example.com"); len(sent) != 0 {
+		t.Fatalf("sent %d digests on Sunday, want none before the chosen day", len(sent))
+	}
+
+	h.nextDay(2)
+	run()
+	run()
+	sent := h.digests("ada@example.com")
+	if len(sent) != 1 {
+		t.Fatalf("sent %d digests on Tuesday after two runs, want exactly one", len(sent))
+	}
+	if want := "Your week in habits: Mar 5 – Mar 11"; sent[0].Subject != want {
+		t.Errorf("subject = %q, want %q", sent[0].Subject, want)
+	}
+	if !strings.Contains(sent[0].Text, "- Read:") || !strings.Contains(sent[0].HTML, "Read") {
+		t.Errorf("digest does not list the habit:\n%s", sent[0].Text)
+	}
+	if got := h.digests("grace@example.com"); len(got) != 0 {
+		t.Errorf("sent %d digests to a user who did not opt in, want none", len(got))
+	}
+
+	// A failed send releases the week, so a later run sends it
+	h.nextDay(7)
+	mail.failing = true
+	run()
+	if got := h.digests("ada@example.com"); len(got) != 1 {
+		t.Fatalf("sent %d digests while the mailer fails, want the first only", len(got))
+	}
+	mail.failing = false
+	h.clock.Advance(time.Hour)
+	run()
+	run()
+	if got := h.digests("ada@example.com"); len(got) != 2 {
+		t.Errorf("sent %d digests after the mailer recovered, want the retry once", len(got))
+	}
+}
@@ -0,0 +1,284 @@
+package router_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// exportNote has everything CSV and iCalendar have to escape, and is long
+// enough to be folded
+const exportNote = "Chapter 12, \"The Return\"; read on the train\nthen finished it before bed, all forty pages of it"
+
+// readingDays is longer than a batch of check-ins loaded by the export
+const readingDays = 501
+
+// importHistory uploads a CSV history with a long habit and a short one,
+// which completed a three day streak and whose latest streak starts with
+// exportNote, and returns the number of check-ins imported
+func (h *harness) importHistory(token string) int {
+	h.t.Helper()
+
+	var history strings.Builder
+	cw := csv.NewWriter(&history)
+	cw.Write([]string{"habit", "date", "notes"})
+	today := h.clock.Now().UTC()
+	for i := readingDays - 1; i >= 0; i-- {
+		cw.Write([]string{"Read", today.AddDate(0, 0, -i).Format("2006-01-02"), ""})
+	}
+	for i := 10; i >= 8; i-- {
+		cw.Write([]string{"Stretch", today.AddDate(0, 0, -i).Format("2006-01-02"), ""})
+	}
+	cw.Write([]string{"Stretch", today.AddDate(0, 0, -2).Format("2006-01-02"), exportNote})
+	cw.Write([]string{"Stretch", today.AddDate(0, 0, -1).Format("2006-01-02"), ""})
+	cw.Flush()
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	part, err := mw.CreateFormFile("file", "history.csv")
+	if err != nil {
+		h.t.Fatal(err)
+	}
+	io.WriteString(part, history.String())
+	mw.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/import?format=csv", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec := httptest.NewRecorder()
+	h.router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusCreated {
+		h.t.Fatalf("import history: got status %d\n%s", rec.Code, rec.Body)
+	}
+	return readingDays + 5
+}
+
+// download requests an export and returns the raw response
+func (h *harness) download(format, token string) *httptest.ResponseRecorder {
+	h.t.Helper()
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/export?format="+format, nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec := httptest.NewRecorder()
+	h.router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		h.t.Fatalf("export %s: got status %d\n%s", format, rec.Code, rec.Body)
+	}
+	if got := rec.Header().Get("Cache-Control"); got != "no-store" {
+		h.t.Errorf("export %s: Cache-Control = %q, want no-store", format, got)
+	}
+	return rec
+}
+
+func TestExportJSON(t *testing.T) {
+	h := newHarness(t)
+	token := h.register("ada@example.com")
+	checkIns := h.importHistory(token)
+
+	rec := h.download("json", token)
+	if got := rec.Header().Get("Content-Type"); got != "application/json; charset=utf-8" {
+		t.Errorf("Content-Type = %q", got)
+	}
+	if got, want := rec.Header().Get("Content-Disposition"), `attachment; filename="consistency-export-2024-03-10.json"`; got != want {
+		t.Errorf("Content-Disposition = %q, want %q", got, want)
+	}
+
+	var archive map[string]json.RawMessage
+	if err := json.Unmarshal(rec.Body.Bytes(), &archive); err != nil {
+		t.Fatalf("export is not a JSON object: %v", err)
+	}
+	var keys []string
+	for key := range archive {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	if got, want := strings.Join(keys, ","), "achievements,check_ins,exported_at,habits,profile,streaks"; got != want {
+		t.Fatalf("archive keys = %s, want %s", got, want)
+	}
+
+	var profile struct {
+		Email string `json:"email"`
+	}
+	var habits, streaks, achievements []json.RawMessage
+	var exported []struct {
+		ID          uint      `json:"id"`
+		HabitName   string    `json:"habit_name"`
+		CheckInDate time.Time `json:"check_in_date"`
+		Notes       string    `json:"notes"`
+	}
+	for key, out := range map[string]interface{}{"profile": &profile, "habits": &habits, "streaks": &streaks, "check_ins": &exported, "achievements": &achievements} {
+		if err := json.Unmarshal(archive[key], out); err != nil {
+			t.Fatalf("decode %s: %v", key, err)
+		}
+	}
+
+	if profile.Email != "ada@example.com" || len(habits) != 2 || len(streaks) != 3 || len(achievements) == 0 {
+		t.Errorf("archive has %s with %d habits, %d streaks and %d achievements, want the user's 2 habits with their 3 streaks and achievements", profile.Email, len(habits), len(streaks), len(achievements))
+	}
+	if len(exported) != checkIns {
+		t.Fatalf("archive has %d check-ins, want %d", len(exported), checkIns)
+	}
+	seen := make(map[uint]bool)
+	for _, checkIn := range exported {
+		if seen[checkIn.ID] {
+			t.Fatalf("check-in %d is exported twice", checkIn.ID)
+		}
+		seen[checkIn.ID] = true
+	}
+	var noted int
+	for _, checkIn := range exported {
+		if checkIn.Notes == exportNote && checkIn.HabitName == "Stretch" {
+			noted++
+		}
+	}
+	if noted != 1 {
+		t.Errorf("found the note on %d check-ins, want 1", noted)
+	}
+}
+
+func TestExportCSV(t *testing.T) {
+	h := newHarness(t)
+	token := h.register("ada@example.com")
+	checkIns := h.importHistory(token)
+
+	rec := h.download("csv", token)
+	if got := rec.Header().Get("Content-Type"); got != "application/zip" {
+		t.Errorf("Content-Type = %q", got)
+	}
+	if got := rec.Header().Get("Content-Disposition"); !strings.HasSuffix(got, `.zip"`) {
+		t.Errorf("Content-Disposition = %q, want a zip", got)
+	}
+
+	body := rec.Body.Bytes()
+	zr, err := zip.NewReader(bytes.NewReader(body), int64(len(body)))
+	if err != nil {
+		t.Fatalf("export is not a zip archive: %v", err)
+	}
+
+	files := make(map[string][][]string)
+	var names []string
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		records, err := csv.NewReader(rc).ReadAll()
+		rc.Close()
+		if err != nil {
+			t.Fatalf("%s is not valid CSV: %v", f.Name, err)
+		}
+		files[f.Name] = records
+		names = append(names, f.Name)
+	}
+	sort.Strings(names)
+	if got, want := strings.Join(names, ","), "achievements.csv,check_ins.csv,habits.csv,profile.csv,streaks.csv"; got != want {
+		t.Fatalf("archive files = %s, want %s", got, want)
+	}
+
+	rows := map[string]int{"profile.csv": 1, "habits.csv": 2, "streaks.csv": 3, "check_ins.csv": checkIns}
+	for name, want := range rows {
+		if got := len(files[name]) - 1; got != want {
+			t.Errorf("%s has %d rows, want %d", name, got, want)
+		}
+	}
+	if len(files["achievements.csv"]) < 2 {
+		t.Error("achievements.csv has no rows")
+	}
+	for name, records := range files {
+		for i, record := range records[1:] {
+			if len(record) != len(records[0]) {
+				t.Errorf("%s row %d has %d fields, want %d", name, i+1, len(record), len(records[0]))
+			}
+		}
+	}
+
+	header := files["check_ins.csv"][0]
+	if got, want := strings.Join(header, ","), "id,habit_id,habit_name,streak_id,check_in_date,checked_in_at,notes"; got != want {
+		t.Fatalf("check_ins.csv header = %s, want %s", got, want)
+	}
+	var noted int
+	for _, record := range files["check_ins.csv"][1:] {
+		if record[6] == exportNote {
+			noted++
+		}
+	}
+	if noted != 1 {
+		t.Errorf("found the note on %d check-ins, want it read back unchanged once", noted)
+	}
+}
+
+func TestExportICS(t *testing.T) {
+	h := newHarness(t)
+	token := h.register("ada@example.com")
+	checkIns := h.importHistory(token)
+
+	rec := h.download("ics", token)
+	if got := rec.Header().Get("Content-Type"); got != "text/calendar; charset=utf-8" {
+		t.Errorf("Content-Type = %q", got)
+	}
+
+	raw := rec.Body.String()
+	if !strings.HasSuffix(raw, "\r\n") {
+		t.Fatal("calendar does not end with CRLF")
+	}
+	for i, line := range strings.Split(strings.TrimSuffix(raw, "\r\n"), "\r\n") {
+		if len(line) > 75 {
+			t.Fatalf("line %d is %d octets, want it folded at 75: %q", i+1, len(line), line)
+		}
+		if strings.Contains(line, "\n") {
+			t.Fatalf("line %d has a bare line feed: %q", i+1, line)
+		}
+	}
+
+	// Unfold the continuation lines before reading the properties
+	lines := strings.Split(strings.ReplaceAll(strings.TrimSuffix(raw, "\r\n"), "\r\n ", ""), "\r\n")
+	if lines[0] != "BEGIN:VCALENDAR" || lines[len(lines)-1] != "END:VCALENDAR" {
+		t.Fatalf("calendar is wrapped in %q and %q", lines[0], lines[len(lines)-1])
+	}
+
+	var events, described int
+	var start string
+	for _, line := range lines {
+		switch {
+		case line == "BEGIN:VEVENT":
+			events++
+			start = ""
+		case strings.HasPrefix(line, "DTSTART"):
+			if !strings.HasPrefix(line, "DTSTART;VALUE=DATE:") {
+				t.Fatalf("event starts with %q, want an all-day date", line)
+			}
+			start = strings.TrimPrefix(line, "DTSTART;VALUE=DATE:")
+		case strings.HasPrefix(line, "DTEND"):
+			day, err := time.Parse("20060102", start)
+			if err != nil {
+				t.Fatalf("event start %q: %v", start, err)
+			}
+			if want := "DTEND;VALUE=DATE:" + day.AddDate(0, 0, 1).Format("20060102"); line != want {
+				t.Fatalf("event ends with %q, want %q", line, want)
+			}
+		case strings.HasPrefix(line, "DESCRIPTION:"):
+			described++
+			want := `DESCRIPTION:Chapter 12\, "The Return"\; read on the train\nthen finished it before bed\, all forty pages of it`
+			if line != want {
+				t.Errorf("description = %q, want %q", line, want)
+			}
+		}
+	}
+	if events != checkIns {
+		t.Errorf("calendar has %d events, want one per check-in (%d)", events, checkIns)
+	}
+	if described != 1 {
+		t.Errorf("calendar has %d descriptions, want 1", described)
+	}
+	if !strings.Contains(raw, "SUMMARY:✓ Read\r\n") {
+		t.Error("calendar has no event for the habit")
+	}
+}
@@ -0,0 +1,340 @@
+package router_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/clock"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/config"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/database"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/health"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/jwtkeys"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/mailer"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/migrate"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/router"
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog"
+)
+
+func TestMain(m *testing.M) {
+	os.Setenv("ENV", "test")
+	os.Setenv("DB_DRIVER", database.DriverSQLite)
+	os.Setenv("DB_SQLITE_PATH", ":memory:")
+	os.Setenv("MAIL_DRIVER", "log")
+
+	gin.SetMode(gin.TestMode)
+	gin.DefaultWriter = io.Discard
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	os.Exit(m.Run())
+}
+
+// harness drives the full HTTP API against a fresh, migrated SQLite database
+type harness struct {
+	t      *testing.T
+	router *gin.Engine
+	clock  *clock.Fake
+	config *config.Holder
+	mail   *mailer.Outbox
+
+	// db is the database behind the router, for running background jobs
+	db *database.Database
+
+	// readiness holds the database checks; tests may add failing ones
+	readiness *health.Readiness
+}
+
+// envelope is the union of SuccessResponse and ErrorResponse. The auth
+// middleware answers with the documented Error schema instead, whose error
+// field is a plain string; it is decoded into Error.Message.
+type envelope struct {
+	Success bool            `json:"success"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data"`
+	Error   errorObject     `json:"error"`
+}
+
+type errorObject struct {
+	Code    string          `json:"code"`
+	Message string          `json:"message"`
+	Details json.RawMessage `json:"details"`
+}
+
+func (e *errorObject) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '"' {
+		return json.Unmarshal(data, &e.Message)
+	}
+	type plain errorObject
+	return json.Unmarshal(data, (*plain)(e))
+}
+
+// response is a recorded API response with its decoded envelope
+type response struct {
+	Status int
+	Header http.Header
+	Body   envelope
+	Raw    string
+}
+
+func newHarness(t *testing.T) *harness {
+	t.Helper()
+	return newHarnessWithClock(t, func(fake *clock.Fake) clock.Clock { return fake })
+}
+
+// newHarnessWithClock builds the router with the clock returned by wrap,
+// which is given the harness's fake clock to build on
+func newHarnessWithClock(t *testing.T, wrap func(*clock.Fake) clock.Clock) *harness {
+	t.Helper()
+
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatalf("load config: %v", err)
+	}
+
+	db, err := database.NewDatabase(cfg)
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	t.Cleanup(db.Close)
+
+	migrator, err := migrate.New(db.DB)
+	if err != nil {
+		t.Fatalf("load migrations: %v", err)
+	}
+	if _, err := migrator.Up(context.Background()); err != nil {
+		t.Fatalf("migrate database: %v", err)
+	}
+
+	return newHarnessOn(t, cfg, db, wrap)
+}
+
+// newHarnessOn builds the router on db, which the caller has opened and
+// migrated
+func newHarnessOn(t *testing.T, cfg *config.Config, db *database.Database, wrap func(*clock.Fake) clock.Clock) *harness {
+	t.Helper()
+
+	clk := clock.NewFake(time.Date(2024, 3, 10, 9, 0, 0, 0, time.UTC))
+	cfgs := config.NewHolder(cfg, "")
+	keys, err := jwtkeys.NewProvider(cfgs)
+	if err != nil {
+		t.Fatalf("load signing keys: %v", err)
+	}
+
+	mail := mailer.NewOutbox()
+	readiness := health.NewReadiness(health.DefaultTimeout, health.Database(db), health.Migrations(db))
+	return &harness{
+		t:         t,
+		router:    router.SetupRouter(cfgs, keys, db, mail, wrap(clk), readiness),
+		clock:     clk,
+		config:    cfgs,
+		mail:      mail,
+		db:        db,
+		readiness: readiness,
+	}
+}
+
+// reconfigure applies a copy of the current configuration changed by edit,
+// as a reload would
+func (h *harness) reconfigure(edit func(*config.Config)) {
+	h.t.Helper()
+	next := *h.config.Get()
+	edit(&next)
+	if _, err := h.config.Apply(&next); err != nil {
+		h.t.Fatalf("apply config: %v", err)
+	}
+}
+
+// nextDay moves the clock forward by the given number of days
+func (h *harness) nextDay(days int) {
+	h.clock.Advance(time.Duration(days) * 24 * time.Hour)
+}
+
+// do sends a request with an optional JSON body and bearer token
+func (h *harness) do(method, path, token string, body interface{}) response {
+	h.t.Helper()
+	return h.doWithHeader(method, path, token, body, nil)
+}
+
+// doWithHeader is do with extra request headers, such as those a proxy adds
+func (h *harness) doWithHeader(method, path, token string, body interface{}, header http.Header) response {
+	h.t.Helper()
+
+	var reader io.Reader
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			h.t.Fatalf("encode request body: %v", err)
+		}
+		reader = bytes.NewReader(payload)
+	}
+
+	req := httptest.NewRequest(method, path, reader)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+
+	rec := httptest.NewRecorder()
+	h.router.ServeHTTP(rec, req)
+
+	res := response{Status: rec.Code, Header: rec.Header(), Raw: rec.Body.String()}
+	if rec.Body.Len() > 0 {
+		if err := json.Unmarshal(rec.Body.Bytes(), &res.Body); err != nil {
+			h.t.Fatalf("%s %s: response is not a JSON envelope: %v\n%s", method, path, err, res.Raw)
+		}
+	}
+	return res
+}
+
+// expectSuccess asserts a SuccessResponse with the status and decodes its data into out
+func (h *harness) expectSuccess(res response, status int, out interface{}) {
+	h.t.Helper()
+	if res.Status != status || !res.Body.Success {
+		h.t.Fatalf("got status %d, want %d with a success envelope:\n%s", res.Status, status, res.Raw)
+	}
+	if out != nil {
+		if err := json.Unmarshal(res.Body.Data, out); err != nil {
+			h.t.Fatalf("decode response data: %v\n%s", err, res.Raw)
+		}
+	}
+}
+
+// expectError asserts an ErrorResponse with the status and error code
+func (h *harness) expectError(res response, status int, code string) {
+	h.t.Helper()
+	if res.Status != status || res.Body.Success || res.Body.Error.Code != code {
+		h.t.Fatalf("got status %d, want %d with error code %s:\n%s", res.Status, status, code, res.Raw)
+	}
+}
+
+// expectUnauthorized asserts the auth middleware's Error body with the message
+func (h *harness) expectUnauthorized(res response, message string) {
+	h.t.Helper()
+	if res.Status != http.StatusUnauthorized || res.Body.Success || res.Body.Error.Message != message {
+		h.t.Fatalf("got status %d, want 401 with error %q:\n%s", res.Status, message, res.Raw)
+	}
+}
+
+// register creates an account, verifies its email address and returns its
+// token
+func (h *harness) register(email string) string {
+	h.t.Helper()
+	token := h.registerUnverified(email)
+	h.expectSuccess(h.do(http.MethodPost, "/api/v1/auth/verify-email", "", gin.H{"token": h.verificationToken(email)}), http.StatusOK, nil)
+	return token
+}
+
+// registerUnverified creates an account without verifying its email address
+// and returns its token
+func (h *harness) registerUnverified(email string) string {
+	h.t.Helper()
+	var auth struct {
+		Token string `json:"token"`
+	}
+	h.expectSuccess(h.do(http.MethodPost, "/api/v1/auth/register", "", gin.H{
+		"name":     "Test User",
+		"email":    email,
+		"password": "password123",
+	}), http.StatusCreated, &auth)
+	if auth.Token == "" {
+		h.t.Fatal("register returned no token")
+	}
+	return auth.Token
+}
+
+var emailedTokenPattern = regexp.MustCompile(`token=([A-Za-z0-9_-]+)`)
+
+// verificationToken returns the token from the latest verification email
+// sent to the address
+func (h *harness) verificationToken(email string) string {
+	h.t.Helper()
+	return h.emailedToken(email, "Confirm your email address")
+}
+
+// emailedToken returns the token from the link in the latest email with the
+// subject sent to the address
+func (h *harness) emailedToken(email, subject string) string {
+	h.t.Helper()
+	messages := h.mail.Messages()
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].To != email || messages[i].Subject != subject {
+			continue
+		}
+		if match := emailedTokenPattern.FindStringSubmatch(messages[i].Text); match != nil {
+			return match[1]
+		}
+	}
+	h.t.Fatalf("no %q email was sent to %s", subject, email)
+	return ""
+}
+
+type habitData struct {
+	ID       uint   `json:"id"`
+	Name     string `json:"name"`
+	Color    string `json:"color"`
+	IsActive bool   `json:"is_active"`
+}
+
+type streakData struct {
+	ID                uint   `json:"id"`
+	HabitID           uint   `json:"habit_id"`
+	TargetDays        int    `json:"target_days"`
+	CurrentStreak     int    `json:"current_streak"`
+	MaxStreakAchieved int    `json:"max_streak_achieved"`
+	Status            string `json:"status"`
+}
+
+type checkInData struct {
+	ID          uint      `json:"id"`
+	StreakID    uint      `json:"streak_id"`
+	CheckInDate time.Time `json:"check_in_date"`
+	Notes       string    `json:"notes"`
+}
+
+type achievementData struct {
+	ID              uint            `json:"id"`
+	HabitID         uint            `json:"habit_id"`
+	AchievementType string          `json:"achievement_type"`
+	TargetDays      int             `json:"target_days"`
+	Metadata        json.RawMessage `json:"metadata"`
+}
+
+// createHabit creates a habit and returns it
+func (h *harness) createHabit(token, name string) habitData {
+	h.t.Helper()
+	var habit habitData
+	h.expectSuccess(h.do(http.MethodPost, "/api/v1/habits", token, gin.H{"name": name, "color": "#00AA00"}), http.StatusCreated, &habit)
+	return habit
+}
+
+// startStreak starts a streak for the habit and returns it
+func (h *harness) startStreak(token string, habitID uint, targetDays int) streakData {
+	h.t.Helper()
+	var streak streakData
+	h.expectSuccess(h.do(http.MethodPost, habitPath(habitID, "/streaks"), token, gin.H{"target_days": targetDays}), http.StatusCreated, &streak)
+	return streak
+}
+
+// checkIn checks in to the habit and returns the raw response
+func (h *harness) checkIn(token string, habitID uint) response {
+	h.t.Helper()
+	return h.do(http.MethodPost, habitPath(habitID, "/check-ins"), token, gin.H{"notes": "done"})
+}
+
+func habitPath(habitID uint, suffix string) string {
+	return fmt.Sprintf("/api/v1/habits/%d%s", habitID, suffix)
+}
@@ -0,0 +1,57 @@
+package router_test
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/config"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/jwtkeys"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestAsymmetricKeysArePublishedAsJWKS(t *testing.T) {
+	h := newHarness(t)
+	hmacToken := h.register("before@example.com")
+
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		t.Fatalf("marshal key: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "signing.pem")
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600); err != nil {
+		t.Fatalf("write key: %v", err)
+	}
+
+	h.reconfigure(func(cfg *config.Config) { cfg.Auth.JWTKeys = []string{"ed-test:" + path} })
+
+	res := h.do(http.MethodGet, "/.well-known/jwks.json", "", nil)
+	var jwks jwtkeys.JWKS
+	if err := json.Unmarshal([]byte(res.Raw), &jwks); err != nil || res.Status != http.StatusOK {
+		t.Fatalf("JWKS: status %d, %v:\n%s", res.Status, err, res.Raw)
+	}
+	if len(jwks.Keys) != 1 || jwks.Keys[0].ID != "ed-test" || jwks.Keys[0].Algorithm != "EdDSA" {
+		t.Fatalf("JWKS = %+v, want only the Ed25519 key", jwks)
+	}
+
+	// New tokens are signed with the Ed25519 key and verify with its public key
+	token := h.register("after@example.com")
+	parsed, err := jwt.Parse(token, func(*jwt.Token) (interface{}, error) { return public, nil }, jwt.WithValidMethods([]string{"EdDSA"}))
+	if err != nil || parsed.Header["kid"] != "ed-test" {
+		t.Fatalf("token is not signed with the Ed25519 key: %v, header %v", err, parsed.Header)
+	}
+	h.expectSuccess(h.do(http.MethodGet, "/api/v1/habits", token, nil), http.StatusOK, nil)
+
+	// The unscheduled key replaced the secret without a date, so it keeps verifying
+	h.expectSuccess(h.do(http.MethodGet, "/api/v1/habits", hmacToken, nil), http.StatusOK, nil)
+}
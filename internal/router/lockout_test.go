@@ -0,0 +1,129 @@
+package router_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/config"
+	"github.com/gin-gonic/gin"
+)
+
+const unlockSubject = "Your account has been locked"
+
+type securityEventData struct {
+	Type      string `json:"type"`
+	IPAddress string `json:"ip_address"`
+}
+
+// login attempts a password login and returns the raw response
+func (h *harness) login(email, password string) response {
+	h.t.Helper()
+	return h.do(http.MethodPost, "/api/v1/auth/login", "", gin.H{"email": email, "password": password})
+}
+
+func TestRepeatedFailedLoginsLockTheAccount(t *testing.T) {
+	h := newHarness(t)
+	token := h.register("ada@example.com")
+
+	// A few mistakes are free
+	for i := 0; i < 3; i++ {
+		h.expectError(h.login("ada@example.com", "wrong-password"), http.StatusUnauthorized, "UNAUTHORIZED")
+	}
+
+	// Then every attempt has to wait, even with the right password
+	res := h.login("ada@example.com", "password123")
+	h.expectError(res, http.StatusTooManyRequests, "TOO_MANY_ATTEMPTS")
+	if got := res.Header.Get("Retry-After"); got != "1" {
+		t.Errorf("Retry-After = %q, want 1", got)
+	}
+
+	// Addresses without an account are treated the same
+	for i := 0; i < 3; i++ {
+		h.expectError(h.login("nobody@example.com", "wrong-password"), http.StatusUnauthorized, "UNAUTHORIZED")
+	}
+	h.expectError(h.login("nobody@example.com", "wrong-password"), http.StatusTooManyRequests, "TOO_MANY_ATTEMPTS")
+
+	// The tenth failure locks the account and emails an unlock link
+	for i := 3; i < 10; i++ {
+		h.clock.Advance(time.Minute)
+		h.expectError(h.login("ada@example.com", "wrong-password"), http.StatusUnauthorized, "UNAUTHORIZED")
+	}
+	h.clock.Advance(time.Minute)
+	res = h.login("ada@example.com", "password123")
+	h.expectError(res, http.StatusTooManyRequests, "ACCOUNT_LOCKED")
+	if got := res.Header.Get("Retry-After"); got != "1740" {
+		t.Errorf("Retry-After = %q, want the rest of the 30 minute lock", got)
+	}
+
+	// Tokens issued before the lock keep working
+	h.expectSuccess(h.do(http.MethodGet, "/api/v1/profile", token, nil), http.StatusOK, nil)
+
+	unlock := h.emailedToken("ada@example.com", unlockSubject)
+	h.expectSuccess(h.do(http.MethodPost, "/api/v1/auth/unlock", "", gin.H{"token": unlock}), http.StatusOK, nil)
+	h.expectError(h.do(http.MethodPost, "/api/v1/auth/unlock", "", gin.H{"token": unlock}), http.StatusBadRequest, "INVALID_TOKEN")
+
+	var auth authData
+	h.expectSuccess(h.login("ada@example.com", "password123"), http.StatusOK, &auth)
+
+	// The account's activity can be reviewed
+	var events []securityEventData
+	h.expectSuccess(h.do(http.MethodGet, "/api/v1/profile/security-events", auth.Token, nil), http.StatusOK, &events)
+	want := []string{"login_succeeded", "account_unlocked", "account_locked", "login_failed"}
+	if len(events) != 13 {
+		t.Fatalf("got %d security events, want 13: %+v", len(events), events)
+	}
+	for i, eventType := range want {
+		if events[i].Type != eventType || events[i].IPAddress == "" {
+			t.Errorf("event %d = %+v, want %s with the client IP", i, events[i], eventType)
+		}
+	}
+}
+
+func TestAccountLockExpires(t *testing.T) {
+	h := newHarness(t)
+	h.register("grace@example.com")
+
+	for i := 0; i < 10; i++ {
+		h.expectError(h.login("grace@example.com", "wrong-password"), http.StatusUnauthorized, "UNAUTHORIZED")
+		h.clock.Advance(time.Minute)
+	}
+	h.expectError(h.login("grace@example.com", "password123"), http.StatusTooManyRequests, "ACCOUNT_LOCKED")
+
+	// The lock lifts by itself, and the emailed link stops working
+	h.clock.Advance(30 * time.Minute)
+	h.expectSuccess(h.login("grace@example.com", "password123"), http.StatusOK, nil)
+	unlock := h.emailedToken("grace@example.com", unlockSubject)
+	h.expectError(h.do(http.MethodPost, "/api/v1/auth/unlock", "", gin.H{"token": unlock}), http.StatusBadRequest, "INVALID_TOKEN")
+}
+
+func TestForwardedForIsOnlyTrustedFromProxies(t *testing.T) {
+	// httptest requests come from 192.0.2.1
+	loginVia := func(h *harness, forwardedFor string) response {
+		t.Helper()
+		return h.doWithHeader(http.MethodPost, "/api/v1/auth/login", "", gin.H{
+			"email": "nobody@example.com", "password": "wrong-password",
+		}, http.Header{"X-Forwarded-For": {forwardedFor}})
+	}
+
+	// Without trusted proxies a client cannot spread its failures over
+	// made-up addresses
+	h := newHarness(t)
+	h.reconfigure(func(cfg *config.Config) { cfg.Lockout.IPLimit = 2 })
+	h.expectError(loginVia(h, "203.0.113.1"), http.StatusUnauthorized, "UNAUTHORIZED")
+	h.expectError(loginVia(h, "203.0.113.2"), http.StatusUnauthorized, "UNAUTHORIZED")
+	h.expectError(loginVia(h, "203.0.113.3"), http.StatusTooManyRequests, "ACCOUNT_LOCKED")
+
+	// Behind a trusted proxy the forwarded address is the client's
+	t.Setenv("SERVER_TRUSTED_PROXIES", "192.0.2.0/24")
+	h = newHarness(t)
+	h.reconfigure(func(cfg *config.Config) {
+		cfg.Lockout.IPLimit = 2
+		cfg.Lockout.AccountLimit = 100
+		cfg.Lockout.DelayAfter = 100
+	})
+	h.expectError(loginVia(h, "203.0.113.1"), http.StatusUnauthorized, "UNAUTHORIZED")
+	h.expectError(loginVia(h, "203.0.113.1"), http.StatusUnauthorized, "UNAUTHORIZED")
+	h.expectError(loginVia(h, "203.0.113.1"), http.StatusTooManyRequests, "ACCOUNT_LOCKED")
+	h.expectError(loginVia(h, "203.0.113.2"), http.StatusUnauthorized, "UNAUTHORIZED")
+}
@@ -0,0 +1,73 @@
+package router_test
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+func TestRequestIDsAndRequestLogs(t *testing.T) {
+	var logs bytes.Buffer
+	global, level := log.Logger, zerolog.GlobalLevel()
+	log.Logger = zerolog.New(&logs)
+	zerolog.SetGlobalLevel(zerolog.InfoLevel)
+	t.Cleanup(func() {
+		log.Logger = global
+		zerolog.SetGlobalLevel(level)
+	})
+
+	h := newHarness(t)
+	ada := h.register("ada@example.com")
+
+	// An ID from the proxy is kept
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/profile", nil)
+	req.Header.Set("Authorization", "Bearer "+ada)
+	req.Header.Set("X-Request-ID", "edge-42")
+	rec := httptest.NewRecorder()
+	h.router.ServeHTTP(rec, req)
+	if got := rec.Header().Get("X-Request-ID"); got != "edge-42" {
+		t.Errorf("X-Request-ID = %q, want edge-42", got)
+	}
+
+	entry := findLog(t, &logs, "edge-42")
+	if entry["route"] != "/api/v1/profile" || entry["user_id"] != float64(1) || entry["status"] != float64(200) {
+		t.Errorf("request log = %v, want route, user ID and status", entry)
+	}
+
+	// Otherwise, or when it is unusable, one is generated
+	res := h.do(http.MethodGet, "/api/v1/health", "", nil)
+	if id := res.Header.Get("X-Request-ID"); len(id) != 32 {
+		t.Errorf("generated X-Request-ID = %q, want 32 hex digits", id)
+	}
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/health", nil)
+	req.Header.Set("X-Request-ID", "bad id\n"+strings.Repeat("x", 200))
+	rec = httptest.NewRecorder()
+	h.router.ServeHTTP(rec, req)
+	if id := rec.Header().Get("X-Request-ID"); len(id) != 32 {
+		t.Errorf("X-Request-ID for an unusable ID = %q, want a generated one", id)
+	}
+}
+
+// findLog returns the request log line with the request ID
+func findLog(t *testing.T, logs *bytes.Buffer, requestID string) map[string]interface{} {
+	t.Helper()
+	scanner := bufio.NewScanner(bytes.NewReader(logs.Bytes()))
+	for scanner.Scan() {
+		var entry map[string]interface{}
+		if json.Unmarshal(scanner.Bytes(), &entry) != nil {
+			continue
+		}
+		if entry["request_id"] == requestID && entry["message"] == "Request processed" {
+			return entry
+		}
+	}
+	t.Fatalf("no request log with ID %s in:\n%s", requestID, logs.String())
+	return nil
+}
@@ -0,0 +1,158 @@
+package router_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/totp"
+	"github.com/gin-gonic/gin"
+)
+
+type mfaStatusData struct {
+	Available         bool  `json:"available"`
+	Enabled           bool  `json:"enabled"`
+	RecoveryCodesLeft int64 `json:"recovery_codes_left"`
+	EnrolmentPending  bool  `json:"enrolment_pending"`
+}
+
+type loginData struct {
+	Token          string `json:"token"`
+	MFARequired    bool   `json:"mfa_required"`
+	ChallengeToken string `json:"challenge_token"`
+}
+
+type recoveryCodesData struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// totpCode returns the authenticator code for the secret at the fake clock's time
+func (h *harness) totpCode(secret string) string {
+	h.t.Helper()
+	code, err := totp.Code(secret, totp.Step(h.clock.Now()))
+	if err != nil {
+		h.t.Fatalf("generate TOTP code: %v", err)
+	}
+	return code
+}
+
+// enableMFA enrols an authenticator app for the user and returns its secret
+// and the recovery codes
+func (h *harness) enableMFA(token string) (string, []string) {
+	h.t.Helper()
+	var enrolment struct {
+		Secret string `json:"secret"`
+		URI    string `json:"uri"`
+	}
+	h.expectSuccess(h.do(http.MethodPost, "/api/v1/profile/mfa/totp", token, gin.H{"password": "password123"}), http.StatusCreated, &enrolment)
+	if enrolment.Secret == "" || enrolment.URI == "" {
+		h.t.Fatalf("enrolment = %+v, want a secret and URI", enrolment)
+	}
+
+	var codes recoveryCodesData
+	h.expectSuccess(h.do(http.MethodPost, "/api/v1/profile/mfa/totp/confirm", token, gin.H{"code": h.totpCode(enrolment.Secret)}), http.StatusOK, &codes)
+	if len(codes.RecoveryCodes) != 10 {
+		h.t.Fatalf("got %d recovery codes, want 10", len(codes.RecoveryCodes))
+	}
+	return enrolment.Secret, codes.RecoveryCodes
+}
+
+// passwordLogin logs in with the password and returns the MFA challenge token
+func (h *harness) passwordLogin(email string) string {
+	h.t.Helper()
+	var login loginData
+	h.expectSuccess(h.login(email, "password123"), http.StatusOK, &login)
+	if !login.MFARequired || login.ChallengeToken == "" || login.Token != "" {
+		h.t.Fatalf("login = %+v, want an MFA challenge instead of a token", login)
+	}
+	return login.ChallengeToken
+}
+
+func TestTwoFactorLogin(t *testing.T) {
+	h := newHarness(t)
+	token := h.register("ada@example.com")
+
+	var status mfaStatusData
+	h.expectSuccess(h.do(http.MethodGet, "/api/v1/profile/mfa", token, nil), http.StatusOK, &status)
+	if !status.Available || status.Enabled {
+		t.Fatalf("status = %+v, want available and not enabled", status)
+	}
+
+	// Enrolment needs the password, and a working code to finish
+	h.expectError(h.do(http.MethodPost, "/api/v1/profile/mfa/totp", token, gin.H{"password": "wrong-password"}), http.StatusForbidden, "INVALID_PASSWORD")
+	h.expectError(h.do(http.MethodPost, "/api/v1/profile/mfa/totp/confirm", token, gin.H{"code": "123456"}), http.StatusConflict, "CONFLICT")
+	secret, recoveryCodes := h.enableMFA(token)
+	h.expectError(h.do(http.MethodPost, "/api/v1/profile/mfa/totp", token, gin.H{"password": "password123"}), http.StatusConflict, "CONFLICT")
+
+	// The password alone no longer signs in, and a code cannot be used twice
+	challenge := h.passwordLogin("ada@example.com")
+	h.expectError(h.do(http.MethodPost, "/api/v1/auth/mfa", "", gin.H{"challenge_token": challenge, "code": h.totpCode(secret)}), http.StatusUnauthorized, "INVALID_CODE")
+	h.clock.Advance(30 * time.Second)
+	var auth authData
+	h.expectSuccess(h.do(http.MethodPost, "/api/v1/auth/mfa", "", gin.H{"challenge_token": challenge, "code": h.totpCode(secret)}), http.StatusOK, &auth)
+	if auth.Token == "" || auth.User.Email != "ada@example.com" {
+		t.Fatalf("auth = %+v, want a token for the user", auth)
+	}
+	h.clock.Advance(30 * time.Second)
+	h.expectError(h.do(http.MethodPost, "/api/v1/auth/mfa", "", gin.H{"challenge_token": challenge, "code": h.totpCode(secret)}), http.StatusUnauthorized, "INVALID_CHALLENGE")
+
+	// Recovery codes work once each, whatever their case
+	challenge = h.passwordLogin("ada@example.com")
+	h.expectSuccess(h.do(http.MethodPost, "/api/v1/auth/mfa", "", gin.H{"challenge_token": challenge, "code": recoveryCodes[0]}), http.StatusOK, nil)
+	challenge = h.passwordLogin("ada@example.com")
+	h.expectError(h.do(http.MethodPost, "/api/v1/auth/mfa", "", gin.H{"challenge_token": challenge, "code": recoveryCodes[0]}), http.StatusUnauthorized, "INVALID_CODE")
+
+	h.expectSuccess(h.do(http.MethodGet, "/api/v1/profile/mfa", auth.Token, nil), http.StatusOK, &status)
+	if !status.Enabled || status.RecoveryCodesLeft != 9 {
+		t.Errorf("status = %+v, want enabled with 9 recovery codes left", status)
+	}
+
+	// New codes replace the old ones
+	var regenerated recoveryCodesData
+	h.expectSuccess(h.do(http.MethodPost, "/api/v1/profile/mfa/recovery-codes", auth.Token, gin.H{"password": "password123"}), http.StatusCreated, &regenerated)
+	if len(regenerated.RecoveryCodes) != 10 {
+		t.Fatalf("got %d recovery codes, want 10", len(regenerated.RecoveryCodes))
+	}
+	h.expectError(h.do(http.MethodPost, "/api/v1/auth/mfa", "", gin.H{"challenge_token": challenge, "code": recoveryCodes[1]}), http.StatusUnauthorized, "INVALID_CODE")
+
+	// Turning it off takes the password and a code
+	h.expectError(h.do(http.MethodDelete, "/api/v1/profile/mfa", auth.Token, gin.H{"password": "password123", "code": "000000"}), http.StatusBadRequest, "INVALID_CODE")
+	h.expectSuccess(h.do(http.MethodDelete, "/api/v1/profile/mfa", auth.Token, gin.H{"password": "password123", "code": regenerated.RecoveryCodes[0]}), http.StatusOK, nil)
+	var login loginData
+	h.expectSuccess(h.login("ada@example.com", "password123"), http.StatusOK, &login)
+	if login.Token == "" || login.MFARequired {
+		t.Errorf("login = %+v, want a token once two-factor authentication is off", login)
+	}
+
+	var events []securityEventData
+	h.expectSuccess(h.do(http.MethodGet, "/api/v1/profile/security-events", login.Token, nil), http.StatusOK, &events)
+	seen := make(map[string]bool)
+	for _, event := range events {
+		seen[event.Type] = true
+	}
+	for _, eventType := range []string{"mfa_enabled", "recovery_code_used", "recovery_codes_regenerated", "mfa_disabled"} {
+		if !seen[eventType] {
+			t.Errorf("no %s security event in %+v", eventType, events)
+		}
+	}
+}
+
+func TestTwoFactorChallengeLimits(t *testing.T) {
+	h := newHarness(t)
+	token := h.register("grace@example.com")
+	secret, _ := h.enableMFA(token)
+	h.clock.Advance(30 * time.Second)
+
+	// Wrong codes count as failed logins
+	challenge := h.passwordLogin("grace@example.com")
+	for i := 0; i < 3; i++ {
+		h.expectError(h.do(http.MethodPost, "/api/v1/auth/mfa", "", gin.H{"challenge_token": challenge, "code": "000000"}), http.StatusUnauthorized, "INVALID_CODE")
+	}
+	h.expectError(h.do(http.MethodPost, "/api/v1/auth/mfa", "", gin.H{"challenge_token": challenge, "code": h.totpCode(secret)}), http.StatusTooManyRequests, "TOO_MANY_ATTEMPTS")
+
+	// The challenge expires after five minutes
+	h.clock.Advance(5 * time.Minute)
+	h.expectError(h.do(http.MethodPost, "/api/v1/auth/mfa", "", gin.H{"challenge_token": challenge, "code": h.totpCode(secret)}), http.StatusUnauthorized, "INVALID_CHALLENGE")
+	challenge = h.passwordLogin("grace@example.com")
+	h.expectSuccess(h.do(http.MethodPost, "/api/v1/auth/mfa", "", gin.H{"challenge_token": challenge, "code": h.totpCode(secret)}), http.StatusOK, nil)
+}
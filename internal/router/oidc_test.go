@@ -0,0 +1,231 @@
+package router_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/config"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/oidc/oidctest"
+	"github.com/gin-gonic/gin"
+)
+
+type authData struct {
+	Token string `json:"token"`
+	User  struct {
+		ID          uint   `json:"id"`
+		Email       string `json:"email"`
+		Name        string `json:"name"`
+		HasPassword bool   `json:"has_password"`
+	} `json:"user"`
+}
+
+type identityData struct {
+	Provider string `json:"provider"`
+	Email    string `json:"email"`
+}
+
+// newOIDCHarness configures fake Google and Apple providers before building
+// the router
+func newOIDCHarness(t *testing.T) (*harness, *oidctest.Provider, *oidctest.Provider) {
+	t.Helper()
+
+	google := oidctest.NewProvider(t, "https://accounts.google.com", "consistency-android.apps.googleusercontent.com")
+	apple := oidctest.NewProvider(t, "https://appleid.apple.com", "app.consistency.ios")
+
+	t.Setenv("OIDC_GOOGLE_CLIENT_IDS", "consistency-web.apps.googleusercontent.com,"+google.ClientID)
+	t.Setenv("OIDC_GOOGLE_JWKS_URL", google.JWKSURL())
+	t.Setenv("OIDC_APPLE_CLIENT_IDS", apple.ClientID)
+	t.Setenv("OIDC_APPLE_JWKS_URL", apple.JWKSURL())
+
+	return newHarness(t), google, apple
+}
+
+// signIn posts an ID token for the provider and returns the raw response
+func (h *harness) signIn(provider, idToken string, extra gin.H) response {
+	h.t.Helper()
+	body := gin.H{"id_token": idToken}
+	for name, value := range extra {
+		body[name] = value
+	}
+	return h.do(http.MethodPost, "/api/v1/auth/oidc/"+provider, "", body)
+}
+
+func TestSignInWithAppleCreatesPasswordlessAccount(t *testing.T) {
+	h, _, apple := newOIDCHarness(t)
+
+	// Apple only shares the name with the app, which passes it along
+	idToken := apple.Token(t, "001234.apple", h.clock.Now(), map[string]interface{}{
+		"email":          "relay@privaterelay.appleid.com",
+		"email_verified": "true",
+		"nonce":          "nonce-1",
+	})
+	var created authData
+	h.expectSuccess(h.signIn("apple", idToken, gin.H{"nonce": "nonce-1", "name": "Grace Hopper"}), http.StatusCreated, &created)
+	if created.Token == "" || created.User.Name != "Grace Hopper" || created.User.HasPassword {
+		t.Fatalf("sign-in created %+v, want a passwordless account named Grace Hopper", created.User)
+	}
+
+	// The account works like any other
+	h.createHabit(created.Token, "Read")
+	var identities []identityData
+	h.expectSuccess(h.do(http.MethodGet, "/api/v1/profile/identities", created.Token, nil), http.StatusOK, &identities)
+	if len(identities) != 1 || identities[0].Provider != "apple" || identities[0].Email != "relay@privaterelay.appleid.com" {
+		t.Fatalf("identities = %+v, want the Apple identity", identities)
+	}
+
+	// Signing in again finds the same account by subject
+	var again authData
+	h.expectSuccess(h.signIn("apple", apple.Token(t, "001234.apple", h.clock.Now(), nil), nil), http.StatusOK, &again)
+	if again.User.ID != created.User.ID {
+		t.Fatalf("second sign-in returned user %d, want %d", again.User.ID, created.User.ID)
+	}
+
+	// There is no password to sign in with until one is set
+	login := gin.H{"email": "relay@privaterelay.appleid.com", "password": "password123"}
+	h.expectError(h.do(http.MethodPost, "/api/v1/auth/login", "", login), http.StatusUnauthorized, "UNAUTHORIZED")
+
+	var updated authData
+	h.expectSuccess(h.do(http.MethodPut, "/api/v1/profile/password", created.Token, gin.H{"new_password": "password123"}), http.StatusOK, &updated.User)
+	if !updated.User.HasPassword {
+		t.Error("has_password is still false after setting a password")
+	}
+	h.expectSuccess(h.do(http.MethodPost, "/api/v1/auth/login", "", login), http.StatusOK, nil)
+
+	// Once set, changing the password needs the current one
+	h.expectError(h.do(http.MethodPut, "/api/v1/profile/password", created.Token, gin.H{"new_password": "another-password"}), http.StatusForbidden, "INVALID_PASSWORD")
+	h.expectSuccess(h.do(http.MethodPut, "/api/v1/profile/password", created.Token, gin.H{"current_password": "password123", "new_password": "another-password"}), http.StatusOK, nil)
+}
+
+func TestSignInWithGoogleLinksVerifiedEmail(t *testing.T) {
+	h, google, _ := newOIDCHarness(t)
+	token := h.register("ada@example.com")
+	var profile struct {
+		ID uint `json:"id"`
+	}
+	h.expectSuccess(h.do(http.MethodGet, "/api/v1/profile", token, nil), http.StatusOK, &profile)
+
+	// An unverified address must not be linked to the account
+	unverified := google.Token(t, "google-1", h.clock.Now(), map[string]interface{}{"email": "ada@example.com", "email_verified": false})
+	h.expectError(h.signIn("google", unverified, nil), http.StatusForbidden, "EMAIL_NOT_VERIFIED")
+
+	verified := google.Token(t, "google-1", h.clock.Now(), map[string]interface{}{"email": "ada@example.com", "email_verified": true})
+	var linked authData
+	h.expectSuccess(h.signIn("google", verified, nil), http.StatusOK, &linked)
+	if linked.User.ID != profile.ID || !linked.User.HasPassword {
+		t.Fatalf("sign-in returned %+v, want the existing account %d", linked.User, profile.ID)
+	}
+
+	// The link holds when the address changes at the provider
+	renamed := google.Token(t, "google-1", h.clock.Now(), map[string]interface{}{"email": "ada@newmail.example", "email_verified": true})
+	h.expectSuccess(h.signIn("google", renamed, nil), http.StatusOK, &linked)
+	if linked.User.ID != profile.ID {
+		t.Fatalf("sign-in after an email change returned user %d, want %d", linked.User.ID, profile.ID)
+	}
+	var identities []identityData
+	h.expectSuccess(h.do(http.MethodGet, "/api/v1/profile/identities", token, nil), http.StatusOK, &identities)
+	if len(identities) != 1 || identities[0].Email != "ada@newmail.example" {
+		t.Fatalf("identities = %+v, want the Google identity with the new email", identities)
+	}
+}
+
+func TestSignInDoesNotLinkUnverifiedAccounts(t *testing.T) {
+	h, google, _ := newOIDCHarness(t)
+
+	// Someone registers the victim's address with a password of their own
+	attacker := h.registerUnverified("victim@example.com")
+
+	verified := google.Token(t, "google-victim", h.clock.Now(), map[string]interface{}{"email": "victim@example.com", "email_verified": true})
+	h.expectError(h.signIn("google", verified, nil), http.StatusConflict, "ACCOUNT_NOT_VERIFIED")
+
+	var identities []identityData
+	h.expectSuccess(h.do(http.MethodGet, "/api/v1/profile/identities", attacker, nil), http.StatusOK, &identities)
+	if len(identities) != 0 {
+		t.Fatalf("identities = %+v, want none linked to the unverified account", identities)
+	}
+
+	// The owner takes the account back through the reset link in their inbox,
+	// which also verifies the address, and can then sign in with Google
+	h.expectSuccess(h.do(http.MethodPost, "/api/v1/auth/forgot-password", "", gin.H{"email": "victim@example.com"}), http.StatusOK, nil)
+	reset := h.emailedToken("victim@example.com", "Reset your password")
+	h.expectSuccess(h.do(http.MethodPost, "/api/v1/auth/reset-password", "", gin.H{"token": reset, "password": "owner-password-1"}), http.StatusOK, nil)
+
+	h.expectUnauthorized(h.do(http.MethodPost, "/api/v1/auth/login", "", gin.H{
+		"email": "victim@example.com", "password": "password123",
+	}), "invalid email or password")
+	var linked authData
+	h.expectSuccess(h.signIn("google", verified, nil), http.StatusOK, &linked)
+	if linked.User.Email != "victim@example.com" {
+		t.Fatalf("sign-in after the reset returned %+v, want the victim's account", linked.User)
+	}
+}
+
+func TestSignInWithProviderRequiresSecondFactor(t *testing.T) {
+	h, google, _ := newOIDCHarness(t)
+	token := h.register("ada@example.com")
+	secret, _ := h.enableMFA(token)
+
+	// Linking the identity and signing in with it both stop at the challenge
+	for _, step := range []string{"link", "linked"} {
+		idToken := google.Token(t, "google-ada", h.clock.Now(), map[string]interface{}{"email": "ada@example.com", "email_verified": true})
+		var login loginData
+		h.expectSuccess(h.signIn("google", idToken, nil), http.StatusOK, &login)
+		if !login.MFARequired || login.ChallengeToken == "" || login.Token != "" {
+			t.Fatalf("%s: sign-in = %+v, want an MFA challenge instead of a token", step, login)
+		}
+
+		h.clock.Advance(30 * time.Second)
+		var auth authData
+		h.expectSuccess(h.do(http.MethodPost, "/api/v1/auth/mfa", "", gin.H{"challenge_token": login.ChallengeToken, "code": h.totpCode(secret)}), http.StatusOK, &auth)
+		if auth.Token == "" || auth.User.Email != "ada@example.com" {
+			t.Fatalf("%s: auth = %+v, want a token for the user", step, auth)
+		}
+	}
+}
+
+func TestSignInRejectsInvalidRequests(t *testing.T) {
+	h, google, apple := newOIDCHarness(t)
+	now := h.clock.Now()
+
+	h.expectError(h.signIn("github", "token", nil), http.StatusNotFound, "NOT_FOUND")
+	h.expectError(h.signIn("google", "not-a-jwt", nil), http.StatusUnauthorized, "INVALID_ID_TOKEN")
+
+	// A Google token is not accepted for Apple, nor is a token for another app
+	h.expectError(h.signIn("apple", google.Token(t, "google-1", now, nil), nil), http.StatusUnauthorized, "INVALID_ID_TOKEN")
+	h.expectError(h.signIn("apple", apple.Token(t, "apple-1", now, map[string]interface{}{"aud": "com.other.app"}), nil), http.StatusUnauthorized, "INVALID_ID_TOKEN")
+	h.expectError(h.signIn("apple", apple.Token(t, "apple-1", now, map[string]interface{}{"nonce": "old"}), gin.H{"nonce": "new"}), http.StatusUnauthorized, "INVALID_ID_TOKEN")
+
+	// Expired tokens are rejected by the API clock
+	expired := apple.Token(t, "apple-1", now, map[string]interface{}{"email": "late@example.com", "email_verified": true})
+	h.nextDay(1)
+	h.expectError(h.signIn("apple", expired, nil), http.StatusUnauthorized, "INVALID_ID_TOKEN")
+
+	// New accounts follow the registration switch, existing ones still sign in
+	h.reconfigure(func(cfg *config.Config) { cfg.Features.Registration = false })
+	fresh := apple.Token(t, "apple-2", h.clock.Now(), map[string]interface{}{"email": "new@example.com", "email_verified": true})
+	h.expectError(h.signIn("apple", fresh, nil), http.StatusForbidden, "FEATURE_DISABLED")
+}
+
+func TestPasswordlessAccountCanBeDeleted(t *testing.T) {
+	h, google, _ := newOIDCHarness(t)
+
+	idToken := google.Token(t, "google-9", h.clock.Now(), map[string]interface{}{
+		"email":          "leaving@example.com",
+		"email_verified": true,
+		"name":           "Leaving User",
+	})
+	var auth authData
+	h.expectSuccess(h.signIn("google", idToken, nil), http.StatusCreated, &auth)
+	if auth.User.Name != "Leaving User" {
+		t.Errorf("name = %q, want the name from the ID token", auth.User.Name)
+	}
+
+	h.expectSuccess(h.do(http.MethodDelete, "/api/v1/profile", auth.Token, gin.H{}), http.StatusAccepted, nil)
+
+	// The identity points at a deleted account until it is purged
+	res := h.signIn("google", google.Token(t, "google-9", h.clock.Now(), nil), nil)
+	if res.Status != http.StatusUnauthorized || !strings.Contains(res.Raw, "Account no longer exists") {
+		t.Fatalf("sign-in to a deleted account: got status %d:\n%s", res.Status, res.Raw)
+	}
+}
@@ -0,0 +1,74 @@
+package router_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestPasswordReset(t *testing.T) {
+	h := newHarness(t)
+	h.register("forgetful@example.com")
+
+	h.expectSuccess(h.do(http.MethodPost, "/api/v1/auth/forgot-password", "", gin.H{"email": "forgetful@example.com"}), http.StatusOK, nil)
+	token := h.emailedToken("forgetful@example.com", "Reset your password")
+
+	// Both parts of the email carry the link
+	for _, message := range h.mail.Messages() {
+		if message.Subject == "Reset your password" && !strings.Contains(message.HTML, token) {
+			t.Error("the HTML part of the reset email has no link")
+		}
+	}
+
+	h.expectError(h.do(http.MethodPost, "/api/v1/auth/reset-password", "", gin.H{"token": token, "password": "short"}), http.StatusBadRequest, "INVALID_REQUEST")
+	h.expectSuccess(h.do(http.MethodPost, "/api/v1/auth/reset-password", "", gin.H{"token": token, "password": "new-password-456"}), http.StatusOK, nil)
+
+	h.expectUnauthorized(h.do(http.MethodPost, "/api/v1/auth/login", "", gin.H{
+		"email": "forgetful@example.com", "password": "password123",
+	}), "invalid email or password")
+	var login struct {
+		Token string `json:"token"`
+	}
+	h.expectSuccess(h.do(http.MethodPost, "/api/v1/auth/login", "", gin.H{
+		"email": "forgetful@example.com", "password": "new-password-456",
+	}), http.StatusOK, &login)
+
+	var events []struct {
+		Type string `json:"type"`
+	}
+	h.expectSuccess(h.do(http.MethodGet, "/api/v1/profile/security-events", login.Token, nil), http.StatusOK, &events)
+	found := false
+	for _, event := range events {
+		found = found || event.Type == "password_reset"
+	}
+	if !found {
+		t.Errorf("security events %+v have no password_reset", events)
+	}
+
+	// Links are single use
+	h.expectError(h.do(http.MethodPost, "/api/v1/auth/reset-password", "", gin.H{"token": token, "password": "another-password"}), http.StatusBadRequest, "INVALID_TOKEN")
+}
+
+func TestPasswordResetLinkExpires(t *testing.T) {
+	h := newHarness(t)
+	h.register("slow@example.com")
+
+	h.expectSuccess(h.do(http.MethodPost, "/api/v1/auth/forgot-password", "", gin.H{"email": "slow@example.com"}), http.StatusOK, nil)
+	token := h.emailedToken("slow@example.com", "Reset your password")
+
+	h.clock.Advance(h.config.Get().Auth.PasswordResetExpiry + time.Second)
+	h.expectError(h.do(http.MethodPost, "/api/v1/auth/reset-password", "", gin.H{"token": token, "password": "new-password-456"}), http.StatusBadRequest, "INVALID_TOKEN")
+}
+
+func TestForgotPasswordDoesNotRevealAccounts(t *testing.T) {
+	h := newHarness(t)
+
+	h.expectSuccess(h.do(http.MethodPost, "/api/v1/auth/forgot-password", "", gin.H{"email": "nobody@example.com"}), http.StatusOK, nil)
+	if n := len(h.mail.Messages()); n != 0 {
+		t.Fatalf("%d emails were sent for an address without an account", n)
+	}
+	h.expectError(h.do(http.MethodPost, "/api/v1/auth/reset-password", "", gin.H{"token": "made-up", "password": "new-password-456"}), http.StatusBadRequest, "INVALID_TOKEN")
+}
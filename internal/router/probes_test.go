@@ -0,0 +1,40 @@
+package router_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/health"
+)
+
+func TestProbes(t *testing.T) {
+	h := newHarness(t)
+
+	h.expectSuccess(h.do(http.MethodGet, "/livez", "", nil), http.StatusOK, nil)
+
+	var report health.Report
+	h.expectSuccess(h.do(http.MethodGet, "/readyz", "", nil), http.StatusOK, &report)
+	if report.Status != health.StatusReady || len(report.Checks) != 2 {
+		t.Fatalf("readiness = %+v, want ready with the database and migration checks", report)
+	}
+	for _, check := range report.Checks {
+		if check.Status != health.StatusUp || check.LatencyMS < 0 {
+			t.Errorf("check %+v, want up with its latency", check)
+		}
+	}
+
+	// One failing check makes the service unready, but not dead
+	h.readiness.Add(health.Checker{Name: "outbox", Check: func(context.Context) error { return errors.New("lagging") }})
+	res := h.do(http.MethodGet, "/readyz", "", nil)
+	h.expectError(res, http.StatusServiceUnavailable, "NOT_READY")
+	if got := string(res.Body.Error.Details); got == "" || !strings.Contains(got, `"name":"outbox","status":"down"`) {
+		t.Errorf("details = %s, want the failing check", got)
+	}
+	if got := string(res.Body.Error.Details); strings.Contains(got, "lagging") || strings.Contains(got, `"error"`) {
+		t.Errorf("details = %s, want the reason kept out of the public report", got)
+	}
+	h.expectSuccess(h.do(http.MethodGet, "/livez", "", nil), http.StatusOK, nil)
+}
@@ -0,0 +1,82 @@
+package router_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/config"
+	"github.com/gin-gonic/gin"
+)
+
+func TestRateLimitsPerUser(t *testing.T) {
+	h := newHarness(t)
+	ada := h.register("ada@example.com")
+	grace := h.register("grace@example.com")
+	h.reconfigure(func(cfg *config.Config) {
+		cfg.API.RateLimit = 3
+		cfg.API.RateLimitWrites = 1
+	})
+
+	res := h.do(http.MethodGet, "/api/v1/profile", ada, nil)
+	h.expectSuccess(res, http.StatusOK, nil)
+	if limit, remaining, reset := res.Header.Get("X-RateLimit-Limit"), res.Header.Get("X-RateLimit-Remaining"), res.Header.Get("X-RateLimit-Reset"); limit != "3" || remaining != "2" || reset != "20" {
+		t.Errorf("X-RateLimit headers = %s, %s, %s, want 3, 2, 20", limit, remaining, reset)
+	}
+	h.do(http.MethodGet, "/api/v1/habits", ada, nil)
+	h.do(http.MethodGet, "/api/v1/habits", ada, nil)
+
+	res = h.do(http.MethodGet, "/api/v1/habits", ada, nil)
+	h.expectError(res, http.StatusTooManyRequests, "RATE_LIMITED")
+	if got := res.Header.Get("Retry-After"); got != "20" {
+		t.Errorf("Retry-After = %q, want 20", got)
+	}
+
+	// Writes and other users have their own budgets
+	h.createHabit(ada, "Read")
+	h.expectError(h.do(http.MethodPost, "/api/v1/habits", ada, map[string]string{"name": "Write"}), http.StatusTooManyRequests, "RATE_LIMITED")
+	h.expectSuccess(h.do(http.MethodGet, "/api/v1/habits", grace, nil), http.StatusOK, nil)
+
+	// Tokens come back over time
+	h.clock.Advance(20 * time.Second)
+	h.expectSuccess(h.do(http.MethodGet, "/api/v1/habits", ada, nil), http.StatusOK, nil)
+}
+
+func TestRateLimitsAuthPerIP(t *testing.T) {
+	h := newHarness(t)
+	h.register("ada@example.com")
+	h.reconfigure(func(cfg *config.Config) { cfg.API.RateLimitAuth = 2 })
+
+	h.expectError(h.login("ada@example.com", "wrong-password"), http.StatusUnauthorized, "UNAUTHORIZED")
+	h.expectError(h.login("nobody@example.com", "wrong-password"), http.StatusUnauthorized, "UNAUTHORIZED")
+	res := h.login("ada@example.com", "password123")
+	h.expectError(res, http.StatusTooManyRequests, "RATE_LIMITED")
+	if got := res.Header.Get("Retry-After"); got != "30" {
+		t.Errorf("Retry-After = %q, want 30", got)
+	}
+
+	// Turning the budget off takes effect on reload
+	h.reconfigure(func(cfg *config.Config) { cfg.API.RateLimitAuth = 0 })
+	res = h.login("ada@example.com", "password123")
+	h.expectSuccess(res, http.StatusOK, nil)
+	if got := res.Header.Get("X-RateLimit-Limit"); got != "" {
+		t.Errorf("X-RateLimit-Limit = %q without a limit, want none", got)
+	}
+}
+
+func TestRateLimitsAuthPerIPIgnoreSpoofedHeaders(t *testing.T) {
+	h := newHarness(t)
+	h.reconfigure(func(cfg *config.Config) { cfg.API.RateLimitAuth = 2 })
+
+	forgot := func(forwardedFor string) response {
+		t.Helper()
+		return h.doWithHeader(http.MethodPost, "/api/v1/auth/forgot-password", "", gin.H{"email": "nobody@example.com"},
+			http.Header{"X-Forwarded-For": {forwardedFor}, "X-Real-Ip": {forwardedFor}})
+	}
+
+	// A new made-up address on every request still shares the bucket of the
+	// connection's address
+	h.expectSuccess(forgot("203.0.113.1"), http.StatusOK, nil)
+	h.expectSuccess(forgot("203.0.113.2"), http.StatusOK, nil)
+	h.expectError(forgot("203.0.113.3"), http.StatusTooManyRequests, "RATE_LIMITED")
+}
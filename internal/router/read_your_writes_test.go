@@ -0,0 +1,117 @@
+package router_test
+
+import (
+	"context"
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/clock"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/config"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/database"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/middleware"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/migrate"
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+)
+
+// migratedDatabase opens and migrates the SQLite database at path
+func migratedDatabase(t *testing.T, cfg *config.Config, path string) *database.Database {
+	t.Helper()
+
+	dbCfg := *cfg
+	dbCfg.Database.SQLitePath = path
+	db, err := database.NewDatabase(&dbCfg)
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	t.Cleanup(db.Close)
+
+	migrator, err := migrate.New(db.DB)
+	if err != nil {
+		t.Fatalf("load migrations: %v", err)
+	}
+	if _, err := migrator.Up(context.Background()); err != nil {
+		t.Fatalf("migrate database: %v", err)
+	}
+	return db
+}
+
+func TestReadYourWritesAcrossInstances(t *testing.T) {
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatalf("load config: %v", err)
+	}
+	cfg.Database.ReadYourWritesWindow = 5 * time.Second
+
+	// The replica never catches up, so each list shows where it was read
+	dir := t.TempDir()
+	db := migratedDatabase(t, cfg, filepath.Join(dir, "primary.db"))
+	replicaPath := filepath.Join(dir, "replica.db")
+	migratedDatabase(t, cfg, replicaPath).Close()
+	if err := database.UseReplicas(db.DB, cfg.Database, []gorm.Dialector{sqlite.Open(replicaPath)}); err != nil {
+		t.Fatalf("use replicas: %v", err)
+	}
+
+	// Two instances behind a load balancer
+	fake := func(fake *clock.Fake) clock.Clock { return fake }
+	first := newHarnessOn(t, cfg, db, fake)
+	second := newHarnessOn(t, cfg, db, fake)
+
+	token := first.register("ada@example.com")
+	res := first.do(http.MethodPost, "/api/v1/habits", token, gin.H{"name": "Read"})
+	var habit habitData
+	first.expectSuccess(res, http.StatusCreated, &habit)
+
+	var lastWrite *http.Cookie
+	for _, cookie := range (&http.Response{Header: res.Header}).Cookies() {
+		if cookie.Name == middleware.LastWriteCookie {
+			lastWrite = cookie
+		}
+	}
+	if lastWrite == nil || lastWrite.MaxAge != 5 || !lastWrite.HttpOnly {
+		t.Fatalf("last write cookie = %+v, want one for the window", lastWrite)
+	}
+	withCookie := http.Header{"Cookie": {lastWrite.String()}}
+
+	habits := func(h *harness, header http.Header) int {
+		t.Helper()
+		var list []habitData
+		h.expectSuccess(h.doWithHeader(http.MethodGet, "/api/v1/habits", token, nil, header), http.StatusOK, &list)
+		return len(list)
+	}
+
+	// Within the window, reads go to the primary on either instance
+	if got := habits(first, nil); got != 1 {
+		t.Errorf("instance that wrote listed %d habits, want the primary's", got)
+	}
+	if got := habits(second, withCookie); got != 1 {
+		t.Errorf("other instance with the cookie listed %d habits, want the primary's", got)
+	}
+	if got := habits(second, nil); got != 0 {
+		t.Errorf("other instance without the cookie listed %d habits, want the replica's", got)
+	}
+
+	// A habit missing on the replica is looked up on the primary
+	second.expectSuccess(second.do(http.MethodGet, habitPath(habit.ID, "/check-ins"), token, nil), http.StatusOK, nil)
+	second.expectSuccess(second.do(http.MethodGet, habitPath(habit.ID, ""), token, nil), http.StatusOK, nil)
+
+	// After the window, reads prefer the replica again
+	first.clock.Advance(6 * time.Second)
+	second.clock.Advance(6 * time.Second)
+	if got := habits(first, nil); got != 0 {
+		t.Errorf("instance that wrote listed %d habits after the window, want the replica's", got)
+	}
+	if got := habits(second, withCookie); got != 0 {
+		t.Errorf("other instance listed %d habits after the window, want the replica's", got)
+	}
+
+	// Failed changes are not remembered
+	res = second.do(http.MethodPost, "/api/v1/habits", token, gin.H{"color": "red"})
+	second.expectError(res, http.StatusBadRequest, "INVALID_REQUEST")
+	if cookies := res.Header.Values("Set-Cookie"); len(cookies) != 0 {
+		t.Errorf("failed change set cookies %v, want none", cookies)
+	}
+}
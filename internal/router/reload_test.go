@@ -0,0 +1,71 @@
+package router_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/config"
+	"github.com/gin-gonic/gin"
+)
+
+func TestReloadTogglesFeatures(t *testing.T) {
+	h := newHarness(t)
+	token := h.register("flags@example.com")
+
+	h.reconfigure(func(cfg *config.Config) {
+		cfg.Features.Registration = false
+		cfg.Features.Export = false
+	})
+
+	h.expectError(h.do(http.MethodPost, "/api/v1/auth/register", "", gin.H{
+		"name":     "Late User",
+		"email":    "late@example.com",
+		"password": "password123",
+	}), http.StatusForbidden, "FEATURE_DISABLED")
+	h.expectError(h.do(http.MethodGet, "/api/v1/export", token, nil), http.StatusForbidden, "FEATURE_DISABLED")
+
+	h.reconfigure(func(cfg *config.Config) { cfg.Features.Export = true })
+	if res := h.do(http.MethodGet, "/api/v1/export", token, nil); res.Status != http.StatusOK {
+		t.Fatalf("export after enabling it again: got status %d:\n%s", res.Status, res.Raw)
+	}
+}
+
+func TestReloadRotatesJWTSecret(t *testing.T) {
+	h := newHarness(t)
+	oldToken := h.register("rotate@example.com")
+	oldSecret := h.config.Get().Auth.JWTSecret
+
+	// Tokens signed with the previous secret keep working while it is listed
+	h.reconfigure(func(cfg *config.Config) {
+		cfg.Auth.JWTSecret = "a-brand-new-secret-for-signing-tokens"
+		cfg.Auth.JWTPreviousSecrets = []string{oldSecret}
+	})
+	h.expectSuccess(h.do(http.MethodGet, "/api/v1/habits", oldToken, nil), http.StatusOK, nil)
+
+	var login struct {
+		Token string `json:"token"`
+	}
+	h.expectSuccess(h.do(http.MethodPost, "/api/v1/auth/login", "", gin.H{
+		"email":    "rotate@example.com",
+		"password": "password123",
+	}), http.StatusOK, &login)
+
+	// Once the previous secret is dropped only new tokens are accepted
+	h.reconfigure(func(cfg *config.Config) { cfg.Auth.JWTPreviousSecrets = nil })
+	h.expectUnauthorized(h.do(http.MethodGet, "/api/v1/habits", oldToken, nil), "Invalid or expired token")
+	h.expectSuccess(h.do(http.MethodGet, "/api/v1/habits", login.Token, nil), http.StatusOK, nil)
+}
+
+func TestReloadRejectsStructuralChanges(t *testing.T) {
+	h := newHarness(t)
+
+	next := *h.config.Get()
+	next.Server.Port = "9999"
+	next.Logger.Level = "warn"
+	if _, err := h.config.Apply(&next); err == nil {
+		t.Fatal("reload changing the port was accepted")
+	}
+	if got := h.config.Get().Logger.Level; got == "warn" {
+		t.Fatal("rejected reload was partially applied")
+	}
+}
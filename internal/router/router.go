@@ -2,23 +2,56 @@ package router
 
 import (
 	"net/http"
+	"time"
 
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/clock"
 	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/config"
 	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/database"
 	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/handlers"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/health"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/jwtkeys"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/lockout"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/mailer"
 	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/middleware"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/notification"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/oidc"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/ratelimit"
 	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/repository"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/secretbox"
 	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/service"
-	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
 )
 
-// SetupRouter sets up the router with all routes and middleware
-func SetupRouter(db *database.Database) *gin.Engine {
-	r := gin.Default()
+// SetupRouter sets up the router with all routes and middleware. Settings
+// that can be reloaded are read from the holder on every request; the rest
+// are read once here. Tokens are signed and verified with the keys from the
+// provider, and account emails are sent with mail. The clock is
+// shared by every service that depends on the current time. Passing a
+// *clock.Offset in development or test also registers the /debug/clock
+// endpoints for moving it. /readyz runs the checks of readiness.
+func SetupRouter(cfgs *config.Holder, keys *jwtkeys.Provider, db *database.Database, mail mailer.Mailer, clk clock.Clock, readiness *health.Readiness) *gin.Engine {
+	cfg := cfgs.Get()
+	r := gin.New()
+
+	// Forwarded client IPs are only believed from the configured proxies, as
+	// anyone can send the headers. The lockout and rate limits key on them.
+	if err := r.SetTrustedProxies(cfg.Server.TrustedProxies); err != nil {
+		log.Error().Err(err).Msg("Invalid trusted proxies, forwarded client IPs are ignored")
+		_ = r.SetTrustedProxies(nil)
+	}
 
 	// Add middlewares
-	r.Use(cors.Default())
+	if cfg.Monitoring.MetricsEnabled {
+		r.Use(middleware.Metrics())
+	}
+	if cfg.Monitoring.TracingEnabled {
+		r.Use(middleware.Tracing())
+	}
+	r.Use(middleware.RequestID())
+	r.Use(middleware.Logger())
+	r.Use(middleware.Recovery())
+	r.Use(middleware.CORS(cfgs))
 	r.Use(middleware.ErrorHandler())
 	r.Use(middleware.ResponseFormatter())
 
@@ -29,36 +62,98 @@ func SetupRouter(db *database.Database) *gin.Engine {
 		})
 	})
 
-	// Load configuration
-	cfg := config.Load()
-
 	// Create repositories
 	userRepo := repository.NewUserRepository(db.DB)
 	habitRepo := repository.NewHabitRepository(db.DB)
 	streakRepo := repository.NewStreakRepository(db.DB)
 	checkInRepo := repository.NewCheckInRepository(db.DB)
 	achievementRepo := repository.NewAchievementRepository(db.DB)
+	deviceRepo := repository.NewDeviceRepository(db.DB)
+	digestRepo := repository.NewDigestRepository(db.DB)
+	identityRepo := repository.NewIdentityRepository(db.DB)
+	verificationRepo := repository.NewEmailVerificationRepository(db.DB)
+	resetRepo := repository.NewPasswordResetRepository(db.DB)
+	eventRepo := repository.NewSecurityEventRepository(db.DB)
+	mfaRepo := repository.NewMFARepository(db.DB)
+
+	// Create push notifier
+	notifier := notification.NewNotifierFromConfig(deviceRepo, cfg)
+
+	// Create the failed login guard
+	guard := lockout.NewGuard(lockout.NewStoreFromConfig(cfg, db.DB), cfgs, clk)
+
+	// Create the rate limiter
+	limiter := ratelimit.NewLimiter(ratelimit.NewStoreFromConfig(cfg, db.DB), cfgs, clk)
+
+	// Create the box TOTP secrets are encrypted with, if two-factor
+	// authentication is configured
+	var box *secretbox.Box
+	if cfg.MFA.Enabled() {
+		var err error
+		if box, err = secretbox.New(cfg.MFA.EncryptionKey); err != nil {
+			log.Error().Err(err).Msg("Invalid MFA encryption key, two-factor authentication is unavailable")
+		}
+	}
+
+	// Create the ID token verifier for sign-in providers
+	verifier := oidc.NewVerifier(cfgs, &http.Client{Timeout: cfg.API.Timeout}, clk)
 
 	// Create services
-	authService := service.NewAuthService(userRepo, cfg)
-	userService := service.NewUserService(userRepo, habitRepo, streakRepo, checkInRepo, achievementRepo)
+	verificationService := service.NewVerificationService(userRepo, verificationRepo, mail, cfgs, clk)
+	securityService := service.NewSecurityService(userRepo, eventRepo, guard, mail, cfgs, clk)
+	mfaService := service.NewMFAService(userRepo, mfaRepo, securityService, box, cfgs, clk)
+	authService := service.NewAuthService(userRepo, resetRepo, verificationService, securityService, mfaService, mail, cfgs, keys, clk)
+	oidcService := service.NewOIDCService(userRepo, identityRepo, verifier, authService, cfgs, clk)
+	userService := service.NewUserService(userRepo, habitRepo, streakRepo, checkInRepo, achievementRepo, clk)
 	habitService := service.NewHabitService(habitRepo, streakRepo)
-	streakService := service.NewStreakService(habitRepo, streakRepo, checkInRepo)
-	checkInService := service.NewCheckInService(habitRepo, streakRepo, checkInRepo, achievementRepo)
+	streakService := service.NewStreakService(habitRepo, streakRepo, checkInRepo, clk)
+	checkInService := service.NewCheckInService(habitRepo, streakRepo, checkInRepo, achievementRepo, clk)
 	achievementService := service.NewAchievementService(achievementRepo, habitRepo)
+	deviceService := service.NewDeviceService(deviceRepo, notifier, clk)
+	digestService := service.NewDigestService(userRepo, achievementRepo, habitRepo, digestRepo, userService, mail, clk)
+	exportService := service.NewExportService(userRepo, habitRepo, streakRepo, checkInRepo, achievementRepo, clk)
+	importService := service.NewImportService(habitRepo, clk)
+	accountService := service.NewAccountService(userRepo, deviceRepo, cfg.Account.DeletionGracePeriod, clk)
 
 	// Create handlers
 	authHandler := handlers.NewAuthHandler(authService)
+	oidcHandler := handlers.NewOIDCHandler(oidcService)
+	verificationHandler := handlers.NewVerificationHandler(verificationService)
+	securityHandler := handlers.NewSecurityHandler(securityService)
+	mfaHandler := handlers.NewMFAHandler(mfaService)
 	userHandler := handlers.NewUserHandler(userService)
 	habitHandler := handlers.NewHabitHandler(habitService)
 	streakHandler := handlers.NewStreakHandler(streakService)
 	checkInHandler := handlers.NewCheckInHandler(checkInService)
 	achievementHandler := handlers.NewAchievementHandler(achievementService)
+	deviceHandler := handlers.NewDeviceHandler(deviceService)
+	digestHandler := handlers.NewDigestHandler(digestService)
+	exportHandler := handlers.NewExportHandler(exportService)
+	importHandler := handlers.NewImportHandler(importService)
+	accountHandler := handlers.NewAccountHandler(accountService)
+
+	// Probes for orchestrators and load balancers
+	r.GET("/livez", handlers.Liveness)
+	r.GET("/readyz", handlers.Readiness(readiness))
 
 	// Root health check endpoints
 	r.GET("/health", handlers.HealthCheck)
 	r.GET("/db-health", handlers.DBHealthCheck(db))
 
+	// Public token signing keys for other services
+	r.GET("/.well-known/jwks.json", handlers.JWKS(keys))
+
+	// Time travel for QA, never available in production
+	if travel, ok := clk.(*clock.Offset); ok && cfg.Server.AllowsTimeTravel() {
+		clockHandler := handlers.NewClockHandler(travel)
+		debug := r.Group("/debug")
+		{
+			debug.GET("/clock", clockHandler.GetClock())
+			debug.PUT("/clock", clockHandler.SetClock())
+			debug.DELETE("/clock", clockHandler.ResetClock())
+		}
+	}
+
 	// Root welcome page
 	r.GET("/", func(c *gin.Context) {
 		middleware.RespondWithOK(c, gin.H{
@@ -68,8 +163,11 @@ func SetupRouter(db *database.Database) *gin.Engine {
 			"status":        "running",
 			"documentation": "/swagger",
 			"endpoints": map[string]string{
+				"livez":     "/livez",
+				"readyz":    "/readyz",
 				"health":    "/health",
 				"db_health": "/db-health",
+				"jwks":      "/.well-known/jwks.json",
 				"api_v1":    "/api/v1",
 			},
 			"repository": "https://github.com/WomenMobileDev/WMD.Consistency.Service",
@@ -129,6 +227,14 @@ func SetupRouter(db *database.Database) *gin.Engine {
 
 	// API v1 routes
 	v1 := r.Group("/api/v1")
+	v1.Use(middleware.Timeout(cfgs, func(route string, api config.APIConfig) time.Duration {
+		// Export and import may take longer than other requests
+		switch route {
+		case "/api/v1/export", "/api/v1/import":
+			return api.TransferTimeout
+		}
+		return api.Timeout
+	}))
 	{
 		// API v1 welcome page
 		v1.GET("/", func(c *gin.Context) {
@@ -144,6 +250,9 @@ func SetupRouter(db *database.Database) *gin.Engine {
 					"profile":      "/api/v1/profile",
 					"habits":       "/api/v1/habits",
 					"achievements": "/api/v1/achievements",
+					"devices":      "/api/v1/devices",
+					"export":       "/api/v1/export",
+					"import":       "/api/v1/import",
 				},
 			})
 		})
@@ -153,20 +262,38 @@ func SetupRouter(db *database.Database) *gin.Engine {
 
 		// Auth routes
 		auth := v1.Group("/auth")
+		auth.Use(middleware.DatabaseReady(db.Ready), middleware.RateLimitByIP(limiter, ratelimit.BudgetAuth))
 		{
-			auth.POST("/register", authHandler.Register())
+			auth.POST("/register", middleware.Feature(cfgs, func(f config.FeatureConfig) bool { return f.Registration }), authHandler.Register())
 			auth.POST("/login", authHandler.Login())
+			auth.POST("/mfa", authHandler.VerifyMFA())
 			auth.POST("/forgot-password", authHandler.ForgotPassword())
 			auth.POST("/reset-password", authHandler.ResetPassword())
+			auth.POST("/oidc/:provider", oidcHandler.Login())
+			auth.POST("/verify-email", verificationHandler.VerifyEmail())
+			auth.POST("/resend-verification", verificationHandler.ResendVerification())
+			auth.POST("/unlock", securityHandler.UnlockAccount())
 		}
 
 		// Protected routes (require authentication)
 		protected := v1.Group("")
-		protected.Use(middleware.Auth())
+		protected.Use(middleware.DatabaseReady(db.Ready), middleware.Auth(keys), middleware.RateLimit(limiter), middleware.ActiveUser(userRepo), middleware.ReadYourWrites(cfg.Database.ReadYourWritesWindow, clk))
 		{
 			// User routes
 			protected.GET("/profile", userHandler.GetProfile())
 			protected.PUT("/profile", userHandler.UpdateProfile())
+			protected.DELETE("/profile", accountHandler.DeleteAccount())
+			protected.PUT("/profile/password", authHandler.ChangePassword())
+			protected.GET("/profile/identities", oidcHandler.ListIdentities())
+			protected.POST("/profile/verification", verificationHandler.ResendMyVerification())
+			protected.GET("/profile/security-events", securityHandler.ListEvents())
+			protected.GET("/profile/mfa", mfaHandler.GetStatus())
+			protected.DELETE("/profile/mfa", mfaHandler.Disable())
+			protected.POST("/profile/mfa/totp", mfaHandler.StartEnrolment())
+			protected.POST("/profile/mfa/totp/confirm", mfaHandler.ConfirmEnrolment())
+			protected.POST("/profile/mfa/recovery-codes", mfaHandler.RegenerateRecoveryCodes())
+			protected.GET("/profile/digest", digestHandler.GetPreferences())
+			protected.PUT("/profile/digest", middleware.VerifiedEmail(cfgs, config.VerifiedDigest), digestHandler.UpdatePreferences())
 
 			// Habit routes
 			habits := protected.Group("/habits")
@@ -196,6 +323,23 @@ func SetupRouter(db *database.Database) *gin.Engine {
 				achievements.GET("", achievementHandler.ListAchievements())
 				achievements.GET("/:id", achievementHandler.GetAchievement())
 			}
+
+			// Data export
+			protected.GET("/export", middleware.Feature(cfgs, func(f config.FeatureConfig) bool { return f.Export }), middleware.VerifiedEmail(cfgs, config.VerifiedExport), exportHandler.Export())
+
+			// Import from other habit trackers
+			protected.POST("/import", middleware.Feature(cfgs, func(f config.FeatureConfig) bool { return f.Import }), middleware.VerifiedEmail(cfgs, config.VerifiedImport), importHandler.Import())
+
+			// Push device routes
+			devices := protected.Group("/devices")
+			devices.Use(middleware.VerifiedEmail(cfgs, config.VerifiedDevices))
+			{
+				devices.POST("", deviceHandler.RegisterDevice())
+				devices.GET("", deviceHandler.ListDevices())
+				devices.PUT("/:id", deviceHandler.RefreshDevice())
+				devices.DELETE("/:id", deviceHandler.UnregisterDevice())
+				devices.POST("/test", deviceHandler.SendTestNotification())
+			}
 		}
 	}
 
@@ -0,0 +1,33 @@
+package router_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/config"
+)
+
+func TestRequestTimeouts(t *testing.T) {
+	h := newHarness(t)
+	ada := h.register("ada@example.com")
+	h.createHabit(ada, "Read")
+
+	// A deadline that has passed before the first query aborts the request
+	h.reconfigure(func(cfg *config.Config) { cfg.API.Timeout = time.Nanosecond })
+	h.expectError(h.do(http.MethodGet, "/api/v1/profile", ada, nil), http.StatusGatewayTimeout, "TIMEOUT")
+	h.expectError(h.do(http.MethodGet, "/api/v1/habits", ada, nil), http.StatusGatewayTimeout, "TIMEOUT")
+
+	// Export and import have their own deadline
+	if res := h.do(http.MethodGet, "/api/v1/export", ada, nil); res.Status != http.StatusOK {
+		t.Fatalf("export: got status %d, want 200:\n%s", res.Status, res.Raw)
+	}
+	h.reconfigure(func(cfg *config.Config) { cfg.API.TransferTimeout = time.Nanosecond })
+	h.expectError(h.do(http.MethodGet, "/api/v1/export", ada, nil), http.StatusGatewayTimeout, "TIMEOUT")
+
+	h.reconfigure(func(cfg *config.Config) {
+		cfg.API.Timeout = 30 * time.Second
+		cfg.API.TransferTimeout = 2 * time.Minute
+	})
+	h.expectSuccess(h.do(http.MethodGet, "/api/v1/profile", ada, nil), http.StatusOK, nil)
+}
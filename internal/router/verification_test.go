@@ -0,0 +1,107 @@
+package router_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/config"
+	"github.com/gin-gonic/gin"
+)
+
+type profileData struct {
+	ID            uint `json:"id"`
+	EmailVerified bool `json:"email_verified"`
+}
+
+func TestUnverifiedAccountIsRestrictedUntilVerified(t *testing.T) {
+	h := newHarness(t)
+	token := h.registerUnverified("ada@example.com")
+
+	var profile profileData
+	h.expectSuccess(h.do(http.MethodGet, "/api/v1/profile", token, nil), http.StatusOK, &profile)
+	if profile.EmailVerified {
+		t.Fatal("a new account is already verified")
+	}
+
+	// Everyday use works, the configured features do not
+	h.createHabit(token, "Read")
+	h.expectError(h.do(http.MethodGet, "/api/v1/export", token, nil), http.StatusForbidden, "EMAIL_NOT_VERIFIED")
+	h.expectError(h.do(http.MethodPut, "/api/v1/profile/digest", token, gin.H{"enabled": true}), http.StatusForbidden, "EMAIL_NOT_VERIFIED")
+
+	// The policy is reloadable
+	h.reconfigure(func(cfg *config.Config) { cfg.Verification.RequiredFor = []string{config.VerifiedDigest} })
+	if res := h.do(http.MethodGet, "/api/v1/export", token, nil); res.Status != http.StatusOK {
+		t.Fatalf("export once it no longer needs verification: got status %d:\n%s", res.Status, res.Raw)
+	}
+
+	// The link from the registration email verifies the address
+	link := h.verificationToken("ada@example.com")
+	var verified profileData
+	h.expectSuccess(h.do(http.MethodPost, "/api/v1/auth/verify-email", "", gin.H{"token": link}), http.StatusOK, &verified)
+	if !verified.EmailVerified || verified.ID != profile.ID {
+		t.Fatalf("verify returned %+v, want account %d verified", verified, profile.ID)
+	}
+	h.expectSuccess(h.do(http.MethodPut, "/api/v1/profile/digest", token, gin.H{"enabled": true}), http.StatusOK, nil)
+
+	// Links are single use and a verified account needs no more emails
+	h.expectError(h.do(http.MethodPost, "/api/v1/auth/verify-email", "", gin.H{"token": link}), http.StatusBadRequest, "INVALID_TOKEN")
+	h.expectError(h.do(http.MethodPost, "/api/v1/profile/verification", token, nil), http.StatusConflict, "CONFLICT")
+}
+
+func TestVerificationEmailsAreThrottled(t *testing.T) {
+	h := newHarness(t)
+	token := h.registerUnverified("grace@example.com")
+	first := h.verificationToken("grace@example.com")
+
+	// The registration email was just sent
+	res := h.do(http.MethodPost, "/api/v1/profile/verification", token, nil)
+	h.expectError(res, http.StatusTooManyRequests, "TOO_MANY_REQUESTS")
+	if got := res.Header.Get("Retry-After"); got != "60" {
+		t.Errorf("Retry-After = %q, want 60", got)
+	}
+
+	// Up to the daily limit once the interval has passed
+	for i := 1; i < 5; i++ {
+		h.clock.Advance(time.Minute)
+		h.expectSuccess(h.do(http.MethodPost, "/api/v1/profile/verification", token, nil), http.StatusAccepted, nil)
+	}
+	h.clock.Advance(time.Minute)
+	h.expectError(h.do(http.MethodPost, "/api/v1/profile/verification", token, nil), http.StatusTooManyRequests, "TOO_MANY_REQUESTS")
+	if sent := len(h.mail.Messages()); sent != 5 {
+		t.Errorf("sent %d emails, want 5", sent)
+	}
+
+	// Earlier links still work until they expire
+	h.expectSuccess(h.do(http.MethodPost, "/api/v1/auth/verify-email", "", gin.H{"token": first}), http.StatusOK, nil)
+}
+
+func TestExpiredVerificationLinkIsRejected(t *testing.T) {
+	h := newHarness(t)
+	token := h.registerUnverified("late@example.com")
+	expired := h.verificationToken("late@example.com")
+
+	h.clock.Advance(48 * time.Hour)
+	h.expectError(h.do(http.MethodPost, "/api/v1/auth/verify-email", "", gin.H{"token": expired}), http.StatusBadRequest, "INVALID_TOKEN")
+	h.expectError(h.do(http.MethodPost, "/api/v1/auth/verify-email", "", gin.H{"token": "made-up"}), http.StatusBadRequest, "INVALID_TOKEN")
+
+	h.expectSuccess(h.do(http.MethodPost, "/api/v1/profile/verification", token, nil), http.StatusAccepted, nil)
+	h.expectSuccess(h.do(http.MethodPost, "/api/v1/auth/verify-email", "", gin.H{"token": h.verificationToken("late@example.com")}), http.StatusOK, nil)
+}
+
+func TestPublicResendDoesNotRevealAccounts(t *testing.T) {
+	h := newHarness(t)
+	h.registerUnverified("known@example.com")
+	h.register("verified@example.com")
+	before := len(h.mail.Messages())
+
+	// Every address gets the same answer, only the unverified account is emailed
+	h.clock.Advance(time.Minute)
+	for _, email := range []string{"known@example.com", "verified@example.com", "unknown@example.com", "known@example.com"} {
+		h.expectSuccess(h.do(http.MethodPost, "/api/v1/auth/resend-verification", "", gin.H{"email": email}), http.StatusAccepted, nil)
+	}
+	messages := h.mail.Messages()[before:]
+	if len(messages) != 1 || messages[0].To != "known@example.com" {
+		t.Fatalf("resend sent %+v, want one email to known@example.com", messages)
+	}
+}
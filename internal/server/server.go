@@ -2,34 +2,90 @@ package server
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"net/http"
+	"time"
 
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/clock"
 	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/config"
 	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/database"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/health"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/jobs"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/jwtkeys"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/lockout"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/mailer"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/metrics"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/ratelimit"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/repository"
 	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/router"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/service"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/tracing"
 	"github.com/gin-gonic/gin"
 	"github.com/rs/zerolog/log"
 )
 
 type Server struct {
-	router *gin.Engine
-	http   *http.Server
-	config *config.Config
-	db     *database.Database
+	router    *gin.Engine
+	http      *http.Server
+	admin     *http.Server
+	config    *config.Config
+	db        *database.Database
+	scheduler *jobs.Scheduler
+
+	// supervisor reconnects to the database in the background
+	supervisor *database.Supervisor
+
+	// shutdownTracing flushes the spans that are not exported yet
+	shutdownTracing func(context.Context) error
 }
 
-func NewServer(cfg *config.Config) *Server {
-	db, err := database.NewDatabase(cfg)
+// NewServer creates the server from the configuration in the holder. It fails when the database schema is behind
+// the migrations embedded in the binary, unless DB_AUTO_MIGRATE is set, in
+// which case the pending migrations are applied first. When the database
+// cannot be reached the server starts anyway and the same happens once it
+// is.
+func NewServer(cfgs *config.Holder) (*Server, error) {
+	cfg := cfgs.Get()
+	shutdownTracing, err := tracing.Init(context.Background(), cfg)
 	if err != nil {
-		log.Warn().Err(err).Msg("Failed to connect to database, continuing without database connection")
-	} else {
-		// Run database migrations
-		if err := db.RunMigrations(); err != nil {
-			log.Error().Err(err).Msg("Failed to run database migrations")
-		} else {
-			log.Info().Msg("Database migrations completed successfully")
+		return nil, fmt.Errorf("failed to set up tracing: %w", err)
+	}
+
+	// The handle is opened without connecting so that the API can start
+	// while the database is down; the supervisor connects in the background
+	// and the API answers 503 until the database is ready
+	db, err := database.Open(cfg)
+	if err != nil {
+		return nil, err
+	}
+	supervisor := database.NewSupervisor(db, cfg.Database, func(ctx context.Context) error {
+		if cfg.Database.AutoMigrate {
+			if err := db.RunMigrations(ctx); err != nil {
+				return fmt.Errorf("failed to run database migrations: %w", err)
+			}
+			return nil
+		}
+		if err := db.CheckMigrations(ctx); err != nil {
+			return fmt.Errorf("%w, run `go run ./cmd/migrate up` or set DB_AUTO_MIGRATE=true", err)
+		}
+		return nil
+	})
+
+	// A reachable database with a schema that is behind is a deployment
+	// mistake, so the server refuses to start instead of waiting
+	if err := supervisor.Connect(context.Background()); errors.Is(err, database.ErrUnavailable) {
+		log.Warn().Err(err).Msg("Database is unavailable, the API answers 503 until it is reached")
+	} else if err != nil {
+		return nil, err
+	}
+
+	if cfg.Monitoring.TracingEnabled {
+		if err := tracing.InstrumentDatabase(db.DB); err != nil {
+			log.Error().Err(err).Msg("Failed to instrument database, queries are not traced")
 		}
 	}
+
 	if cfg.Server.Env == "production" {
 		gin.SetMode(gin.ReleaseMode)
 	} else if cfg.Server.Env == "test" {
@@ -38,31 +94,158 @@ func NewServer(cfg *config.Config) *Server {
 		gin.SetMode(gin.DebugMode)
 	}
 
+	// The clock can be moved through /debug/clock outside production
+	var clk clock.Clock = clock.New()
+	if cfg.Server.AllowsTimeTravel() {
+		clk = clock.NewOffset(clk)
+	}
+
+	keys, err := jwtkeys.NewProvider(cfgs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load token signing keys: %w", err)
+	}
+
+	// Account emails fall back to the log mailer so sign-up keeps working,
+	// but the digest job is not started with an invalid configuration
+	mail, err := mailer.NewFromConfig(cfg)
+	if err != nil {
+		log.Error().Err(err).Msg("Invalid mail configuration, falling back to log mailer")
+	}
+	accountMail := mail
+	if accountMail == nil {
+		accountMail = mailer.NewLogMailer()
+	}
+
+	// The service is ready once the database is reachable and up to date and
+	// the background jobs are running
+	scheduler := jobs.NewScheduler()
+	readiness := health.NewReadiness(health.DefaultTimeout,
+		health.Database(db),
+		health.Migrations(db),
+		health.Scheduler(scheduler),
+	)
+	readiness.CacheFor(health.DefaultCacheTTL)
+
 	// Use the router from the router package
-	r := router.SetupRouter(db)
+	r := router.SetupRouter(cfgs, keys, db, accountMail, clk, readiness)
+
+	// Register background jobs
+	registerJobs(scheduler, cfg, db, mail, clk)
 
 	srv := &Server{
-		router: r,
-		config: cfg,
-		db:     db,
+		router:     r,
+		config:     cfg,
+		db:         db,
+		supervisor: supervisor,
+		scheduler:  scheduler,
+
+		shutdownTracing: shutdownTracing,
 		http: &http.Server{
-			Addr:    ":" + cfg.Server.Port,
-			Handler: r,
+			Addr:              ":" + cfg.Server.Port,
+			Handler:           r,
+			ReadHeaderTimeout: cfg.Server.ReadHeaderTimeout,
+			ReadTimeout:       cfg.Server.ReadTimeout,
+			WriteTimeout:      cfg.Server.WriteTimeout,
+			IdleTimeout:       cfg.Server.IdleTimeout,
 		},
 	}
 
-	return srv
+	// Metrics go on their own port, which is kept off the internet
+	if cfg.Monitoring.MetricsEnabled {
+		if err := metrics.InstrumentDatabase(db.DB, cfg.Database.Name); err != nil {
+			log.Error().Err(err).Msg("Failed to instrument database, database metrics are unavailable")
+		}
+
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", metrics.Handler())
+		srv.admin = &http.Server{
+			Addr:              ":" + cfg.Monitoring.AdminPort,
+			Handler:           mux,
+			ReadHeaderTimeout: cfg.Server.ReadHeaderTimeout,
+			ReadTimeout:       cfg.Server.ReadTimeout,
+			WriteTimeout:      cfg.Server.WriteTimeout,
+			IdleTimeout:       cfg.Server.IdleTimeout,
+		}
+	}
+
+	return srv, nil
 }
 
+// registerJobs wires the background jobs and the services they depend on.
+// mail is nil when the mail configuration is invalid. The jobs are skipped
+// while the database is unavailable.
+func registerJobs(scheduler *jobs.Scheduler, cfg *config.Config, db *database.Database, mail mailer.Mailer, clk clock.Clock) {
+	register := func(job jobs.Job, interval time.Duration) {
+		scheduler.Register(jobs.RequireReady(job, db.Ready), interval)
+	}
+
+	userRepo := repository.NewUserRepository(db.DB)
+	habitRepo := repository.NewHabitRepository(db.DB)
+	streakRepo := repository.NewStreakRepository(db.DB)
+	checkInRepo := repository.NewCheckInRepository(db.DB)
+	achievementRepo := repository.NewAchievementRepository(db.DB)
+	deviceRepo := repository.NewDeviceRepository(db.DB)
+	digestRepo := repository.NewDigestRepository(db.DB)
 
+	if cfg.Account.PurgeInterval > 0 {
+		accountService := service.NewAccountService(userRepo, deviceRepo, cfg.Account.DeletionGracePeriod, clk)
+		register(jobs.NewAccountPurgeJob(accountService, clk), cfg.Account.PurgeInterval)
+	}
+
+	// The in-memory store prunes itself
+	if cfg.Lockout.Store == config.LockoutStoreDatabase {
+		store := lockout.NewGormStore(db.DB)
+		register(jobs.NewLoginAttemptPruneJob(store, cfg.Lockout.Window, clk), time.Hour)
+	}
+	if cfg.API.RateLimitStore == config.RateLimitStoreDatabase {
+		store := ratelimit.NewGormStore(db.DB)
+		register(jobs.NewRateLimitPruneJob(store, clk), time.Hour)
+	}
+
+	if !cfg.Digest.Enabled {
+		return
+	}
+
+	if mail == nil {
+		log.Error().Msg("Invalid mail configuration, weekly digest job is disabled")
+		return
+	}
+
+	userService := service.NewUserService(userRepo, habitRepo, streakRepo, checkInRepo, achievementRepo, clk)
+	digestService := service.NewDigestService(userRepo, achievementRepo, habitRepo, digestRepo, userService, mail, clk)
+
+	register(jobs.NewWeeklyDigestJob(digestService, clk), cfg.Digest.Interval)
+}
 
 func (s *Server) ListenAndServe() error {
+	s.supervisor.Start(context.Background())
+	s.scheduler.Start(context.Background())
+	if s.admin != nil {
+		go func() {
+			log.Info().Str("port", s.config.Monitoring.AdminPort).Msg("Serving metrics on the admin port")
+			if err := s.admin.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				log.Error().Err(err).Msg("Admin server failed, metrics are unavailable")
+			}
+		}()
+	}
 	return s.http.ListenAndServe()
 }
 
+// Shutdown stops the server gracefully. In-flight requests are drained
+// before the background work stops and the database closes, and traces are
+// flushed last so that the shutdown itself is recorded.
 func (s *Server) Shutdown(ctx context.Context) error {
-	if s.db != nil {
-		s.db.Close()
+	err := s.http.Shutdown(ctx)
+	if s.admin != nil {
+		if err := s.admin.Shutdown(ctx); err != nil {
+			log.Error().Err(err).Msg("Admin server shutdown failed")
+		}
+	}
+	s.scheduler.Stop()
+	s.supervisor.Stop()
+	s.db.Close()
+	if err := s.shutdownTracing(ctx); err != nil {
+		log.Error().Err(err).Msg("Failed to flush traces")
 	}
-	return s.http.Shutdown(ctx)
+	return err
 }
@@ -0,0 +1,122 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/clock"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/models"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/repository"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/tracing"
+	"github.com/rs/zerolog/log"
+)
+
+// AccountService handles account deletion and the erasure of user data
+type AccountService struct {
+	userRepo    repository.UserRepository
+	deviceRepo  repository.DeviceRepository
+	gracePeriod time.Duration
+	clock       clock.Clock
+}
+
+// NewAccountService creates a new account service
+func NewAccountService(userRepo repository.UserRepository, deviceRepo repository.DeviceRepository, gracePeriod time.Duration, clk clock.Clock) *AccountService {
+	return &AccountService{
+		userRepo:    userRepo,
+		deviceRepo:  deviceRepo,
+		gracePeriod: gracePeriod,
+		clock:       clk,
+	}
+}
+
+// DeleteAccountRequest represents the request for deleting the current
+// account. Accounts created with a sign-in provider have no password to confirm.
+type DeleteAccountRequest struct {
+	Password string `json:"password"`
+}
+
+// DeleteAccount schedules the account for erasure after the password, if the
+// account has one, is confirmed. The account is locked straight away and its push devices are
+// removed; everything else is purged once the grace period has passed.
+func (s *AccountService) DeleteAccount(ctx context.Context, userID uint, req DeleteAccountRequest) (*models.AccountDeletionResponse, error) {
+	ctx, span := tracing.Start(ctx, "AccountService.DeleteAccount")
+	defer span.End()
+
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, errors.New("user not found")
+	}
+
+	if user.HasPassword() && !user.CheckPassword(req.Password) {
+		return nil, errors.New("invalid password")
+	}
+
+	now := s.clock.Now().UTC()
+	purgeAfter := now.Add(s.gracePeriod)
+	user.DeletionRequestedAt = &now
+	user.PurgeAfter = &purgeAfter
+
+	if err := s.userRepo.Update(ctx, user); err != nil {
+		return nil, err
+	}
+
+	// Stop push notifications right away rather than at purge time
+	devices, err := s.deviceRepo.FindByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, device := range devices {
+		if err := s.deviceRepo.Delete(ctx, device.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.userRepo.Delete(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	return &models.AccountDeletionResponse{
+		DeletionRequestedAt: now,
+		PurgeAfter:          purgeAfter,
+	}, nil
+}
+
+// PurgeDueAccounts permanently erases every deleted account whose grace
+// period has passed and returns the number of accounts purged
+func (s *AccountService) PurgeDueAccounts(ctx context.Context, now time.Time) (int, error) {
+	ctx, span := tracing.Start(ctx, "AccountService.PurgeDueAccounts")
+	defer span.End()
+
+	users, err := s.userRepo.FindDueForPurge(ctx, now)
+	if err != nil {
+		return 0, err
+	}
+
+	purged := 0
+	for _, user := range users {
+		if err := ctx.Err(); err != nil {
+			return purged, err
+		}
+
+		audit := &models.ErasureAudit{
+			RequestedAt: user.DeletedAt.Time,
+			PurgedAt:    now.UTC(),
+		}
+		if user.DeletionRequestedAt != nil {
+			audit.RequestedAt = *user.DeletionRequestedAt
+		}
+
+		if err := s.userRepo.Purge(ctx, user.ID, audit); err != nil {
+			log.Ctx(ctx).Error().Err(err).Uint("userID", user.ID).Msg("Failed to purge account")
+			continue
+		}
+		log.Ctx(ctx).Info().Uint("auditID", audit.ID).Msg("Account purged")
+		purged++
+	}
+
+	return purged, nil
+}
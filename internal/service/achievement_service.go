@@ -4,8 +4,10 @@ import (
 	"context"
 	"errors"
 
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/database"
 	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/models"
 	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/repository"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/tracing"
 )
 
 // AchievementService handles achievement-related business logic
@@ -24,6 +26,10 @@ func NewAchievementService(achievementRepo repository.AchievementRepository, hab
 
 // ListAchievements lists all achievements for a user
 func (s *AchievementService) ListAchievements(ctx context.Context, userID uint) ([]models.AchievementResponse, error) {
+	ctx, span := tracing.Start(ctx, "AchievementService.ListAchievements")
+	defer span.End()
+	ctx = database.PreferReplica(ctx)
+
 	// Find all achievements for the user
 	achievements, err := s.achievementRepo.FindByUserID(ctx, userID)
 	if err != nil {
@@ -49,6 +55,9 @@ func (s *AchievementService) ListAchievements(ctx context.Context, userID uint)
 
 // GetAchievement gets a specific achievement
 func (s *AchievementService) GetAchievement(ctx context.Context, userID uint, achievementID uint) (*models.AchievementResponse, error) {
+	ctx, span := tracing.Start(ctx, "AchievementService.GetAchievement")
+	defer span.End()
+
 	// Find the achievement
 	achievement, err := s.achievementRepo.FindByID(ctx, achievementID)
 	if err != nil {
@@ -75,6 +84,10 @@ func (s *AchievementService) GetAchievement(ctx context.Context, userID uint, ac
 
 // ListHabitAchievements lists all achievements for a habit
 func (s *AchievementService) ListHabitAchievements(ctx context.Context, userID uint, habitID uint) ([]models.AchievementResponse, error) {
+	ctx, span := tracing.Start(ctx, "AchievementService.ListHabitAchievements")
+	defer span.End()
+	ctx = database.PreferReplica(ctx)
+
 	// Verify the habit belongs to the user
 	habit, err := s.habitRepo.FindByID(ctx, habitID)
 	if err != nil {
@@ -4,24 +4,54 @@ import (
 	"context"
 	"errors"
 
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/accountmail"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/clock"
 	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/config"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/jwtkeys"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/mailer"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/metrics"
 	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/middleware"
 	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/models"
 	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/repository"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/tracing"
 	"github.com/rs/zerolog/log"
 )
 
 // AuthService handles authentication-related business logic
 type AuthService struct {
-	userRepo repository.UserRepository
-	config   *config.Config
+	userRepo     repository.UserRepository
+	resetRepo    repository.PasswordResetRepository
+	verification *VerificationService
+	security     *SecurityService
+	mfa          *MFAService
+	mailer       mailer.Mailer
+	config       *config.Holder
+	keys         *jwtkeys.Provider
+	clock        clock.Clock
 }
 
 // NewAuthService creates a new auth service
-func NewAuthService(userRepo repository.UserRepository, config *config.Config) *AuthService {
+func NewAuthService(
+	userRepo repository.UserRepository,
+	resetRepo repository.PasswordResetRepository,
+	verification *VerificationService,
+	security *SecurityService,
+	mfa *MFAService,
+	mail mailer.Mailer,
+	config *config.Holder,
+	keys *jwtkeys.Provider,
+	clk clock.Clock,
+) *AuthService {
 	return &AuthService{
-		userRepo: userRepo,
-		config:   config,
+		userRepo:     userRepo,
+		resetRepo:    resetRepo,
+		verification: verification,
+		security:     security,
+		mfa:          mfa,
+		mailer:       mail,
+		config:       config,
+		keys:         keys,
+		clock:        clk,
 	}
 }
 
@@ -38,6 +68,14 @@ type LoginRequest struct {
 	Password string `json:"password" binding:"required"`
 }
 
+// LoginResponse is the response for a password login. It holds either the
+// access token or, for users with two-factor authentication, the challenge
+// for the second step.
+type LoginResponse struct {
+	*AuthResponse
+	*MFAChallengeResponse
+}
+
 // ForgotPasswordRequest represents the request for forgot password
 type ForgotPasswordRequest struct {
 	Email string `json:"email" binding:"required,email"`
@@ -49,6 +87,13 @@ type ResetPasswordRequest struct {
 	Password string `json:"password" binding:"required,min=8"`
 }
 
+// ChangePasswordRequest represents the request for setting or changing the
+// password. The current password is only needed when one is set.
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password" binding:"required,min=8"`
+}
+
 // AuthResponse represents the response for authentication
 type AuthResponse struct {
 	Token string              `json:"token"`
@@ -57,12 +102,15 @@ type AuthResponse struct {
 
 // Register handles user registration
 func (s *AuthService) Register(ctx context.Context, req RegisterRequest) (*AuthResponse, error) {
-	// Check if the email is already registered
-	existingUser, err := s.userRepo.FindByEmail(ctx, req.Email)
+	ctx, span := tracing.Start(ctx, "AuthService.Register")
+	defer span.End()
+
+	// Check if the email is already registered, deleted accounts keep it until they are purged
+	exists, err := s.userRepo.ExistsByEmail(ctx, req.Email)
 	if err != nil {
 		return nil, err
 	}
-	if existingUser != nil {
+	if exists {
 		return nil, errors.New("email already registered")
 	}
 
@@ -74,7 +122,7 @@ func (s *AuthService) Register(ctx context.Context, req RegisterRequest) (*AuthR
 
 	// Set the password
 	if err := user.SetPassword(req.Password); err != nil {
-		log.Error().Err(err).Msg("Failed to hash password")
+		log.Ctx(ctx).Error().Err(err).Msg("Failed to hash password")
 		return nil, errors.New("failed to hash password")
 	}
 
@@ -83,8 +131,14 @@ func (s *AuthService) Register(ctx context.Context, req RegisterRequest) (*AuthR
 		return nil, err
 	}
 
+	// The account can be used straight away, within the limits for unverified
+	// accounts. If the email fails, the user can ask for another one.
+	if err := s.verification.Send(ctx, &user); err != nil {
+		log.Ctx(ctx).Error().Err(err).Uint("userID", user.ID).Msg("Failed to send verification email")
+	}
+
 	// Generate a JWT token
-	token, err := middleware.GenerateToken(user.ID, user.Email)
+	token, err := s.issueToken(&user)
 	if err != nil {
 		return nil, err
 	}
@@ -96,27 +150,95 @@ func (s *AuthService) Register(ctx context.Context, req RegisterRequest) (*AuthR
 	}, nil
 }
 
-// Login handles user login
-func (s *AuthService) Login(ctx context.Context, req LoginRequest) (*AuthResponse, error) {
+// Login handles user login from the client IP. Repeated failures delay and
+// then lock further attempts, which fail with a *lockout.Error. Users with
+// two-factor authentication get a challenge to complete with VerifyMFA; the
+// login only counts as successful once they do.
+func (s *AuthService) Login(ctx context.Context, req LoginRequest, clientIP string) (*LoginResponse, error) {
+	ctx, span := tracing.Start(ctx, "AuthService.Login")
+	defer span.End()
+
+	// Refuse before checking anything while the account or IP has to wait
+	if err := s.security.CheckLogin(ctx, req.Email, clientIP); err != nil {
+		return nil, err
+	}
+
 	// Find the user by email
 	user, err := s.userRepo.FindByEmail(ctx, req.Email)
 	if err != nil {
 		return nil, err
 	}
-	if user == nil {
-		return nil, errors.New("invalid email or password")
-	}
 
 	// Check the password
-	if !user.CheckPassword(req.Password) {
+	if user == nil || !user.CheckPassword(req.Password) {
+		s.security.LoginFailed(ctx, user, req.Email, clientIP)
 		return nil, errors.New("invalid email or password")
 	}
 
+	if user.MFAEnabled() {
+		challenge, err := s.mfa.CreateChallenge(ctx, user)
+		if err != nil {
+			return nil, err
+		}
+		return &LoginResponse{MFAChallengeResponse: challenge}, nil
+	}
+
+	response, err := s.completeLogin(ctx, user, clientIP)
+	if err != nil {
+		return nil, err
+	}
+	return &LoginResponse{AuthResponse: response}, nil
+}
+
+// VerifyMFA completes a password login with an authenticator or recovery
+// code. Wrong codes count as failed logins.
+func (s *AuthService) VerifyMFA(ctx context.Context, req VerifyMFARequest, clientIP string) (*AuthResponse, error) {
+	ctx, span := tracing.Start(ctx, "AuthService.VerifyMFA")
+	defer span.End()
+
+	challenge, user, err := s.mfa.FindChallenge(ctx, req.ChallengeToken)
+	if err != nil {
+		return nil, err
+	}
+	if challenge == nil {
+		return nil, errors.New("invalid or expired challenge")
+	}
+
+	if err := s.security.CheckLogin(ctx, user.Email, clientIP); err != nil {
+		return nil, err
+	}
+
+	ok, err := s.mfa.VerifyCode(ctx, user, req.Code, clientIP)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		s.security.LoginFailed(ctx, user, user.Email, clientIP)
+		return nil, errors.New("invalid code")
+	}
+
+	// Each challenge completes one login
+	completed, err := s.mfa.CompleteChallenge(ctx, challenge)
+	if err != nil {
+		return nil, err
+	}
+	if !completed {
+		return nil, errors.New("invalid or expired challenge")
+	}
+
+	return s.completeLogin(ctx, user, clientIP)
+}
+
+// completeLogin records the successful login and issues the access token
+func (s *AuthService) completeLogin(ctx context.Context, user *models.User, clientIP string) (*AuthResponse, error) {
+	s.security.LoginSucceeded(ctx, user, clientIP)
+
 	// Generate a JWT token
-	token, err := middleware.GenerateToken(user.ID, user.Email)
+	token, err := s.issueToken(user)
 	if err != nil {
 		return nil, err
 	}
+	metrics.Login("password")
 
 	// Return the token and user
 	return &AuthResponse{
@@ -125,8 +247,13 @@ func (s *AuthService) Login(ctx context.Context, req LoginRequest) (*AuthRespons
 	}, nil
 }
 
-// ForgotPassword handles forgot password requests
+// ForgotPassword emails a single-use password reset link to the account with
+// the address, if there is one. Nothing is reported back, so the endpoint does
+// not reveal which addresses have an account.
 func (s *AuthService) ForgotPassword(ctx context.Context, req ForgotPasswordRequest) error {
+	ctx, span := tracing.Start(ctx, "AuthService.ForgotPassword")
+	defer span.End()
+
 	// Find the user by email
 	user, err := s.userRepo.FindByEmail(ctx, req.Email)
 	if err != nil {
@@ -137,19 +264,123 @@ func (s *AuthService) ForgotPassword(ctx context.Context, req ForgotPasswordRequ
 		return nil
 	}
 
-	// In a real application, generate a reset token and send an email
-	// For this example, we'll just log it
-	log.Info().Str("email", user.Email).Msg("Password reset requested")
+	cfg := s.config.Get().Auth
+	token, err := newVerificationToken()
+	if err != nil {
+		return err
+	}
+	link, err := accountmail.Link(cfg.PasswordResetURL, token)
+	if err != nil {
+		return err
+	}
 
-	return nil
+	reset := &models.PasswordReset{
+		UserID:    user.ID,
+		TokenHash: hashVerificationToken(token),
+		ExpiresAt: s.clock.Now().UTC().Add(cfg.PasswordResetExpiry),
+		CreatedAt: s.clock.Now().UTC(),
+	}
+	if err := s.resetRepo.Create(ctx, reset); err != nil {
+		return err
+	}
+
+	rendered, err := accountmail.PasswordReset(accountmail.PasswordResetData{
+		Name:     user.Name,
+		Link:     link,
+		Lifetime: cfg.PasswordResetExpiry,
+	})
+	if err != nil {
+		return err
+	}
+
+	log.Ctx(ctx).Info().Uint("userID", user.ID).Msg("Password reset requested")
+	return s.mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: rendered.Subject,
+		HTML:    rendered.HTML,
+		Text:    rendered.Text,
+	})
 }
 
-// ResetPassword handles password reset
-func (s *AuthService) ResetPassword(ctx context.Context, req ResetPasswordRequest) error {
-	// In a real application, verify the token and find the user
-	// For this example, we'll just log it
-	log.Info().Str("token", req.Token).Msg("Password reset token received")
+// ResetPassword sets a new password with the token from a reset link. The
+// token and any other pending ones of the account are used up.
+func (s *AuthService) ResetPassword(ctx context.Context, req ResetPasswordRequest, clientIP string) error {
+	ctx, span := tracing.Start(ctx, "AuthService.ResetPassword")
+	defer span.End()
 
-	// Mock response for demonstration
+	reset, err := s.resetRepo.FindByTokenHash(ctx, hashVerificationToken(req.Token))
+	if err != nil {
+		return err
+	}
+	now := s.clock.Now().UTC()
+	if reset == nil || !now.Before(reset.ExpiresAt) {
+		return errors.New("invalid or expired token")
+	}
+
+	user, err := s.userRepo.FindByID(ctx, reset.UserID)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return errors.New("invalid or expired token")
+	}
+
+	if err := user.SetPassword(req.Password); err != nil {
+		log.Ctx(ctx).Error().Err(err).Msg("Failed to hash password")
+		return errors.New("failed to hash password")
+	}
+	// The link was opened from the inbox, which proves the address
+	if !user.EmailVerified() {
+		user.EmailVerifiedAt = &now
+	}
+	if err := s.userRepo.Update(ctx, user); err != nil {
+		return err
+	}
+
+	if err := s.resetRepo.DeleteByUserID(ctx, user.ID); err != nil {
+		return err
+	}
+	s.security.record(ctx, user.ID, models.SecurityEventPasswordReset, clientIP)
 	return nil
 }
+
+// ChangePassword sets a new password for the user. Users who signed up with
+// a sign-in provider have no password, and can set one here to also sign in
+// with their email.
+func (s *AuthService) ChangePassword(ctx context.Context, userID uint, req ChangePasswordRequest) (*models.UserResponse, error) {
+	ctx, span := tracing.Start(ctx, "AuthService.ChangePassword")
+	defer span.End()
+
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, errors.New("user not found")
+	}
+
+	if user.HasPassword() && !user.CheckPassword(req.CurrentPassword) {
+		return nil, errors.New("invalid password")
+	}
+
+	if err := user.SetPassword(req.NewPassword); err != nil {
+		log.Ctx(ctx).Error().Err(err).Msg("Failed to hash password")
+		return nil, errors.New("failed to hash password")
+	}
+
+	if err := s.userRepo.Update(ctx, user); err != nil {
+		return nil, err
+	}
+
+	response := user.ToResponse()
+	return &response, nil
+}
+
+// issueToken signs an access token for the user with the active key
+func (s *AuthService) issueToken(user *models.User) (string, error) {
+	keys, err := s.keys.Current()
+	if err != nil {
+		return "", err
+	}
+	return middleware.GenerateToken(s.config.Get(), keys, user.ID, user.Email)
+}
@@ -7,8 +7,12 @@ import (
 	"strconv"
 	"time"
 
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/clock"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/database"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/metrics"
 	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/models"
 	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/repository"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/tracing"
 	"gorm.io/datatypes"
 )
 
@@ -18,6 +22,7 @@ type CheckInService struct {
 	streakRepo      repository.StreakRepository
 	checkInRepo     repository.CheckInRepository
 	achievementRepo repository.AchievementRepository
+	clock           clock.Clock
 }
 
 // NewCheckInService creates a new check-in service
@@ -26,12 +31,14 @@ func NewCheckInService(
 	streakRepo repository.StreakRepository,
 	checkInRepo repository.CheckInRepository,
 	achievementRepo repository.AchievementRepository,
+	clk clock.Clock,
 ) *CheckInService {
 	return &CheckInService{
 		habitRepo:       habitRepo,
 		streakRepo:      streakRepo,
 		checkInRepo:     checkInRepo,
 		achievementRepo: achievementRepo,
+		clock:           clk,
 	}
 }
 
@@ -40,6 +47,9 @@ type CheckInRequest struct {
 }
 
 func (s *CheckInService) CheckIn(ctx context.Context, userID uint, habitID uint, req CheckInRequest) (*models.HabitCheckInResponse, error) {
+	ctx, span := tracing.Start(ctx, "CheckInService.CheckIn")
+	defer span.End()
+
 	habit, err := s.habitRepo.FindByID(ctx, habitID)
 	if err != nil {
 		return nil, err
@@ -63,7 +73,7 @@ func (s *CheckInService) CheckIn(ctx context.Context, userID uint, habitID uint,
 		}
 	}
 
-	today := time.Now().UTC().Truncate(24 * time.Hour)
+	today := s.clock.Now().UTC().Truncate(24 * time.Hour)
 	todayStr := today.Format("2006-01-02")
 
 	existingCheckIn, err := s.checkInRepo.FindByDate(ctx, streak.ID, todayStr)
@@ -104,6 +114,7 @@ func (s *CheckInService) CheckIn(ctx context.Context, userID uint, habitID uint,
 			if err := s.streakRepo.Delete(ctx, streakID); err != nil {
 				return nil, err
 			}
+			metrics.StreakFailed()
 
 			return nil, &models.AppError{
 				Code:    "STREAK_BROKEN",
@@ -140,6 +151,7 @@ func (s *CheckInService) CheckIn(ctx context.Context, userID uint, habitID uint,
 		if err := s.achievementRepo.Create(ctx, &achievement); err != nil {
 			return nil, err
 		}
+		metrics.AchievementAwarded(achievement.AchievementType)
 	}
 
 	if streak.CurrentStreak > streak.MaxStreakAchieved {
@@ -150,12 +162,21 @@ func (s *CheckInService) CheckIn(ctx context.Context, userID uint, habitID uint,
 		return nil, err
 	}
 
+	metrics.CheckIn()
+	if streak.Status == "completed" {
+		metrics.StreakCompleted()
+	}
+
 	response := checkIn.ToResponse()
 	return &response, nil
 }
 
 // ListCheckIns lists all check-ins for a habit
 func (s *CheckInService) ListCheckIns(ctx context.Context, userID uint, habitID uint) ([]models.HabitCheckInResponse, error) {
+	ctx, span := tracing.Start(ctx, "CheckInService.ListCheckIns")
+	defer span.End()
+	ctx = database.PreferReplica(ctx)
+
 	// Verify the habit belongs to the user
 	habit, err := s.habitRepo.FindByID(ctx, habitID)
 	if err != nil {
@@ -0,0 +1,175 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/clock"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/models"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/notification"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/repository"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/tracing"
+)
+
+// DeviceService handles push device registration business logic
+type DeviceService struct {
+	deviceRepo repository.DeviceRepository
+	notifier   *notification.Notifier
+	clock      clock.Clock
+}
+
+// NewDeviceService creates a new device service
+func NewDeviceService(deviceRepo repository.DeviceRepository, notifier *notification.Notifier, clk clock.Clock) *DeviceService {
+	return &DeviceService{
+		deviceRepo: deviceRepo,
+		notifier:   notifier,
+		clock:      clk,
+	}
+}
+
+// RegisterDeviceRequest represents the request for registering a device
+type RegisterDeviceRequest struct {
+	Token      string `json:"token" binding:"required"`
+	Platform   string `json:"platform" binding:"required,oneof=ios android"`
+	AppVersion string `json:"app_version" binding:"omitempty,max=32"`
+}
+
+// RefreshDeviceRequest represents the request for refreshing a device token
+type RefreshDeviceRequest struct {
+	Token      string `json:"token"`
+	AppVersion string `json:"app_version" binding:"omitempty,max=32"`
+}
+
+// RegisterDevice registers a device token for the user. Registering a token
+// that is already known moves it to the user and refreshes its details.
+func (s *DeviceService) RegisterDevice(ctx context.Context, userID uint, req RegisterDeviceRequest) (*models.DeviceResponse, error) {
+	ctx, span := tracing.Start(ctx, "DeviceService.RegisterDevice")
+	defer span.End()
+
+	now := s.clock.Now().UTC()
+
+	device, err := s.deviceRepo.FindByToken(ctx, req.Token)
+	if err != nil {
+		return nil, err
+	}
+
+	if device != nil {
+		device.UserID = userID
+		device.Platform = req.Platform
+		device.AppVersion = req.AppVersion
+		device.LastSeenAt = now
+
+		if err := s.deviceRepo.Update(ctx, device); err != nil {
+			return nil, err
+		}
+
+		response := device.ToResponse()
+		return &response, nil
+	}
+
+	device = &models.Device{
+		UserID:     userID,
+		Token:      req.Token,
+		Platform:   req.Platform,
+		AppVersion: req.AppVersion,
+		LastSeenAt: now,
+	}
+
+	if err := s.deviceRepo.Create(ctx, device); err != nil {
+		return nil, err
+	}
+
+	response := device.ToResponse()
+	return &response, nil
+}
+
+// RefreshDevice updates the token or app version of a device and marks it as seen
+func (s *DeviceService) RefreshDevice(ctx context.Context, userID uint, deviceID uint, req RefreshDeviceRequest) (*models.DeviceResponse, error) {
+	ctx, span := tracing.Start(ctx, "DeviceService.RefreshDevice")
+	defer span.End()
+
+	device, err := s.findUserDevice(ctx, userID, deviceID)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Token != "" && req.Token != device.Token {
+		// The provider reissued the token, drop any stale registration of it
+		existing, err := s.deviceRepo.FindByToken(ctx, req.Token)
+		if err != nil {
+			return nil, err
+		}
+		if existing != nil {
+			if err := s.deviceRepo.Delete(ctx, existing.ID); err != nil {
+				return nil, err
+			}
+		}
+		device.Token = req.Token
+	}
+
+	if req.AppVersion != "" {
+		device.AppVersion = req.AppVersion
+	}
+	device.LastSeenAt = s.clock.Now().UTC()
+
+	if err := s.deviceRepo.Update(ctx, device); err != nil {
+		return nil, err
+	}
+
+	response := device.ToResponse()
+	return &response, nil
+}
+
+// UnregisterDevice removes a device so it no longer receives notifications
+func (s *DeviceService) UnregisterDevice(ctx context.Context, userID uint, deviceID uint) error {
+	ctx, span := tracing.Start(ctx, "DeviceService.UnregisterDevice")
+	defer span.End()
+
+	device, err := s.findUserDevice(ctx, userID, deviceID)
+	if err != nil {
+		return err
+	}
+
+	return s.deviceRepo.Delete(ctx, device.ID)
+}
+
+// ListDevices lists all devices registered by the user
+func (s *DeviceService) ListDevices(ctx context.Context, userID uint) ([]models.DeviceResponse, error) {
+	ctx, span := tracing.Start(ctx, "DeviceService.ListDevices")
+	defer span.End()
+
+	devices, err := s.deviceRepo.FindByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	responses := []models.DeviceResponse{}
+	for _, device := range devices {
+		responses = append(responses, device.ToResponse())
+	}
+
+	return responses, nil
+}
+
+// SendTestNotification sends a test notification to all of the user's devices
+func (s *DeviceService) SendTestNotification(ctx context.Context, userID uint) (*notification.Result, error) {
+	ctx, span := tracing.Start(ctx, "DeviceService.SendTestNotification")
+	defer span.End()
+
+	return s.notifier.NotifyUser(ctx, userID, notification.Notification{
+		Title: "Consistency",
+		Body:  "Push notifications are working!",
+		Data:  map[string]string{"type": "test"},
+	})
+}
+
+func (s *DeviceService) findUserDevice(ctx context.Context, userID uint, deviceID uint) (*models.Device, error) {
+	device, err := s.deviceRepo.FindByID(ctx, deviceID)
+	if err != nil {
+		return nil, err
+	}
+	if device == nil || device.UserID != userID {
+		return nil, errors.New("device not found")
+	}
+	return device, nil
+}
@@ -0,0 +1,245 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/clock"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/digest"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/mailer"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/models"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/repository"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/tracing"
+	"github.com/rs/zerolog/log"
+)
+
+// DigestService handles weekly digest preferences, rendering and delivery
+type DigestService struct {
+	userRepo        repository.UserRepository
+	achievementRepo repository.AchievementRepository
+	habitRepo       repository.HabitRepository
+	digestRepo      repository.DigestRepository
+	userService     *UserService
+	mailer          mailer.Mailer
+	clock           clock.Clock
+}
+
+// NewDigestService creates a new digest service
+func NewDigestService(
+	userRepo repository.UserRepository,
+	achievementRepo repository.AchievementRepository,
+	habitRepo repository.HabitRepository,
+	digestRepo repository.DigestRepository,
+	userService *UserService,
+	mailer mailer.Mailer,
+	clk clock.Clock,
+) *DigestService {
+	return &DigestService{
+		userRepo:        userRepo,
+		achievementRepo: achievementRepo,
+		habitRepo:       habitRepo,
+		digestRepo:      digestRepo,
+		userService:     userService,
+		mailer:          mailer,
+		clock:           clk,
+	}
+}
+
+// UpdateDigestPreferencesRequest represents the request for updating digest preferences
+type UpdateDigestPreferencesRequest struct {
+	Enabled *bool  `json:"enabled" binding:"required"`
+	SendDay string `json:"send_day" binding:"omitempty,oneof=sunday monday tuesday wednesday thursday friday saturday"`
+}
+
+// GetPreferences gets the user's weekly digest preferences
+func (s *DigestService) GetPreferences(ctx context.Context, userID uint) (*models.DigestPreferencesResponse, error) {
+	ctx, span := tracing.Start(ctx, "DigestService.GetPreferences")
+	defer span.End()
+
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, errors.New("user not found")
+	}
+
+	return digestPreferences(user), nil
+}
+
+// UpdatePreferences opts the user in or out of the weekly digest and sets the send day
+func (s *DigestService) UpdatePreferences(ctx context.Context, userID uint, req UpdateDigestPreferencesRequest) (*models.DigestPreferencesResponse, error) {
+	ctx, span := tracing.Start(ctx, "DigestService.UpdatePreferences")
+	defer span.End()
+
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, errors.New("user not found")
+	}
+
+	user.WeeklyDigestEnabled = *req.Enabled
+	if req.SendDay != "" {
+		user.WeeklyDigestDay = int(parseWeekday(req.SendDay))
+	}
+
+	if err := s.userRepo.Update(ctx, user); err != nil {
+		return nil, err
+	}
+
+	return digestPreferences(user), nil
+}
+
+// BuildDigest collects the digest data for the week [periodStart, periodEnd)
+func (s *DigestService) BuildDigest(ctx context.Context, user *models.User, periodStart, periodEnd time.Time) (*digest.Data, error) {
+	ctx, span := tracing.Start(ctx, "DigestService.BuildDigest")
+	defer span.End()
+
+	profile, err := s.userService.GetProfile(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	achievements, err := s.achievementRepo.FindByUserID(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Achievements are ordered newest first, so stop at the first one before the period
+	var newAchievements []models.AchievementResponse
+	for _, achievement := range achievements {
+		if achievement.AchievedAt.Before(periodStart) {
+			break
+		}
+		if !achievement.AchievedAt.Before(periodEnd) {
+			continue
+		}
+
+		response := achievement.ToResponse()
+		habit, err := s.habitRepo.FindByID(ctx, achievement.HabitID)
+		if err == nil && habit != nil {
+			response.HabitName = habit.Name
+		}
+		newAchievements = append(newAchievements, response)
+	}
+
+	return &digest.Data{
+		Name:             user.Name,
+		PeriodStart:      periodStart,
+		PeriodEnd:        periodEnd,
+		Overview:         profile.Overview,
+		TopHabits:        profile.TopHabits,
+		ImprovementTrend: profile.ImprovementTrend,
+		NewAchievements:  newAchievements,
+	}, nil
+}
+
+// SendDueDigests sends the digest to every opted-in user whose send day is
+// today and who has not received the digest for this week yet. It returns the
+// number of digests sent.
+func (s *DigestService) SendDueDigests(ctx context.Context, now time.Time) (int, error) {
+	ctx, span := tracing.Start(ctx, "DigestService.SendDueDigests")
+	defer span.End()
+
+	today := now.UTC().Truncate(24 * time.Hour)
+	periodStart := today.AddDate(0, 0, -7)
+
+	users, err := s.userRepo.FindDigestRecipients(ctx, today.Weekday())
+	if err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	for i := range users {
+		if err := ctx.Err(); err != nil {
+			return sent, err
+		}
+
+		ok, err := s.sendDigest(ctx, &users[i], periodStart, today)
+		if err != nil {
+			log.Ctx(ctx).Error().Err(err).Uint("userID", users[i].ID).Msg("Failed to send weekly digest")
+			continue
+		}
+		if ok {
+			sent++
+		}
+	}
+
+	return sent, nil
+}
+
+// digestClaimLease is how long a claim holds a period before another run may
+// take it over, in case the claiming process died before sending
+const digestClaimLease = time.Hour
+
+// sendDigest claims the period, renders and sends the digest. The claim is
+// released when delivery fails so that the next run retries it.
+func (s *DigestService) sendDigest(ctx context.Context, user *models.User, periodStart, periodEnd time.Time) (bool, error) {
+	now := s.clock.Now().UTC()
+	send := &models.DigestSend{
+		UserID:      user.ID,
+		PeriodStart: periodStart,
+		PeriodEnd:   periodEnd,
+		CreatedAt:   now,
+	}
+
+	claimed, err := s.digestRepo.Claim(ctx, send, now.Add(-digestClaimLease))
+	if err != nil {
+		return false, err
+	}
+	if !claimed {
+		return false, nil
+	}
+
+	deliver := func() error {
+		data, err := s.BuildDigest(ctx, user, periodStart, periodEnd)
+		if err != nil {
+			return err
+		}
+
+		rendered, err := digest.Render(*data)
+		if err != nil {
+			return err
+		}
+
+		return s.mailer.Send(ctx, mailer.Message{
+			To:      user.Email,
+			Subject: rendered.Subject,
+			HTML:    rendered.HTML,
+			Text:    rendered.Text,
+		})
+	}
+
+	if err := deliver(); err != nil {
+		if releaseErr := s.digestRepo.Delete(ctx, send.ID); releaseErr != nil {
+			log.Ctx(ctx).Error().Err(releaseErr).Uint("userID", user.ID).Msg("Failed to release digest claim")
+		}
+		return false, err
+	}
+
+	if err := s.digestRepo.MarkSent(ctx, send.ID, s.clock.Now().UTC()); err != nil {
+		return true, err
+	}
+
+	return true, nil
+}
+
+func digestPreferences(user *models.User) *models.DigestPreferencesResponse {
+	return &models.DigestPreferencesResponse{
+		Enabled: user.WeeklyDigestEnabled,
+		SendDay: strings.ToLower(time.Weekday(user.WeeklyDigestDay).String()),
+	}
+}
+
+func parseWeekday(day string) time.Weekday {
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		if strings.EqualFold(d.String(), day) {
+			return d
+		}
+	}
+	return time.Sunday
+}
@@ -0,0 +1,374 @@
+package service
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/clock"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/export"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/models"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/repository"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/tracing"
+)
+
+// Supported export formats
+const (
+	ExportFormatJSON = "json"
+	ExportFormatCSV  = "csv"
+	ExportFormatICS  = "ics"
+)
+
+// exportBatchSize is the number of check-ins loaded per query while exporting
+const exportBatchSize = 500
+
+// ExportService builds data exports of everything stored for a user
+type ExportService struct {
+	userRepo        repository.UserRepository
+	habitRepo       repository.HabitRepository
+	streakRepo      repository.StreakRepository
+	checkInRepo     repository.CheckInRepository
+	achievementRepo repository.AchievementRepository
+	clock           clock.Clock
+}
+
+// NewExportService creates a new export service
+func NewExportService(
+	userRepo repository.UserRepository,
+	habitRepo repository.HabitRepository,
+	streakRepo repository.StreakRepository,
+	checkInRepo repository.CheckInRepository,
+	achievementRepo repository.AchievementRepository,
+	clk clock.Clock,
+) *ExportService {
+	return &ExportService{
+		userRepo:        userRepo,
+		habitRepo:       habitRepo,
+		streakRepo:      streakRepo,
+		checkInRepo:     checkInRepo,
+		achievementRepo: achievementRepo,
+		clock:           clk,
+	}
+}
+
+// UserExport is an export of a single user's data that has not been written yet
+type UserExport struct {
+	service    *ExportService
+	user       *models.User
+	habits     []models.Habit
+	exportedAt time.Time
+}
+
+// ExportCheckIn is a check-in as it appears in an export
+type ExportCheckIn struct {
+	ID          uint      `json:"id"`
+	HabitID     uint      `json:"habit_id"`
+	HabitName   string    `json:"habit_name"`
+	StreakID    uint      `json:"streak_id"`
+	CheckInDate time.Time `json:"check_in_date"`
+	CheckedInAt time.Time `json:"checked_in_at"`
+	Notes       string    `json:"notes"`
+}
+
+// NewExport prepares an export for the user. It fails before anything is
+// written if the user does not exist.
+func (s *ExportService) NewExport(ctx context.Context, userID uint) (*UserExport, error) {
+	ctx, span := tracing.Start(ctx, "ExportService.NewExport")
+	defer span.End()
+
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, errors.New("user not found")
+	}
+
+	habits, err := s.habitRepo.FindByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &UserExport{
+		service:    s,
+		user:       user,
+		habits:     habits,
+		exportedAt: s.clock.Now().UTC(),
+	}, nil
+}
+
+// Filename returns the download file name for the given format
+func (e *UserExport) Filename(format string) string {
+	ext := format
+	if format == ExportFormatCSV {
+		ext = "zip"
+	}
+	return fmt.Sprintf("consistency-export-%s.%s", e.exportedAt.Format("2006-01-02"), ext)
+}
+
+// ContentType returns the MIME type for the given format
+func (e *UserExport) ContentType(format string) string {
+	switch format {
+	case ExportFormatCSV:
+		return "application/zip"
+	case ExportFormatICS:
+		return "text/calendar; charset=utf-8"
+	default:
+		return "application/json; charset=utf-8"
+	}
+}
+
+// Write streams the export in the given format
+func (e *UserExport) Write(ctx context.Context, format string, w io.Writer) error {
+	switch format {
+	case ExportFormatJSON:
+		return e.WriteJSON(ctx, w)
+	case ExportFormatCSV:
+		return e.WriteCSV(ctx, w)
+	case ExportFormatICS:
+		return e.WriteICS(ctx, w)
+	default:
+		return fmt.Errorf("unsupported export format %q", format)
+	}
+}
+
+// WriteJSON streams a full JSON archive with one array per entity
+func (e *UserExport) WriteJSON(ctx context.Context, w io.Writer) error {
+	jw := export.NewJSONWriter(w)
+
+	if err := jw.Field("exported_at", e.exportedAt); err != nil {
+		return err
+	}
+	if err := jw.Field("profile", e.user.ToResponse()); err != nil {
+		return err
+	}
+
+	if err := jw.BeginArray("habits"); err != nil {
+		return err
+	}
+	for _, habit := range e.habits {
+		if err := jw.Item(habit); err != nil {
+			return err
+		}
+	}
+	if err := jw.EndArray(); err != nil {
+		return err
+	}
+
+	if err := jw.BeginArray("streaks"); err != nil {
+		return err
+	}
+	if err := e.eachStreak(ctx, func(_ models.Habit, streak models.HabitStreak) error {
+		return jw.Item(streak)
+	}); err != nil {
+		return err
+	}
+	if err := jw.EndArray(); err != nil {
+		return err
+	}
+
+	if err := jw.BeginArray("check_ins"); err != nil {
+		return err
+	}
+	if err := e.eachCheckIn(ctx, func(checkIn ExportCheckIn) error {
+		return jw.Item(checkIn)
+	}); err != nil {
+		return err
+	}
+	if err := jw.EndArray(); err != nil {
+		return err
+	}
+
+	achievements, err := e.service.achievementRepo.FindByUserID(ctx, e.user.ID)
+	if err != nil {
+		return err
+	}
+	if err := jw.BeginArray("achievements"); err != nil {
+		return err
+	}
+	for _, achievement := range achievements {
+		if err := jw.Item(achievement.ToResponse()); err != nil {
+			return err
+		}
+	}
+	if err := jw.EndArray(); err != nil {
+		return err
+	}
+
+	return jw.Close()
+}
+
+// WriteCSV streams a zip archive with one CSV file per entity
+func (e *UserExport) WriteCSV(ctx context.Context, w io.Writer) error {
+	zw := zip.NewWriter(w)
+
+	writeFile := func(name string, header []string, rows func(write func([]string) error) error) error {
+		f, err := zw.Create(name)
+		if err != nil {
+			return err
+		}
+		cw := csv.NewWriter(f)
+		if err := cw.Write(header); err != nil {
+			return err
+		}
+		if err := rows(cw.Write); err != nil {
+			return err
+		}
+		cw.Flush()
+		return cw.Error()
+	}
+
+	if err := writeFile("profile.csv",
+		[]string{"id", "email", "name", "created_at", "weekly_digest_enabled", "weekly_digest_day"},
+		func(write func([]string) error) error {
+			return write([]string{
+				formatUint(e.user.ID), e.user.Email, e.user.Name, formatTime(e.user.CreatedAt),
+				strconv.FormatBool(e.user.WeeklyDigestEnabled), time.Weekday(e.user.WeeklyDigestDay).String(),
+			})
+		}); err != nil {
+		return err
+	}
+
+	if err := writeFile("habits.csv",
+		[]string{"id", "name", "description", "color", "icon", "is_active", "created_at", "updated_at"},
+		func(write func([]string) error) error {
+			for _, habit := range e.habits {
+				if err := write([]string{
+					formatUint(habit.ID), habit.Name, habit.Description, habit.Color, habit.Icon,
+					strconv.FormatBool(habit.IsActive), formatTime(habit.CreatedAt), formatTime(habit.UpdatedAt),
+				}); err != nil {
+					return err
+				}
+			}
+			return nil
+		}); err != nil {
+		return err
+	}
+
+	if err := writeFile("streaks.csv",
+		[]string{"id", "habit_id", "target_days", "current_streak", "max_streak_achieved", "start_date", "last_check_in_date", "status", "completed_at", "failed_at", "created_at"},
+		func(write func([]string) error) error {
+			return e.eachStreak(ctx, func(_ models.Habit, streak models.HabitStreak) error {
+				return write([]string{
+					formatUint(streak.ID), formatUint(streak.HabitID), strconv.Itoa(streak.TargetDays),
+					strconv.Itoa(streak.CurrentStreak), strconv.Itoa(streak.MaxStreakAchieved),
+					formatTime(streak.StartDate), formatTimePtr(streak.LastCheckInDate), streak.Status,
+					formatTimePtr(streak.CompletedAt), formatTimePtr(streak.FailedAt), formatTime(streak.CreatedAt),
+				})
+			})
+		}); err != nil {
+		return err
+	}
+
+	if err := writeFile("check_ins.csv",
+		[]string{"id", "habit_id", "habit_name", "streak_id", "check_in_date", "checked_in_at", "notes"},
+		func(write func([]string) error) error {
+			return e.eachCheckIn(ctx, func(checkIn ExportCheckIn) error {
+				return write([]string{
+					formatUint(checkIn.ID), formatUint(checkIn.HabitID), checkIn.HabitName, formatUint(checkIn.StreakID),
+					checkIn.CheckInDate.Format("2006-01-02"), formatTime(checkIn.CheckedInAt), checkIn.Notes,
+				})
+			})
+		}); err != nil {
+		return err
+	}
+
+	if err := writeFile("achievements.csv",
+		[]string{"id", "habit_id", "achievement_type", "target_days", "achieved_at", "metadata"},
+		func(write func([]string) error) error {
+			achievements, err := e.service.achievementRepo.FindByUserID(ctx, e.user.ID)
+			if err != nil {
+				return err
+			}
+			for _, achievement := range achievements {
+				if err := write([]string{
+					formatUint(achievement.ID), formatUint(achievement.HabitID), achievement.AchievementType,
+					strconv.Itoa(achievement.TargetDays), formatTime(achievement.AchievedAt), string(achievement.Metadata),
+				}); err != nil {
+					return err
+				}
+			}
+			return nil
+		}); err != nil {
+		return err
+	}
+
+	return zw.Close()
+}
+
+// WriteICS streams an iCalendar file with every check-in as an all-day event
+func (e *UserExport) WriteICS(ctx context.Context, w io.Writer) error {
+	iw := export.NewICSWriter(w, "Consistency check-ins")
+
+	if err := e.eachCheckIn(ctx, func(checkIn ExportCheckIn) error {
+		return iw.WriteEvent(export.ICSEvent{
+			UID:         fmt.Sprintf("checkin-%d@consistency", checkIn.ID),
+			Date:        checkIn.CheckInDate,
+			Summary:     "✓ " + checkIn.HabitName,
+			Description: checkIn.Notes,
+			Created:     checkIn.CheckedInAt,
+		})
+	}); err != nil {
+		return err
+	}
+
+	return iw.Close()
+}
+
+// eachStreak calls fn for every streak of every habit in the export
+func (e *UserExport) eachStreak(ctx context.Context, fn func(models.Habit, models.HabitStreak) error) error {
+	for _, habit := range e.habits {
+		streaks, err := e.service.streakRepo.FindByHabitID(ctx, habit.ID)
+		if err != nil {
+			return err
+		}
+		for _, streak := range streaks {
+			if err := fn(habit, streak); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// eachCheckIn calls fn for every check-in in the export, loading check-ins in batches
+func (e *UserExport) eachCheckIn(ctx context.Context, fn func(ExportCheckIn) error) error {
+	return e.eachStreak(ctx, func(habit models.Habit, streak models.HabitStreak) error {
+		return e.service.checkInRepo.FindByStreakIDInBatches(ctx, streak.ID, exportBatchSize, func(checkIns []models.HabitCheckIn) error {
+			for _, checkIn := range checkIns {
+				if err := fn(ExportCheckIn{
+					ID:          checkIn.ID,
+					HabitID:     habit.ID,
+					HabitName:   habit.Name,
+					StreakID:    checkIn.StreakID,
+					CheckInDate: checkIn.CheckInDate,
+					CheckedInAt: checkIn.CheckedInAt,
+					Notes:       checkIn.Notes,
+				}); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	})
+}
+
+func formatUint(v uint) string {
+	return strconv.FormatUint(uint64(v), 10)
+}
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+func formatTimePtr(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return formatTime(*t)
+}
@@ -4,8 +4,10 @@ import (
 	"context"
 	"errors"
 
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/database"
 	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/models"
 	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/repository"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/tracing"
 )
 
 type HabitService struct {
@@ -36,6 +38,10 @@ type UpdateHabitRequest struct {
 }
 
 func (s *HabitService) ListHabits(ctx context.Context, userID uint) ([]models.HabitResponse, error) {
+	ctx, span := tracing.Start(ctx, "HabitService.ListHabits")
+	defer span.End()
+	ctx = database.PreferReplica(ctx)
+
 	habits, err := s.habitRepo.FindByUserID(ctx, userID)
 	if err != nil {
 		return nil, err
@@ -53,6 +59,9 @@ func (s *HabitService) ListHabits(ctx context.Context, userID uint) ([]models.Ha
 }
 
 func (s *HabitService) CreateHabit(ctx context.Context, userID uint, req CreateHabitRequest) (*models.HabitResponse, error) {
+	ctx, span := tracing.Start(ctx, "HabitService.CreateHabit")
+	defer span.End()
+
 	habit := models.Habit{
 		UserID:      userID,
 		Name:        req.Name,
@@ -71,6 +80,9 @@ func (s *HabitService) CreateHabit(ctx context.Context, userID uint, req CreateH
 }
 
 func (s *HabitService) GetHabit(ctx context.Context, userID uint, habitID uint) (*models.HabitResponse, error) {
+	ctx, span := tracing.Start(ctx, "HabitService.GetHabit")
+	defer span.End()
+
 	habit, err := s.habitRepo.FindByID(ctx, habitID)
 	if err != nil {
 		return nil, err
@@ -91,6 +103,9 @@ func (s *HabitService) GetHabit(ctx context.Context, userID uint, habitID uint)
 }
 
 func (s *HabitService) UpdateHabit(ctx context.Context, userID uint, habitID uint, req UpdateHabitRequest) (*models.HabitResponse, error) {
+	ctx, span := tracing.Start(ctx, "HabitService.UpdateHabit")
+	defer span.End()
+
 	habit, err := s.habitRepo.FindByID(ctx, habitID)
 	if err != nil {
 		return nil, err
@@ -122,6 +137,9 @@ func (s *HabitService) UpdateHabit(ctx context.Context, userID uint, habitID uin
 }
 
 func (s *HabitService) DeleteHabit(ctx context.Context, userID uint, habitID uint) error {
+	ctx, span := tracing.Start(ctx, "HabitService.DeleteHabit")
+	defer span.End()
+
 	habit, err := s.habitRepo.FindByID(ctx, habitID)
 	if err != nil {
 		return err
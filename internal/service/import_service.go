@@ -0,0 +1,220 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/clock"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/importer"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/models"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/repository"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/tracing"
+	"gorm.io/datatypes"
+)
+
+// ImportService imports habit histories exported by other habit trackers
+type ImportService struct {
+	habitRepo repository.HabitRepository
+	clock     clock.Clock
+}
+
+// NewImportService creates a new import service
+func NewImportService(habitRepo repository.HabitRepository, clk clock.Clock) *ImportService {
+	return &ImportService{
+		habitRepo: habitRepo,
+		clock:     clk,
+	}
+}
+
+// Import parses the export and creates a habit for every source habit that
+// does not clash with an existing one by name. Streaks are reconstructed from
+// runs of consecutive days, and completed runs are awarded their historical
+// achievements. In a dry run nothing is written and the report describes what
+// would have been created.
+func (s *ImportService) Import(ctx context.Context, userID uint, format string, data []byte, dryRun bool) (*models.ImportReport, error) {
+	ctx, span := tracing.Start(ctx, "ImportService.Import")
+	defer span.End()
+
+	dataset, err := importer.Parse(format, data)
+	if err != nil {
+		return nil, &models.AppError{
+			Code:    "INVALID_IMPORT",
+			Message: err.Error(),
+		}
+	}
+
+	existing, err := s.habitRepo.FindByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	existingByName := make(map[string]uint, len(existing))
+	for _, habit := range existing {
+		existingByName[habitNameKey(habit.Name)] = habit.ID
+	}
+
+	report := &models.ImportReport{
+		Format:    format,
+		DryRun:    dryRun,
+		Habits:    []models.ImportHabitReport{},
+		Conflicts: []models.ImportConflict{},
+	}
+	today := s.clock.Now().UTC().Truncate(24 * time.Hour)
+	seen := make(map[string]bool, len(dataset.Habits))
+
+	for _, source := range dataset.Habits {
+		key := habitNameKey(source.Name)
+		if id, ok := existingByName[key]; ok {
+			report.Conflicts = append(report.Conflicts, models.ImportConflict{
+				Name:            source.Name,
+				ExistingHabitID: id,
+				Reason:          "a habit with this name already exists",
+			})
+			continue
+		}
+		if seen[key] {
+			report.Conflicts = append(report.Conflicts, models.ImportConflict{
+				Name:   source.Name,
+				Reason: "the import contains this habit more than once",
+			})
+			continue
+		}
+		seen[key] = true
+
+		runs := importer.ReconstructRuns(source.Completions, today)
+
+		var habitReport *models.ImportHabitReport
+		if dryRun {
+			habitReport = planHabit(source, runs)
+		} else {
+			habitReport, err = s.importHabit(ctx, userID, format, source, runs)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		report.Habits = append(report.Habits, *habitReport)
+		report.Streaks += habitReport.Streaks
+		report.CheckIns += habitReport.CheckIns
+		report.Achievements += habitReport.Achievements
+	}
+
+	return report, nil
+}
+
+// importHabit creates the habit with its streaks, check-ins and achievements
+// in one transaction, so that a failure leaves nothing behind to conflict
+// with a retry
+func (s *ImportService) importHabit(ctx context.Context, userID uint, format string, source importer.Habit, runs []importer.Run) (*models.ImportHabitReport, error) {
+	habit := models.Habit{
+		UserID:      userID,
+		Name:        source.Name,
+		Description: source.Description,
+		Color:       source.Color,
+		IsActive:    true,
+		CreatedAt:   source.CreatedAt,
+	}
+
+	history := make([]repository.StreakHistory, len(runs))
+	for i, run := range runs {
+		history[i] = streakHistory(userID, format, run)
+	}
+
+	if err := s.habitRepo.CreateWithHistory(ctx, &habit, history); err != nil {
+		return nil, err
+	}
+
+	report := planHabit(source, runs)
+	report.ID = habit.ID
+	return report, nil
+}
+
+// streakHistory describes the streak for a run together with its check-ins
+// and, for completed runs, the achievement earned when the milestone was
+// reached
+func streakHistory(userID uint, format string, run importer.Run) repository.StreakHistory {
+	end := run.End
+	streak := models.HabitStreak{
+		TargetDays:        run.TargetDays,
+		CurrentStreak:     run.Length(),
+		MaxStreakAchieved: run.Length(),
+		StartDate:         run.Start,
+		LastCheckInDate:   &end,
+		Status:            run.Status,
+	}
+
+	switch run.Status {
+	case importer.StatusCompleted:
+		streak.CompletedAt = &end
+	case importer.StatusFailed:
+		failedAt := end.AddDate(0, 0, 1)
+		streak.FailedAt = &failedAt
+	}
+
+	checkIns := make([]models.HabitCheckIn, len(run.Completions))
+	for i, completion := range run.Completions {
+		checkIns[i] = models.HabitCheckIn{
+			CheckInDate: completion.Date,
+			CheckedInAt: completion.Date,
+			Notes:       completion.Notes,
+		}
+	}
+
+	history := repository.StreakHistory{Streak: streak, CheckIns: checkIns}
+	if run.Status != importer.StatusCompleted {
+		return history
+	}
+
+	history.Achievement = func(streak *models.HabitStreak) (*models.Achievement, error) {
+		metadata, err := json.Marshal(map[string]interface{}{
+			"streak_id": streak.ID,
+			"imported":  true,
+			"source":    format,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		return &models.Achievement{
+			UserID:          userID,
+			AchievementType: "streak_completed",
+			TargetDays:      run.TargetDays,
+			AchievedAt:      run.MilestoneReachedAt(),
+			Metadata:        datatypes.JSON(metadata),
+		}, nil
+	}
+	return history
+}
+
+// planHabit summarises what importing the habit creates
+func planHabit(source importer.Habit, runs []importer.Run) *models.ImportHabitReport {
+	report := &models.ImportHabitReport{
+		Name:     source.Name,
+		Streaks:  len(runs),
+		CheckIns: len(source.Completions),
+	}
+
+	if n := len(source.Completions); n > 0 {
+		first, last := source.Completions[0].Date, source.Completions[n-1].Date
+		report.FirstDay = &first
+		report.LastDay = &last
+	}
+
+	for _, run := range runs {
+		switch run.Status {
+		case importer.StatusCompleted:
+			report.Achievements++
+		case importer.StatusActive:
+			length := run.Length()
+			report.ActiveStreak = &length
+		}
+	}
+
+	return report
+}
+
+func habitNameKey(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
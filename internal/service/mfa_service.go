@@ -0,0 +1,440 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/clock"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/config"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/models"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/repository"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/secretbox"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/totp"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/tracing"
+)
+
+const (
+	// recoveryCodeCount is how many recovery codes a user gets at a time
+	recoveryCodeCount = 10
+	// recoveryCodeSize is the number of random bytes in a recovery code,
+	// which is written as 16 base32 characters in groups of four
+	recoveryCodeSize = 10
+)
+
+var recoveryEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// MFAService manages two-factor authentication with authenticator apps and
+// one-time recovery codes, and the challenges of the second login step
+type MFAService struct {
+	userRepo repository.UserRepository
+	mfaRepo  repository.MFARepository
+	security *SecurityService
+	box      *secretbox.Box
+	config   *config.Holder
+	clock    clock.Clock
+}
+
+// NewMFAService creates a new MFA service. TOTP secrets are encrypted with
+// the box; when it is nil, users cannot enable two-factor authentication.
+func NewMFAService(
+	userRepo repository.UserRepository,
+	mfaRepo repository.MFARepository,
+	security *SecurityService,
+	box *secretbox.Box,
+	config *config.Holder,
+	clk clock.Clock,
+) *MFAService {
+	return &MFAService{
+		userRepo: userRepo,
+		mfaRepo:  mfaRepo,
+		security: security,
+		box:      box,
+		config:   config,
+		clock:    clk,
+	}
+}
+
+// MFAPasswordRequest represents a request that must be confirmed with the
+// current password
+type MFAPasswordRequest struct {
+	Password string `json:"password" binding:"required"`
+}
+
+// MFACodeRequest represents the request for confirming an authenticator app
+type MFACodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// DisableMFARequest represents the request for disabling two-factor
+// authentication. The code is an authenticator or a recovery code, and is
+// not needed to abandon a pending enrolment.
+type DisableMFARequest struct {
+	Password string `json:"password" binding:"required"`
+	Code     string `json:"code"`
+}
+
+// VerifyMFARequest represents the second login step. The code is an
+// authenticator or a recovery code.
+type VerifyMFARequest struct {
+	ChallengeToken string `json:"challenge_token" binding:"required"`
+	Code           string `json:"code" binding:"required"`
+}
+
+// MFAStatusResponse describes the user's two-factor authentication
+type MFAStatusResponse struct {
+	Available         bool       `json:"available"`
+	Enabled           bool       `json:"enabled"`
+	EnabledAt         *time.Time `json:"enabled_at,omitempty"`
+	RecoveryCodesLeft int64      `json:"recovery_codes_left"`
+	EnrolmentPending  bool       `json:"enrolment_pending"`
+}
+
+// TOTPEnrolmentResponse holds the secret to add to an authenticator app,
+// directly or through the otpauth URI
+type TOTPEnrolmentResponse struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+}
+
+// RecoveryCodesResponse holds newly generated recovery codes. They are only
+// shown once.
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// MFAChallengeResponse is returned by a password login instead of an access
+// token when the user has two-factor authentication enabled
+type MFAChallengeResponse struct {
+	MFARequired    bool      `json:"mfa_required"`
+	ChallengeToken string    `json:"challenge_token"`
+	ExpiresAt      time.Time `json:"expires_at"`
+}
+
+// Status describes the user's two-factor authentication
+func (s *MFAService) Status(ctx context.Context, userID uint) (*MFAStatusResponse, error) {
+	ctx, span := tracing.Start(ctx, "MFAService.Status")
+	defer span.End()
+
+	user, err := s.findUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &MFAStatusResponse{
+		Available: s.box != nil,
+		Enabled:   user.MFAEnabled(),
+		EnabledAt: user.MFAEnabledAt,
+	}
+	if status.Enabled {
+		if status.RecoveryCodesLeft, err = s.mfaRepo.CountUnusedRecoveryCodes(ctx, userID); err != nil {
+			return nil, err
+		}
+		return status, nil
+	}
+
+	credential, err := s.mfaRepo.FindTOTP(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	status.EnrolmentPending = credential != nil
+	return status, nil
+}
+
+// StartEnrolment generates a new authenticator secret for the user. It has to
+// be confirmed with a code before it is required at login; starting again
+// replaces an unconfirmed secret.
+func (s *MFAService) StartEnrolment(ctx context.Context, userID uint, req MFAPasswordRequest) (*TOTPEnrolmentResponse, error) {
+	ctx, span := tracing.Start(ctx, "MFAService.StartEnrolment")
+	defer span.End()
+
+	if s.box == nil {
+		return nil, errors.New("two-factor authentication is not available")
+	}
+	user, err := s.findUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.MFAEnabled() {
+		return nil, errors.New("two-factor authentication is already enabled")
+	}
+	if err := s.checkPassword(user, req.Password); err != nil {
+		return nil, err
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+	sealed, err := s.box.Seal(secret)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.mfaRepo.SaveTOTP(ctx, &models.TOTPCredential{UserID: user.ID, Secret: sealed}); err != nil {
+		return nil, err
+	}
+
+	return &TOTPEnrolmentResponse{
+		Secret: secret,
+		URI:    totp.URI(s.config.Get().MFA.Issuer, user.Email, secret),
+	}, nil
+}
+
+// ConfirmEnrolment enables two-factor authentication once the user proves
+// the authenticator app works, and returns the first recovery codes
+func (s *MFAService) ConfirmEnrolment(ctx context.Context, userID uint, req MFACodeRequest, ip string) (*RecoveryCodesResponse, error) {
+	ctx, span := tracing.Start(ctx, "MFAService.ConfirmEnrolment")
+	defer span.End()
+
+	user, err := s.findUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.MFAEnabled() {
+		return nil, errors.New("two-factor authentication is already enabled")
+	}
+	credential, err := s.mfaRepo.FindTOTP(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if credential == nil {
+		return nil, errors.New("no pending enrolment")
+	}
+
+	ok, err := s.checkTOTP(ctx, credential, req.Code)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, errors.New("invalid code")
+	}
+
+	codes, records, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.mfaRepo.Enable(ctx, userID, s.clock.Now().UTC(), records); err != nil {
+		return nil, err
+	}
+	s.security.record(ctx, userID, models.SecurityEventMFAEnabled, ip)
+
+	return &RecoveryCodesResponse{RecoveryCodes: codes}, nil
+}
+
+// RegenerateRecoveryCodes replaces the user's recovery codes, used or not
+func (s *MFAService) RegenerateRecoveryCodes(ctx context.Context, userID uint, req MFAPasswordRequest, ip string) (*RecoveryCodesResponse, error) {
+	ctx, span := tracing.Start(ctx, "MFAService.RegenerateRecoveryCodes")
+	defer span.End()
+
+	user, err := s.findUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !user.MFAEnabled() {
+		return nil, errors.New("two-factor authentication is not enabled")
+	}
+	if err := s.checkPassword(user, req.Password); err != nil {
+		return nil, err
+	}
+
+	codes, records, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.mfaRepo.ReplaceRecoveryCodes(ctx, userID, records); err != nil {
+		return nil, err
+	}
+	s.security.record(ctx, userID, models.SecurityEventRecoveryCodes, ip)
+
+	return &RecoveryCodesResponse{RecoveryCodes: codes}, nil
+}
+
+// Disable turns two-factor authentication off, or abandons a pending
+// enrolment. Once enabled, it takes the password and a current code.
+func (s *MFAService) Disable(ctx context.Context, userID uint, req DisableMFARequest, ip string) error {
+	ctx, span := tracing.Start(ctx, "MFAService.Disable")
+	defer span.End()
+
+	user, err := s.findUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if err := s.checkPassword(user, req.Password); err != nil {
+		return err
+	}
+
+	if user.MFAEnabled() {
+		ok, err := s.VerifyCode(ctx, user, req.Code, ip)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return errors.New("invalid code")
+		}
+	}
+
+	if err := s.mfaRepo.Disable(ctx, userID); err != nil {
+		return err
+	}
+	if user.MFAEnabled() {
+		s.security.record(ctx, userID, models.SecurityEventMFADisabled, ip)
+	}
+	return nil
+}
+
+// VerifyCode checks an authenticator code, which cannot be used twice, or
+// uses up a recovery code
+func (s *MFAService) VerifyCode(ctx context.Context, user *models.User, code, ip string) (bool, error) {
+	ctx, span := tracing.Start(ctx, "MFAService.VerifyCode")
+	defer span.End()
+
+	code = strings.TrimSpace(code)
+	if len(code) == totp.Digits {
+		credential, err := s.mfaRepo.FindTOTP(ctx, user.ID)
+		if err != nil || credential == nil || credential.ConfirmedAt == nil {
+			return false, err
+		}
+		return s.checkTOTP(ctx, credential, code)
+	}
+
+	used, err := s.mfaRepo.UseRecoveryCode(ctx, user.ID, hashRecoveryCode(code), s.clock.Now().UTC())
+	if err != nil {
+		return false, err
+	}
+	if used {
+		s.security.record(ctx, user.ID, models.SecurityEventRecoveryUsed, ip)
+	}
+	return used, nil
+}
+
+// CreateChallenge starts the second login step for the user
+func (s *MFAService) CreateChallenge(ctx context.Context, user *models.User) (*MFAChallengeResponse, error) {
+	ctx, span := tracing.Start(ctx, "MFAService.CreateChallenge")
+	defer span.End()
+
+	now := s.clock.Now().UTC()
+	if err := s.mfaRepo.DeleteExpiredChallenges(ctx, user.ID, now); err != nil {
+		return nil, err
+	}
+
+	token, err := newVerificationToken()
+	if err != nil {
+		return nil, err
+	}
+	challenge := &models.MFAChallenge{
+		UserID:    user.ID,
+		TokenHash: hashVerificationToken(token),
+		ExpiresAt: now.Add(s.config.Get().MFA.ChallengeLifetime),
+	}
+	if err := s.mfaRepo.CreateChallenge(ctx, challenge); err != nil {
+		return nil, err
+	}
+
+	return &MFAChallengeResponse{
+		MFARequired:    true,
+		ChallengeToken: token,
+		ExpiresAt:      challenge.ExpiresAt,
+	}, nil
+}
+
+// FindChallenge returns the pending challenge for the token and its user,
+// or nil if the token is unknown or expired
+func (s *MFAService) FindChallenge(ctx context.Context, token string) (*models.MFAChallenge, *models.User, error) {
+	ctx, span := tracing.Start(ctx, "MFAService.FindChallenge")
+	defer span.End()
+
+	challenge, err := s.mfaRepo.FindChallengeByTokenHash(ctx, hashVerificationToken(token))
+	if err != nil || challenge == nil {
+		return nil, nil, err
+	}
+	if !s.clock.Now().Before(challenge.ExpiresAt) {
+		return nil, nil, nil
+	}
+
+	user, err := s.userRepo.FindByID(ctx, challenge.UserID)
+	if err != nil || user == nil {
+		return nil, nil, err
+	}
+	return challenge, user, nil
+}
+
+// CompleteChallenge uses up the challenge and reports false if it was
+// already used
+func (s *MFAService) CompleteChallenge(ctx context.Context, challenge *models.MFAChallenge) (bool, error) {
+	ctx, span := tracing.Start(ctx, "MFAService.CompleteChallenge")
+	defer span.End()
+
+	return s.mfaRepo.DeleteChallenge(ctx, challenge.ID)
+}
+
+// findUser finds the user or fails with "user not found"
+func (s *MFAService) findUser(ctx context.Context, userID uint) (*models.User, error) {
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, errors.New("user not found")
+	}
+	return user, nil
+}
+
+// checkPassword confirms the current password. Accounts without one, from a
+// sign-in provider, have to set a password first as the second factor only
+// protects password logins.
+func (s *MFAService) checkPassword(user *models.User, password string) error {
+	if !user.HasPassword() {
+		return errors.New("password not set")
+	}
+	if !user.CheckPassword(password) {
+		return errors.New("invalid password")
+	}
+	return nil
+}
+
+// checkTOTP validates an authenticator code and records its time step, so
+// that it cannot be replayed
+func (s *MFAService) checkTOTP(ctx context.Context, credential *models.TOTPCredential, code string) (bool, error) {
+	if s.box == nil {
+		return false, errors.New("two-factor authentication is not available")
+	}
+	secret, err := s.box.Open(credential.Secret)
+	if err != nil {
+		return false, err
+	}
+	step, ok, err := totp.Validate(secret, code, s.clock.Now())
+	if err != nil || !ok {
+		return false, err
+	}
+	return s.mfaRepo.UseTOTPStep(ctx, credential.UserID, step)
+}
+
+// newRecoveryCodes returns new recovery codes to show to the user and the
+// records that store their hashes
+func newRecoveryCodes() ([]string, []models.RecoveryCode, error) {
+	codes := make([]string, recoveryCodeCount)
+	records := make([]models.RecoveryCode, recoveryCodeCount)
+	buf := make([]byte, recoveryCodeSize)
+	for i := range codes {
+		if _, err := rand.Read(buf); err != nil {
+			return nil, nil, err
+		}
+		raw := strings.ToLower(recoveryEncoding.EncodeToString(buf))
+		codes[i] = raw[0:4] + "-" + raw[4:8] + "-" + raw[8:12] + "-" + raw[12:16]
+		records[i] = models.RecoveryCode{CodeHash: hashRecoveryCode(codes[i])}
+	}
+	return codes, records, nil
+}
+
+// hashRecoveryCode returns the hash stored in place of a recovery code. Case,
+// dashes and spaces do not matter.
+func hashRecoveryCode(code string) string {
+	normalized := strings.NewReplacer("-", "", " ", "").Replace(strings.ToLower(code))
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,214 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/clock"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/config"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/metrics"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/models"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/oidc"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/repository"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/tracing"
+	"github.com/rs/zerolog/log"
+)
+
+// OIDCService signs users in with an OpenID Connect provider
+type OIDCService struct {
+	userRepo     repository.UserRepository
+	identityRepo repository.IdentityRepository
+	verifier     *oidc.Verifier
+	authService  *AuthService
+	config       *config.Holder
+	clock        clock.Clock
+}
+
+// NewOIDCService creates a new OIDC service
+func NewOIDCService(
+	userRepo repository.UserRepository,
+	identityRepo repository.IdentityRepository,
+	verifier *oidc.Verifier,
+	authService *AuthService,
+	config *config.Holder,
+	clk clock.Clock,
+) *OIDCService {
+	return &OIDCService{
+		userRepo:     userRepo,
+		identityRepo: identityRepo,
+		verifier:     verifier,
+		authService:  authService,
+		config:       config,
+		clock:        clk,
+	}
+}
+
+// OIDCLoginRequest represents the request for signing in with a provider
+type OIDCLoginRequest struct {
+	IDToken string `json:"id_token" binding:"required"`
+	Nonce   string `json:"nonce"`
+	// Name is used for new accounts when the token has none, as Apple only
+	// shares the user's name with the app on the first sign-in
+	Name string `json:"name" binding:"max=100"`
+}
+
+// Login verifies the provider's ID token and signs the user in. Users are
+// matched by the provider's subject, then by verified email, and are created
+// when neither matches. Accounts whose own address is not verified yet are
+// never linked. Users with two-factor authentication get a challenge to
+// complete with AuthService.VerifyMFA, as for a password login. created
+// reports whether a new account was created.
+func (s *OIDCService) Login(ctx context.Context, provider string, req OIDCLoginRequest) (response *LoginResponse, created bool, err error) {
+	ctx, span := tracing.Start(ctx, "OIDCService.Login")
+	defer span.End()
+
+	claims, err := s.verifier.Verify(ctx, provider, req.IDToken, req.Nonce)
+	switch {
+	case errors.Is(err, oidc.ErrUnknownProvider), errors.Is(err, oidc.ErrProviderDisabled):
+		return nil, false, errors.New("provider not found")
+	case errors.Is(err, oidc.ErrKeysUnavailable):
+		log.Ctx(ctx).Error().Err(err).Str("provider", provider).Msg("Failed to fetch provider keys")
+		return nil, false, errors.New("provider unavailable")
+	case err != nil:
+		log.Ctx(ctx).Info().Err(err).Str("provider", provider).Msg("Rejected ID token")
+		return nil, false, errors.New("invalid ID token")
+	}
+
+	now := s.clock.Now().UTC()
+
+	// A returning user
+	identity, err := s.identityRepo.FindByProviderSubject(ctx, provider, claims.Subject)
+	if err != nil {
+		return nil, false, err
+	}
+	if identity != nil {
+		user, err := s.userRepo.FindByID(ctx, identity.UserID)
+		if err != nil {
+			return nil, false, err
+		}
+		if user == nil {
+			return nil, false, errors.New("account no longer exists")
+		}
+
+		identity.LastLoginAt = now
+		if claims.Email != "" {
+			identity.Email = claims.Email
+		}
+		if err := s.identityRepo.Update(ctx, identity); err != nil {
+			return nil, false, err
+		}
+
+		response, err := s.respond(ctx, provider, user)
+		return response, false, err
+	}
+
+	// Only an address the provider has verified may be linked to an account,
+	// otherwise anyone could take over an account by claiming its email
+	if !claims.EmailVerified {
+		return nil, false, errors.New("email not verified")
+	}
+
+	identity = &models.Identity{
+		Provider:    provider,
+		Subject:     claims.Subject,
+		Email:       claims.Email,
+		LastLoginAt: now,
+	}
+
+	// An existing user signing in with the provider for the first time
+	user, err := s.userRepo.FindByEmail(ctx, claims.Email)
+	if err != nil {
+		return nil, false, err
+	}
+	if user != nil {
+		// Anyone can register an address they do not own and wait for its
+		// owner to sign in with the provider, keeping the password they chose.
+		// The owner has to verify the address or reset the password first.
+		if !user.EmailVerified() {
+			log.Ctx(ctx).Info().Uint("userID", user.ID).Str("provider", provider).Msg("Refused to link sign-in identity to unverified account")
+			return nil, false, errors.New("account not verified")
+		}
+
+		identity.UserID = user.ID
+		if err := s.identityRepo.Create(ctx, identity); err != nil {
+			return nil, false, err
+		}
+		log.Ctx(ctx).Info().Uint("userID", user.ID).Str("provider", provider).Msg("Linked sign-in identity to existing account")
+
+		response, err := s.respond(ctx, provider, user)
+		return response, false, err
+	}
+
+	// A new user, who has no password until they set one
+	if !s.config.Get().Features.Registration {
+		return nil, false, errors.New("registration disabled")
+	}
+	// Deleted accounts keep their email until they are purged
+	exists, err := s.userRepo.ExistsByEmail(ctx, claims.Email)
+	if err != nil {
+		return nil, false, err
+	}
+	if exists {
+		return nil, false, errors.New("email already registered")
+	}
+
+	name := claims.Name
+	if name == "" {
+		name = req.Name
+	}
+	if name == "" {
+		name = claims.Email
+	}
+
+	user = &models.User{
+		Name:            name,
+		Email:           claims.Email,
+		EmailVerifiedAt: &now,
+	}
+	if err := s.identityRepo.CreateWithUser(ctx, user, identity); err != nil {
+		return nil, false, err
+	}
+	log.Ctx(ctx).Info().Uint("userID", user.ID).Str("provider", provider).Msg("Created account from sign-in identity")
+
+	response, err = s.respond(ctx, provider, user)
+	return response, true, err
+}
+
+// ListIdentities returns the identities linked to the user
+func (s *OIDCService) ListIdentities(ctx context.Context, userID uint) ([]models.IdentityResponse, error) {
+	ctx, span := tracing.Start(ctx, "OIDCService.ListIdentities")
+	defer span.End()
+
+	identities, err := s.identityRepo.FindByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	responses := make([]models.IdentityResponse, len(identities))
+	for i := range identities {
+		responses[i] = identities[i].ToResponse()
+	}
+	return responses, nil
+}
+
+// respond issues the access token, or the challenge for the second step to
+// users with two-factor authentication, which the provider does not replace
+func (s *OIDCService) respond(ctx context.Context, provider string, user *models.User) (*LoginResponse, error) {
+	if user.MFAEnabled() {
+		challenge, err := s.authService.mfa.CreateChallenge(ctx, user)
+		if err != nil {
+			return nil, err
+		}
+		return &LoginResponse{MFAChallengeResponse: challenge}, nil
+	}
+
+	token, err := s.authService.issueToken(user)
+	if err != nil {
+		return nil, err
+	}
+	metrics.Login(provider)
+	return &LoginResponse{AuthResponse: &AuthResponse{
+		Token: token,
+		User:  user.ToResponse(),
+	}}, nil
+}
@@ -0,0 +1,185 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/accountmail"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/clock"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/config"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/lockout"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/mailer"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/models"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/repository"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/tracing"
+	"github.com/rs/zerolog/log"
+)
+
+// securityEventLimit is how many security events a user can review
+const securityEventLimit = 50
+
+// SecurityService guards password logins against guessing and keeps the
+// security events of each account
+type SecurityService struct {
+	userRepo  repository.UserRepository
+	eventRepo repository.SecurityEventRepository
+	guard     *lockout.Guard
+	mailer    mailer.Mailer
+	config    *config.Holder
+	clock     clock.Clock
+}
+
+// NewSecurityService creates a new security service
+func NewSecurityService(
+	userRepo repository.UserRepository,
+	eventRepo repository.SecurityEventRepository,
+	guard *lockout.Guard,
+	mail mailer.Mailer,
+	config *config.Holder,
+	clk clock.Clock,
+) *SecurityService {
+	return &SecurityService{
+		userRepo:  userRepo,
+		eventRepo: eventRepo,
+		guard:     guard,
+		mailer:    mail,
+		config:    config,
+		clock:     clk,
+	}
+}
+
+// UnlockAccountRequest represents the request for unlocking an account with
+// the emailed token
+type UnlockAccountRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// CheckLogin returns a *lockout.Error if a login to the account with the
+// email from the client IP has to wait
+func (s *SecurityService) CheckLogin(ctx context.Context, email, ip string) error {
+	ctx, span := tracing.Start(ctx, "SecurityService.CheckLogin")
+	defer span.End()
+
+	return s.guard.Check(ctx, email, ip)
+}
+
+// LoginFailed records a failed login. user is nil when no account has the
+// email, which is counted all the same so that the answers do not reveal
+// which accounts exist. Problems are logged rather than returned, so that
+// the caller always answers with invalid credentials.
+func (s *SecurityService) LoginFailed(ctx context.Context, user *models.User, email, ip string) {
+	ctx, span := tracing.Start(ctx, "SecurityService.LoginFailed")
+	defer span.End()
+
+	outcome, err := s.guard.Failure(ctx, email, ip)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msg("Failed to record failed login")
+	}
+	if outcome.IPLocked {
+		log.Ctx(ctx).Warn().Str("ip", ip).Msg("Client IP locked after too many failed logins")
+	}
+
+	if user == nil {
+		return
+	}
+	s.record(ctx, user.ID, models.SecurityEventLoginFailed, ip)
+
+	if !outcome.AccountLocked {
+		return
+	}
+	log.Ctx(ctx).Warn().Uint("userID", user.ID).Msg("Account locked after too many failed logins")
+	s.record(ctx, user.ID, models.SecurityEventAccountLocked, ip)
+	if err := s.sendUnlock(ctx, user, outcome.UnlockToken); err != nil {
+		log.Ctx(ctx).Error().Err(err).Uint("userID", user.ID).Msg("Failed to send unlock email")
+	}
+}
+
+// LoginSucceeded records a successful login and forgets the account's failed
+// logins
+func (s *SecurityService) LoginSucceeded(ctx context.Context, user *models.User, ip string) {
+	ctx, span := tracing.Start(ctx, "SecurityService.LoginSucceeded")
+	defer span.End()
+
+	if err := s.guard.Success(ctx, user.Email); err != nil {
+		log.Ctx(ctx).Error().Err(err).Uint("userID", user.ID).Msg("Failed to reset failed logins")
+	}
+	s.record(ctx, user.ID, models.SecurityEventLoginSucceeded, ip)
+}
+
+// Unlock unlocks the account the token was emailed for
+func (s *SecurityService) Unlock(ctx context.Context, req UnlockAccountRequest, ip string) error {
+	ctx, span := tracing.Start(ctx, "SecurityService.Unlock")
+	defer span.End()
+
+	email, err := s.guard.Unlock(ctx, req.Token)
+	if err != nil {
+		return err
+	}
+	if email == "" {
+		return errors.New("invalid or expired token")
+	}
+
+	user, err := s.userRepo.FindByEmail(ctx, email)
+	if err != nil {
+		return err
+	}
+	if user != nil {
+		s.record(ctx, user.ID, models.SecurityEventAccountUnlocked, ip)
+	}
+	return nil
+}
+
+// ListEvents returns the user's latest security events, newest first
+func (s *SecurityService) ListEvents(ctx context.Context, userID uint) ([]models.SecurityEventResponse, error) {
+	ctx, span := tracing.Start(ctx, "SecurityService.ListEvents")
+	defer span.End()
+
+	events, err := s.eventRepo.FindByUserID(ctx, userID, securityEventLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	responses := make([]models.SecurityEventResponse, len(events))
+	for i := range events {
+		responses[i] = events[i].ToResponse()
+	}
+	return responses, nil
+}
+
+// record saves a security event, logging rather than returning failures
+func (s *SecurityService) record(ctx context.Context, userID uint, eventType, ip string) {
+	event := &models.SecurityEvent{
+		UserID:    userID,
+		Type:      eventType,
+		IPAddress: ip,
+		CreatedAt: s.clock.Now().UTC(),
+	}
+	if err := s.eventRepo.Create(ctx, event); err != nil {
+		log.Ctx(ctx).Error().Err(err).Uint("userID", userID).Str("type", eventType).Msg("Failed to record security event")
+	}
+}
+
+// sendUnlock emails the link that unlocks the account
+func (s *SecurityService) sendUnlock(ctx context.Context, user *models.User, token string) error {
+	cfg := s.config.Get().Lockout
+
+	link, err := accountmail.Link(cfg.UnlockURL, token)
+	if err != nil {
+		return err
+	}
+	rendered, err := accountmail.Unlock(accountmail.UnlockData{
+		Name:     user.Name,
+		Link:     link,
+		Duration: cfg.Duration,
+	})
+	if err != nil {
+		return err
+	}
+
+	return s.mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: rendered.Subject,
+		HTML:    rendered.HTML,
+		Text:    rendered.Text,
+	})
+}
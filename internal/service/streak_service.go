@@ -3,10 +3,13 @@ package service
 import (
 	"context"
 	"errors"
-	"time"
 
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/clock"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/database"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/metrics"
 	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/models"
 	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/repository"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/tracing"
 )
 
 // StreakService handles streak-related business logic
@@ -14,14 +17,16 @@ type StreakService struct {
 	habitRepo   repository.HabitRepository
 	streakRepo  repository.StreakRepository
 	checkInRepo repository.CheckInRepository
+	clock       clock.Clock
 }
 
 // NewStreakService creates a new streak service
-func NewStreakService(habitRepo repository.HabitRepository, streakRepo repository.StreakRepository, checkInRepo repository.CheckInRepository) *StreakService {
+func NewStreakService(habitRepo repository.HabitRepository, streakRepo repository.StreakRepository, checkInRepo repository.CheckInRepository, clk clock.Clock) *StreakService {
 	return &StreakService{
 		habitRepo:   habitRepo,
 		streakRepo:  streakRepo,
 		checkInRepo: checkInRepo,
+		clock:       clk,
 	}
 }
 
@@ -32,6 +37,9 @@ type CreateStreakRequest struct {
 
 // CreateStreak creates a new streak for a habit
 func (s *StreakService) CreateStreak(ctx context.Context, userID uint, habitID uint, req CreateStreakRequest) (*models.HabitStreakResponse, error) {
+	ctx, span := tracing.Start(ctx, "StreakService.CreateStreak")
+	defer span.End()
+
 	// Verify the habit belongs to the user
 	habit, err := s.habitRepo.FindByID(ctx, habitID)
 	if err != nil {
@@ -56,7 +64,7 @@ func (s *StreakService) CreateStreak(ctx context.Context, userID uint, habitID u
 	}
 
 	// Create a new streak
-	now := time.Now().UTC()
+	now := s.clock.Now().UTC()
 	streak := models.HabitStreak{
 		HabitID:       habitID,
 		TargetDays:    req.TargetDays,
@@ -69,6 +77,7 @@ func (s *StreakService) CreateStreak(ctx context.Context, userID uint, habitID u
 	if err := s.streakRepo.Create(ctx, &streak); err != nil {
 		return nil, err
 	}
+	metrics.StreakStarted()
 
 	// Return the created streak
 	response := streak.ToResponse()
@@ -77,6 +86,10 @@ func (s *StreakService) CreateStreak(ctx context.Context, userID uint, habitID u
 
 // ListStreaks lists all streaks for a habit
 func (s *StreakService) ListStreaks(ctx context.Context, userID uint, habitID uint) ([]models.HabitStreakResponse, error) {
+	ctx, span := tracing.Start(ctx, "StreakService.ListStreaks")
+	defer span.End()
+	ctx = database.PreferReplica(ctx)
+
 	// Verify the habit belongs to the user
 	habit, err := s.habitRepo.FindByID(ctx, habitID)
 	if err != nil {
@@ -103,6 +116,9 @@ func (s *StreakService) ListStreaks(ctx context.Context, userID uint, habitID ui
 
 // GetCurrentStreak gets the current active streak for a habit
 func (s *StreakService) GetCurrentStreak(ctx context.Context, userID uint, habitID uint) (*models.HabitStreakResponse, error) {
+	ctx, span := tracing.Start(ctx, "StreakService.GetCurrentStreak")
+	defer span.End()
+
 	// Verify the habit belongs to the user
 	habit, err := s.habitRepo.FindByID(ctx, habitID)
 	if err != nil {
@@ -7,8 +7,11 @@ import (
 	"sort"
 	"time"
 
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/clock"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/database"
 	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/models"
 	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/repository"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/tracing"
 )
 
 type UserService struct {
@@ -17,6 +20,7 @@ type UserService struct {
 	streakRepo      repository.StreakRepository
 	checkInRepo     repository.CheckInRepository
 	achievementRepo repository.AchievementRepository
+	clock           clock.Clock
 }
 
 func NewUserService(
@@ -25,6 +29,7 @@ func NewUserService(
 	streakRepo repository.StreakRepository,
 	checkInRepo repository.CheckInRepository,
 	achievementRepo repository.AchievementRepository,
+	clk clock.Clock,
 ) *UserService {
 	return &UserService{
 		userRepo:        userRepo,
@@ -32,6 +37,7 @@ func NewUserService(
 		streakRepo:      streakRepo,
 		checkInRepo:     checkInRepo,
 		achievementRepo: achievementRepo,
+		clock:           clk,
 	}
 }
 
@@ -40,6 +46,11 @@ type UpdateProfileRequest struct {
 }
 
 func (s *UserService) GetProfile(ctx context.Context, userID uint) (*models.UserProfileResponse, error) {
+	ctx, span := tracing.Start(ctx, "UserService.GetProfile")
+	defer span.End()
+	// The analytics tolerate replica lag, unless the user just wrote
+	ctx = database.PreferReplica(ctx)
+
 	user, err := s.userRepo.FindByID(ctx, userID)
 	if err != nil {
 		return nil, err
@@ -58,7 +69,7 @@ func (s *UserService) GetProfile(ctx context.Context, userID uint) (*models.User
 		return nil, err
 	}
 
-	overview := s.calculateOverviewStats(user, habits, achievements)
+	overview := s.calculateOverviewStats(ctx, user, habits, achievements)
 	streakInsights := s.calculateStreakInsights(ctx, habits)
 	consistencyChart := s.calculateConsistencyChart(ctx, habits, 30) // Last 30 days
 	topHabits := s.calculateTopHabits(ctx, habits)
@@ -68,6 +79,12 @@ func (s *UserService) GetProfile(ctx context.Context, userID uint) (*models.User
 	improvementTrend := s.calculateImprovementTrend(consistencyChart)
 	nextMilestone := s.predictNextMilestone(ctx, habits, achievements)
 
+	// The statistics skip the lookups that fail, so a request that ran out of
+	// time would otherwise return a profile with zeros in it
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	profile := &models.UserProfileResponse{
 		ID:        user.ID,
 		Email:     user.Email,
@@ -88,8 +105,8 @@ func (s *UserService) GetProfile(ctx context.Context, userID uint) (*models.User
 	return profile, nil
 }
 
-func (s *UserService) calculateOverviewStats(user *models.User, habits []models.Habit, achievements []models.Achievement) models.OverviewStats {
-	now := time.Now()
+func (s *UserService) calculateOverviewStats(ctx context.Context, user *models.User, habits []models.Habit, achievements []models.Achievement) models.OverviewStats {
+	now := s.clock.Now()
 	daysSinceJoined := int(now.Sub(user.CreatedAt).Hours() / 24)
 
 	activeHabits := 0
@@ -100,12 +117,12 @@ func (s *UserService) calculateOverviewStats(user *models.User, habits []models.
 	}
 
 	// Calculate total check-ins across all habits
-	totalCheckIns := s.calculateTotalCheckIns(context.Background(), habits)
+	totalCheckIns := s.calculateTotalCheckIns(ctx, habits)
 
 	// Calculate consistency percentages
-	overallConsistency := s.calculateConsistencyForPeriod(context.Background(), habits, 0) // All time
-	weeklyConsistency := s.calculateConsistencyForPeriod(context.Background(), habits, 7)
-	monthlyConsistency := s.calculateConsistencyForPeriod(context.Background(), habits, 30)
+	overallConsistency := s.calculateConsistencyForPeriod(ctx, habits, 0) // All time
+	weeklyConsistency := s.calculateConsistencyForPeriod(ctx, habits, 7)
+	monthlyConsistency := s.calculateConsistencyForPeriod(ctx, habits, 30)
 
 	return models.OverviewStats{
 		TotalHabits:        len(habits),
@@ -167,9 +184,12 @@ func (s *UserService) calculateStreakInsights(ctx context.Context, habits []mode
 
 func (s *UserService) calculateConsistencyChart(ctx context.Context, habits []models.Habit, days int) []models.ConsistencyDataPoint {
 	var dataPoints []models.ConsistencyDataPoint
-	now := time.Now().UTC().Truncate(24 * time.Hour)
+	now := s.clock.Now().UTC().Truncate(24 * time.Hour)
 
 	for i := days - 1; i >= 0; i-- {
+		if ctx.Err() != nil {
+			break
+		}
 		date := now.AddDate(0, 0, -i)
 		dateStr := date.Format("2006-01-02")
 
@@ -297,7 +317,7 @@ func (s *UserService) calculateConsistencyForPeriod(ctx context.Context, habits
 		return 0
 	}
 
-	now := time.Now().UTC()
+	now := s.clock.Now().UTC()
 	var totalPossible, totalActual int
 
 	for _, habit := range habits {
@@ -435,6 +455,9 @@ func (s *UserService) predictNextMilestone(ctx context.Context, habits []models.
 
 // UpdateProfile updates the user profile
 func (s *UserService) UpdateProfile(ctx context.Context, userID uint, req UpdateProfileRequest) (*models.UserResponse, error) {
+	ctx, span := tracing.Start(ctx, "UserService.UpdateProfile")
+	defer span.End()
+
 	// Find the user by ID
 	user, err := s.userRepo.FindByID(ctx, userID)
 	if err != nil {
@@ -0,0 +1,224 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/accountmail"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/clock"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/config"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/mailer"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/models"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/repository"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/tracing"
+	"github.com/rs/zerolog/log"
+)
+
+// ThrottledError is returned when a verification email was requested too
+// soon after the last one, or too often in a day
+type ThrottledError struct {
+	RetryAfter time.Duration
+}
+
+func (e *ThrottledError) Error() string {
+	return "verification email throttled"
+}
+
+// VerificationService confirms that users own their email address
+type VerificationService struct {
+	userRepo         repository.UserRepository
+	verificationRepo repository.EmailVerificationRepository
+	mailer           mailer.Mailer
+	config           *config.Holder
+	clock            clock.Clock
+}
+
+// NewVerificationService creates a new verification service
+func NewVerificationService(
+	userRepo repository.UserRepository,
+	verificationRepo repository.EmailVerificationRepository,
+	mail mailer.Mailer,
+	config *config.Holder,
+	clk clock.Clock,
+) *VerificationService {
+	return &VerificationService{
+		userRepo:         userRepo,
+		verificationRepo: verificationRepo,
+		mailer:           mail,
+		config:           config,
+		clock:            clk,
+	}
+}
+
+// VerifyEmailRequest represents the request for confirming an email address
+type VerifyEmailRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// ResendVerificationRequest represents the request for a new verification
+// email for an account that is not signed in
+type ResendVerificationRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// Send creates a verification token for the user and emails it. Earlier
+// tokens stay valid until they expire, so any link the user already received
+// still works.
+func (s *VerificationService) Send(ctx context.Context, user *models.User) error {
+	ctx, span := tracing.Start(ctx, "VerificationService.Send")
+	defer span.End()
+
+	cfg := s.config.Get().Verification
+	now := s.clock.Now().UTC()
+
+	recent, err := s.verificationRepo.FindByUserIDSince(ctx, user.ID, now.Add(-24*time.Hour))
+	if err != nil {
+		return err
+	}
+	if len(recent) > 0 {
+		if wait := recent[0].CreatedAt.Add(cfg.ResendInterval).Sub(now); wait > 0 {
+			return &ThrottledError{RetryAfter: wait}
+		}
+	}
+	if len(recent) >= cfg.DailyLimit {
+		// The oldest email of the last day has to age out first
+		oldest := recent[len(recent)-1]
+		return &ThrottledError{RetryAfter: oldest.CreatedAt.Add(24 * time.Hour).Sub(now)}
+	}
+
+	token, err := newVerificationToken()
+	if err != nil {
+		return err
+	}
+	link, err := accountmail.Link(cfg.LinkURL, token)
+	if err != nil {
+		return err
+	}
+
+	verification := &models.EmailVerification{
+		UserID:    user.ID,
+		TokenHash: hashVerificationToken(token),
+		ExpiresAt: now.Add(cfg.TokenLifetime),
+		CreatedAt: now,
+	}
+	if err := s.verificationRepo.Create(ctx, verification); err != nil {
+		return err
+	}
+
+	rendered, err := accountmail.Verification(accountmail.VerificationData{
+		Name:     user.Name,
+		Link:     link,
+		Lifetime: cfg.TokenLifetime,
+	})
+	if err != nil {
+		return err
+	}
+
+	return s.mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: rendered.Subject,
+		HTML:    rendered.HTML,
+		Text:    rendered.Text,
+	})
+}
+
+// Resend sends a new verification email to the signed-in user
+func (s *VerificationService) Resend(ctx context.Context, userID uint) error {
+	ctx, span := tracing.Start(ctx, "VerificationService.Resend")
+	defer span.End()
+
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return errors.New("user not found")
+	}
+	if user.EmailVerified() {
+		return errors.New("email already verified")
+	}
+
+	return s.Send(ctx, user)
+}
+
+// ResendByEmail sends a new verification email to the account with the
+// address, if there is an unverified one. Nothing is reported back, so the
+// endpoint does not reveal which addresses have an account.
+func (s *VerificationService) ResendByEmail(ctx context.Context, req ResendVerificationRequest) error {
+	ctx, span := tracing.Start(ctx, "VerificationService.ResendByEmail")
+	defer span.End()
+
+	user, err := s.userRepo.FindByEmail(ctx, req.Email)
+	if err != nil {
+		return err
+	}
+	if user == nil || user.EmailVerified() {
+		return nil
+	}
+
+	var throttled *ThrottledError
+	if err := s.Send(ctx, user); errors.As(err, &throttled) {
+		log.Ctx(ctx).Info().Uint("userID", user.ID).Msg("Verification email throttled")
+	} else if err != nil {
+		return err
+	}
+	return nil
+}
+
+// Verify confirms the address of the account the token was sent to
+func (s *VerificationService) Verify(ctx context.Context, req VerifyEmailRequest) (*models.UserResponse, error) {
+	ctx, span := tracing.Start(ctx, "VerificationService.Verify")
+	defer span.End()
+
+	verification, err := s.verificationRepo.FindByTokenHash(ctx, hashVerificationToken(req.Token))
+	if err != nil {
+		return nil, err
+	}
+	now := s.clock.Now().UTC()
+	if verification == nil || !now.Before(verification.ExpiresAt) {
+		return nil, errors.New("invalid or expired token")
+	}
+
+	user, err := s.userRepo.FindByID(ctx, verification.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, errors.New("invalid or expired token")
+	}
+
+	if !user.EmailVerified() {
+		user.EmailVerifiedAt = &now
+		if err := s.userRepo.Update(ctx, user); err != nil {
+			return nil, err
+		}
+	}
+
+	// The remaining tokens are of no further use
+	if err := s.verificationRepo.DeleteByUserID(ctx, user.ID); err != nil {
+		return nil, err
+	}
+
+	response := user.ToResponse()
+	return &response, nil
+}
+
+// newVerificationToken returns a random URL-safe token
+func newVerificationToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// hashVerificationToken returns the hash stored in place of the token
+func hashVerificationToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
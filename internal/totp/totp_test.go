@@ -0,0 +1,69 @@
+package totp_test
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/totp"
+)
+
+// rfcSecret is the SHA1 key from the RFC 6238 test vectors
+var rfcSecret = base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+
+func TestCodeMatchesRFC6238(t *testing.T) {
+	// The RFC lists eight digits; six digit codes are the last six of them
+	for unix, want := range map[int64]string{
+		59:          "287082",
+		1111111109:  "081804",
+		1111111111:  "050471",
+		1234567890:  "005924",
+		2000000000:  "279037",
+		20000000000: "353130",
+	} {
+		got, err := totp.Code(rfcSecret, totp.Step(time.Unix(unix, 0)))
+		if err != nil {
+			t.Fatalf("Code: %v", err)
+		}
+		if got != want {
+			t.Errorf("code at %d = %s, want %s", unix, got, want)
+		}
+	}
+}
+
+func TestValidateAllowsOneStepOfDrift(t *testing.T) {
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		t.Fatalf("GenerateSecret: %v", err)
+	}
+	now := time.Date(2024, 3, 10, 9, 0, 10, 0, time.UTC)
+	current := totp.Step(now)
+
+	for offset, valid := range map[int64]bool{-2: false, -1: true, 0: true, 1: true, 2: false} {
+		code, err := totp.Code(secret, current+offset)
+		if err != nil {
+			t.Fatalf("Code: %v", err)
+		}
+		step, ok, err := totp.Validate(secret, code, now)
+		if err != nil {
+			t.Fatalf("Validate: %v", err)
+		}
+		if ok != valid || (ok && step != current+offset) {
+			t.Errorf("code for step %+d: valid = %v at step %d, want %v", offset, ok, step, valid)
+		}
+	}
+
+	if _, ok, _ := totp.Validate(secret, "12345", now); ok {
+		t.Error("a five digit code was accepted")
+	}
+}
+
+func TestURI(t *testing.T) {
+	uri := totp.URI("Consistency", "ada@example.com", "JBSWY3DPEHPK3PXP")
+	for _, part := range []string{"otpauth://totp/Consistency:ada@example.com?", "secret=JBSWY3DPEHPK3PXP", "issuer=Consistency", "digits=6", "period=30"} {
+		if !strings.Contains(uri, part) {
+			t.Errorf("URI %s does not contain %s", uri, part)
+		}
+	}
+}
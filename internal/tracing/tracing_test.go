@@ -0,0 +1,112 @@
+package tracing_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/config"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/middleware"
+	"github.com/WomenMobileDev/WMD.Consistency.Service/internal/tracing"
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"gorm.io/gorm"
+)
+
+func TestRequestAndQuerySpans(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	if err := tracing.InstrumentDatabase(db); err != nil {
+		t.Fatalf("instrument database: %v", err)
+	}
+	if err := db.Exec("CREATE TABLE notes (id integer)").Error; err != nil {
+		t.Fatalf("create table: %v", err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(middleware.Tracing())
+	r.GET("/notes/:id", func(c *gin.Context) {
+		ctx, span := tracing.Start(c.Request.Context(), "NoteService.GetNote")
+		defer span.End()
+		var ids []int
+		db.WithContext(ctx).Table("notes").Where("id = ?", c.Param("id")).Pluck("id", &ids)
+		c.Status(http.StatusNoContent)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/notes/7", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := map[string]sdktrace.ReadOnlySpan{}
+	for _, span := range recorder.Ended() {
+		spans[span.Name()] = span
+	}
+	request, service, query := spans["GET /notes/:id"], spans["NoteService.GetNote"], spans["gorm.query notes"]
+	if request == nil || service == nil || query == nil {
+		t.Fatalf("missing spans, got %v", spans)
+	}
+
+	if got := request.SpanContext().TraceID().String(); got != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("request span trace ID = %s, want the one from traceparent", got)
+	}
+	if got := request.Parent().SpanID().String(); got != "00f067aa0ba902b7" {
+		t.Errorf("request span parent = %s, want the one from traceparent", got)
+	}
+	if service.Parent().SpanID() != request.SpanContext().SpanID() {
+		t.Error("service span is not a child of the request span")
+	}
+	if query.Parent().SpanID() != service.SpanContext().SpanID() {
+		t.Error("query span is not a child of the service span")
+	}
+	for _, attr := range query.Attributes() {
+		if attr.Key == "db.query.text" && strings.Contains(attr.Value.AsString(), "7") {
+			t.Errorf("query text %q contains the query's values", attr.Value.AsString())
+		}
+	}
+}
+
+func TestInitWritesTracesToFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "traces.json")
+	cfg := &config.Config{
+		Server: config.ServerConfig{Env: "test"},
+		Monitoring: config.MonitoringConfig{
+			TracingEnabled:     true,
+			TracingExporter:    config.TracingExporterStdout,
+			TracingFile:        path,
+			TracingSampleRatio: 1,
+		},
+	}
+
+	shutdown, err := tracing.Init(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("init tracing: %v", err)
+	}
+	_, span := tracing.Start(context.Background(), "HabitService.ListHabits")
+	span.End()
+	if err := shutdown(context.Background()); err != nil {
+		t.Fatalf("shut down tracing: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read traces: %v", err)
+	}
+	if !strings.Contains(string(data), `"Name":"HabitService.ListHabits"`) {
+		t.Errorf("trace file does not contain the span:\n%s", data)
+	}
+}